@@ -0,0 +1,169 @@
+package analytics
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/ito-system/clear-up-share/backend/database"
+	"github.com/ito-system/clear-up-share/backend/models"
+)
+
+// 送信するイベントの種類
+const (
+	EventGroupCreated       = "group_created"
+	EventExpenseAdded       = "expense_added"
+	EventSettlementRecorded = "settlement_recorded"
+)
+
+// 送信先の種類
+const (
+	SinkPostgres = "postgres" // analytics_events テーブルに保存
+	SinkHTTP     = "http"     // Segment 互換の track API に送信
+)
+
+const (
+	defaultHTTPURL = "https://api.segment.io/v1/track"
+	queueSize      = 1000             // 送信待ちイベントの最大数（超えた分は破棄）
+	httpTimeout    = 10 * time.Second // 1回の送信のタイムアウト
+)
+
+// event は送信待ちのイベント
+type event struct {
+	Name        string
+	AnonymousID string
+	Properties  map[string]interface{}
+	OccurredAt  time.Time
+}
+
+var (
+	sink     string
+	httpURL  string
+	writeKey string
+	salt     []byte
+	queue    chan event
+	client   = &http.Client{Timeout: httpTimeout}
+)
+
+// Init は環境変数から送信先を設定し、送信ワーカーを起動します
+// ANALYTICS_SINK が未設定の場合はイベントを一切収集しません（オプトイン）
+func Init() {
+	sink = os.Getenv("ANALYTICS_SINK")
+	switch sink {
+	case "":
+		return
+	case SinkPostgres:
+	case SinkHTTP:
+		httpURL = os.Getenv("ANALYTICS_HTTP_URL")
+		if httpURL == "" {
+			httpURL = defaultHTTPURL
+		}
+		writeKey = os.Getenv("ANALYTICS_WRITE_KEY")
+	default:
+		log.Printf("Warning: unknown ANALYTICS_SINK %q, analytics disabled", sink)
+		sink = ""
+		return
+	}
+
+	// 匿名化に使うソルト（未設定の場合は起動ごとに変わるため、再起動をまたいだ集計はできません）
+	salt = []byte(os.Getenv("ANALYTICS_SALT"))
+	if len(salt) == 0 {
+		log.Println("Warning: ANALYTICS_SALT not set, anonymous IDs will change on restart")
+		salt = []byte(strconv.FormatInt(time.Now().UnixNano(), 10))
+	}
+
+	queue = make(chan event, queueSize)
+	go func() {
+		for e := range queue {
+			if err := send(e); err != nil {
+				log.Printf("Failed to send analytics event %s: %v", e.Name, err)
+			}
+		}
+	}()
+}
+
+// Enabled は利用状況の収集が有効かを返します
+func Enabled() bool {
+	return sink != ""
+}
+
+// Anonymize はIDをソルト付きハッシュで匿名化します
+func Anonymize(kind string, id uint) string {
+	mac := hmac.New(sha256.New, salt)
+	fmt.Fprintf(mac, "%s:%d", kind, id)
+	return hex.EncodeToString(mac.Sum(nil))[:32]
+}
+
+// Track はイベントを送信待ちに追加します（リクエスト処理を妨げないよう、待ちが溢れた場合は破棄します）
+// properties にはユーザー名・金額・説明などの個人情報を含めないでください
+func Track(name string, userID uint, properties map[string]interface{}) {
+	if !Enabled() {
+		return
+	}
+
+	e := event{
+		Name:        name,
+		AnonymousID: Anonymize("user", userID),
+		Properties:  properties,
+		OccurredAt:  time.Now().UTC(),
+	}
+
+	select {
+	case queue <- e:
+	default:
+		log.Printf("Analytics queue is full, dropping event %s", name)
+	}
+}
+
+// send はイベントを設定された送信先に送信します
+func send(e event) error {
+	properties, err := json.Marshal(e.Properties)
+	if err != nil {
+		return err
+	}
+
+	if sink == SinkPostgres {
+		return database.DB.Create(&models.AnalyticsEvent{
+			Event:       e.Name,
+			AnonymousID: e.AnonymousID,
+			Properties:  string(properties),
+			OccurredAt:  e.OccurredAt,
+		}).Error
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"anonymousId": e.AnonymousID,
+		"event":       e.Name,
+		"properties":  json.RawMessage(properties),
+		"timestamp":   e.OccurredAt.Format(time.RFC3339),
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, httpURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.SetBasicAuth(writeKey, "")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -42,18 +42,100 @@ func InitDB() {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
 
+	// 仮メンバー導入前の Username / Email の一意インデックスを削除
+	// （仮メンバーを除外する部分インデックスに置き換えるため）
+	for _, index := range []string{"idx_users_username", "idx_users_email"} {
+		if DB.Migrator().HasIndex(&models.User{}, index) {
+			if err := DB.Migrator().DropIndex(&models.User{}, index); err != nil {
+				log.Fatalf("Failed to drop index %s: %v", index, err)
+			}
+		}
+	}
+
 	// マイグレーション実行
 	err = DB.AutoMigrate(
 		&models.User{},
 		&models.Group{},
 		&models.Membership{},
+		&models.GroupSettings{},
+		&models.Invitation{},
+		&models.JoinRequest{},
 		&models.Expense{},
 		&models.Split{},
+		&models.ExpenseItem{},
+		&models.ExpenseRevision{},
+		&models.ExpenseAcknowledgement{},
+		&models.Receipt{},
+		&models.GroupOrder{},
+		&models.GroupOrderItem{},
+		&models.GroupOrderClaim{},
+		&models.Category{},
+		&models.Budget{},
+		&models.Tag{},
+		&models.RecurringExpense{},
+		&models.SplitPreset{},
 		&models.Settlement{},
+		&models.SettlementBatch{},
+		&models.SettlementRevision{},
+		&models.SettlementAllocation{},
+		&models.DebtReminder{},
+		&models.PayoutProfile{},
+		&models.StripeAccount{},
+		&models.SettlementPeriod{},
+		&models.Forgiveness{},
+		&models.IOU{},
+		&models.IOUPayment{},
+		&models.ReimbursementRequest{},
+		&models.ActivityLog{},
+		&models.Notification{},
+		&models.NotificationSetting{},
+		&models.SpendingLimit{},
+		&models.OnboardingProgress{},
+		&models.OAuthClient{},
+		&models.OAuthAuthorizationCode{},
+		&models.OAuthGrant{},
+		&models.SupportAccessGrant{},
+		&models.SupportAccessLog{},
+		&models.Webhook{},
+		&models.WebhookDelivery{},
+		&models.AnalyticsEvent{},
 	)
 	if err != nil {
 		log.Fatalf("Failed to migrate database: %v", err)
 	}
 
+	// 大文字小文字だけが異なるメールアドレスで別のアカウントを登録させない
+	// （登録時に小文字に揃える前のアカウントも小文字に揃えてから一意インデックスを作成）
+	if err := DB.Exec("UPDATE users SET email = LOWER(TRIM(email)) WHERE email <> LOWER(TRIM(email))").Error; err != nil {
+		log.Fatalf("Failed to normalize user emails: %v", err)
+	}
+	if err := DB.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_users_registered_email_lower ON users (LOWER(email)) WHERE is_placeholder = false").Error; err != nil {
+		log.Fatalf("Failed to create user email index: %v", err)
+	}
+
+	// 支出の説明文の全文検索用インデックス（handler.SearchExpenses の検索式と同じ式）
+	if err := DB.Exec("CREATE INDEX IF NOT EXISTS idx_expenses_description_fts ON expenses USING GIN (to_tsvector('simple', description))").Error; err != nil {
+		log.Fatalf("Failed to create expense search index: %v", err)
+	}
+
+	// ロール導入前に作成されたグループのオーナーにownerロールを付与
+	err = DB.Exec(
+		"UPDATE memberships SET role = ? FROM groups WHERE memberships.group_id = groups.id AND memberships.user_id = groups.owner_id AND memberships.role <> ?",
+		models.RoleOwner, models.RoleOwner,
+	).Error
+	if err != nil {
+		log.Fatalf("Failed to backfill owner roles: %v", err)
+	}
+
+	// 登録者の記録導入前の支出は支払者が登録したものとみなす
+	if err := DB.Exec("UPDATE expenses SET created_by_id = payer_id WHERE created_by_id = 0").Error; err != nil {
+		log.Fatalf("Failed to backfill expense creators: %v", err)
+	}
+
+	// 支払日の記録導入前の清算は記録した日（UTC）に支払ったものとみなす
+	if err := DB.Exec("UPDATE settlements SET date = ((created_at AT TIME ZONE 'UTC')::date)::timestamp AT TIME ZONE 'UTC' WHERE date IS NULL").Error; err != nil {
+		log.Fatalf("Failed to backfill settlement dates: %v", err)
+	}
+
 	log.Println("Database connected and migrated successfully")
 }
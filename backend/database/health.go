@@ -0,0 +1,61 @@
+package database
+
+import (
+	"context"
+	"log"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	healthCheckInterval = 5 * time.Second // 死活確認の間隔
+	pingTimeout         = 2 * time.Second // 1回の死活確認のタイムアウト
+	failureThreshold    = 3               // 縮退運転に切り替えるまでの連続失敗回数
+)
+
+// degraded はデータベースに接続できず縮退運転（読み取り専用）中かを表します
+var degraded atomic.Bool
+
+// Degraded はデータベースが利用できず縮退運転中かを返します
+func Degraded() bool {
+	return degraded.Load()
+}
+
+// StartHealthCheck はデータベースの死活を定期的に確認し、連続して失敗した場合は縮退運転に切り替えます
+// 接続が回復すると通常運転に戻ります
+func StartHealthCheck() {
+	go func() {
+		failures := 0
+		ticker := time.NewTicker(healthCheckInterval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if err := ping(); err != nil {
+				failures++
+				if failures >= failureThreshold && !degraded.Load() {
+					log.Printf("Database unavailable, switching to degraded mode: %v", err)
+					degraded.Store(true)
+				}
+				continue
+			}
+
+			failures = 0
+			if degraded.Load() {
+				log.Println("Database recovered, leaving degraded mode")
+				degraded.Store(false)
+			}
+		}
+	}()
+}
+
+// ping はデータベースへの接続を確認します
+func ping() error {
+	sqlDB, err := DB.DB()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), pingTimeout)
+	defer cancel()
+	return sqlDB.PingContext(ctx)
+}
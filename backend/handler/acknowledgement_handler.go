@@ -0,0 +1,174 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ito-system/clear-up-share/backend/database"
+	"github.com/ito-system/clear-up-share/backend/models"
+	"gorm.io/gorm"
+)
+
+// AcknowledgementStatus は支出の詳細に含めるメンバーごとの確認状況
+type AcknowledgementStatus struct {
+	MemberID       uint       `json:"memberID"`
+	Username       string     `json:"username"`
+	Acknowledged   bool       `json:"acknowledged"`
+	AcknowledgedAt *time.Time `json:"acknowledgedAt"`
+}
+
+// AcknowledgeExpense は支出を自分が確認済み（内容に問題なし）として記録します
+// 既に確認済みの場合は最初に確認した日時のまま返します。支出を編集すると確認は取り消されます
+// POST /api/v1/groups/:groupID/expenses/:expenseID/ack
+func AcknowledgeExpense(c *gin.Context) {
+	// パスパラメータからgroupIDとexpenseIDを取得
+	groupIDStr := c.Param("groupID")
+	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	expenseIDStr := c.Param("expenseID")
+	expenseID, err := strconv.ParseUint(expenseIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid expense ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// ユーザーがグループのメンバーであることを確認
+	var membership models.Membership
+	if err := database.DB.Preload("Group").Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	// アーカイブ済みのグループは読み取り専用
+	if membership.Group.ArchivedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Group is archived and read-only"})
+		return
+	}
+
+	// Expenseを取得（下書きは確認できない）
+	var expense models.Expense
+	if err := database.DB.Scopes(visibleExpenses(userID, false)).Where("id = ? AND group_id = ?", expenseID, groupID).First(&expense).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Expense not found"})
+		return
+	}
+
+	// 確認を記録（既に確認済みの場合はそのまま）
+	ack := models.ExpenseAcknowledgement{ExpenseID: expense.ID, UserID: userID.(uint)}
+	if err := database.DB.Where("expense_id = ? AND user_id = ?", expense.ID, userID).FirstOrCreate(&ack).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to acknowledge expense"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Expense acknowledged",
+		"acknowledgement": gin.H{
+			"expenseID":      expense.ID,
+			"memberID":       ack.UserID,
+			"acknowledgedAt": ack.CreatedAt,
+		},
+	})
+}
+
+// UnacknowledgeExpense は支出の自分の確認を取り消します（確認していない場合も成功を返します）
+// DELETE /api/v1/groups/:groupID/expenses/:expenseID/ack
+func UnacknowledgeExpense(c *gin.Context) {
+	// パスパラメータからgroupIDとexpenseIDを取得
+	groupIDStr := c.Param("groupID")
+	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	expenseIDStr := c.Param("expenseID")
+	expenseID, err := strconv.ParseUint(expenseIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid expense ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// ユーザーがグループのメンバーであることを確認
+	var membership models.Membership
+	if err := database.DB.Preload("Group").Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	// アーカイブ済みのグループは読み取り専用
+	if membership.Group.ArchivedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Group is archived and read-only"})
+		return
+	}
+
+	// Expenseを取得
+	var expense models.Expense
+	if err := database.DB.Scopes(visibleExpenses(userID, false)).Where("id = ? AND group_id = ?", expenseID, groupID).First(&expense).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Expense not found"})
+		return
+	}
+
+	if err := database.DB.Where("expense_id = ? AND user_id = ?", expense.ID, userID).Delete(&models.ExpenseAcknowledgement{}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove acknowledgement"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Acknowledgement removed"})
+}
+
+// expenseAcknowledgements は支払者・負担者（と確認した他のメンバー）ごとの支出の確認状況を返します
+// 支払者、負担者の順に並べ、支払者・負担者以外で確認したメンバーは確認した順に続けます
+func expenseAcknowledgements(db *gorm.DB, expense models.Expense, splits []models.Split, displayName func(models.User) string) ([]AcknowledgementStatus, error) {
+	var acks []models.ExpenseAcknowledgement
+	if err := db.Preload("User").Where("expense_id = ?", expense.ID).Order("created_at, id").Find(&acks).Error; err != nil {
+		return nil, err
+	}
+	acked := make(map[uint]models.ExpenseAcknowledgement, len(acks))
+	for _, ack := range acks {
+		acked[ack.UserID] = ack
+	}
+
+	statuses := []AcknowledgementStatus{}
+	seen := map[uint]bool{}
+	add := func(user models.User) {
+		if seen[user.ID] {
+			return
+		}
+		seen[user.ID] = true
+		status := AcknowledgementStatus{MemberID: user.ID, Username: displayName(user)}
+		if ack, ok := acked[user.ID]; ok {
+			acknowledgedAt := ack.CreatedAt
+			status.Acknowledged = true
+			status.AcknowledgedAt = &acknowledgedAt
+		}
+		statuses = append(statuses, status)
+	}
+
+	add(expense.Payer)
+	for _, split := range splits {
+		add(split.Debtor)
+	}
+	for _, ack := range acks {
+		add(ack.User)
+	}
+	return statuses, nil
+}
@@ -0,0 +1,151 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ito-system/clear-up-share/backend/database"
+	"github.com/ito-system/clear-up-share/backend/models"
+	"gorm.io/gorm"
+)
+
+// ActivityItem はアクティビティログのレスポンス形式
+type ActivityItem struct {
+	ID         uint            `json:"id"`
+	Action     string          `json:"action"`
+	ActorID    uint            `json:"actorID"`
+	ActorName  string          `json:"actorName"`
+	TargetType string          `json:"targetType"`
+	TargetID   uint            `json:"targetID"`
+	Details    json.RawMessage `json:"details,omitempty"`
+	CreatedAt  time.Time       `json:"createdAt"`
+}
+
+// recordActivity はアクティビティログを記録し、初期設定の進捗を更新して、購読しているWebhookへの配信を登録します
+// 変更操作と同じトランザクション (tx) 内で呼び出してください
+func recordActivity(tx *gorm.DB, groupID, actorID uint, action, targetType string, targetID uint, details map[string]interface{}) error {
+	var detailsJSON string
+	if details != nil {
+		b, err := json.Marshal(details)
+		if err != nil {
+			return err
+		}
+		detailsJSON = string(b)
+	}
+
+	activity := models.ActivityLog{
+		GroupID:    groupID,
+		ActorID:    actorID,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Details:    detailsJSON,
+	}
+	if err := tx.Create(&activity).Error; err != nil {
+		return err
+	}
+
+	// 初期設定のステップの完了を記録
+	if err := trackOnboarding(tx, action, actorID, targetID, details); err != nil {
+		return err
+	}
+
+	// 購読しているWebhookへの配信を登録
+	return enqueueWebhooks(tx, WebhookPayload{
+		Event:      action,
+		GroupID:    groupID,
+		ActorID:    actorID,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Details:    details,
+		OccurredAt: activity.CreatedAt,
+	})
+}
+
+// parsePagination はクエリパラメータ page / limit を解析します
+func parsePagination(c *gin.Context, defaultLimit, maxLimit int) (page, limit int) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	limit, err = strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultLimit)))
+	if err != nil || limit < 1 {
+		limit = defaultLimit
+	}
+	if limit > maxLimit {
+		limit = maxLimit
+	}
+	return page, limit
+}
+
+// GetGroupActivity はグループのアクティビティログを新しい順に取得します
+// GET /api/v1/groups/:groupID/activity
+func GetGroupActivity(c *gin.Context) {
+	// パスパラメータからgroupIDを取得
+	groupIDStr := c.Param("groupID")
+	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// ユーザーがグループのメンバーであることを確認
+	var membership models.Membership
+	if err := database.DB.Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	page, limit := parsePagination(c, 20, 100)
+
+	var total int64
+	if err := database.DB.Model(&models.ActivityLog{}).Where("group_id = ?", groupID).Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count activity"})
+		return
+	}
+
+	var logs []models.ActivityLog
+	if err := database.DB.Preload("Actor").
+		Where("group_id = ?", groupID).
+		Order("created_at DESC, id DESC").
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Find(&logs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch activity"})
+		return
+	}
+
+	activities := make([]ActivityItem, len(logs))
+	for i, l := range logs {
+		activities[i] = ActivityItem{
+			ID:         l.ID,
+			Action:     l.Action,
+			ActorID:    l.ActorID,
+			ActorName:  l.Actor.Username,
+			TargetType: l.TargetType,
+			TargetID:   l.TargetID,
+			CreatedAt:  l.CreatedAt,
+		}
+		if l.Details != "" {
+			activities[i].Details = json.RawMessage(l.Details)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"groupID":    groupID,
+		"activities": activities,
+		"page":       page,
+		"limit":      limit,
+		"total":      total,
+	})
+}
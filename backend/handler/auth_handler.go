@@ -2,6 +2,7 @@ package handler
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/ito-system/clear-up-share/backend/database"
@@ -23,6 +24,16 @@ type LoginInput struct {
 	Password string `json:"password" binding:"required"`
 }
 
+// ExchangeTokenInput はトークン交換リクエストの入力形式
+type ExchangeTokenInput struct {
+	Scope     string `json:"scope" binding:"required,oneof=full read"`
+	GroupID   uint   `json:"groupID"`
+	ExpiresIn int    `json:"expiresIn" binding:"omitempty,min=60"` // 有効期間（秒）
+}
+
+// maxScopedTokenTTL は交換で発行できるトークンの最大有効期間
+const maxScopedTokenTTL = 30 * 24 * time.Hour
+
 // RegisterUser はユーザー登録を処理します
 // POST /api/v1/auth/register
 func RegisterUser(c *gin.Context) {
@@ -42,7 +53,7 @@ func RegisterUser(c *gin.Context) {
 	// ユーザー作成
 	user := models.User{
 		Username:       input.Username,
-		Email:          input.Email,
+		Email:          utils.NormalizeEmail(input.Email),
 		HashedPassword: string(hashedPassword),
 	}
 
@@ -72,7 +83,7 @@ func LoginUser(c *gin.Context) {
 
 	// メールでユーザーを検索
 	var user models.User
-	if err := database.DB.Where("email = ?", input.Email).First(&user).Error; err != nil {
+	if err := database.DB.Where("LOWER(email) = ? AND is_placeholder = ?", utils.NormalizeEmail(input.Email), false).First(&user).Error; err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
 		return
 	}
@@ -90,6 +101,12 @@ func LoginUser(c *gin.Context) {
 		return
 	}
 
+	// 最終ログイン日時を記録
+	if err := database.DB.Model(&user).Update("last_login_at", time.Now()).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record login"})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"token": token,
 		"user": gin.H{
@@ -107,3 +124,79 @@ func LogoutUser(c *gin.Context) {
 		"message": "Logged out successfully",
 	})
 }
+
+// ExchangeToken は現在のトークンを、権限を絞ったトークンに交換します
+// 共有リンクやキオスク端末向けに、読み取り専用・単一グループ限定のトークンを発行できます
+// POST /api/v1/auth/token/exchange
+func ExchangeToken(c *gin.Context) {
+	var input ExchangeTokenInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// コンテキストからuserIDと現在のトークンの権限範囲を取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	currentScope := c.GetString("tokenScope")
+
+	// OAuth2トークンを取り消し不能なトークンに交換させない
+	if c.GetUint("oauthGrantID") != 0 {
+		c.JSON(http.StatusForbidden, gin.H{"error": "OAuth tokens cannot be exchanged"})
+		return
+	}
+
+	// サポートでのなりすましトークンを記録の残らないトークンに交換させない
+	if c.GetUint("supportGrantID") != 0 {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Support access tokens cannot be exchanged"})
+		return
+	}
+
+	// 交換したトークンをさらに交換できると、漏れたトークンが有効期限を延ばし続けられるため拒否する
+	if c.GetBool("tokenExchanged") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Exchanged tokens cannot be exchanged again"})
+		return
+	}
+
+	// 現在のトークンより広い権限には交換できない
+	// 読み取り専用トークンの POST はミドルウェアで拒否され、グループ限定トークンはグループ外のルートが拒否されるため
+	// 通常はここに到達しないが、ミドルウェアの変更に備えて念のため確認する
+	if currentScope == utils.ScopeRead && input.Scope != utils.ScopeRead {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Cannot widen token scope"})
+		return
+	}
+
+	// グループを限定する場合はメンバーであることを確認
+	if input.GroupID != 0 {
+		var membership models.Membership
+		if err := database.DB.Where("user_id = ? AND group_id = ?", userID, input.GroupID).First(&membership).Error; err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+			return
+		}
+	}
+
+	ttl := time.Hour
+	if input.ExpiresIn > 0 {
+		ttl = time.Duration(input.ExpiresIn) * time.Second
+	}
+	if ttl > maxScopedTokenTTL {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "expiresIn exceeds the maximum of 30 days"})
+		return
+	}
+
+	token, err := utils.GenerateExchangedJWT(userID.(uint), input.Scope, input.GroupID, ttl)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"token":     token,
+		"scope":     input.Scope,
+		"groupID":   input.GroupID,
+		"expiresAt": time.Now().Add(ttl),
+	})
+}
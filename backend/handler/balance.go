@@ -0,0 +1,244 @@
+package handler
+
+import (
+	"sort"
+
+	"github.com/ito-system/clear-up-share/backend/models"
+	"github.com/ito-system/clear-up-share/backend/money"
+	"gorm.io/gorm"
+)
+
+// balanceEntriesSQL は指定グループ内の貸借に影響する全ての金額を (group_id, user_id, amount) の行として列挙するSQL
+// - 支出の支払者は支払額だけ受け取る権利が増える
+// - Splitの負担者は負担額だけ支払う義務が増える
+// - 清算の送金者は送金額だけ支払う義務が減り、受取者は受取額だけ受け取る権利が減る（取り消した清算は除く）
+// - 確定した債務免除は清算と同様に、免除された側の義務と免除した側の権利を免除額だけ減らす
+const balanceEntriesSQL = `
+	SELECT e.group_id AS group_id, e.payer_id AS user_id, e.amount AS amount
+	FROM expenses e
+	WHERE e.group_id IN @groupIDs AND e.status = @confirmed AND e.deleted_at IS NULL
+	UNION ALL
+	SELECT e.group_id, s.debtor_id, -s.amount_due
+	FROM splits s JOIN expenses e ON e.id = s.expense_id
+	WHERE e.group_id IN @groupIDs AND e.status = @confirmed AND e.deleted_at IS NULL AND s.deleted_at IS NULL
+	UNION ALL
+	SELECT st.group_id, st.payer_id, st.amount
+	FROM settlements st
+	WHERE st.group_id IN @groupIDs AND st.status = @settled AND st.deleted_at IS NULL AND st.voided_at IS NULL
+	UNION ALL
+	SELECT st.group_id, st.receiver_id, -st.amount
+	FROM settlements st
+	WHERE st.group_id IN @groupIDs AND st.status = @settled AND st.deleted_at IS NULL AND st.voided_at IS NULL
+	UNION ALL
+	SELECT f.group_id, f.debtor_id, f.amount
+	FROM forgivenesses f
+	WHERE f.group_id IN @groupIDs AND f.status = @forgiven AND f.deleted_at IS NULL
+	UNION ALL
+	SELECT f.group_id, f.receiver_id, -f.amount
+	FROM forgivenesses f
+	WHERE f.group_id IN @groupIDs AND f.status = @forgiven AND f.deleted_at IS NULL`
+
+// groupBalances はグループ内の各ユーザーの貸借額を集計SQLで計算します
+// 正の値は受け取る側（債権者）、負の値は支払う側（債務者）を表します
+func groupBalances(db *gorm.DB, groupID uint) (map[uint]float64, error) {
+	type row struct {
+		UserID  uint
+		Balance float64
+	}
+
+	var rows []row
+	err := db.Raw(
+		"SELECT user_id, SUM(amount) AS balance FROM ("+balanceEntriesSQL+") entries GROUP BY user_id",
+		map[string]interface{}{
+			"groupIDs":  []uint{groupID},
+			"confirmed": models.ExpenseStatusConfirmed,
+			"forgiven":  models.ForgivenessStatusConfirmed,
+			"settled":   models.SettlementStatusConfirmed,
+		},
+	).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	balances := make(map[uint]float64, len(rows))
+	for _, r := range rows {
+		balances[r.UserID] = r.Balance
+	}
+	return balances, nil
+}
+
+// pairwiseEntriesSQL は指定グループ内の2人の間の貸し借りを (debtor_id, creditor_id, amount) の行として列挙するSQL
+// - Splitの負担者は支出の支払者に負担額だけ借りる（支払者自身の負担分は除く）
+// - 清算の送金者は受取者への借りが送金額だけ減る（確認待ち・取り消した清算は除く）
+// - 確定した債務免除は、免除された側の免除した側への借りを免除額だけ減らす
+const pairwiseEntriesSQL = `
+	SELECT s.debtor_id AS debtor_id, e.payer_id AS creditor_id, s.amount_due AS amount
+	FROM splits s JOIN expenses e ON e.id = s.expense_id
+	WHERE e.group_id = @groupID AND e.status = @confirmed AND e.deleted_at IS NULL AND s.deleted_at IS NULL AND s.debtor_id <> e.payer_id
+	UNION ALL
+	SELECT st.payer_id, st.receiver_id, -st.amount
+	FROM settlements st
+	WHERE st.group_id = @groupID AND st.status = @settled AND st.deleted_at IS NULL AND st.voided_at IS NULL
+	UNION ALL
+	SELECT f.debtor_id, f.receiver_id, -f.amount
+	FROM forgivenesses f
+	WHERE f.group_id = @groupID AND f.status = @forgiven AND f.deleted_at IS NULL`
+
+// PairwiseDebt は2人の間の差し引きの借りを表します（FromUserID が ToUserID に Amount を支払う）
+type PairwiseDebt struct {
+	FromUserID uint
+	ToUserID   uint
+	Amount     float64
+}
+
+// groupPairwiseDebts はグループ内の2人ずつの貸し借りを差し引きし、借りが残っている組を返します
+// 各ユーザーの差し引きの合計は groupBalances の貸借額と一致します
+func groupPairwiseDebts(db *gorm.DB, groupID uint) ([]PairwiseDebt, error) {
+	type row struct {
+		DebtorID   uint
+		CreditorID uint
+		Amount     float64
+	}
+
+	var rows []row
+	err := db.Raw(
+		"SELECT debtor_id, creditor_id, SUM(amount) AS amount FROM ("+pairwiseEntriesSQL+") entries GROUP BY debtor_id, creditor_id",
+		map[string]interface{}{
+			"groupID":   groupID,
+			"confirmed": models.ExpenseStatusConfirmed,
+			"forgiven":  models.ForgivenessStatusConfirmed,
+			"settled":   models.SettlementStatusConfirmed,
+		},
+	).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// 同じ2人の両方向の行を、IDの小さい方から大きい方への借りとして差し引く
+	type pair struct{ low, high uint }
+	net := make(map[pair]float64)
+	for _, r := range rows {
+		if r.DebtorID < r.CreditorID {
+			net[pair{r.DebtorID, r.CreditorID}] += r.Amount
+		} else {
+			net[pair{r.CreditorID, r.DebtorID}] -= r.Amount
+		}
+	}
+
+	var debts []PairwiseDebt
+	for p, amount := range net {
+		switch {
+		case amount > 0:
+			debts = append(debts, PairwiseDebt{FromUserID: p.low, ToUserID: p.high, Amount: amount})
+		case amount < 0:
+			debts = append(debts, PairwiseDebt{FromUserID: p.high, ToUserID: p.low, Amount: -amount})
+		}
+	}
+	sort.Slice(debts, func(i, j int) bool {
+		if debts[i].FromUserID != debts[j].FromUserID {
+			return debts[i].FromUserID < debts[j].FromUserID
+		}
+		return debts[i].ToUserID < debts[j].ToUserID
+	})
+	return debts, nil
+}
+
+// pairwiseDebtBetween は fromUserID が toUserID に支払う差し引きの借りを返します（toUserID の方が借りている場合は負）
+func pairwiseDebtBetween(db *gorm.DB, groupID, fromUserID, toUserID uint) (float64, error) {
+	debts, err := groupPairwiseDebts(db, groupID)
+	if err != nil {
+		return 0, err
+	}
+	for _, d := range debts {
+		switch {
+		case d.FromUserID == fromUserID && d.ToUserID == toUserID:
+			return d.Amount, nil
+		case d.FromUserID == toUserID && d.ToUserID == fromUserID:
+			return -d.Amount, nil
+		}
+	}
+	return 0, nil
+}
+
+// settlementOverpayment は送金者の受取者への現在の借りと、amount がそれを超える額（補助単位、超えない場合は 0 以下）を返します
+// 現在の借りは確定した清算に加えて確認待ちの清算（一括清算・支払いリンク・カード決済）も差し引いたもので、
+// 編集・確認する清算自身（excludeID）は含めません。lockSettlementPair でロックしたトランザクションで呼び出します
+func settlementOverpayment(tx *gorm.DB, groupID, payerID, receiverID uint, amount float64, currency string, excludeID uint) (float64, int64, error) {
+	debt, err := pairwiseDebtBetween(tx, groupID, payerID, receiverID)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	// 編集する確定済みの清算は、編集前の金額を借りに戻す
+	if excludeID != 0 {
+		var excluded models.Settlement
+		if err := tx.First(&excluded, excludeID).Error; err != nil {
+			return 0, 0, err
+		}
+		if excluded.Status == models.SettlementStatusConfirmed && excluded.VoidedAt == nil {
+			debt += excluded.Amount
+		}
+	}
+
+	var pending float64
+	if err := tx.Model(&models.Settlement{}).
+		Where("group_id = ? AND payer_id = ? AND receiver_id = ? AND status = ? AND voided_at IS NULL AND id <> ?",
+			groupID, payerID, receiverID, models.SettlementStatusPending, excludeID).
+		Select("COALESCE(SUM(amount), 0)").Scan(&pending).Error; err != nil {
+		return 0, 0, err
+	}
+
+	current := max(money.ToMinor(debt-pending, currency), 0)
+	return money.FromMinor(current, currency), money.ToMinor(amount, currency) - current, nil
+}
+
+// GroupBalanceTotals はグループ一覧で表示する貸借額の集計値
+type GroupBalanceTotals struct {
+	MyBalance      float64 // 指定ユーザーの貸借額
+	TotalUnsettled float64 // 未精算の合計額（債権者の貸借額の合計）
+}
+
+// groupsBalanceTotals は複数グループの貸借額の集計値を1つの集計SQLで計算します
+func groupsBalanceTotals(db *gorm.DB, groupIDs []uint, userID uint) (map[uint]GroupBalanceTotals, error) {
+	totals := make(map[uint]GroupBalanceTotals, len(groupIDs))
+	if len(groupIDs) == 0 {
+		return totals, nil
+	}
+
+	type row struct {
+		GroupID        uint
+		MyBalance      float64
+		TotalUnsettled float64
+	}
+
+	var rows []row
+	err := db.Raw(`
+		SELECT group_id,
+			SUM(CASE WHEN user_id = @userID THEN balance ELSE 0 END) AS my_balance,
+			SUM(CASE WHEN balance > 0 THEN balance ELSE 0 END) AS total_unsettled
+		FROM (
+			SELECT group_id, user_id, SUM(amount) AS balance
+			FROM (`+balanceEntriesSQL+`) entries
+			GROUP BY group_id, user_id
+		) balances
+		GROUP BY group_id`,
+		map[string]interface{}{
+			"groupIDs":  groupIDs,
+			"userID":    userID,
+			"confirmed": models.ExpenseStatusConfirmed,
+			"forgiven":  models.ForgivenessStatusConfirmed,
+			"settled":   models.SettlementStatusConfirmed,
+		},
+	).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, r := range rows {
+		totals[r.GroupID] = GroupBalanceTotals{
+			MyBalance:      r.MyBalance,
+			TotalUnsettled: r.TotalUnsettled,
+		}
+	}
+	return totals, nil
+}
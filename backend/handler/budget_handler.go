@@ -0,0 +1,505 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ito-system/clear-up-share/backend/database"
+	"github.com/ito-system/clear-up-share/backend/models"
+	"github.com/ito-system/clear-up-share/backend/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// budgetAlertThresholds は予算に対する支出の割合（%）で、超えたときに警告する値（大きい順）
+var budgetAlertThresholds = []float64{100, 80}
+
+// CreateBudgetInput は予算の作成リクエストの入力形式
+type CreateBudgetInput struct {
+	CategoryID uint    `json:"categoryID" binding:"required"`
+	Month      string  `json:"month" binding:"required"` // YYYY-MM
+	Amount     float64 `json:"amount" binding:"required,gt=0"`
+}
+
+// UpdateBudgetInput は予算の更新リクエストの入力形式（予算額のみ変更できます）
+type UpdateBudgetInput struct {
+	Amount float64 `json:"amount" binding:"required,gt=0"`
+}
+
+// BudgetResponse は予算のレスポンス形式（期間内の確定済みの支出の合計額を含みます）
+type BudgetResponse struct {
+	ID         uint      `json:"id"`
+	CategoryID uint      `json:"categoryID"`
+	Category   string    `json:"category"`
+	Month      string    `json:"month"`
+	StartDate  time.Time `json:"startDate"`
+	EndDate    time.Time `json:"endDate"` // 期間に含まれない
+	Amount     float64   `json:"amount"`
+	Spent      float64   `json:"spent"`
+	Percent    float64   `json:"percent"`
+}
+
+// BudgetAlert は支出の登録で予算に対する支出の割合がしきい値を超えたことを表します
+type BudgetAlert struct {
+	BudgetID   uint    `json:"budgetID"`
+	CategoryID uint    `json:"categoryID"`
+	Category   string  `json:"category"`
+	Month      string  `json:"month"`
+	Amount     float64 `json:"amount"`
+	Spent      float64 `json:"spent"`
+	Percent    float64 `json:"percent"`
+	Threshold  float64 `json:"threshold"` // 超えたしきい値（80 または 100）
+}
+
+// budgetPeriod は予算の月（YYYY-MM）に対応する期間 [start, end) をグループの月の開始日に従って返します
+func budgetPeriod(month string, settings models.GroupSettings) (time.Time, time.Time, error) {
+	t, err := time.Parse("2006-01", month)
+	if err != nil {
+		return time.Time{}, time.Time{}, errors.New("month must be in YYYY-MM format")
+	}
+	// 開始日（1〜28日）以降の日付を渡してその月に始まる期間にする
+	start, end := utils.MonthPeriod(t.AddDate(0, 0, 27), settings.MonthStartDay)
+	return start, end, nil
+}
+
+// budgetMonth は支出の日付が含まれる予算の月（YYYY-MM）を返します
+func budgetMonth(date time.Time, settings models.GroupSettings) string {
+	start, _ := utils.MonthPeriod(date, settings.MonthStartDay)
+	return start.Format("2006-01")
+}
+
+// budgetSpent は期間内のカテゴリの確定済みの支出の合計額を返します（返金は差し引かれます）
+func budgetSpent(db *gorm.DB, budget models.Budget, settings models.GroupSettings) (float64, error) {
+	start, end, err := budgetPeriod(budget.Month, settings)
+	if err != nil {
+		return 0, err
+	}
+	var spent float64
+	err = db.Model(&models.Expense{}).
+		Select("COALESCE(SUM(amount), 0)").
+		Where("group_id = ? AND category_id = ? AND status = ? AND date >= ? AND date < ?",
+			budget.GroupID, budget.CategoryID, models.ExpenseStatusConfirmed, start, end).
+		Scan(&spent).Error
+	return spent, err
+}
+
+// budgetPercent は予算に対する支出の割合（%、小数第1位まで）を返します
+func budgetPercent(spent, amount float64) float64 {
+	return math.Round(spent/amount*1000) / 10
+}
+
+// budgetResponse は予算をレスポンス形式に変換します
+func budgetResponse(budget models.Budget, spent float64, settings models.GroupSettings) BudgetResponse {
+	start, end, _ := budgetPeriod(budget.Month, settings)
+	return BudgetResponse{
+		ID:         budget.ID,
+		CategoryID: budget.CategoryID,
+		Category:   budget.Category.Name,
+		Month:      budget.Month,
+		StartDate:  start,
+		EndDate:    end,
+		Amount:     budget.Amount,
+		Spent:      spent,
+		Percent:    budgetPercent(spent, budget.Amount),
+	}
+}
+
+// checkBudgetAlert は登録した支出によってカテゴリの予算に対する支出の割合がしきい値を超えたかを判定し、超えた場合はグループのメンバーに通知します
+// 確定済みでない支出・未分類の支出・予算のないカテゴリの場合は nil を返します
+// 同時に登録された支出で警告が重複・欠落しないよう、予算の行をトランザクションが終わるまでロックします
+func checkBudgetAlert(tx *gorm.DB, expense models.Expense, settings models.GroupSettings) (*BudgetAlert, error) {
+	if expense.Status != models.ExpenseStatusConfirmed || expense.CategoryID == nil {
+		return nil, nil
+	}
+
+	var budget models.Budget
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("group_id = ? AND category_id = ? AND month = ?", expense.GroupID, *expense.CategoryID, budgetMonth(expense.Date, settings)).
+		First(&budget).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// 登録した支出を含む合計額と、登録前の合計額を比べる
+	spent, err := budgetSpent(tx, budget, settings)
+	if err != nil {
+		return nil, err
+	}
+	previous := spent - expense.Amount
+
+	var threshold float64
+	for _, t := range budgetAlertThresholds {
+		limit := budget.Amount * t / 100
+		if previous < limit && spent >= limit {
+			threshold = t
+			break
+		}
+	}
+	if threshold == 0 {
+		return nil, nil
+	}
+
+	var category models.Category
+	if err := tx.Unscoped().First(&category, budget.CategoryID).Error; err != nil {
+		return nil, err
+	}
+
+	alert := &BudgetAlert{
+		BudgetID:   budget.ID,
+		CategoryID: budget.CategoryID,
+		Category:   category.Name,
+		Month:      budget.Month,
+		Amount:     budget.Amount,
+		Spent:      spent,
+		Percent:    budgetPercent(spent, budget.Amount),
+		Threshold:  threshold,
+	}
+
+	var memberships []models.Membership
+	if err := tx.Where("group_id = ?", expense.GroupID).Find(&memberships).Error; err != nil {
+		return nil, err
+	}
+	message := fmt.Sprintf("Spending on \"%s\" reached %.0f%% of the budget for %s", category.Name, threshold, budget.Month)
+	for _, m := range memberships {
+		if err := notify(tx, m.UserID, expense.GroupID, models.NotificationBudgetThreshold, message, "budget", budget.ID); err != nil {
+			return nil, err
+		}
+	}
+	return alert, nil
+}
+
+// GetBudgets はグループの指定した月（?month=YYYY-MM、省略時は今月）の予算と、カテゴリごとの支出の合計額を取得します
+// GET /api/v1/groups/:groupID/budgets
+func GetBudgets(c *gin.Context) {
+	// パスパラメータからgroupIDを取得
+	groupIDStr := c.Param("groupID")
+	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// ユーザーがグループのメンバーであることを確認
+	var membership models.Membership
+	if err := database.DB.Preload("Group").Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	// グループのポリシー設定を取得
+	settings, err := loadGroupSettings(database.DB, uint(groupID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch group settings"})
+		return
+	}
+
+	month := c.Query("month")
+	if month == "" {
+		month = budgetMonth(groupToday(settings), settings)
+	}
+	if _, _, err := budgetPeriod(month, settings); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var budgets []models.Budget
+	if err := database.DB.Joins("Category").
+		Where("budgets.group_id = ? AND budgets.month = ?", groupID, month).
+		Order("\"Category\".name").
+		Find(&budgets).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch budgets"})
+		return
+	}
+
+	responses := make([]BudgetResponse, len(budgets))
+	for i, budget := range budgets {
+		spent, err := budgetSpent(database.DB, budget, settings)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate budget spending"})
+			return
+		}
+		responses[i] = budgetResponse(budget, spent, settings)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"groupID":  groupID,
+		"month":    month,
+		"currency": membership.Group.Currency,
+		"budgets":  responses,
+	})
+}
+
+// CreateBudget はカテゴリの月次予算を設定します（同じカテゴリ・月の予算は1つまで）
+// POST /api/v1/groups/:groupID/budgets
+func CreateBudget(c *gin.Context) {
+	// パスパラメータからgroupIDを取得
+	groupIDStr := c.Param("groupID")
+	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// ユーザーがグループのメンバーであることを確認
+	var membership models.Membership
+	if err := database.DB.Preload("Group").Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	// アーカイブ済みのグループは読み取り専用
+	if membership.Group.ArchivedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Group is archived and read-only"})
+		return
+	}
+
+	// 予算を設定する権限があることを確認
+	if !hasPermission(membership.Role, PermManageGroup) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to manage budgets"})
+		return
+	}
+
+	// リクエストボディをバインド
+	var input CreateBudgetInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// グループのポリシー設定を取得
+	settings, err := loadGroupSettings(database.DB, uint(groupID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch group settings"})
+		return
+	}
+	if _, _, err := budgetPeriod(input.Month, settings); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// トランザクション開始
+	tx := database.DB.Begin()
+
+	// カテゴリがグループのものであることを確認し、完了するまで削除されないようにする
+	var category models.Category
+	if err := tx.Clauses(clause.Locking{Strength: "SHARE"}).Where("id = ? AND group_id = ?", input.CategoryID, groupID).First(&category).Error; err != nil {
+		tx.Rollback()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Category not found in this group"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check category"})
+		return
+	}
+
+	// 同じカテゴリ・月の予算は作成しない
+	var count int64
+	if err := tx.Model(&models.Budget{}).Where("group_id = ? AND category_id = ? AND month = ?", groupID, category.ID, input.Month).Count(&count).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check budgets"})
+		return
+	}
+	if count > 0 {
+		tx.Rollback()
+		c.JSON(http.StatusConflict, gin.H{"error": "Budget already exists for this category and month"})
+		return
+	}
+
+	budget := models.Budget{
+		GroupID:    uint(groupID),
+		CategoryID: category.ID,
+		Month:      input.Month,
+		Amount:     input.Amount,
+		Category:   category,
+	}
+	if err := tx.Omit("Category").Create(&budget).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create budget"})
+		return
+	}
+
+	spent, err := budgetSpent(tx, budget, settings)
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate budget spending"})
+		return
+	}
+
+	tx.Commit()
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Budget created successfully",
+		"budget":  budgetResponse(budget, spent, settings),
+	})
+}
+
+// UpdateBudget は予算額を変更します
+// PUT /api/v1/groups/:groupID/budgets/:budgetID
+func UpdateBudget(c *gin.Context) {
+	// パスパラメータからgroupIDとbudgetIDを取得
+	groupIDStr := c.Param("groupID")
+	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	budgetIDStr := c.Param("budgetID")
+	budgetID, err := strconv.ParseUint(budgetIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid budget ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// ユーザーがグループのメンバーであることを確認
+	var membership models.Membership
+	if err := database.DB.Preload("Group").Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	// アーカイブ済みのグループは読み取り専用
+	if membership.Group.ArchivedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Group is archived and read-only"})
+		return
+	}
+
+	// リーガルホールド中のグループでは記録を変更できない
+	if rejectIfLegalHold(c, membership.Group) {
+		return
+	}
+
+	// 予算を変更する権限があることを確認
+	if !hasPermission(membership.Role, PermManageGroup) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to manage budgets"})
+		return
+	}
+
+	// リクエストボディをバインド
+	var input UpdateBudgetInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var budget models.Budget
+	if err := database.DB.Preload("Category").Where("id = ? AND group_id = ?", budgetID, groupID).First(&budget).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Budget not found"})
+		return
+	}
+
+	// グループのポリシー設定を取得
+	settings, err := loadGroupSettings(database.DB, uint(groupID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch group settings"})
+		return
+	}
+
+	if err := database.DB.Model(&budget).Update("amount", input.Amount).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update budget"})
+		return
+	}
+
+	spent, err := budgetSpent(database.DB, budget, settings)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate budget spending"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Budget updated successfully",
+		"budget":  budgetResponse(budget, spent, settings),
+	})
+}
+
+// DeleteBudget は予算を削除します（登録済みの支出には影響しません）
+// DELETE /api/v1/groups/:groupID/budgets/:budgetID
+func DeleteBudget(c *gin.Context) {
+	// パスパラメータからgroupIDとbudgetIDを取得
+	groupIDStr := c.Param("groupID")
+	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	budgetIDStr := c.Param("budgetID")
+	budgetID, err := strconv.ParseUint(budgetIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid budget ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// ユーザーがグループのメンバーであることを確認
+	var membership models.Membership
+	if err := database.DB.Preload("Group").Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	// アーカイブ済みのグループは読み取り専用
+	if membership.Group.ArchivedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Group is archived and read-only"})
+		return
+	}
+
+	// リーガルホールド中のグループでは記録を変更できない
+	if rejectIfLegalHold(c, membership.Group) {
+		return
+	}
+
+	// 予算を削除する権限があることを確認
+	if !hasPermission(membership.Role, PermManageGroup) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to manage budgets"})
+		return
+	}
+
+	var budget models.Budget
+	if err := database.DB.Where("id = ? AND group_id = ?", budgetID, groupID).First(&budget).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Budget not found"})
+		return
+	}
+
+	if err := database.DB.Delete(&budget).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete budget"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Budget deleted successfully"})
+}
@@ -0,0 +1,308 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/ito-system/clear-up-share/backend/analytics"
+	"github.com/ito-system/clear-up-share/backend/database"
+	"github.com/ito-system/clear-up-share/backend/models"
+	"gorm.io/gorm"
+)
+
+// bulkInsertBatchSize は一括登録でSplitと品目をまとめて INSERT する件数
+const bulkInsertBatchSize = 500
+
+// BulkExpenseInput は支出の一括登録リクエストの入力形式（各支出は AddExpense と同じ形式）
+// 一度に登録できるのは100件までです
+type BulkExpenseInput struct {
+	Expenses []AddExpenseInput `json:"expenses" binding:"required,min=1,max=100"`
+}
+
+// BulkExpenseError は一括登録で登録できなかった支出とその理由
+type BulkExpenseError struct {
+	Index  int    `json:"index"` // expenses 内の位置（0 始まり）
+	Error  string `json:"error"`
+	Fields gin.H  `json:"fields,omitempty"` // グループのメンバーでない支払者・負担者のID（AddExpense と同じ形式）
+}
+
+// bulkExpense は一括登録する支出の入力と、入力から決めた負担額・品目
+type bulkExpense struct {
+	input      AddExpenseInput
+	date       time.Time
+	occurredAt *time.Time
+	currency   string
+	splits     []models.Split
+	items      []models.ExpenseItem
+}
+
+// AddExpensesBulk は複数の支出を1つのトランザクションで登録します（旅行のレシートをまとめて取り込む場合など）
+// 1件でも登録できない支出があれば何も登録せず、該当する支出の位置と理由を errors に返します
+// POST /api/v1/groups/:groupID/expenses/bulk
+func AddExpensesBulk(c *gin.Context) {
+	// パスパラメータからgroupIDを取得
+	groupIDStr := c.Param("groupID")
+	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// ユーザーがグループのメンバーであることを確認
+	var membership models.Membership
+	if err := database.DB.Preload("Group").Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	// アーカイブ済みのグループは読み取り専用
+	if membership.Group.ArchivedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Group is archived and read-only"})
+		return
+	}
+
+	// 支出を追加する権限があることを確認
+	if !hasPermission(membership.Role, PermAddExpense) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to add expenses"})
+		return
+	}
+
+	// リクエストボディをバインド（各支出の入力は位置ごとに確認する）
+	var input BulkExpenseInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// グループのポリシー設定を取得
+	settings, err := loadGroupSettings(database.DB, uint(groupID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch group settings"})
+		return
+	}
+
+	// 各支出の入力を確認し、負担者ごとの負担額を決定
+	var failures []BulkExpenseError
+	entries := make([]bulkExpense, len(input.Expenses))
+	var published int64
+	for i, item := range input.Expenses {
+		if err := binding.Validator.ValidateStruct(&item); err != nil {
+			failures = append(failures, BulkExpenseError{Index: i, Error: err.Error()})
+			continue
+		}
+		date, occurredAt, err := parseExpenseDate(item.Date, groupLocation(settings))
+		if err != nil {
+			failures = append(failures, BulkExpenseError{Index: i, Error: err.Error()})
+			continue
+		}
+		currency, err := expenseCurrency(item, membership.Group.Currency)
+		if err != nil {
+			failures = append(failures, BulkExpenseError{Index: i, Error: err.Error()})
+			continue
+		}
+		if err := applySplitPreset(database.DB, uint(groupID), &item); err != nil {
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch split preset"})
+				return
+			}
+			failures = append(failures, BulkExpenseError{Index: i, Error: "Split preset not found in this group"})
+			continue
+		}
+		splits, items, err := expenseSplits(item, settings.RoundingMode, currency)
+		if err != nil {
+			failures = append(failures, BulkExpenseError{Index: i, Error: err.Error()})
+			continue
+		}
+		entries[i] = bulkExpense{input: item, date: date, occurredAt: occurredAt, currency: currency, splits: splits, items: items}
+		if !item.Draft {
+			published++
+		}
+	}
+	if len(failures) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Some expenses are invalid", "errors": failures})
+		return
+	}
+
+	// 今月の支出数に全件を加えても上限に達しないことを確認（下書きは公開時に確認する）
+	if published > 0 {
+		if err := checkExpenseQuota(database.DB, uint(groupID), settings, published); err != nil {
+			respondQuotaError(c, err)
+			return
+		}
+	}
+
+	// トランザクション開始
+	tx := database.DB.Begin()
+
+	// 全ての支払者と負担者がグループのメンバーであることをまとめて確認し、完了するまで退会・除名されないようにする
+	var userIDs []uint
+	for _, entry := range entries {
+		userIDs = append(userIDs, entry.input.PayerID)
+		userIDs = append(userIDs, splitDebtorIDs(entry.splits)...)
+	}
+	nonMembers, err := lockGroupNonMembers(tx, uint(groupID), userIDs)
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check members"})
+		return
+	}
+
+	// 支出ごとにメンバーとカテゴリを確認
+	for i, entry := range entries {
+		if fields := expenseNonMemberFields(entry.input, entry.splits, nonMembers); len(fields) > 0 {
+			failures = append(failures, BulkExpenseError{Index: i, Error: "Payer and split members must belong to this group", Fields: fields})
+			continue
+		}
+		if err := lockExpenseCategory(tx, uint(groupID), entry.input.CategoryID); err != nil {
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				tx.Rollback()
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check category"})
+				return
+			}
+			failures = append(failures, BulkExpenseError{Index: i, Error: "Category not found in this group"})
+		}
+	}
+	if len(failures) > 0 {
+		tx.Rollback()
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Some expenses are invalid", "errors": failures})
+		return
+	}
+
+	// Expenseを作成
+	expenses := make([]models.Expense, len(entries))
+	delegated := make([]bool, len(entries))
+	for i, entry := range entries {
+		// タグを取得（まだないものは作成）
+		tags, err := resolveTags(tx, uint(groupID), normalizeTags(entry.input.Tags))
+		if err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save tags"})
+			return
+		}
+
+		// 承認が必要なグループでは承認待ち、他のメンバーの代理で登録した支出は支払者の承認待ちになる
+		status := models.ExpenseStatusConfirmed
+		if settings.RequireExpenseApproval && !hasPermission(membership.Role, PermApproveExpense) {
+			status = models.ExpenseStatusPending
+		}
+		needsPayerApproval, err := requiresPayerApproval(entry.input.PayerID, userID.(uint))
+		if err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch payer"})
+			return
+		}
+		if needsPayerApproval {
+			status = models.ExpenseStatusAwaitingPayer
+			delegated[i] = true
+		}
+		if entry.input.Draft {
+			status = models.ExpenseStatusDraft
+			delegated[i] = false
+		}
+
+		expenses[i] = models.Expense{
+			GroupID:     uint(groupID),
+			PayerID:     entry.input.PayerID,
+			Description: entry.input.Description,
+			Notes:       entry.input.Notes,
+			Date:        entry.date,
+			OccurredAt:  entry.occurredAt,
+			Latitude:    entry.input.Latitude,
+			Longitude:   entry.input.Longitude,
+			PlaceName:   entry.input.PlaceName,
+			Status:      status,
+			CreatedByID: userID.(uint),
+			CategoryID:  entry.input.CategoryID,
+			Tags:        tags,
+		}
+		setExpenseAmount(&expenses[i], entry.input, entry.currency, membership.Group.Currency, entry.splits, entry.items)
+	}
+	if err := tx.Create(&expenses).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create expenses"})
+		return
+	}
+
+	// Splitと品目をまとめて作成
+	var splits []models.Split
+	var items []models.ExpenseItem
+	for i, entry := range entries {
+		for _, split := range entry.splits {
+			split.ExpenseID = expenses[i].ID
+			splits = append(splits, split)
+		}
+		for _, item := range entry.items {
+			item.ExpenseID = expenses[i].ID
+			items = append(items, item)
+		}
+	}
+	if err := tx.CreateInBatches(&splits, bulkInsertBatchSize).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create splits"})
+		return
+	}
+	if len(items) > 0 {
+		if err := tx.CreateInBatches(&items, bulkInsertBatchSize).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create expense items"})
+			return
+		}
+	}
+
+	for i, expense := range expenses {
+		// 代理入力の場合は支払者に承認を依頼する
+		if delegated[i] {
+			if err := notifyPayerApproval(tx, expense); err != nil {
+				tx.Rollback()
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to notify payer"})
+				return
+			}
+		}
+
+		// アクティビティを記録
+		if expense.Status != models.ExpenseStatusDraft {
+			if err := recordActivity(tx, expense.GroupID, userID.(uint), models.ActivityExpenseAdded, "expense", expense.ID, map[string]interface{}{
+				"description": expense.Description,
+				"amount":      expense.Amount,
+				"payerID":     expense.PayerID,
+			}); err != nil {
+				tx.Rollback()
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record activity"})
+				return
+			}
+		}
+	}
+
+	tx.Commit()
+
+	responses := make([]gin.H, len(expenses))
+	for i, expense := range expenses {
+		if expense.Status != models.ExpenseStatusDraft {
+			analytics.Track(analytics.EventExpenseAdded, userID.(uint), map[string]interface{}{
+				"groupId":     analytics.Anonymize("group", expense.GroupID),
+				"memberCount": len(entries[i].splits),
+				"status":      expense.Status,
+				"delegated":   delegated[i],
+				"bulk":        true,
+			})
+		}
+		responses[i] = expenseResponse(expense, membership.Group.Currency)
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":  "Expenses created successfully",
+		"count":    len(expenses),
+		"expenses": responses,
+	})
+}
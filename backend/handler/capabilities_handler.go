@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ito-system/clear-up-share/backend/database"
+	"github.com/ito-system/clear-up-share/backend/models"
+	"github.com/ito-system/clear-up-share/backend/utils"
+)
+
+// GetCapabilities は呼び出し元が利用できる機能・利用上限・ロールごとの権限を返します
+// groupID を指定した場合は、そのグループでの自分のロールと権限も返します
+// GET /api/v1/capabilities?groupID=
+func GetCapabilities(c *gin.Context) {
+	// コンテキストからuserIDとトークンのスコープを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	scope := c.GetString("tokenScope")
+	tokenGroupID := c.GetUint("tokenGroupID")
+
+	// 機能ごとの有効・無効
+	features := gin.H{}
+	for _, name := range utils.Features {
+		features[name] = utils.FeatureEnabled(name)
+	}
+
+	// ロールごとに許可される操作
+	roles := gin.H{}
+	for _, role := range []string{models.RoleOwner, models.RoleAdmin, models.RoleMember, models.RoleViewer} {
+		roles[role] = rolePermissionNames(role, utils.ScopeFull)
+	}
+
+	limits := utils.DefaultLimits
+	response := gin.H{
+		"apiVersion": "v1",
+		"features":   features,
+		"roles":      roles,
+		"currencies": utils.SupportedCurrencies(),
+		"limits": gin.H{
+			"maxGroupsPerUser":    limits.MaxGroupsPerUser,
+			"maxMembersPerGroup":  limits.MaxMembersPerGroup,
+			"maxExpensesPerMonth": limits.MaxExpensesPerMonth,
+		},
+		"token": gin.H{
+			"scope":   scope,
+			"groupID": tokenGroupID,
+		},
+	}
+
+	// グループが指定された場合は、そのグループでの権限を返す
+	if groupIDStr := c.Query("groupID"); groupIDStr != "" {
+		groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+			return
+		}
+
+		// グループ限定トークンでは対象グループ以外は参照できない
+		if tokenGroupID != 0 && uint(groupID) != tokenGroupID {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Token is restricted to a different group"})
+			return
+		}
+
+		// ユーザーがグループのメンバーであることを確認
+		var membership models.Membership
+		if err := database.DB.Preload("Group").Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+			return
+		}
+
+		// アーカイブ済みのグループでは閲覧とアーカイブ解除（グループ管理）のみ可能
+		permissions := rolePermissionNames(membership.Role, scope)
+		if membership.Group.ArchivedAt != nil {
+			permissions = rolePermissionNames(membership.Role, utils.ScopeRead)
+			if scope != utils.ScopeRead && hasPermission(membership.Role, PermManageGroup) {
+				permissions = append(permissions, permissionNames[PermManageGroup])
+			}
+		}
+
+		response["group"] = gin.H{
+			"groupID":     groupID,
+			"role":        membership.Role,
+			"archived":    membership.Group.ArchivedAt != nil,
+			"permissions": permissions,
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,398 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ito-system/clear-up-share/backend/database"
+	"github.com/ito-system/clear-up-share/backend/models"
+	"github.com/ito-system/clear-up-share/backend/money"
+	"github.com/ito-system/clear-up-share/backend/stripe"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// stripeWebhookMaxBody は Stripe の Webhook のリクエストボディの上限
+const stripeWebhookMaxBody = 1 << 20
+
+// stripeAccountResponse は連結アカウントのレスポンス形式を返します
+func stripeAccountResponse(account models.StripeAccount) gin.H {
+	return gin.H{
+		"accountID":      account.AccountID,
+		"chargesEnabled": account.ChargesEnabled,
+		"createdAt":      account.CreatedAt,
+	}
+}
+
+// ConnectMyStripeAccount は認証ユーザーがカード決済で清算を受け取るための Stripe の連結アカウントを作成し、本人確認・口座登録の画面のURLを返します
+// 連結アカウントを作成済みの場合は、登録を続けるための新しいURLを返します
+// POST /api/v1/users/me/stripe-account
+func ConnectMyStripeAccount(c *gin.Context) {
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	if !stripe.Enabled() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Card payments are not configured"})
+		return
+	}
+
+	// トランザクション開始
+	tx := database.DB.Begin()
+
+	// 同時に作成して連結アカウントが二重にできないよう、ユーザーをロックする
+	var user models.User
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, userID).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	var account models.StripeAccount
+	err := tx.Where("user_id = ?", user.ID).First(&account).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch Stripe account"})
+		return
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		created, err := stripe.CreateAccount(c.Request.Context(), user.Email, map[string]string{"userID": strconv.FormatUint(uint64(user.ID), 10)})
+		if err != nil {
+			tx.Rollback()
+			log.Printf("Failed to create Stripe account for user %d: %v", user.ID, err)
+			c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to create Stripe account"})
+			return
+		}
+		account = models.StripeAccount{UserID: user.ID, AccountID: created.ID, ChargesEnabled: created.ChargesEnabled}
+		if err := tx.Create(&account).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save Stripe account"})
+			return
+		}
+	}
+
+	tx.Commit()
+
+	onboardingURL, err := stripe.CreateAccountLink(c.Request.Context(), account.AccountID)
+	if err != nil {
+		log.Printf("Failed to create Stripe account link for user %d: %v", user.ID, err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to create Stripe onboarding link"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"stripeAccount": stripeAccountResponse(account),
+		"onboardingURL": onboardingURL,
+	})
+}
+
+// GetMyStripeAccount は認証ユーザーの連結アカウントの状態を Stripe から取得して返します
+// GET /api/v1/users/me/stripe-account
+func GetMyStripeAccount(c *gin.Context) {
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	if !stripe.Enabled() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Card payments are not configured"})
+		return
+	}
+
+	var account models.StripeAccount
+	if err := database.DB.Where("user_id = ?", userID).First(&account).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Stripe account not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch Stripe account"})
+		return
+	}
+
+	// 本人確認が済んだかは Webhook でも反映されるが、登録画面から戻った直後に確認できるよう Stripe から取得する
+	current, err := stripe.GetAccount(c.Request.Context(), account.AccountID)
+	if err != nil {
+		log.Printf("Failed to fetch Stripe account %s: %v", account.AccountID, err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to fetch Stripe account"})
+		return
+	}
+	if current.ChargesEnabled != account.ChargesEnabled {
+		if err := database.DB.Model(&account).Update("charges_enabled", current.ChargesEnabled).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update Stripe account"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"stripeAccount": stripeAccountResponse(account)})
+}
+
+// CreateSettlementPaymentIntent は確認待ちの清算をアプリ内のカード決済で支払うための Stripe の PaymentIntent を作成します
+// 支払いは受取者の連結アカウントに送金され、Stripe の Webhook で支払いが完了すると清算が確定します
+// 同じ清算で再度呼び出した場合は作成済みの PaymentIntent を返します
+// POST /api/v1/groups/:groupID/settlements/:settlementID/payment-intent
+func CreateSettlementPaymentIntent(c *gin.Context) {
+	// パスパラメータからgroupIDとsettlementIDを取得
+	groupIDStr := c.Param("groupID")
+	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	settlementIDStr := c.Param("settlementID")
+	settlementID, err := strconv.ParseUint(settlementIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid settlement ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// ユーザーがグループのメンバーであることを確認
+	var membership models.Membership
+	if err := database.DB.Preload("Group").Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	// アーカイブ済みのグループは読み取り専用
+	if membership.Group.ArchivedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Group is archived and read-only"})
+		return
+	}
+
+	if !stripe.Enabled() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Card payments are not configured"})
+		return
+	}
+
+	var settlement models.Settlement
+	if err := database.DB.Where("id = ? AND group_id = ?", settlementID, groupID).First(&settlement).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Settlement not found"})
+		return
+	}
+
+	// 送金者本人のみ支払える
+	if settlement.PayerID != userID.(uint) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the payer can pay this settlement"})
+		return
+	}
+
+	if settlement.Status != models.SettlementStatusPending || settlement.VoidedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Settlement is not pending confirmation"})
+		return
+	}
+
+	// 作成済みの場合は同じ PaymentIntent で支払いを続ける
+	if settlement.PaymentReference != "" {
+		intent, err := stripe.GetPaymentIntent(c.Request.Context(), settlement.PaymentReference)
+		if err != nil {
+			log.Printf("Failed to fetch Stripe payment intent %s: %v", settlement.PaymentReference, err)
+			c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to fetch card payment"})
+			return
+		}
+		if intent.Status != stripe.PaymentIntentCanceled {
+			c.JSON(http.StatusOK, paymentIntentResponse(settlement, intent, membership.Group.Currency))
+			return
+		}
+	}
+
+	// 受取者がカード決済を受け取れることを確認
+	var account models.StripeAccount
+	if err := database.DB.Where("user_id = ?", settlement.ReceiverID).First(&account).Error; err != nil || !account.ChargesEnabled {
+		c.JSON(http.StatusConflict, gin.H{"error": "Receiver cannot accept card payments yet"})
+		return
+	}
+
+	currency := membership.Group.Currency
+	intent, err := stripe.CreatePaymentIntent(c.Request.Context(), stripe.PaymentIntentParams{
+		Amount:      money.ToMinor(settlement.Amount, currency),
+		Currency:    currency,
+		Destination: account.AccountID,
+		Description: fmt.Sprintf("%s settlement #%d", membership.Group.Name, settlement.ID),
+		Metadata: map[string]string{
+			"settlementID": strconv.FormatUint(uint64(settlement.ID), 10),
+			"groupID":      strconv.FormatUint(groupID, 10),
+		},
+		// キャンセルされた PaymentIntent の後に作り直せるよう、置き換える PaymentIntent をキーに含める
+		IdempotencyKey: fmt.Sprintf("settlement-%d-%s", settlement.ID, settlement.PaymentReference),
+	})
+	if err != nil {
+		log.Printf("Failed to create Stripe payment intent for settlement %d: %v", settlement.ID, err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to create card payment"})
+		return
+	}
+
+	// 同時に作成された場合に入金と照合できない PaymentIntent を残さない
+	result := database.DB.Model(&models.Settlement{}).
+		Where("id = ? AND payment_reference = ? AND status = ? AND voided_at IS NULL", settlement.ID, settlement.PaymentReference, models.SettlementStatusPending).
+		Update("payment_reference", intent.ID)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update settlement"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Settlement was updated, please retry"})
+		return
+	}
+	settlement.PaymentReference = intent.ID
+
+	c.JSON(http.StatusCreated, paymentIntentResponse(settlement, intent, currency))
+}
+
+// paymentIntentResponse はクライアントが Stripe.js などで支払いを確定するための値を返します
+func paymentIntentResponse(settlement models.Settlement, intent stripe.PaymentIntent, currency string) gin.H {
+	return gin.H{
+		"settlementID":    settlement.ID,
+		"paymentIntentID": intent.ID,
+		"clientSecret":    intent.ClientSecret,
+		"publishableKey":  stripe.PublishableKey(),
+		"status":          intent.Status,
+		"amount":          settlement.Amount,
+		"currency":        currency,
+	}
+}
+
+// HandleStripeWebhook は Stripe の Webhook を受け取り、カード決済が完了した清算の確定と連結アカウントの状態の更新を行います
+// 応答が 2xx 以外の場合は Stripe が再送するため、処理しないイベントにも 200 を返します
+// POST /api/v1/stripe/webhook
+func HandleStripeWebhook(c *gin.Context) {
+	payload, err := io.ReadAll(io.LimitReader(c.Request.Body, stripeWebhookMaxBody))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+		return
+	}
+
+	event, err := stripe.ParseWebhook(payload, c.GetHeader("Stripe-Signature"))
+	if err != nil {
+		if errors.Is(err, stripe.ErrNotConfigured) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Card payments are not configured"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid signature"})
+		return
+	}
+
+	switch event.Type {
+	case stripe.EventPaymentIntentSucceeded:
+		var intent stripe.PaymentIntent
+		if err := json.Unmarshal(event.Data.Object, &intent); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payment intent"})
+			return
+		}
+		if err := confirmCardSettlement(intent); err != nil {
+			log.Printf("Failed to confirm card payment %s: %v", intent.ID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to confirm settlement"})
+			return
+		}
+
+	case stripe.EventAccountUpdated:
+		var account stripe.Account
+		if err := json.Unmarshal(event.Data.Object, &account); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid account"})
+			return
+		}
+		if err := database.DB.Model(&models.StripeAccount{}).Where("account_id = ?", account.ID).Update("charges_enabled", account.ChargesEnabled).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update Stripe account"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"received": true})
+}
+
+// confirmCardSettlement はカード決済が完了した PaymentIntent の清算を確定し、送金者と受取者に通知します
+// 照合できない支払いや確定できない清算は返金などの対応が必要なため、ログに残して処理を終えます
+func confirmCardSettlement(intent stripe.PaymentIntent) error {
+	tx := database.DB.Begin()
+
+	var settlement models.Settlement
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Group").Where("payment_reference = ?", intent.ID).First(&settlement).Error
+	if err != nil {
+		tx.Rollback()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Printf("Card payment %s does not match any settlement", intent.ID)
+			return nil
+		}
+		return err
+	}
+
+	// Webhook は再送されるため、確定済みの場合は何もしない
+	if settlement.Status == models.SettlementStatusConfirmed {
+		tx.Rollback()
+		return nil
+	}
+	if settlement.VoidedAt != nil {
+		tx.Rollback()
+		log.Printf("Card payment %s succeeded for voided settlement %d, refund required", intent.ID, settlement.ID)
+		return nil
+	}
+
+	currency := settlement.Group.Currency
+	if intent.Amount != money.ToMinor(settlement.Amount, currency) || !strings.EqualFold(intent.Currency, currency) {
+		tx.Rollback()
+		log.Printf("Card payment %s amount %d %s does not match settlement %d", intent.ID, intent.Amount, intent.Currency, settlement.ID)
+		return nil
+	}
+
+	// 貸借が変わるため、送金者・受取者が退会していないことを確認する
+	ok, err := lockGroupMembers(tx, settlement.GroupID, []uint{settlement.PayerID, settlement.ReceiverID})
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if !ok {
+		tx.Rollback()
+		log.Printf("Card payment %s succeeded but payer or receiver of settlement %d has left", intent.ID, settlement.ID)
+		return nil
+	}
+
+	if err := tx.Model(&settlement).Updates(map[string]interface{}{
+		"status": models.SettlementStatusConfirmed,
+		"method": models.SettlementMethodCard,
+	}).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	amount := formatGlanceAmount(settlement.Amount, currency)
+	if err := notify(tx, settlement.PayerID, settlement.GroupID, models.NotificationSettlementConfirmed, "Your card payment of "+amount+" was completed", "settlement", settlement.ID); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := notify(tx, settlement.ReceiverID, settlement.GroupID, models.NotificationSettlementConfirmed, "You received a card payment of "+amount, "settlement", settlement.ID); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	// アクティビティを記録（支払った送金者の操作として記録する）
+	if err := recordActivity(tx, settlement.GroupID, settlement.PayerID, models.ActivitySettlementConfirmed, "settlement", settlement.ID, map[string]interface{}{
+		"payerID":          settlement.PayerID,
+		"receiverID":       settlement.ReceiverID,
+		"amount":           settlement.Amount,
+		"method":           models.SettlementMethodCard,
+		"paymentReference": settlement.PaymentReference,
+	}); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
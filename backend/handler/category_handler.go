@@ -0,0 +1,311 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ito-system/clear-up-share/backend/database"
+	"github.com/ito-system/clear-up-share/backend/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CategoryInput はカテゴリ作成リクエストの入力形式
+type CategoryInput struct {
+	Name string `json:"name" binding:"required,max=50"`
+}
+
+// CategoryRef は支出の詳細に含めるカテゴリの形式
+type CategoryRef struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+}
+
+// CategoryResponse はカテゴリのレスポンス形式（確定済みの支出の件数と合計額を含みます）
+type CategoryResponse struct {
+	ID           uint    `json:"id"`
+	Name         string  `json:"name"`
+	ExpenseCount int64   `json:"expenseCount"`
+	Total        float64 `json:"total"`
+}
+
+// lockExpenseCategory はカテゴリがグループのものであることを確認し、トランザクションが終わるまで削除されないようにします
+// categoryID が nil の場合（未分類）は何もしません
+func lockExpenseCategory(tx *gorm.DB, groupID uint, categoryID *uint) error {
+	if categoryID == nil {
+		return nil
+	}
+	var category models.Category
+	return tx.Clauses(clause.Locking{Strength: "SHARE"}).
+		Where("id = ? AND group_id = ?", *categoryID, groupID).
+		First(&category).Error
+}
+
+// categoryFilter は ?category= の値から支出の絞り込み条件を作ります
+// "none" は未分類の支出、数値はそのカテゴリの支出に絞り込みます
+func categoryFilter(value string) (func(*gorm.DB) *gorm.DB, error) {
+	if value == "none" {
+		return func(db *gorm.DB) *gorm.DB {
+			return db.Where("category_id IS NULL")
+		}, nil
+	}
+	categoryID, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return nil, err
+	}
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("category_id = ?", categoryID)
+	}, nil
+}
+
+// GetCategories はグループのカテゴリ一覧をカテゴリごとの支出の合計額とともに取得します
+// GET /api/v1/groups/:groupID/categories
+func GetCategories(c *gin.Context) {
+	// パスパラメータからgroupIDを取得
+	groupIDStr := c.Param("groupID")
+	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// ユーザーがグループのメンバーであることを確認
+	var membership models.Membership
+	if err := database.DB.Preload("Group").Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	var categories []models.Category
+	if err := database.DB.Where("group_id = ?", groupID).Order("name").Find(&categories).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch categories"})
+		return
+	}
+
+	// 確定済みの支出をカテゴリごとに集計（category_id が NULL の行は未分類）
+	type categoryTotal struct {
+		CategoryID   *uint
+		ExpenseCount int64
+		Total        float64
+	}
+	var totals []categoryTotal
+	if err := database.DB.Model(&models.Expense{}).
+		Select("category_id, COUNT(*) AS expense_count, COALESCE(SUM(amount), 0) AS total").
+		Where("group_id = ? AND status = ?", groupID, models.ExpenseStatusConfirmed).
+		Group("category_id").
+		Scan(&totals).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate category totals"})
+		return
+	}
+
+	byCategory := make(map[uint]categoryTotal, len(totals))
+	uncategorized := categoryTotal{}
+	for _, t := range totals {
+		if t.CategoryID == nil {
+			uncategorized = t
+			continue
+		}
+		byCategory[*t.CategoryID] = t
+	}
+
+	responses := make([]CategoryResponse, len(categories))
+	for i, category := range categories {
+		responses[i] = CategoryResponse{
+			ID:           category.ID,
+			Name:         category.Name,
+			ExpenseCount: byCategory[category.ID].ExpenseCount,
+			Total:        byCategory[category.ID].Total,
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"groupID":    groupID,
+		"currency":   membership.Group.Currency,
+		"categories": responses,
+		"uncategorized": gin.H{
+			"expenseCount": uncategorized.ExpenseCount,
+			"total":        uncategorized.Total,
+		},
+	})
+}
+
+// CreateCategory はグループにカテゴリを追加します
+// POST /api/v1/groups/:groupID/categories
+func CreateCategory(c *gin.Context) {
+	// パスパラメータからgroupIDを取得
+	groupIDStr := c.Param("groupID")
+	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// ユーザーがグループのメンバーであることを確認
+	var membership models.Membership
+	if err := database.DB.Preload("Group").Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	// アーカイブ済みのグループは読み取り専用
+	if membership.Group.ArchivedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Group is archived and read-only"})
+		return
+	}
+
+	// 支出を追加できるメンバーはカテゴリも追加できる
+	if !hasPermission(membership.Role, PermAddExpense) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to add categories"})
+		return
+	}
+
+	// リクエストボディをバインド
+	var input CategoryInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	name := strings.TrimSpace(input.Name)
+	if name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Category name is required"})
+		return
+	}
+
+	// 同じ名前のカテゴリは作成しない
+	var count int64
+	if err := database.DB.Model(&models.Category{}).Where("group_id = ? AND name = ?", groupID, name).Count(&count).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check categories"})
+		return
+	}
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Category already exists"})
+		return
+	}
+
+	category := models.Category{
+		GroupID: uint(groupID),
+		Name:    name,
+	}
+	if err := database.DB.Create(&category).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create category"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Category created successfully",
+		"category": CategoryResponse{
+			ID:   category.ID,
+			Name: category.Name,
+		},
+	})
+}
+
+// DeleteCategory はカテゴリを削除します（そのカテゴリの支出は未分類になります）
+// DELETE /api/v1/groups/:groupID/categories/:categoryID
+func DeleteCategory(c *gin.Context) {
+	// パスパラメータからgroupIDとcategoryIDを取得
+	groupIDStr := c.Param("groupID")
+	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	categoryIDStr := c.Param("categoryID")
+	categoryID, err := strconv.ParseUint(categoryIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// ユーザーがグループのメンバーであることを確認
+	var membership models.Membership
+	if err := database.DB.Preload("Group").Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	// アーカイブ済みのグループは読み取り専用
+	if membership.Group.ArchivedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Group is archived and read-only"})
+		return
+	}
+
+	// リーガルホールド中のグループでは支出のカテゴリを書き換えられない
+	if rejectIfLegalHold(c, membership.Group) {
+		return
+	}
+
+	// カテゴリを削除する権限があることを確認
+	if !hasPermission(membership.Role, PermManageGroup) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to delete categories"})
+		return
+	}
+
+	// トランザクション開始
+	tx := database.DB.Begin()
+
+	var category models.Category
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ? AND group_id = ?", categoryID, groupID).First(&category).Error; err != nil {
+		tx.Rollback()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Category not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch category"})
+		return
+	}
+
+	// 削除済みのものも含めて支出を未分類に戻す
+	if err := tx.Unscoped().Model(&models.Expense{}).Where("category_id = ?", category.ID).Update("category_id", nil).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to uncategorize expenses"})
+		return
+	}
+	if err := tx.Unscoped().Model(&models.RecurringExpense{}).Where("category_id = ?", category.ID).Update("category_id", nil).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to uncategorize recurring expenses"})
+		return
+	}
+
+	// カテゴリの予算も削除する
+	if err := tx.Where("category_id = ?", category.ID).Delete(&models.Budget{}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete budgets"})
+		return
+	}
+
+	if err := tx.Delete(&category).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete category"})
+		return
+	}
+
+	tx.Commit()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Category deleted successfully"})
+}
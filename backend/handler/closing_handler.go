@@ -0,0 +1,161 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/ito-system/clear-up-share/backend/database"
+	"github.com/ito-system/clear-up-share/backend/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// closingPollInterval は清算期間を自動で締めるグループを確認する間隔
+const closingPollInterval = time.Hour
+
+// closingBatchSize は1回の確認でまとめて取得するグループ設定の件数
+const closingBatchSize = 100
+
+// nextClosingDate は today より後で最初の締め日の日付を返します（締め日が 0 の場合は nil）
+func nextClosingDate(today time.Time, closingDay int) *time.Time {
+	if closingDay <= 0 {
+		return nil
+	}
+	next := time.Date(today.Year(), today.Month(), closingDay, 0, 0, 0, 0, time.UTC)
+	if !next.After(today) {
+		next = next.AddDate(0, 1, 0)
+	}
+	return &next
+}
+
+// latestClosingDate は today 以前で最後の締め日の日付を返します
+func latestClosingDate(today time.Time, closingDay int) time.Time {
+	latest := time.Date(today.Year(), today.Month(), closingDay, 0, 0, 0, 0, time.UTC)
+	if latest.After(today) {
+		latest = latest.AddDate(0, -1, 0)
+	}
+	return latest
+}
+
+// StartClosingScheduler は締め日を設定したグループの清算期間を毎月自動で締めるバックグラウンド処理を開始します
+func StartClosingScheduler() {
+	go func() {
+		for {
+			closeScheduledSettlementPeriods()
+			time.Sleep(closingPollInterval)
+		}
+	}()
+}
+
+// closeScheduledSettlementPeriods は締め日が来ている可能性のある全てのグループを確認します（アーカイブ済みのグループは除く）
+// グループごとのタイムゾーンでの日付は closeGroupSettlementPeriod で判定するため、ここでは1日早めに取得します
+func closeScheduledSettlementPeriods() {
+	var settingsList []models.GroupSettings
+	err := database.DB.
+		Where("closing_day > 0 AND next_closing_date <= ?", time.Now().UTC().AddDate(0, 0, 1)).
+		Where("group_id NOT IN (SELECT id FROM groups WHERE archived_at IS NOT NULL OR deleted_at IS NOT NULL)").
+		FindInBatches(&settingsList, closingBatchSize, func(batch *gorm.DB, _ int) error {
+			for _, settings := range settingsList {
+				if err := closeGroupSettlementPeriod(settings.ID); err != nil {
+					log.Printf("Failed to close settlement period for group %d: %v", settings.GroupID, err)
+				}
+			}
+			return nil
+		}).Error
+	if err != nil {
+		log.Printf("Failed to fetch group settings: %v", err)
+	}
+}
+
+// closeGroupSettlementPeriod は締め日が来たグループの前日までの清算期間を締め、メンバーに送金の提案を通知します
+// 締め日が過ぎたまま処理されなかった場合（サーバーの停止など）は、最後の締め日の前日までをまとめて締めます
+func closeGroupSettlementPeriod(settingsID uint) error {
+	tx := database.DB.Begin()
+
+	// 複数のサーバーで動かしている場合に二重に締めない
+	var settings models.GroupSettings
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).Preload("Group").First(&settings, settingsID).Error; err != nil {
+		tx.Rollback()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	// 確認の間に締め日を止めた、またはアーカイブしたグループは締めない
+	today := groupToday(settings)
+	if settings.ClosingDay <= 0 || settings.NextClosingDate == nil || settings.NextClosingDate.After(today) || settings.Group.ArchivedAt != nil {
+		tx.Rollback()
+		return nil
+	}
+
+	// 管理者が手動で同じ日以降まで締めていた場合は、次の締め日を進めるだけにする
+	endDate := latestClosingDate(today, settings.ClosingDay).AddDate(0, 0, -1)
+	var closed int64
+	if err := tx.Model(&models.SettlementPeriod{}).Where("group_id = ? AND end_date >= ?", settings.GroupID, endDate).Count(&closed).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if closed == 0 {
+		// 自動で締めた期間はグループの所有者が締めたものとして記録する
+		period, transfers, err := closeSettlementPeriod(tx, settings.Group, endDate, settings.Group.OwnerID, true)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := notifySettlementPeriodClosed(tx, settings.Group, period, transfers); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	if err := tx.Model(&settings).Update("next_closing_date", nextClosingDate(today, settings.ClosingDay)).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+// notifySettlementPeriodClosed は自動で締めた清算期間について、各メンバーに支払う・受け取る送金を通知します
+func notifySettlementPeriodClosed(tx *gorm.DB, group models.Group, period models.SettlementPeriod, transfers []SuggestedTransfer) error {
+	// 仮メンバーはログインできないため送らない
+	var memberIDs []uint
+	if err := tx.Model(&models.Membership{}).
+		Joins("JOIN users ON users.id = memberships.user_id").
+		Where("memberships.group_id = ? AND users.is_placeholder = ?", group.ID, false).
+		Order("memberships.user_id").
+		Pluck("memberships.user_id", &memberIDs).Error; err != nil {
+		return err
+	}
+
+	prefix := fmt.Sprintf("%s closed the period up to %s.", group.Name, period.EndDate.Format("2006-01-02"))
+	for _, userID := range memberIDs {
+		var pays, receives []string
+		for _, t := range transfers {
+			amount := formatGlanceAmount(t.Amount, group.Currency)
+			if t.PayerID == userID {
+				pays = append(pays, amount+" to "+t.ReceiverName)
+			}
+			if t.ReceiverID == userID {
+				receives = append(receives, amount+" from "+t.PayerName)
+			}
+		}
+
+		message := prefix + " You are all settled up"
+		switch {
+		case len(pays) > 0:
+			message = prefix + " Please pay " + strings.Join(pays, ", ")
+		case len(receives) > 0:
+			message = prefix + " You will receive " + strings.Join(receives, ", ")
+		}
+		if err := notify(tx, userID, group.ID, models.NotificationSettlementPeriodClosed, message, "settlement_period", period.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
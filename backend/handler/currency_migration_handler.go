@@ -0,0 +1,422 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ito-system/clear-up-share/backend/database"
+	"github.com/ito-system/clear-up-share/backend/models"
+	"github.com/ito-system/clear-up-share/backend/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CurrencyRateInput は換算レートの入力形式（Date 以降の記録に適用される、旧通貨1単位あたりの新通貨の金額）
+type CurrencyRateInput struct {
+	Date string  `json:"date" binding:"required"`
+	Rate float64 `json:"rate" binding:"required,gt=0"`
+}
+
+// CurrencyMigrationInput は通貨移行リクエストの入力形式
+// 実行時はプレビューで返された PreviewToken が必要です
+type CurrencyMigrationInput struct {
+	Currency     string              `json:"currency" binding:"required"`
+	Mode         string              `json:"mode" binding:"required,oneof=convert dual"`
+	Rates        []CurrencyRateInput `json:"rates" binding:"required,min=1,dive"`
+	PreviewToken string              `json:"previewToken"`
+}
+
+// currencyRate は日付をパースした換算レート
+type currencyRate struct {
+	From time.Time
+	Rate float64
+}
+
+// convertedAmount は通貨移行で換算される1件の金額
+type convertedAmount struct {
+	Type   string  `json:"type"` // "expense"、"split"、"item"、"settlement"、"forgiveness" または "recurring"
+	ID     uint    `json:"id"`
+	Rate   float64 `json:"rate"`
+	Before float64 `json:"before"`
+	After  float64 `json:"after"`
+}
+
+// PreviewCurrencyMigration は通貨移行を実行した場合の換算結果と貸借額をプレビューします（データは変更しません）
+// POST /api/v1/groups/:groupID/currency-migration/preview
+func PreviewCurrencyMigration(c *gin.Context) {
+	runCurrencyMigration(c, true)
+}
+
+// MigrateGroupCurrency はグループの通貨を変更し、過去の支出・清算・債務免除の金額を換算します
+// プレビュー後に記録が変更された場合は 409 を返すので、再度プレビューしてください
+// POST /api/v1/groups/:groupID/currency-migration
+func MigrateGroupCurrency(c *gin.Context) {
+	runCurrencyMigration(c, false)
+}
+
+// runCurrencyMigration は通貨移行のプレビューと実行を処理します
+// プレビューも実際にトランザクション内で換算を行い、結果を確認した後にロールバックします
+func runCurrencyMigration(c *gin.Context, dryRun bool) {
+	// パスパラメータからgroupIDを取得
+	groupIDStr := c.Param("groupID")
+	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// ユーザーがグループのメンバーであることを確認
+	var membership models.Membership
+	if err := database.DB.Preload("Group").Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	// アーカイブ済みのグループは読み取り専用
+	if membership.Group.ArchivedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Group is archived and read-only"})
+		return
+	}
+
+	// リーガルホールド中のグループでは記録を書き換えられない
+	if rejectIfLegalHold(c, membership.Group) {
+		return
+	}
+
+	// グループを管理する権限があることを確認
+	if !hasPermission(membership.Role, PermManageGroup) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to manage this group"})
+		return
+	}
+
+	// リクエストボディをバインド
+	var input CurrencyMigrationInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	code, ok := utils.NormalizeCurrency(input.Currency)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported currency code"})
+		return
+	}
+	from := membership.Group.Currency
+	if code == from {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Group already uses this currency"})
+		return
+	}
+	if !dryRun && input.PreviewToken == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "previewToken is required. Preview the migration first"})
+		return
+	}
+
+	// 換算レートを日付順に並べる
+	rates := make([]currencyRate, len(input.Rates))
+	for i, r := range input.Rates {
+		date, err := time.Parse("2006-01-02", r.Date)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid rate date format. Use YYYY-MM-DD"})
+			return
+		}
+		rates[i] = currencyRate{From: date, Rate: r.Rate}
+	}
+	sort.Slice(rates, func(i, j int) bool {
+		return rates[i].From.Before(rates[j].From)
+	})
+
+	// トランザクション開始
+	tx := database.DB.Begin()
+
+	// 換算が終わるまで支出・清算の追加や退会・除名を止める
+	var locked []models.Membership
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("group_id = ?", groupID).Find(&locked).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to lock group"})
+		return
+	}
+
+	before, err := groupBalances(tx, uint(groupID))
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate balances"})
+		return
+	}
+
+	changes, err := convertGroupAmounts(tx, uint(groupID), from, code, input.Mode, rates)
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to convert amounts"})
+		return
+	}
+
+	after, err := groupBalances(tx, uint(groupID))
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate balances"})
+		return
+	}
+
+	// プレビュー時と同じ換算結果になることを確認するためのトークン
+	data, err := json.Marshal(gin.H{"currency": code, "mode": input.Mode, "changes": changes})
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build preview"})
+		return
+	}
+	sum := sha256.Sum256(data)
+	token := hex.EncodeToString(sum[:])
+
+	// メンバーごとの換算前後の貸借額
+	type balanceChange struct {
+		UserID uint    `json:"userID"`
+		Before float64 `json:"before"`
+		After  float64 `json:"after"`
+	}
+	balances := []balanceChange{}
+	for id, balance := range before {
+		balances = append(balances, balanceChange{UserID: id, Before: balance, After: after[id]})
+	}
+	sort.Slice(balances, func(i, j int) bool {
+		return balances[i].UserID < balances[j].UserID
+	})
+
+	counts := map[string]int{}
+	for _, change := range changes {
+		counts[change.Type]++
+	}
+
+	response := gin.H{
+		"groupID":  groupID,
+		"from":     from,
+		"to":       code,
+		"mode":     input.Mode,
+		"counts":   counts,
+		"balances": balances,
+		"changes":  changes,
+	}
+
+	if dryRun {
+		tx.Rollback()
+		response["previewToken"] = token
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
+	if input.PreviewToken != token {
+		tx.Rollback()
+		c.JSON(http.StatusConflict, gin.H{"error": "Records changed since the preview. Preview the migration again"})
+		return
+	}
+
+	if err := tx.Model(&membership.Group).Update("currency", code).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update group"})
+		return
+	}
+
+	// アクティビティを記録
+	rateDetails := make([]map[string]interface{}, len(rates))
+	for i, r := range rates {
+		rateDetails[i] = map[string]interface{}{"date": r.From.Format("2006-01-02"), "rate": r.Rate}
+	}
+	if err := recordActivity(tx, uint(groupID), userID.(uint), models.ActivityCurrencyMigrated, "group", uint(groupID), map[string]interface{}{
+		"from":  from,
+		"to":    code,
+		"mode":  input.Mode,
+		"rates": rateDetails,
+	}); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record activity"})
+		return
+	}
+
+	tx.Commit()
+
+	response["message"] = "Group currency migrated successfully"
+	c.JSON(http.StatusOK, response)
+}
+
+// rateFor は記録の日付に適用する換算レートを返します
+// 日付以前で最も新しいレートを使い、全てのレートより前の記録には最も古いレートを使います
+func rateFor(rates []currencyRate, date time.Time) float64 {
+	rate := rates[0].Rate
+	for _, r := range rates {
+		if r.From.After(date) {
+			break
+		}
+		rate = r.Rate
+	}
+	return rate
+}
+
+// convertGroupAmounts はグループの支出・Split・品目・清算・債務免除（論理削除済みを含む）の金額を新しい通貨に換算します
+// 金額は新しい通貨の補助単位で丸め、Splitの端数は支出ごとに最初の負担者が吸収して負担額の合計を保ちます
+func convertGroupAmounts(tx *gorm.DB, groupID uint, from, to, mode string, rates []currencyRate) ([]convertedAmount, error) {
+	scale := math.Pow(10, float64(utils.CurrencyMinorUnits(to)))
+	round := func(amount float64) float64 {
+		return math.Round(amount*scale) / scale
+	}
+
+	// 併記する形式では最初の換算前の金額を残し、換算のみの形式では消す
+	originals := func(before float64, original *float64, originalCurrency string) map[string]interface{} {
+		if mode == models.CurrencyMigrationConvert {
+			return map[string]interface{}{"original_amount": nil, "original_currency": ""}
+		}
+		if originalCurrency != "" {
+			return map[string]interface{}{"original_amount": original, "original_currency": originalCurrency}
+		}
+		return map[string]interface{}{"original_amount": before, "original_currency": from}
+	}
+
+	changes := []convertedAmount{}
+
+	var expenses []models.Expense
+	if err := tx.Unscoped().Where("group_id = ?", groupID).Order("id").Find(&expenses).Error; err != nil {
+		return nil, err
+	}
+	for _, e := range expenses {
+		rate := rateFor(rates, e.Date)
+		converted := round(e.Amount * rate)
+		updates := originals(e.Amount, e.OriginalAmount, e.OriginalCurrency)
+		updates["amount"] = converted
+		// 他の通貨で入力した支出は、入力時のレートを新しい通貨に対するレートに置き換える
+		if e.ExchangeRate != nil {
+			updates["exchange_rate"] = *e.ExchangeRate * rate
+		}
+		if e.Tax != nil {
+			updates["tax"] = round(*e.Tax * rate)
+			updates["tip"] = round(*e.Tip * rate)
+		}
+		if err := tx.Unscoped().Model(&models.Expense{}).Where("id = ?", e.ID).Updates(updates).Error; err != nil {
+			return nil, err
+		}
+		changes = append(changes, convertedAmount{Type: "expense", ID: e.ID, Rate: rate, Before: e.Amount, After: converted})
+
+		// 品目の金額は支出と同じレートで換算する（負担額は Split に保存済みのため再計算しない）
+		var items []models.ExpenseItem
+		if err := tx.Unscoped().Where("expense_id = ?", e.ID).Order("id").Find(&items).Error; err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			price := round(item.Price * rate)
+			if err := tx.Unscoped().Model(&models.ExpenseItem{}).Where("id = ?", item.ID).Update("price", price).Error; err != nil {
+				return nil, err
+			}
+			changes = append(changes, convertedAmount{Type: "item", ID: item.ID, Rate: rate, Before: item.Price, After: price})
+		}
+
+		var splits []models.Split
+		if err := tx.Unscoped().Where("expense_id = ?", e.ID).Order("id").Find(&splits).Error; err != nil {
+			return nil, err
+		}
+		if len(splits) == 0 {
+			continue
+		}
+
+		// 換算前の支出額と負担額の合計の差（通常は0）を保ったまま、最初の負担者が端数を吸収する
+		shares := make([]float64, len(splits))
+		total := 0.0
+		originalTotal := 0.0
+		for i, s := range splits {
+			shares[i] = round(s.AmountDue * rate)
+			total += shares[i]
+			originalTotal += s.AmountDue
+		}
+		shares[0] = round(shares[0] + converted - round((e.Amount-originalTotal)*rate) - total)
+
+		for i, s := range splits {
+			if err := tx.Unscoped().Model(&models.Split{}).Where("id = ?", s.ID).Update("amount_due", shares[i]).Error; err != nil {
+				return nil, err
+			}
+			changes = append(changes, convertedAmount{Type: "split", ID: s.ID, Rate: rate, Before: s.AmountDue, After: shares[i]})
+		}
+	}
+
+	var settlements []models.Settlement
+	if err := tx.Unscoped().Where("group_id = ?", groupID).Order("id").Find(&settlements).Error; err != nil {
+		return nil, err
+	}
+	for _, s := range settlements {
+		rate := rateFor(rates, s.CreatedAt)
+		converted := round(s.Amount * rate)
+		updates := originals(s.Amount, s.OriginalAmount, s.OriginalCurrency)
+		updates["amount"] = converted
+		// 他の通貨で支払った清算は、記録時のレートを新しい通貨に対するレートに置き換える
+		if s.ExchangeRate != nil {
+			updates["exchange_rate"] = *s.ExchangeRate * rate
+		}
+		if err := tx.Unscoped().Model(&models.Settlement{}).Where("id = ?", s.ID).Updates(updates).Error; err != nil {
+			return nil, err
+		}
+		changes = append(changes, convertedAmount{Type: "settlement", ID: s.ID, Rate: rate, Before: s.Amount, After: converted})
+	}
+
+	var forgivenesses []models.Forgiveness
+	if err := tx.Unscoped().Where("group_id = ?", groupID).Order("id").Find(&forgivenesses).Error; err != nil {
+		return nil, err
+	}
+	for _, f := range forgivenesses {
+		rate := rateFor(rates, f.CreatedAt)
+		converted := round(f.Amount * rate)
+		updates := originals(f.Amount, f.OriginalAmount, f.OriginalCurrency)
+		updates["amount"] = converted
+		if err := tx.Unscoped().Model(&models.Forgiveness{}).Where("id = ?", f.ID).Updates(updates).Error; err != nil {
+			return nil, err
+		}
+		changes = append(changes, convertedAmount{Type: "forgiveness", ID: f.ID, Rate: rate, Before: f.Amount, After: converted})
+	}
+
+	// 定期的な支出は次に登録する日付のレートで換算する（今後登録される支出が新しい通貨になる）
+	var recurring []models.RecurringExpense
+	if err := tx.Unscoped().Where("group_id = ?", groupID).Order("id").Find(&recurring).Error; err != nil {
+		return nil, err
+	}
+	for _, r := range recurring {
+		rate := rateFor(rates, r.NextRunDate)
+		converted := round(r.Amount * rate)
+		updates := map[string]interface{}{"amount": converted}
+
+		// 金額で分けている場合は、合計が支出額と一致するように最初の負担者が端数を吸収する
+		if r.SplitType == models.SplitTypeExact {
+			fields := strings.Fields(r.Shares)
+			shares := make([]string, len(fields))
+			total := 0.0
+			values := make([]float64, len(fields))
+			for i, field := range fields {
+				share, _ := strconv.ParseFloat(field, 64)
+				values[i] = round(share * rate)
+				total += values[i]
+			}
+			if len(values) > 0 {
+				values[0] = round(values[0] + converted - total)
+			}
+			for i, value := range values {
+				shares[i] = strconv.FormatFloat(value, 'f', -1, 64)
+			}
+			updates["shares"] = strings.Join(shares, " ")
+		}
+
+		if err := tx.Unscoped().Model(&models.RecurringExpense{}).Where("id = ?", r.ID).Updates(updates).Error; err != nil {
+			return nil, err
+		}
+		changes = append(changes, convertedAmount{Type: "recurring", ID: r.ID, Rate: rate, Before: r.Amount, After: converted})
+	}
+
+	return changes, nil
+}
@@ -0,0 +1,263 @@
+package handler
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ito-system/clear-up-share/backend/database"
+	"github.com/ito-system/clear-up-share/backend/models"
+	"github.com/ito-system/clear-up-share/backend/money"
+)
+
+// agingEntriesSQL は指定グループ内の貸借に影響する全ての金額を (user_id, amount, date, kind) の行として日付順に列挙するSQL
+// 金額の符号は balanceEntriesSQL と同じで、負の値が支払う義務の発生、正の値が義務の解消を表します
+// 債務免除は日付を持たないため、確定した日時を日付として使います
+const agingEntriesSQL = `
+	SELECT user_id, amount, date, kind FROM (
+		SELECT e.payer_id AS user_id, e.amount AS amount, e.date AS date, 'expense' AS kind
+		FROM expenses e
+		WHERE e.group_id = @groupID AND e.status = @confirmed AND e.deleted_at IS NULL
+		UNION ALL
+		SELECT s.debtor_id, -s.amount_due, e.date, 'expense'
+		FROM splits s JOIN expenses e ON e.id = s.expense_id
+		WHERE e.group_id = @groupID AND e.status = @confirmed AND e.deleted_at IS NULL AND s.deleted_at IS NULL
+		UNION ALL
+		SELECT st.payer_id, st.amount, st.date, 'settlement'
+		FROM settlements st
+		WHERE st.group_id = @groupID AND st.status = @settled AND st.deleted_at IS NULL AND st.voided_at IS NULL
+		UNION ALL
+		SELECT st.receiver_id, -st.amount, st.date, 'settlement'
+		FROM settlements st
+		WHERE st.group_id = @groupID AND st.status = @settled AND st.deleted_at IS NULL AND st.voided_at IS NULL
+		UNION ALL
+		SELECT f.debtor_id, f.amount, COALESCE(f.confirmed_at, f.created_at), 'forgiveness'
+		FROM forgivenesses f
+		WHERE f.group_id = @groupID AND f.status = @forgiven AND f.deleted_at IS NULL
+		UNION ALL
+		SELECT f.receiver_id, -f.amount, COALESCE(f.confirmed_at, f.created_at), 'forgiveness'
+		FROM forgivenesses f
+		WHERE f.group_id = @groupID AND f.status = @forgiven AND f.deleted_at IS NULL
+	) entries
+	ORDER BY date, user_id`
+
+// agingBucket は借りの経過日数の区分（ToDays が 0 の区分には上限がない）
+type agingBucket struct {
+	Label    string
+	FromDays int
+	ToDays   int
+}
+
+// agingBuckets は借りの経過日数の区分の一覧
+var agingBuckets = []agingBucket{
+	{Label: "0-30", FromDays: 0, ToDays: 30},
+	{Label: "31-60", FromDays: 31, ToDays: 60},
+	{Label: "61-90", FromDays: 61, ToDays: 90},
+	{Label: "90+", FromDays: 91},
+}
+
+// DebtAgingBucket は経過日数の区分ごとの未精算額を表す形式
+type DebtAgingBucket struct {
+	Label    string  `json:"label"`
+	FromDays int     `json:"fromDays"`
+	ToDays   *int    `json:"toDays"` // 上限のない区分は null
+	Amount   float64 `json:"amount"`
+}
+
+// MemberDebtAging はメンバーの未精算額の経過日数の内訳を表す形式
+type MemberDebtAging struct {
+	UserID      uint              `json:"userID"`
+	Username    string            `json:"username"`
+	Outstanding float64           `json:"outstanding"` // 未精算額（貸借額の符号を反転した値）
+	OldestDate  string            `json:"oldestDate"`  // 未精算の借りのうち最も古いものが発生した日付
+	OldestDays  int               `json:"oldestDays"`  // OldestDate からの経過日数
+	AverageDays int               `json:"averageDays"` // 未精算額で加重平均した経過日数
+	Buckets     []DebtAgingBucket `json:"buckets"`
+	Left        bool              `json:"left,omitempty"` // 貸借を残して退会したメンバー
+}
+
+// outstandingDebt は清算などを充てた後に残っている借り
+type outstandingDebt struct {
+	date  time.Time
+	units int64
+}
+
+// GetDebtAging は支払う側のメンバーごとに、未精算額を借りが発生してからの経過日数で分けて返します
+// 清算・債務免除などの義務を減らす金額は、古い借りから順に充てたものとして計算します
+// GET /api/v1/groups/:groupID/debts/aging
+func GetDebtAging(c *gin.Context) {
+	// パスパラメータからgroupIDを取得
+	groupIDStr := c.Param("groupID")
+	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// ユーザーがグループのメンバーであることを確認
+	var membership models.Membership
+	if err := database.DB.Preload("Group").Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	// グループのポリシー設定を取得
+	settings, err := loadGroupSettings(database.DB, uint(groupID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch group settings"})
+		return
+	}
+
+	type row struct {
+		UserID uint
+		Amount float64
+		Date   time.Time
+		Kind   string
+	}
+
+	var rows []row
+	if err := database.DB.Raw(agingEntriesSQL, map[string]interface{}{
+		"groupID":   groupID,
+		"confirmed": models.ExpenseStatusConfirmed,
+		"forgiven":  models.ForgivenessStatusConfirmed,
+		"settled":   models.SettlementStatusConfirmed,
+	}).Scan(&rows).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate balances"})
+		return
+	}
+
+	// ユーザーごとに、義務を減らす金額を残っている借りの古い順に充てる
+	// 借りがないときに義務を減らした金額（受け取る権利）は、後から発生した借りに充てる
+	currency := membership.Group.Currency
+	loc := groupLocation(settings)
+	debts := make(map[uint][]outstandingDebt)
+	credits := make(map[uint]int64)
+	for _, r := range rows {
+		date := r.Date
+		if r.Kind == "forgiveness" {
+			date = localDate(date, loc)
+		}
+		units := money.ToMinor(r.Amount, currency)
+
+		if units < 0 {
+			units = -units
+			applied := min(units, credits[r.UserID])
+			credits[r.UserID] -= applied
+			if units > applied {
+				debts[r.UserID] = append(debts[r.UserID], outstandingDebt{date: date, units: units - applied})
+			}
+			continue
+		}
+
+		queue := debts[r.UserID]
+		for len(queue) > 0 && units > 0 {
+			applied := min(units, queue[0].units)
+			queue[0].units -= applied
+			units -= applied
+			if queue[0].units == 0 {
+				queue = queue[1:]
+			}
+		}
+		debts[r.UserID] = queue
+		credits[r.UserID] += units
+	}
+
+	// 退会したメンバーはユーザー名で表示する
+	names, err := groupDisplayNames(database.DB, uint(groupID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch members"})
+		return
+	}
+	var leftIDs []uint
+	for debtorID, queue := range debts {
+		if _, ok := names[debtorID]; !ok && len(queue) > 0 {
+			leftIDs = append(leftIDs, debtorID)
+		}
+	}
+	left := make(map[uint]bool, len(leftIDs))
+	if len(leftIDs) > 0 {
+		var leftUsers []models.User
+		if err := database.DB.Where("id IN ?", leftIDs).Find(&leftUsers).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch members"})
+			return
+		}
+		for _, u := range leftUsers {
+			names[u.ID] = u.Username
+			left[u.ID] = true
+		}
+	}
+
+	today := groupToday(settings)
+	members := []MemberDebtAging{}
+	for debtorID, queue := range debts {
+		if len(queue) == 0 {
+			continue
+		}
+
+		bucketUnits := make([]int64, len(agingBuckets))
+		var total, weightedDays int64
+		for _, d := range queue {
+			days := max(int(today.Sub(d.date).Hours()/24), 0)
+			for i := len(agingBuckets) - 1; i >= 0; i-- {
+				if days >= agingBuckets[i].FromDays {
+					bucketUnits[i] += d.units
+					break
+				}
+			}
+			total += d.units
+			weightedDays += d.units * int64(days)
+		}
+
+		buckets := make([]DebtAgingBucket, len(agingBuckets))
+		for i, b := range agingBuckets {
+			buckets[i] = DebtAgingBucket{
+				Label:    b.Label,
+				FromDays: b.FromDays,
+				Amount:   money.FromMinor(bucketUnits[i], currency),
+			}
+			if b.ToDays > 0 {
+				toDays := b.ToDays
+				buckets[i].ToDays = &toDays
+			}
+		}
+
+		oldest := queue[0].date
+		members = append(members, MemberDebtAging{
+			UserID:      debtorID,
+			Username:    names[debtorID],
+			Outstanding: money.FromMinor(total, currency),
+			OldestDate:  oldest.Format("2006-01-02"),
+			OldestDays:  max(int(today.Sub(oldest).Hours()/24), 0),
+			AverageDays: int(weightedDays / total),
+			Buckets:     buckets,
+			Left:        left[debtorID],
+		})
+	}
+
+	// 長く精算されていないメンバーから順に並べる
+	sort.Slice(members, func(i, j int) bool {
+		if members[i].OldestDays != members[j].OldestDays {
+			return members[i].OldestDays > members[j].OldestDays
+		}
+		if members[i].Outstanding != members[j].Outstanding {
+			return members[i].Outstanding > members[j].Outstanding
+		}
+		return members[i].UserID < members[j].UserID
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"groupID":  groupID,
+		"currency": currency,
+		"asOf":     today.Format("2006-01-02"),
+		"members":  members,
+	})
+}
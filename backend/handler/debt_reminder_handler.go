@@ -0,0 +1,223 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ito-system/clear-up-share/backend/database"
+	"github.com/ito-system/clear-up-share/backend/models"
+	"github.com/ito-system/clear-up-share/backend/money"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// debtReminderPollInterval は未精算のリマインダーを送るグループを確認する間隔
+const debtReminderPollInterval = time.Hour
+
+// debtReminderBatchSize は1回の確認でまとめて取得するグループ設定の件数
+const debtReminderBatchSize = 100
+
+// SentReminder は未精算のリマインダーを送ったメンバーと借りの額を表す形式
+type SentReminder struct {
+	UserID   uint    `json:"userID"`
+	Username string  `json:"username"`
+	Amount   float64 `json:"amount"`
+}
+
+// reminderDue はメンバーに前回リマインダーを送った日時から、間隔が過ぎているかを判定します
+func reminderDue(last time.Time, cadence string, now time.Time) bool {
+	switch cadence {
+	case models.ReminderCadenceWeekly:
+		return !now.Before(last.AddDate(0, 0, 7))
+	case models.ReminderCadenceMonthly:
+		return !now.Before(last.AddDate(0, 1, 0))
+	}
+	return false
+}
+
+// sendDebtReminders は借りがしきい値を超えているグループのメンバーに未精算のリマインダーを通知し、送った記録を残します
+// manual が false の場合は、前回送った日時からグループのリマインダーの間隔が過ぎたメンバーにのみ送ります
+func sendDebtReminders(tx *gorm.DB, group models.Group, settings models.GroupSettings, manual bool) ([]SentReminder, error) {
+	balances, err := groupBalances(tx, group.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 退会したメンバーには送らない
+	names, err := groupDisplayNames(tx, group.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	threshold := money.ToMinor(settings.ReminderThreshold, group.Currency)
+	var debtorIDs []uint
+	for userID, balance := range balances {
+		if _, ok := names[userID]; ok && -money.ToMinor(balance, group.Currency) > threshold {
+			debtorIDs = append(debtorIDs, userID)
+		}
+	}
+
+	// 仮メンバーはログインできないため送らない
+	if len(debtorIDs) > 0 {
+		if err := tx.Model(&models.User{}).Where("id IN ? AND is_placeholder = ?", debtorIDs, false).Order("id").Pluck("id", &debtorIDs).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	sent := []SentReminder{}
+	for _, userID := range debtorIDs {
+		if !manual {
+			var last models.DebtReminder
+			err := tx.Where("group_id = ? AND user_id = ?", group.ID, userID).Order("created_at DESC").First(&last).Error
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, err
+			}
+			if err == nil && !reminderDue(last.CreatedAt, settings.ReminderCadence, now) {
+				continue
+			}
+		}
+
+		amount := money.Round(-balances[userID], group.Currency)
+		message := fmt.Sprintf("You owe %s in %s. Please settle up when you can", formatGlanceAmount(amount, group.Currency), group.Name)
+		if err := notify(tx, userID, group.ID, models.NotificationDebtReminder, message, "group", group.ID); err != nil {
+			return nil, err
+		}
+
+		// 前回までの記録は次に送る日時の判定に使わないため削除する
+		if err := tx.Where("group_id = ? AND user_id = ?", group.ID, userID).Delete(&models.DebtReminder{}).Error; err != nil {
+			return nil, err
+		}
+		if err := tx.Create(&models.DebtReminder{GroupID: group.ID, UserID: userID, Amount: amount, Manual: manual}).Error; err != nil {
+			return nil, err
+		}
+		sent = append(sent, SentReminder{UserID: userID, Username: names[userID], Amount: amount})
+	}
+	return sent, nil
+}
+
+// RemindDebts はリマインダーの間隔に関わらず、借りがしきい値を超えている全てのメンバーに未精算のリマインダーを送ります
+// POST /api/v1/groups/:groupID/debts/remind
+func RemindDebts(c *gin.Context) {
+	// パスパラメータからgroupIDを取得
+	groupIDStr := c.Param("groupID")
+	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// ユーザーがグループのメンバーであることを確認
+	var membership models.Membership
+	if err := database.DB.Preload("Group").Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	// アーカイブ済みのグループは読み取り専用
+	if membership.Group.ArchivedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Group is archived and read-only"})
+		return
+	}
+
+	// グループを管理する権限があることを確認
+	if !hasPermission(membership.Role, PermManageGroup) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to manage this group"})
+		return
+	}
+
+	// グループのポリシー設定を取得
+	settings, err := loadGroupSettings(database.DB, uint(groupID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch group settings"})
+		return
+	}
+
+	// トランザクション開始
+	tx := database.DB.Begin()
+
+	sent, err := sendDebtReminders(tx, membership.Group, settings, true)
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send reminders"})
+		return
+	}
+
+	tx.Commit()
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "Reminders sent successfully",
+		"groupID":   groupID,
+		"currency":  membership.Group.Currency,
+		"reminders": sent,
+	})
+}
+
+// StartDebtReminderScheduler は未精算のリマインダーを定期的に送るバックグラウンド処理を開始します
+func StartDebtReminderScheduler() {
+	go func() {
+		for {
+			sendScheduledDebtReminders()
+			time.Sleep(debtReminderPollInterval)
+		}
+	}()
+}
+
+// sendScheduledDebtReminders はリマインダーの間隔を設定した全てのグループを確認します（アーカイブ済みのグループは除く）
+func sendScheduledDebtReminders() {
+	var settingsList []models.GroupSettings
+	err := database.DB.
+		Where("reminder_cadence <> ?", models.ReminderCadenceOff).
+		Where("group_id NOT IN (SELECT id FROM groups WHERE archived_at IS NOT NULL OR deleted_at IS NOT NULL)").
+		FindInBatches(&settingsList, debtReminderBatchSize, func(batch *gorm.DB, _ int) error {
+			for _, settings := range settingsList {
+				if err := sendGroupDebtReminders(settings.ID); err != nil {
+					log.Printf("Failed to send debt reminders for group %d: %v", settings.GroupID, err)
+				}
+			}
+			return nil
+		}).Error
+	if err != nil {
+		log.Printf("Failed to fetch group settings: %v", err)
+	}
+}
+
+// sendGroupDebtReminders は1つのグループで間隔が過ぎたメンバーに未精算のリマインダーを送ります
+func sendGroupDebtReminders(settingsID uint) error {
+	tx := database.DB.Begin()
+
+	// 複数のサーバーで動かしている場合に二重に送らない
+	var settings models.GroupSettings
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).Preload("Group").First(&settings, settingsID).Error; err != nil {
+		tx.Rollback()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	// 確認の間にリマインダーを止めた、またはアーカイブしたグループには送らない
+	if settings.ReminderCadence == models.ReminderCadenceOff || settings.Group.ArchivedAt != nil {
+		tx.Rollback()
+		return nil
+	}
+
+	if _, err := sendDebtReminders(tx, settings.Group, settings, false); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
@@ -0,0 +1,248 @@
+package handler
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ito-system/clear-up-share/backend/database"
+	"github.com/ito-system/clear-up-share/backend/models"
+	"github.com/ito-system/clear-up-share/backend/money"
+)
+
+// DebtBetweenItem は2人の間の貸し借りに影響する支出・清算・債務免除を表す形式
+type DebtBetweenItem struct {
+	Kind        string    `json:"kind"` // expense / settlement / forgiveness
+	ID          uint      `json:"id"`
+	Date        string    `json:"date"` // 債務免除は確定した日
+	Description string    `json:"description,omitempty"`
+	FromUserID  uint      `json:"fromUserID"` // 支出の負担者・清算の送金者・免除された側
+	ToUserID    uint      `json:"toUserID"`   // 支出の支払者・清算の受取者・免除した側
+	Amount      float64   `json:"amount"`     // 支出は負担額、清算は送金額、債務免除は免除額
+	Effect      float64   `json:"effect"`     // userA から見た差し引きの変化（正: userB の userA への借りが増える）
+	sortDate    time.Time // 並び替えに使う日付
+}
+
+// DebtBetweenMember は2人の間の貸し借りの当事者を表す形式
+type DebtBetweenMember struct {
+	UserID   uint   `json:"userID"`
+	Username string `json:"username"`
+	Left     bool   `json:"left,omitempty"` // 退会・除名されたメンバー
+}
+
+// GetDebtsBetween は2人のメンバーの間だけの差し引きの借りと、それに影響した支出・清算・債務免除を日付順に返します
+// 差し引きは2人ずつの借り（debts/pairwise）と同じ計算で、net が正の場合は userB が userA に net を支払います
+// GET /api/v1/groups/:groupID/debts/between?userA=&userB=
+func GetDebtsBetween(c *gin.Context) {
+	// パスパラメータからgroupIDを取得
+	groupIDStr := c.Param("groupID")
+	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	// クエリパラメータから2人のユーザーIDを取得
+	userAID, err := strconv.ParseUint(c.Query("userA"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid userA"})
+		return
+	}
+	userBID, err := strconv.ParseUint(c.Query("userB"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid userB"})
+		return
+	}
+	if userAID == userBID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "userA and userB must be different users"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// ユーザーがグループのメンバーであることを確認
+	var membership models.Membership
+	if err := database.DB.Preload("Group").Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	// 2人がグループのメンバーである（または以前メンバーだった）ことを確認
+	var memberIDs []uint
+	if err := database.DB.Unscoped().Model(&models.Membership{}).
+		Where("group_id = ? AND user_id IN ?", groupID, []uint64{userAID, userBID}).
+		Distinct().Pluck("user_id", &memberIDs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch members"})
+		return
+	}
+	if len(memberIDs) != 2 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Member not found"})
+		return
+	}
+
+	// グループのポリシー設定を取得
+	settings, err := loadGroupSettings(database.DB, uint(groupID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch group settings"})
+		return
+	}
+
+	a, b := uint(userAID), uint(userBID)
+	currency := membership.Group.Currency
+	items := []DebtBetweenItem{}
+
+	// effect は from が to に amount を借りる（負の場合は借りが減る）ときの userA から見た変化を返します
+	effect := func(from uint, amount float64) float64 {
+		if from == b {
+			return amount
+		}
+		return -amount
+	}
+
+	// 一方が支払った支出のもう一方の負担分（承認待ち・下書きの支出は含めない）
+	var splits []models.Split
+	if err := database.DB.Preload("Expense").
+		Joins("JOIN expenses ON expenses.id = splits.expense_id").
+		Where("expenses.group_id = ? AND expenses.status = ? AND expenses.deleted_at IS NULL", groupID, models.ExpenseStatusConfirmed).
+		Where("(expenses.payer_id = ? AND splits.debtor_id = ?) OR (expenses.payer_id = ? AND splits.debtor_id = ?)", a, b, b, a).
+		Find(&splits).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch expenses"})
+		return
+	}
+	for _, s := range splits {
+		items = append(items, DebtBetweenItem{
+			Kind:        "expense",
+			ID:          s.ExpenseID,
+			Date:        s.Expense.Date.Format("2006-01-02"),
+			Description: s.Expense.Description,
+			FromUserID:  s.DebtorID,
+			ToUserID:    s.Expense.PayerID,
+			Amount:      s.AmountDue,
+			Effect:      effect(s.DebtorID, s.AmountDue),
+			sortDate:    s.Expense.Date,
+		})
+	}
+
+	// 2人の間の確定済みで取り消していない清算
+	var settlements []models.Settlement
+	if err := database.DB.
+		Where("group_id = ? AND status = ? AND voided_at IS NULL", groupID, models.SettlementStatusConfirmed).
+		Where("(payer_id = ? AND receiver_id = ?) OR (payer_id = ? AND receiver_id = ?)", a, b, b, a).
+		Find(&settlements).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch settlements"})
+		return
+	}
+	for _, s := range settlements {
+		items = append(items, DebtBetweenItem{
+			Kind:        "settlement",
+			ID:          s.ID,
+			Date:        s.Date.Format("2006-01-02"),
+			Description: s.Note,
+			FromUserID:  s.PayerID,
+			ToUserID:    s.ReceiverID,
+			Amount:      s.Amount,
+			Effect:      effect(s.PayerID, -s.Amount),
+			sortDate:    s.Date,
+		})
+	}
+
+	// 2人の間の確定した債務免除
+	var forgivenesses []models.Forgiveness
+	if err := database.DB.
+		Where("group_id = ? AND status = ?", groupID, models.ForgivenessStatusConfirmed).
+		Where("(debtor_id = ? AND receiver_id = ?) OR (debtor_id = ? AND receiver_id = ?)", a, b, b, a).
+		Find(&forgivenesses).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch forgivenesses"})
+		return
+	}
+	loc := groupLocation(settings)
+	for _, f := range forgivenesses {
+		confirmedAt := f.CreatedAt
+		if f.ConfirmedAt != nil {
+			confirmedAt = *f.ConfirmedAt
+		}
+		date := localDate(confirmedAt, loc)
+		items = append(items, DebtBetweenItem{
+			Kind:        "forgiveness",
+			ID:          f.ID,
+			Date:        date.Format("2006-01-02"),
+			Description: f.Note,
+			FromUserID:  f.DebtorID,
+			ToUserID:    f.ReceiverID,
+			Amount:      f.Amount,
+			Effect:      effect(f.DebtorID, -f.Amount),
+			sortDate:    date,
+		})
+	}
+
+	// 差し引きは補助単位で合計する
+	var net int64
+	for _, item := range items {
+		net += money.ToMinor(item.Effect, currency)
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		if !items[i].sortDate.Equal(items[j].sortDate) {
+			return items[i].sortDate.Before(items[j].sortDate)
+		}
+		if items[i].Kind != items[j].Kind {
+			return items[i].Kind < items[j].Kind
+		}
+		return items[i].ID < items[j].ID
+	})
+
+	// 退会したメンバーはユーザー名で表示する
+	names, err := groupDisplayNames(database.DB, uint(groupID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch members"})
+		return
+	}
+	members := []DebtBetweenMember{{UserID: a}, {UserID: b}}
+	for i := range members {
+		if name, ok := names[members[i].UserID]; ok {
+			members[i].Username = name
+			continue
+		}
+		var user models.User
+		if err := database.DB.First(&user, members[i].UserID).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch members"})
+			return
+		}
+		members[i].Username = user.Username
+		members[i].Left = true
+	}
+
+	// 差し引きの借りを一方からもう一方への支払いとして表す（貸し借りがない場合は null）
+	var debt *PairwiseDebtSummary
+	if net != 0 {
+		from, to := members[1], members[0]
+		if net < 0 {
+			from, to = to, from
+		}
+		debt = &PairwiseDebtSummary{
+			FromUserID: from.UserID,
+			FromName:   from.Username,
+			ToUserID:   to.UserID,
+			ToName:     to.Username,
+			Amount:     money.FromMinor(max(net, -net), currency),
+			Left:       from.Left || to.Left,
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"groupID":  groupID,
+		"currency": currency,
+		"userA":    members[0],
+		"userB":    members[1],
+		"net":      money.FromMinor(net, currency),
+		"debt":     debt,
+		"items":    items,
+	})
+}
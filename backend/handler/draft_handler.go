@@ -0,0 +1,224 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ito-system/clear-up-share/backend/analytics"
+	"github.com/ito-system/clear-up-share/backend/database"
+	"github.com/ito-system/clear-up-share/backend/models"
+	"gorm.io/gorm/clause"
+)
+
+// GetDrafts は自分が保存したグループの下書きの支出を更新日時の新しい順に取得します
+// 下書きは AddExpense に draft: true を指定して保存し、EditExpense で編集できます
+// GET /api/v1/groups/:groupID/expenses/drafts
+func GetDrafts(c *gin.Context) {
+	// パスパラメータからgroupIDを取得
+	groupIDStr := c.Param("groupID")
+	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// ユーザーがグループのメンバーであることを確認
+	var membership models.Membership
+	if err := database.DB.Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	var drafts []models.Expense
+	if err := database.DB.Preload("Tags", orderTagsByName).
+		Where("group_id = ? AND status = ? AND created_by_id = ?", groupID, models.ExpenseStatusDraft, userID).
+		Order("updated_at DESC, id DESC").
+		Find(&drafts).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch drafts"})
+		return
+	}
+
+	responses := make([]gin.H, len(drafts))
+	for i, expense := range drafts {
+		responses[i] = gin.H{
+			"id":          expense.ID,
+			"payerID":     expense.PayerID,
+			"amount":      expense.Amount,
+			"description": expense.Description,
+			"date":        expense.Date.Format("2006-01-02"),
+			"categoryID":  expense.CategoryID,
+			"tags":        tagNames(expense.Tags),
+			"updatedAt":   expense.UpdatedAt,
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"groupID": groupID,
+		"drafts":  responses,
+	})
+}
+
+// PublishExpense は下書きの支出を公開し、通常の支出として負債計算と履歴に含めます
+// 登録時と同じく、承認が必要なグループや代理入力の場合は承認待ちになります
+// POST /api/v1/groups/:groupID/expenses/:expenseID/publish
+func PublishExpense(c *gin.Context) {
+	// パスパラメータからgroupIDとexpenseIDを取得
+	groupIDStr := c.Param("groupID")
+	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	expenseIDStr := c.Param("expenseID")
+	expenseID, err := strconv.ParseUint(expenseIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid expense ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// ユーザーがグループのメンバーであることを確認
+	var membership models.Membership
+	if err := database.DB.Preload("Group").Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	// アーカイブ済みのグループは読み取り専用
+	if membership.Group.ArchivedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Group is archived and read-only"})
+		return
+	}
+
+	// 支出を追加する権限があることを確認
+	if !hasPermission(membership.Role, PermAddExpense) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to add expenses"})
+		return
+	}
+
+	// グループのポリシー設定を取得
+	settings, err := loadGroupSettings(database.DB, uint(groupID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch group settings"})
+		return
+	}
+
+	// 今月の支出数が上限に達していないことを確認
+	if err := checkExpenseQuota(database.DB, uint(groupID), settings, 1); err != nil {
+		respondQuotaError(c, err)
+		return
+	}
+
+	// トランザクション開始
+	tx := database.DB.Begin()
+
+	// 自分の下書きを取得し、同時に公開されないようにする
+	var expense models.Expense
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ? AND group_id = ? AND status = ? AND created_by_id = ?", expenseID, groupID, models.ExpenseStatusDraft, userID).
+		First(&expense).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusNotFound, gin.H{"error": "Draft not found"})
+		return
+	}
+
+	var splits []models.Split
+	if err := tx.Where("expense_id = ?", expense.ID).Find(&splits).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch splits"})
+		return
+	}
+
+	// 下書きを保存した後に支払者や負担者が退会していないことを確認
+	nonMembers, err := lockGroupNonMembers(tx, uint(groupID), append([]uint{expense.PayerID}, splitDebtorIDs(splits)...))
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check members"})
+		return
+	}
+	if len(nonMembers) > 0 {
+		tx.Rollback()
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Payer and split members must belong to this group",
+			"userIDs": nonMembers,
+		})
+		return
+	}
+
+	// 承認が必要なグループでは、承認権限のないメンバーの支出は承認待ちになる
+	status := models.ExpenseStatusConfirmed
+	if settings.RequireExpenseApproval && !hasPermission(membership.Role, PermApproveExpense) {
+		status = models.ExpenseStatusPending
+	}
+
+	// 他のメンバーの代理で登録した支出は、支払者が承認するまで負債に含めない
+	needsPayerApproval, err := requiresPayerApproval(expense.PayerID, userID.(uint))
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Payer not found"})
+		return
+	}
+	if needsPayerApproval {
+		status = models.ExpenseStatusAwaitingPayer
+	}
+
+	expense.Status = status
+	if err := tx.Model(&expense).Update("status", expense.Status).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to publish expense"})
+		return
+	}
+
+	// 代理入力の場合は支払者に承認を依頼する
+	if needsPayerApproval {
+		if err := notifyPayerApproval(tx, expense); err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to notify payer"})
+			return
+		}
+	}
+
+	// アクティビティを記録（他のメンバーには公開した時点で追加されたものとして見える）
+	if err := recordActivity(tx, expense.GroupID, userID.(uint), models.ActivityExpenseAdded, "expense", expense.ID, map[string]interface{}{
+		"description": expense.Description,
+		"amount":      expense.Amount,
+		"payerID":     expense.PayerID,
+	}); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record activity"})
+		return
+	}
+
+	tx.Commit()
+
+	analytics.Track(analytics.EventExpenseAdded, userID.(uint), map[string]interface{}{
+		"groupId":     analytics.Anonymize("group", expense.GroupID),
+		"memberCount": len(splits),
+		"status":      expense.Status,
+		"delegated":   needsPayerApproval,
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Expense published successfully",
+		"expense": gin.H{
+			"id":      expense.ID,
+			"groupID": expense.GroupID,
+			"status":  expense.Status,
+		},
+	})
+}
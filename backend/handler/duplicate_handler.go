@@ -0,0 +1,228 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ito-system/clear-up-share/backend/analytics"
+	"github.com/ito-system/clear-up-share/backend/database"
+	"github.com/ito-system/clear-up-share/backend/models"
+)
+
+// DuplicateExpense は既存の支出の説明・メモ・金額（税金とチップを含む）・カテゴリ・タグ・負担の分け方（負担額と品目）をコピーし、今日の日付で新しい支出として登録します
+// 毎週の買い物など同じ内容の支出を繰り返し登録する場合に使います（承認の要否は新規登録と同じく判定します）
+// POST /api/v1/groups/:groupID/expenses/:expenseID/duplicate
+func DuplicateExpense(c *gin.Context) {
+	// パスパラメータからgroupIDとexpenseIDを取得
+	groupIDStr := c.Param("groupID")
+	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	expenseIDStr := c.Param("expenseID")
+	expenseID, err := strconv.ParseUint(expenseIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid expense ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// ユーザーがグループのメンバーであることを確認
+	var membership models.Membership
+	if err := database.DB.Preload("Group").Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	// アーカイブ済みのグループは読み取り専用
+	if membership.Group.ArchivedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Group is archived and read-only"})
+		return
+	}
+
+	// 支出を追加する権限があることを確認
+	if !hasPermission(membership.Role, PermAddExpense) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to add expenses"})
+		return
+	}
+
+	// コピー元の支出を取得（下書きはコピーできない）
+	var source models.Expense
+	if err := database.DB.Preload("Tags").Scopes(visibleExpenses(userID, false)).Where("id = ? AND group_id = ?", expenseID, groupID).First(&source).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Expense not found"})
+		return
+	}
+
+	// グループのポリシー設定を取得
+	settings, err := loadGroupSettings(database.DB, uint(groupID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch group settings"})
+		return
+	}
+
+	// 今月の支出数が上限に達していないことを確認
+	if err := checkExpenseQuota(database.DB, uint(groupID), settings, 1); err != nil {
+		respondQuotaError(c, err)
+		return
+	}
+
+	// トランザクション開始
+	tx := database.DB.Begin()
+
+	var sourceSplits []models.Split
+	if err := tx.Where("expense_id = ?", source.ID).Order("id").Find(&sourceSplits).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch splits"})
+		return
+	}
+	var sourceItems []models.ExpenseItem
+	if err := tx.Where("expense_id = ?", source.ID).Order("id").Find(&sourceItems).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch expense items"})
+		return
+	}
+
+	// 元の支出を登録した後に支払者や負担者が退会していないことを確認し、完了するまで退会・除名されないようにする
+	nonMembers, err := lockGroupNonMembers(tx, uint(groupID), append([]uint{source.PayerID}, splitDebtorIDs(sourceSplits)...))
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check members"})
+		return
+	}
+	if len(nonMembers) > 0 {
+		tx.Rollback()
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "Payer or split members have left this group",
+			"userIDs": nonMembers,
+		})
+		return
+	}
+
+	// カテゴリが削除されていないことを確認し、完了するまで削除されないようにする
+	if err := lockExpenseCategory(tx, uint(groupID), source.CategoryID); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check category"})
+		return
+	}
+
+	// 承認が必要なグループでは、承認権限のないメンバーの支出は承認待ちになる
+	status := models.ExpenseStatusConfirmed
+	if settings.RequireExpenseApproval && !hasPermission(membership.Role, PermApproveExpense) {
+		status = models.ExpenseStatusPending
+	}
+
+	// 他のメンバーの代理で登録した支出は、支払者が承認するまで負債に含めない
+	needsPayerApproval, err := requiresPayerApproval(source.PayerID, userID.(uint))
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Payer not found"})
+		return
+	}
+	if needsPayerApproval {
+		status = models.ExpenseStatusAwaitingPayer
+	}
+
+	// Expenseを作成（日付はグループのタイムゾーンでの今日の日付）
+	expense := models.Expense{
+		GroupID:        uint(groupID),
+		PayerID:        source.PayerID,
+		Amount:         source.Amount,
+		Type:           source.Type,
+		Description:    source.Description,
+		Notes:          source.Notes,
+		Latitude:       source.Latitude,
+		Longitude:      source.Longitude,
+		PlaceName:      source.PlaceName,
+		Date:           groupToday(settings),
+		Status:         status,
+		CreatedByID:    userID.(uint),
+		Currency:       source.Currency,
+		ForeignAmount:  source.ForeignAmount,
+		ExchangeRate:   source.ExchangeRate,
+		Tax:            source.Tax,
+		Tip:            source.Tip,
+		SurchargeSplit: source.SurchargeSplit,
+		CategoryID:     source.CategoryID,
+		Tags:           source.Tags,
+	}
+	if err := tx.Create(&expense).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create expense"})
+		return
+	}
+
+	// Splitをコピー
+	for _, sourceSplit := range sourceSplits {
+		split := models.Split{
+			ExpenseID: expense.ID,
+			DebtorID:  sourceSplit.DebtorID,
+			AmountDue: sourceSplit.AmountDue,
+			Percent:   sourceSplit.Percent,
+		}
+		if err := tx.Create(&split).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create split"})
+			return
+		}
+	}
+
+	// 品目をコピー
+	for _, sourceItem := range sourceItems {
+		item := models.ExpenseItem{
+			ExpenseID: expense.ID,
+			Name:      sourceItem.Name,
+			Price:     sourceItem.Price,
+			MemberIDs: sourceItem.MemberIDs,
+		}
+		if err := tx.Create(&item).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create expense item"})
+			return
+		}
+	}
+
+	// 代理入力の場合は支払者に承認を依頼する
+	if needsPayerApproval {
+		if err := notifyPayerApproval(tx, expense); err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to notify payer"})
+			return
+		}
+	}
+
+	// アクティビティを記録
+	if err := recordActivity(tx, expense.GroupID, userID.(uint), models.ActivityExpenseAdded, "expense", expense.ID, map[string]interface{}{
+		"description":    expense.Description,
+		"amount":         expense.Amount,
+		"payerID":        expense.PayerID,
+		"duplicatedFrom": source.ID,
+	}); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record activity"})
+		return
+	}
+
+	tx.Commit()
+
+	analytics.Track(analytics.EventExpenseAdded, userID.(uint), map[string]interface{}{
+		"groupId":     analytics.Anonymize("group", expense.GroupID),
+		"memberCount": len(sourceSplits),
+		"status":      expense.Status,
+		"delegated":   needsPayerApproval,
+		"duplicated":  true,
+	})
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Expense duplicated successfully",
+		"expense": expenseResponse(expense, membership.Group.Currency),
+	})
+}
@@ -1,22 +1,65 @@
 package handler
 
 import (
+	"errors"
+	"fmt"
+	"math"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/ito-system/clear-up-share/backend/analytics"
 	"github.com/ito-system/clear-up-share/backend/database"
 	"github.com/ito-system/clear-up-share/backend/models"
+	"github.com/ito-system/clear-up-share/backend/money"
+	"github.com/ito-system/clear-up-share/backend/utils"
+	"gorm.io/gorm"
 )
 
+// SplitInput は負担者ごとの負担額の入力形式（SplitType が exact の場合は Amount、percentage の場合は Percent を使います）
+type SplitInput struct {
+	MemberID uint    `json:"memberID" binding:"required"`
+	Amount   float64 `json:"amount" binding:"gte=0"`
+	Percent  float64 `json:"percent" binding:"gte=0,lte=100"`
+}
+
+// ExpenseItemInput は品目ごとに分ける支出の1品目の入力形式
+type ExpenseItemInput struct {
+	Name      string  `json:"name" binding:"required,max=100"`
+	Price     float64 `json:"price" binding:"gt=0"`
+	MemberIDs []uint  `json:"memberIDs" binding:"required,min=1"`
+}
+
 // AddExpenseInput は支出追加リクエストの入力形式
+// SplitType を省略した場合は、Items があれば itemized、Splits があれば exact、どちらもなければ equal として扱います
+// exact / percentage では Splits、itemized では Items の負担者で記録します（MemberIDs は無視されます）
+// personal では支払者が全額を負担します（MemberIDs・Splits・Items は無視されます）
+// PresetID を指定した場合は SplitType・MemberIDs・Splits・Items の代わりにプリセットの分け方を使います
 type AddExpenseInput struct {
-	Description string  `json:"description" binding:"required"`
-	Amount      float64 `json:"amount" binding:"required,gt=0"`
-	PayerID     uint    `json:"payerID" binding:"required"`
-	Date        string  `json:"date" binding:"required"`
-	MemberIDs   []uint  `json:"memberIDs" binding:"required,min=1"`
+	Description    string             `json:"description" binding:"required"`
+	Notes          string             `json:"notes" binding:"max=5000"`
+	Amount         float64            `json:"amount" binding:"required,gt=0"`
+	Type           string             `json:"type" binding:"omitempty,oneof=expense refund"` // refund の場合は amount を払い戻された額として負担者の負担を減らす（省略時は expense）
+	PayerID        uint               `json:"payerID" binding:"required"`
+	Date           string             `json:"date" binding:"required"` // YYYY-MM-DD または RFC3339 の日時
+	SplitType      string             `json:"splitType" binding:"omitempty,oneof=equal exact percentage itemized personal"`
+	MemberIDs      []uint             `json:"memberIDs" binding:"omitempty,min=1"`
+	Splits         []SplitInput       `json:"splits" binding:"omitempty,min=1,dive"`
+	Items          []ExpenseItemInput `json:"items" binding:"omitempty,min=1,dive"`
+	PresetID       *uint              `json:"presetID"`                                                              // 指定した場合は分け方のプリセットの負担者と割合を使う
+	Currency       string             `json:"currency"`                                                              // 省略した場合はグループの通貨
+	ExchangeRate   float64            `json:"exchangeRate" binding:"omitempty,gt=0"`                                 // currency の1単位あたりのグループの通貨の金額
+	Latitude       *float64           `json:"latitude" binding:"required_with=Longitude,omitempty,gte=-90,lte=90"`   // 支払った場所の緯度
+	Longitude      *float64           `json:"longitude" binding:"required_with=Latitude,omitempty,gte=-180,lte=180"` // 支払った場所の経度
+	PlaceName      string             `json:"placeName" binding:"max=100"`                                           // 支払った場所の名前
+	Tax            float64            `json:"tax" binding:"gte=0"`                                                   // 税金（品目ごとに分ける場合のみ、amount に含める）
+	Tip            float64            `json:"tip" binding:"gte=0"`                                                   // チップ・サービス料（品目ごとに分ける場合のみ、amount に含める）
+	SurchargeSplit string             `json:"surchargeSplit" binding:"omitempty,oneof=proportional equal"`           // 税金とチップの分け方（省略時は proportional）
+	CategoryID     *uint              `json:"categoryID"`                                                            // 省略した場合は未分類
+	Tags           []string           `json:"tags" binding:"omitempty,max=20,dive,max=30"`
+	Draft          bool               `json:"draft"` // true の場合は下書きとして保存（登録時のみ、公開するまで登録者本人にのみ表示）
 }
 
 // AddExpense は新規支出を追加します
@@ -39,11 +82,23 @@ func AddExpense(c *gin.Context) {
 
 	// ユーザーがグループのメンバーであることを確認
 	var membership models.Membership
-	if err := database.DB.Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+	if err := database.DB.Preload("Group").Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
 		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
 		return
 	}
 
+	// アーカイブ済みのグループは読み取り専用
+	if membership.Group.ArchivedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Group is archived and read-only"})
+		return
+	}
+
+	// 支出を追加する権限があることを確認
+	if !hasPermission(membership.Role, PermAddExpense) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to add expenses"})
+		return
+	}
+
 	// リクエストボディをバインド
 	var input AddExpenseInput
 	if err := c.ShouldBindJSON(&input); err != nil {
@@ -51,24 +106,126 @@ func AddExpense(c *gin.Context) {
 		return
 	}
 
-	// 日付をパース
-	date, err := time.Parse("2006-01-02", input.Date)
+	// グループのポリシー設定を取得
+	settings, err := loadGroupSettings(database.DB, uint(groupID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch group settings"})
+		return
+	}
+
+	// 日付をパース（日時の場合はグループのタイムゾーンでの日付にする）
+	date, occurredAt, err := parseExpenseDate(input.Date, groupLocation(settings))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 今月の支出数が上限に達していないことを確認（下書きは公開時に確認する）
+	if !input.Draft {
+		if err := checkExpenseQuota(database.DB, uint(groupID), settings, 1); err != nil {
+			respondQuotaError(c, err)
+			return
+		}
+	}
+
+	// 支出を入力した通貨を確認
+	currency, err := expenseCurrency(input, membership.Group.Currency)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 分け方のプリセットが指定されていれば反映する
+	if err := applySplitPreset(database.DB, uint(groupID), &input); err != nil {
+		respondSplitPresetError(c, err)
+		return
+	}
+
+	// 負担者ごとの負担額を決定（指定がなければ均等割り）
+	splits, items, err := expenseSplits(input, settings.RoundingMode, currency)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format. Use YYYY-MM-DD"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
+	// 承認が必要なグループでは、承認権限のないメンバーの支出は承認待ちになる
+	status := models.ExpenseStatusConfirmed
+	if settings.RequireExpenseApproval && !hasPermission(membership.Role, PermApproveExpense) {
+		status = models.ExpenseStatusPending
+	}
+
 	// トランザクション開始
 	tx := database.DB.Begin()
 
+	// 支払者と負担者がグループのメンバーであることを確認し、完了するまで退会・除名されないようにする
+	nonMembers, err := lockGroupNonMembers(tx, uint(groupID), append([]uint{input.PayerID}, splitDebtorIDs(splits)...))
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check members"})
+		return
+	}
+	if len(nonMembers) > 0 {
+		tx.Rollback()
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":  "Payer and split members must belong to this group",
+			"fields": expenseNonMemberFields(input, splits, nonMembers),
+		})
+		return
+	}
+
+	// カテゴリがグループのものであることを確認し、完了するまで削除されないようにする
+	if err := lockExpenseCategory(tx, uint(groupID), input.CategoryID); err != nil {
+		tx.Rollback()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Category not found in this group"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check category"})
+		return
+	}
+
+	// タグを取得（まだないものは作成）
+	tags, err := resolveTags(tx, uint(groupID), normalizeTags(input.Tags))
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save tags"})
+		return
+	}
+
+	// 他のメンバーの代理で登録した支出は、支払者が承認するまで負債に含めない
+	needsPayerApproval, err := requiresPayerApproval(input.PayerID, userID.(uint))
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Payer not found"})
+		return
+	}
+	if needsPayerApproval {
+		status = models.ExpenseStatusAwaitingPayer
+	}
+
+	// 下書きは公開するまで承認の依頼やアクティビティの記録をしない
+	if input.Draft {
+		status = models.ExpenseStatusDraft
+		needsPayerApproval = false
+	}
+
 	// Expenseを作成
 	expense := models.Expense{
 		GroupID:     uint(groupID),
 		PayerID:     input.PayerID,
-		Amount:      input.Amount,
 		Description: input.Description,
+		Notes:       input.Notes,
 		Date:        date,
+		OccurredAt:  occurredAt,
+		Latitude:    input.Latitude,
+		Longitude:   input.Longitude,
+		PlaceName:   input.PlaceName,
+		Status:      status,
+		CreatedByID: userID.(uint),
+		CategoryID:  input.CategoryID,
+		Tags:        tags,
 	}
+	setExpenseAmount(&expense, input, currency, membership.Group.Currency, splits, items)
 
 	if err := tx.Create(&expense).Error; err != nil {
 		tx.Rollback()
@@ -76,16 +233,9 @@ func AddExpense(c *gin.Context) {
 		return
 	}
 
-	// Splitを作成（均等割り）
-	memberCount := len(input.MemberIDs)
-	amountPerMember := input.Amount / float64(memberCount)
-
-	for _, memberID := range input.MemberIDs {
-		split := models.Split{
-			ExpenseID: expense.ID,
-			DebtorID:  memberID,
-			AmountDue: amountPerMember,
-		}
+	// Splitを作成
+	for _, split := range splits {
+		split.ExpenseID = expense.ID
 		if err := tx.Create(&split).Error; err != nil {
 			tx.Rollback()
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create split"})
@@ -93,19 +243,95 @@ func AddExpense(c *gin.Context) {
 		}
 	}
 
+	// 品目を保存
+	for _, item := range items {
+		item.ExpenseID = expense.ID
+		if err := tx.Create(&item).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create expense item"})
+			return
+		}
+	}
+
+	// 代理入力の場合は支払者に承認を依頼する
+	if needsPayerApproval {
+		if err := notifyPayerApproval(tx, expense); err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to notify payer"})
+			return
+		}
+	}
+
+	// アクティビティを記録
+	if !input.Draft {
+		if err := recordActivity(tx, expense.GroupID, userID.(uint), models.ActivityExpenseAdded, "expense", expense.ID, map[string]interface{}{
+			"description": expense.Description,
+			"amount":      expense.Amount,
+			"payerID":     expense.PayerID,
+		}); err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record activity"})
+			return
+		}
+	}
+
+	// カテゴリの予算に対する支出の割合がしきい値を超えた場合はメンバーに通知する
+	budgetAlert, err := checkBudgetAlert(tx, expense, settings)
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check budget"})
+		return
+	}
+
 	tx.Commit()
 
-	c.JSON(http.StatusCreated, gin.H{
+	if !input.Draft {
+		analytics.Track(analytics.EventExpenseAdded, userID.(uint), map[string]interface{}{
+			"groupId":     analytics.Anonymize("group", expense.GroupID),
+			"memberCount": len(splits),
+			"status":      expense.Status,
+			"delegated":   needsPayerApproval,
+		})
+	}
+
+	response := gin.H{
 		"message": "Expense created successfully",
-		"expense": gin.H{
-			"id":          expense.ID,
-			"groupID":     expense.GroupID,
-			"payerID":     expense.PayerID,
-			"amount":      expense.Amount,
-			"description": expense.Description,
-			"date":        expense.Date.Format("2006-01-02"),
-		},
-	})
+		"expense": expenseResponse(expense, membership.Group.Currency),
+	}
+	if budgetAlert != nil {
+		response["budgetAlert"] = budgetAlert
+	}
+	c.JSON(http.StatusCreated, response)
+}
+
+// expenseResponse は登録・編集した支出のレスポンスの形式を返します
+func expenseResponse(expense models.Expense, groupCurrency string) gin.H {
+	return gin.H{
+		"id":                 expense.ID,
+		"groupID":            expense.GroupID,
+		"payerID":            expense.PayerID,
+		"amount":             expense.Amount,
+		"type":               expense.Type,
+		"description":        expense.Description,
+		"notes":              expense.Notes,
+		"date":               expense.Date.Format("2006-01-02"),
+		"occurredAt":         expense.OccurredAt,
+		"latitude":           expense.Latitude,
+		"longitude":          expense.Longitude,
+		"placeName":          expense.PlaceName,
+		"currency":           groupCurrency,
+		"foreignCurrency":    expense.Currency,
+		"foreignAmount":      expense.ForeignAmount,
+		"exchangeRate":       expense.ExchangeRate,
+		"tax":                expense.Tax,
+		"tip":                expense.Tip,
+		"surchargeSplit":     expense.SurchargeSplit,
+		"status":             expense.Status,
+		"settlementPeriodID": expense.SettlementPeriodID,
+		"createdByID":        expense.CreatedByID,
+		"categoryID":         expense.CategoryID,
+		"tags":               tagNames(expense.Tags),
+	}
 }
 
 // EditExpense は既存の支出を編集します
@@ -135,18 +361,54 @@ func EditExpense(c *gin.Context) {
 
 	// ユーザーがグループのメンバーであることを確認
 	var membership models.Membership
-	if err := database.DB.Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+	if err := database.DB.Preload("Group").Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
 		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
 		return
 	}
 
-	// 既存のExpenseを取得
+	// アーカイブ済みのグループは読み取り専用
+	if membership.Group.ArchivedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Group is archived and read-only"})
+		return
+	}
+
+	// リーガルホールド中のグループでは記録を変更できない
+	if rejectIfLegalHold(c, membership.Group) {
+		return
+	}
+
+	// 既存のExpenseを取得（他のメンバーの下書きは見つからないものとして扱う）
 	var expense models.Expense
-	if err := database.DB.Where("id = ? AND group_id = ?", expenseID, groupID).First(&expense).Error; err != nil {
+	if err := database.DB.Scopes(visibleExpenses(userID, true)).Where("id = ? AND group_id = ?", expenseID, groupID).First(&expense).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Expense not found"})
 		return
 	}
 
+	// 支出を編集する権限があることを確認
+	if !hasPermission(membership.Role, expenseEditPermission(expense, userID.(uint))) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to edit this expense"})
+		return
+	}
+
+	// 締めた清算期間に含まれる支出は、管理者がロックを解除するまで編集できない
+	if expense.SettlementPeriodID != nil {
+		respondExpenseLocked(c, expense)
+		return
+	}
+
+	// グループのポリシー設定を取得
+	settings, err := loadGroupSettings(database.DB, uint(groupID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch group settings"})
+		return
+	}
+
+	// メンバーによる編集が許可されていない場合は、管理者のみ編集できる
+	if !settings.AllowMemberEdit && !hasPermission(membership.Role, PermEditAnyExpense) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only admins can edit expenses in this group"})
+		return
+	}
+
 	// リクエストボディをバインド
 	var input AddExpenseInput
 	if err := c.ShouldBindJSON(&input); err != nil {
@@ -154,28 +416,163 @@ func EditExpense(c *gin.Context) {
 		return
 	}
 
-	// 日付をパース
-	date, err := time.Parse("2006-01-02", input.Date)
+	// 日付をパース（日時の場合はグループのタイムゾーンでの日付にする）
+	date, occurredAt, err := parseExpenseDate(input.Date, groupLocation(settings))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 支出を入力した通貨を確認
+	currency, err := expenseCurrency(input, membership.Group.Currency)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 分け方のプリセットが指定されていれば反映する
+	if err := applySplitPreset(database.DB, uint(groupID), &input); err != nil {
+		respondSplitPresetError(c, err)
+		return
+	}
+
+	// 負担者ごとの負担額を決定（指定がなければ均等割り）
+	splits, items, err := expenseSplits(input, settings.RoundingMode, currency)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format. Use YYYY-MM-DD"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
 	// トランザクション開始
 	tx := database.DB.Begin()
 
-	// 既存のSplitを削除
+	// 支払者と負担者がグループのメンバーであることを確認し、完了するまで退会・除名されないようにする
+	nonMembers, err := lockGroupNonMembers(tx, uint(groupID), append([]uint{input.PayerID}, splitDebtorIDs(splits)...))
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check members"})
+		return
+	}
+	if len(nonMembers) > 0 {
+		tx.Rollback()
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":  "Payer and split members must belong to this group",
+			"fields": expenseNonMemberFields(input, splits, nonMembers),
+		})
+		return
+	}
+
+	// カテゴリがグループのものであることを確認し、完了するまで削除されないようにする
+	if err := lockExpenseCategory(tx, uint(groupID), input.CategoryID); err != nil {
+		tx.Rollback()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Category not found in this group"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check category"})
+		return
+	}
+
+	// タグを取得（まだないものは作成）
+	tags, err := resolveTags(tx, uint(groupID), normalizeTags(input.Tags))
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save tags"})
+		return
+	}
+
+	// 金額・負担額が変わったかを比べるため、編集前の負担額と品目を取得
+	var beforeSplits []models.Split
+	var beforeItems []models.ExpenseItem
+	if err := tx.Where("expense_id = ?", expenseID).Find(&beforeSplits).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch splits"})
+		return
+	}
+	if err := tx.Where("expense_id = ?", expenseID).Order("id").Find(&beforeItems).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch expense items"})
+		return
+	}
+
+	// 編集前の内容を編集履歴に保存
+	if err := recordExpenseRevision(tx, expense, userID.(uint)); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save expense revision"})
+		return
+	}
+
+	// 既存のSplitと品目を削除
 	if err := tx.Where("expense_id = ?", expenseID).Delete(&models.Split{}).Error; err != nil {
 		tx.Rollback()
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete existing splits"})
 		return
 	}
+	if err := tx.Where("expense_id = ?", expenseID).Delete(&models.ExpenseItem{}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete existing expense items"})
+		return
+	}
+
+	// 内容が変わるため、メンバーの確認を取り消す
+	if err := tx.Where("expense_id = ?", expenseID).Delete(&models.ExpenseAcknowledgement{}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset acknowledgements"})
+		return
+	}
+
+	// 変更前の値を保持
+	before := expense
 
 	// Expenseを更新
 	expense.PayerID = input.PayerID
-	expense.Amount = input.Amount
+	setExpenseAmount(&expense, input, currency, membership.Group.Currency, splits, items)
 	expense.Description = input.Description
+	expense.Notes = input.Notes
 	expense.Date = date
+	expense.OccurredAt = occurredAt
+	expense.Latitude = input.Latitude
+	expense.Longitude = input.Longitude
+	expense.PlaceName = input.PlaceName
+	expense.CategoryID = input.CategoryID
+
+	// 承認が必要なグループでは、承認権限のないメンバーが編集すると再び承認待ちになる（下書きは下書きのまま）
+	isDraft := expense.Status == models.ExpenseStatusDraft
+	if !isDraft && settings.RequireExpenseApproval && !hasPermission(membership.Role, PermApproveExpense) {
+		expense.Status = models.ExpenseStatusPending
+	}
+
+	// 支払者を他のメンバーに変更した場合は、新しい支払者の承認が必要
+	needsPayerApproval := false
+	if !isDraft && expense.PayerID != before.PayerID {
+		needsPayerApproval, err = requiresPayerApproval(expense.PayerID, userID.(uint))
+		if err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Payer not found"})
+			return
+		}
+		if needsPayerApproval {
+			expense.Status = models.ExpenseStatusAwaitingPayer
+		}
+	}
+
+	// 支払者・管理者以外（代理入力したメンバー）が確定済みの支出の金額や負担額を変えた場合は、支払者の承認をやり直す
+	if !needsPayerApproval && before.Status == models.ExpenseStatusConfirmed && expense.PayerID != userID.(uint) &&
+		!hasPermission(membership.Role, PermEditAnyExpense) &&
+		expenseAmountsChanged(before, beforeSplits, beforeItems, expense, splits, items, membership.Group.Currency) {
+		needsPayerApproval, err = requiresPayerApproval(expense.PayerID, userID.(uint))
+		if err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Payer not found"})
+			return
+		}
+		if needsPayerApproval {
+			expense.Status = models.ExpenseStatusAwaitingPayer
+		}
+	}
+	if expense.Status != models.ExpenseStatusConfirmed {
+		expense.ApprovedByID = nil
+	}
 
 	if err := tx.Save(&expense).Error; err != nil {
 		tx.Rollback()
@@ -183,16 +580,16 @@ func EditExpense(c *gin.Context) {
 		return
 	}
 
-	// 新しいSplitを作成（均等割り）
-	memberCount := len(input.MemberIDs)
-	amountPerMember := input.Amount / float64(memberCount)
+	// タグを付け替える
+	if err := tx.Model(&expense).Association("Tags").Replace(tags); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update tags"})
+		return
+	}
 
-	for _, memberID := range input.MemberIDs {
-		split := models.Split{
-			ExpenseID: expense.ID,
-			DebtorID:  memberID,
-			AmountDue: amountPerMember,
-		}
+	// 新しいSplitを作成
+	for _, split := range splits {
+		split.ExpenseID = expense.ID
 		if err := tx.Create(&split).Error; err != nil {
 			tx.Rollback()
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create split"})
@@ -200,18 +597,52 @@ func EditExpense(c *gin.Context) {
 		}
 	}
 
+	// 品目を保存
+	for _, item := range items {
+		item.ExpenseID = expense.ID
+		if err := tx.Create(&item).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create expense item"})
+			return
+		}
+	}
+
+	// 代理入力の場合は支払者に承認を依頼する
+	if needsPayerApproval {
+		if err := notifyPayerApproval(tx, expense); err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to notify payer"})
+			return
+		}
+	}
+
+	// アクティビティを記録（下書きの編集は記録しない）
+	if !isDraft {
+		if err := recordActivity(tx, expense.GroupID, userID.(uint), models.ActivityExpenseEdited, "expense", expense.ID, map[string]interface{}{
+			"before": map[string]interface{}{
+				"description": before.Description,
+				"amount":      before.Amount,
+				"payerID":     before.PayerID,
+				"date":        before.Date.Format("2006-01-02"),
+			},
+			"after": map[string]interface{}{
+				"description": expense.Description,
+				"amount":      expense.Amount,
+				"payerID":     expense.PayerID,
+				"date":        expense.Date.Format("2006-01-02"),
+			},
+		}); err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record activity"})
+			return
+		}
+	}
+
 	tx.Commit()
 
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Expense updated successfully",
-		"expense": gin.H{
-			"id":          expense.ID,
-			"groupID":     expense.GroupID,
-			"payerID":     expense.PayerID,
-			"amount":      expense.Amount,
-			"description": expense.Description,
-			"date":        expense.Date.Format("2006-01-02"),
-		},
+		"expense": expenseResponse(expense, membership.Group.Currency),
 	})
 }
 
@@ -242,38 +673,917 @@ func DeleteExpense(c *gin.Context) {
 
 	// ユーザーがグループのメンバーであることを確認
 	var membership models.Membership
-	if err := database.DB.Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+	if err := database.DB.Preload("Group").Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
 		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
 		return
 	}
 
+	// アーカイブ済みのグループは読み取り専用
+	if membership.Group.ArchivedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Group is archived and read-only"})
+		return
+	}
+
+	// リーガルホールド中のグループでは記録を削除できない
+	if rejectIfLegalHold(c, membership.Group) {
+		return
+	}
+
 	// 既存のExpenseを取得
 	var expense models.Expense
-	if err := database.DB.Where("id = ? AND group_id = ?", expenseID, groupID).First(&expense).Error; err != nil {
+	if err := database.DB.Scopes(visibleExpenses(userID, true)).Where("id = ? AND group_id = ?", expenseID, groupID).First(&expense).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Expense not found"})
 		return
 	}
 
+	// 支出を削除する権限があることを確認
+	if !hasPermission(membership.Role, expenseEditPermission(expense, userID.(uint))) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to delete this expense"})
+		return
+	}
+
+	// 締めた清算期間に含まれる支出は、管理者がロックを解除するまで削除できない
+	if expense.SettlementPeriodID != nil {
+		respondExpenseLocked(c, expense)
+		return
+	}
+
+	// グループのポリシー設定を取得
+	settings, err := loadGroupSettings(database.DB, uint(groupID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch group settings"})
+		return
+	}
+
+	// メンバーによる編集が許可されていない場合は、管理者のみ削除できる
+	if !settings.AllowMemberEdit && !hasPermission(membership.Role, PermEditAnyExpense) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only admins can delete expenses in this group"})
+		return
+	}
+
 	// トランザクション開始
 	tx := database.DB.Begin()
 
+	// 支出と関連するレコードを同じ日時で論理削除する（RestoreExpense はこの日時で削除されたレコードのみ復元する）
+	deletedAt := time.Now()
+
 	// 関連するSplitを削除
-	if err := tx.Where("expense_id = ?", expenseID).Delete(&models.Split{}).Error; err != nil {
+	if err := tx.Model(&models.Split{}).Where("expense_id = ?", expenseID).Update("deleted_at", deletedAt).Error; err != nil {
 		tx.Rollback()
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete splits"})
 		return
 	}
+	if err := tx.Model(&models.ExpenseItem{}).Where("expense_id = ?", expenseID).Update("deleted_at", deletedAt).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete expense items"})
+		return
+	}
+	// レシートのファイルは保持期間ポリシーで物理削除されるまで残す
+	if err := tx.Model(&models.Receipt{}).Where("expense_id = ?", expenseID).Update("deleted_at", deletedAt).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete receipts"})
+		return
+	}
 
-	// Expenseを削除
-	if err := tx.Delete(&expense).Error; err != nil {
+	// Expenseを削除（ゴミ箱に移動）
+	if err := tx.Model(&expense).Update("deleted_at", deletedAt).Error; err != nil {
 		tx.Rollback()
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete expense"})
 		return
 	}
 
+	// アクティビティを記録（他のメンバーに見えない下書きの削除は記録しない）
+	if expense.Status != models.ExpenseStatusDraft {
+		if err := recordActivity(tx, expense.GroupID, userID.(uint), models.ActivityExpenseDeleted, "expense", expense.ID, map[string]interface{}{
+			"description": expense.Description,
+			"amount":      expense.Amount,
+		}); err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record activity"})
+			return
+		}
+	}
+
 	tx.Commit()
 
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Expense deleted successfully",
 	})
 }
+
+// ApproveExpense は承認待ちの支出を承認します
+// POST /api/v1/groups/:groupID/expenses/:expenseID/approve
+func ApproveExpense(c *gin.Context) {
+	// パスパラメータからgroupIDとexpenseIDを取得
+	groupIDStr := c.Param("groupID")
+	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	expenseIDStr := c.Param("expenseID")
+	expenseID, err := strconv.ParseUint(expenseIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid expense ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// ユーザーがグループのメンバーであることを確認
+	var membership models.Membership
+	if err := database.DB.Preload("Group").Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	// アーカイブ済みのグループは読み取り専用
+	if membership.Group.ArchivedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Group is archived and read-only"})
+		return
+	}
+
+	// 既存のExpenseを取得
+	var expense models.Expense
+	if err := database.DB.Scopes(visibleExpenses(userID, true)).Where("id = ? AND group_id = ?", expenseID, groupID).First(&expense).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Expense not found"})
+		return
+	}
+
+	// 承認待ちの種類に応じて承認できるユーザーを確認し、承認後のステータスを決める
+	newStatus := models.ExpenseStatusConfirmed
+	switch expense.Status {
+	case models.ExpenseStatusAwaitingPayer:
+		// 代理入力された支出は支払者本人のみ承認できる
+		if expense.PayerID != userID.(uint) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only the payer can approve this expense"})
+			return
+		}
+
+		// 承認が必要なグループでは、支払者が承認権限を持たない場合は続けて管理者の承認待ちになる
+		settings, err := loadGroupSettings(database.DB, uint(groupID))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch group settings"})
+			return
+		}
+		if settings.RequireExpenseApproval && !hasPermission(membership.Role, PermApproveExpense) {
+			newStatus = models.ExpenseStatusPending
+		}
+	case models.ExpenseStatusPending:
+		// 支出を承認する権限があることを確認
+		if !hasPermission(membership.Role, PermApproveExpense) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to approve expenses"})
+			return
+		}
+	default:
+		c.JSON(http.StatusConflict, gin.H{"error": "Expense is not pending approval"})
+		return
+	}
+
+	// トランザクション開始
+	tx := database.DB.Begin()
+
+	approverID := userID.(uint)
+	expense.Status = newStatus
+	expense.ApprovedByID = &approverID
+	if err := tx.Model(&expense).Updates(map[string]interface{}{
+		"status":         expense.Status,
+		"approved_by_id": expense.ApprovedByID,
+	}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to approve expense"})
+		return
+	}
+
+	// 代理入力した登録者に承認されたことを通知
+	if expense.CreatedByID != 0 && expense.CreatedByID != approverID {
+		message := "Your expense \"" + expense.Description + "\" was approved"
+		if err := notify(tx, expense.CreatedByID, expense.GroupID, models.NotificationExpenseApproved, message, "expense", expense.ID); err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send notification"})
+			return
+		}
+	}
+
+	// アクティビティを記録
+	if err := recordActivity(tx, expense.GroupID, userID.(uint), models.ActivityExpenseApproved, "expense", expense.ID, nil); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record activity"})
+		return
+	}
+
+	tx.Commit()
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Expense approved successfully",
+		"expense": gin.H{
+			"id":           expense.ID,
+			"groupID":      expense.GroupID,
+			"status":       expense.Status,
+			"approvedByID": expense.ApprovedByID,
+		},
+	})
+}
+
+// GetExpenses はグループの支出を日付の新しい順（同じ日付の中では支払日時、日付のみの場合は登録日時の新しい順）に取得します
+// category を指定した場合はそのカテゴリ（none の場合は未分類）、tag を指定した場合はそのタグが付いた支出に絞り込み、
+// 支払者・日付の範囲（from〜to、両端を含む）・金額の範囲・説明文（q、部分一致）でも絞り込めます
+// 絞り込みとページングはSQLで行い、totalAmount に絞り込んだ確定済みの支出の合計額を返します
+// 下書きは含めず、includeDrafts=true の場合は自分の下書きも含めます
+// GET /api/v1/groups/:groupID/expenses?category=<categoryID|none>&tag=<name>&payerID=&from=&to=&minAmount=&maxAmount=&q=&includeDrafts=&page=&limit=
+func GetExpenses(c *gin.Context) {
+	// パスパラメータからgroupIDを取得
+	groupIDStr := c.Param("groupID")
+	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// ユーザーがグループのメンバーであることを確認
+	var membership models.Membership
+	if err := database.DB.Preload("Group").Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	// 下書きは includeDrafts=true の場合に自分のものだけを含める
+	includeDrafts, _ := strconv.ParseBool(c.DefaultQuery("includeDrafts", "false"))
+	query := database.DB.Model(&models.Expense{}).Where("group_id = ?", groupID).Scopes(visibleExpenses(userID, includeDrafts))
+	if value := c.Query("category"); value != "" {
+		filter, err := categoryFilter(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category"})
+			return
+		}
+		query = query.Scopes(filter)
+	}
+	if tag := c.Query("tag"); tag != "" {
+		query = query.Scopes(tagFilter(tag))
+	}
+	if value := c.Query("payerID"); value != "" {
+		payerID, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payer ID"})
+			return
+		}
+		query = query.Where("payer_id = ?", payerID)
+	}
+	from, err := parseOptionalDate(c.Query("from"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from date format. Use YYYY-MM-DD"})
+		return
+	}
+	if from != nil {
+		query = query.Where("date >= ?", *from)
+	}
+	to, err := parseOptionalDate(c.Query("to"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to date format. Use YYYY-MM-DD"})
+		return
+	}
+	if to != nil {
+		query = query.Where("date < ?", to.AddDate(0, 0, 1))
+	}
+	if value := c.Query("minAmount"); value != "" {
+		minAmount, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid minAmount"})
+			return
+		}
+		query = query.Where("amount >= ?", minAmount)
+	}
+	if value := c.Query("maxAmount"); value != "" {
+		maxAmount, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid maxAmount"})
+			return
+		}
+		query = query.Where("amount <= ?", maxAmount)
+	}
+	if q := strings.TrimSpace(c.Query("q")); q != "" {
+		query = query.Where("LOWER(description) LIKE ?", "%"+strings.ToLower(q)+"%")
+	}
+
+	page, limit := parsePagination(c, 20, 100)
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count expenses"})
+		return
+	}
+
+	var totalAmount float64
+	if err := query.Session(&gorm.Session{}).
+		Where("status = ?", models.ExpenseStatusConfirmed).
+		Select("COALESCE(SUM(amount), 0)").
+		Scan(&totalAmount).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate expense total"})
+		return
+	}
+
+	var expenses []models.Expense
+	if err := query.Session(&gorm.Session{}).
+		Preload("Tags", orderTagsByName).
+		Order("date DESC, COALESCE(occurred_at, created_at) DESC, id DESC").
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Find(&expenses).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch expenses"})
+		return
+	}
+
+	responses := make([]gin.H, len(expenses))
+	for i, expense := range expenses {
+		responses[i] = gin.H{
+			"id":                 expense.ID,
+			"payerID":            expense.PayerID,
+			"amount":             expense.Amount,
+			"type":               expense.Type,
+			"foreignCurrency":    expense.Currency,
+			"foreignAmount":      expense.ForeignAmount,
+			"exchangeRate":       expense.ExchangeRate,
+			"description":        expense.Description,
+			"date":               expense.Date.Format("2006-01-02"),
+			"occurredAt":         expense.OccurredAt,
+			"status":             expense.Status,
+			"settlementPeriodID": expense.SettlementPeriodID,
+			"createdByID":        expense.CreatedByID,
+			"categoryID":         expense.CategoryID,
+			"tags":               tagNames(expense.Tags),
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"groupID":     groupID,
+		"currency":    membership.Group.Currency,
+		"expenses":    responses,
+		"totalAmount": totalAmount,
+		"page":        page,
+		"limit":       limit,
+		"total":       total,
+	})
+}
+
+// GetExpense は支出の詳細（負担者ごとの負担額・品目・レシート・カテゴリ・支払者と負担者の確認状況）を取得します
+// 編集フォームの初期値に使えるよう、負担者と支払者のグループ内の表示名も返します
+// GET /api/v1/groups/:groupID/expenses/:expenseID
+func GetExpense(c *gin.Context) {
+	// パスパラメータからgroupIDとexpenseIDを取得
+	groupIDStr := c.Param("groupID")
+	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	expenseIDStr := c.Param("expenseID")
+	expenseID, err := strconv.ParseUint(expenseIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid expense ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// ユーザーがグループのメンバーであることを確認
+	var membership models.Membership
+	if err := database.DB.Preload("Group").Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	// Expenseを取得
+	var expense models.Expense
+	if err := database.DB.Preload("Payer").Preload("Tags", orderTagsByName).Scopes(visibleExpenses(userID, true)).Where("id = ? AND group_id = ?", expenseID, groupID).First(&expense).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Expense not found"})
+		return
+	}
+
+	var splits []models.Split
+	if err := database.DB.Preload("Debtor").Where("expense_id = ?", expense.ID).Order("id").Find(&splits).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch splits"})
+		return
+	}
+
+	// カテゴリを取得（未分類の場合は null）
+	var category *CategoryRef
+	if expense.CategoryID != nil {
+		var found models.Category
+		if err := database.DB.First(&found, *expense.CategoryID).Error; err == nil {
+			category = &CategoryRef{ID: found.ID, Name: found.Name}
+		}
+	}
+
+	// グループ内の表示名を取得（退会済みのユーザーはユーザー名を表示）
+	names, err := groupDisplayNames(database.DB, uint(groupID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch members"})
+		return
+	}
+	displayName := func(user models.User) string {
+		if name, ok := names[user.ID]; ok {
+			return name
+		}
+		return user.Username
+	}
+
+	var items []models.ExpenseItem
+	if err := database.DB.Where("expense_id = ?", expense.ID).Order("id").Find(&items).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch expense items"})
+		return
+	}
+
+	var receipts []models.Receipt
+	if err := database.DB.Where("expense_id = ?", expense.ID).Order("id").Find(&receipts).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch receipts"})
+		return
+	}
+	receiptResponses, err := signReceipts(receipts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign receipt URLs"})
+		return
+	}
+
+	// 支払者・負担者ごとの確認状況を取得
+	acknowledgements, err := expenseAcknowledgements(database.DB, expense, splits, displayName)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch acknowledgements"})
+		return
+	}
+
+	splitResponses := make([]gin.H, len(splits))
+	for i, split := range splits {
+		splitResponses[i] = gin.H{
+			"memberID":  split.DebtorID,
+			"username":  displayName(split.Debtor),
+			"amountDue": split.AmountDue,
+			"percent":   split.Percent,
+		}
+	}
+
+	itemResponses := make([]gin.H, len(items))
+	for i, item := range items {
+		memberIDs := []uint{}
+		for _, id := range strings.Fields(item.MemberIDs) {
+			if memberID, err := strconv.ParseUint(id, 10, 32); err == nil {
+				memberIDs = append(memberIDs, uint(memberID))
+			}
+		}
+		itemResponses[i] = gin.H{
+			"id":        item.ID,
+			"name":      item.Name,
+			"price":     item.Price,
+			"memberIDs": memberIDs,
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"expense": gin.H{
+			"id":                 expense.ID,
+			"groupID":            expense.GroupID,
+			"payerID":            expense.PayerID,
+			"payerName":          displayName(expense.Payer),
+			"amount":             expense.Amount,
+			"type":               expense.Type,
+			"description":        expense.Description,
+			"notes":              expense.Notes,
+			"date":               expense.Date.Format("2006-01-02"),
+			"occurredAt":         expense.OccurredAt,
+			"latitude":           expense.Latitude,
+			"longitude":          expense.Longitude,
+			"placeName":          expense.PlaceName,
+			"currency":           membership.Group.Currency,
+			"foreignCurrency":    expense.Currency,
+			"foreignAmount":      expense.ForeignAmount,
+			"exchangeRate":       expense.ExchangeRate,
+			"tax":                expense.Tax,
+			"tip":                expense.Tip,
+			"surchargeSplit":     expense.SurchargeSplit,
+			"status":             expense.Status,
+			"settlementPeriodID": expense.SettlementPeriodID,
+			"createdByID":        expense.CreatedByID,
+			"categoryID":         expense.CategoryID,
+			"category":           category,
+			"tags":               tagNames(expense.Tags),
+			"splits":             splitResponses,
+			"items":              itemResponses,
+			"receipts":           receiptResponses,
+			"acknowledgements":   acknowledgements,
+		},
+	})
+}
+
+// requiresPayerApproval は代理入力された支出に支払者の承認が必要かを判定します
+// ログインできない仮メンバーが支払者の場合は承認不要です
+func requiresPayerApproval(payerID, submitterID uint) (bool, error) {
+	if payerID == submitterID {
+		return false, nil
+	}
+	var payer models.User
+	if err := database.DB.First(&payer, payerID).Error; err != nil {
+		return false, err
+	}
+	return !payer.IsPlaceholder, nil
+}
+
+// expenseAmountsChanged は支出の編集で金額・通貨・税金・チップ・負担額・品目のいずれかが変わったかを判定します
+// 金額はグループの通貨の補助単位で比べます
+func expenseAmountsChanged(before models.Expense, beforeSplits []models.Split, beforeItems []models.ExpenseItem, after models.Expense, splits []models.Split, items []models.ExpenseItem, currency string) bool {
+	minor := func(amount float64) int64 {
+		return money.ToMinor(amount, currency)
+	}
+	optional := func(a, b *float64) bool {
+		if a == nil || b == nil {
+			return a == b
+		}
+		return *a == *b
+	}
+	if minor(before.Amount) != minor(after.Amount) || before.Type != after.Type || before.Currency != after.Currency ||
+		!optional(before.ForeignAmount, after.ForeignAmount) || !optional(before.ExchangeRate, after.ExchangeRate) ||
+		!optional(before.Tax, after.Tax) || !optional(before.Tip, after.Tip) || before.SurchargeSplit != after.SurchargeSplit {
+		return true
+	}
+
+	// 負担額は負担者ごとに比べる
+	if len(beforeSplits) != len(splits) {
+		return true
+	}
+	shares := make(map[uint]int64, len(beforeSplits))
+	for _, s := range beforeSplits {
+		shares[s.DebtorID] += minor(s.AmountDue)
+	}
+	for _, s := range splits {
+		shares[s.DebtorID] -= minor(s.AmountDue)
+	}
+	for _, diff := range shares {
+		if diff != 0 {
+			return true
+		}
+	}
+
+	if len(beforeItems) != len(items) {
+		return true
+	}
+	for i := range items {
+		if beforeItems[i].Name != items[i].Name || minor(beforeItems[i].Price) != minor(items[i].Price) || beforeItems[i].MemberIDs != items[i].MemberIDs {
+			return true
+		}
+	}
+	return false
+}
+
+// notifyPayerApproval は代理入力された支出の承認を支払者に依頼する通知を作成します
+func notifyPayerApproval(tx *gorm.DB, expense models.Expense) error {
+	message := "An expense \"" + expense.Description + "\" was submitted on your behalf and needs your approval"
+	return notify(tx, expense.PayerID, expense.GroupID, models.NotificationExpenseApprovalRequested, message, "expense", expense.ID)
+}
+
+// expenseSplits は支出の負担者ごとのSplitと品目（どちらも ExpenseID は未設定）を返します
+// - equal: MemberIDs で均等割りします
+// - exact: 負担額の合計が支出額と一致する（通貨の補助単位の端数まで）ことを確認してそのまま使います
+// - percentage: 割合の合計が100%であることを確認し、端数処理モードに従って負担額に換算します（割合もSplitに保存します）
+// - itemized: 品目ごとの負担者から負担額を計算します（itemizedSplits を参照）
+func expenseSplits(input AddExpenseInput, mode string, currency string) ([]models.Split, []models.ExpenseItem, error) {
+	splitType := resolveSplitType(input)
+	if splitType == models.SplitTypeItemized {
+		return itemizedSplits(input, mode, currency)
+	}
+	if input.Tax > 0 || input.Tip > 0 {
+		return nil, nil, errors.New("Tax and tip can be set only for itemized splits")
+	}
+
+	// 個人的な支出は支払者が全額を負担する（支払者の貸借は支払額と負担額で相殺される）
+	if splitType == models.SplitTypePersonal {
+		return []models.Split{{DebtorID: input.PayerID, AmountDue: input.Amount}}, nil, nil
+	}
+
+	if splitType == models.SplitTypeEqual {
+		if len(input.MemberIDs) == 0 {
+			return nil, nil, errors.New("memberIDs is required for equal splits")
+		}
+		shares := splitEqually(input.Amount, len(input.MemberIDs), mode, currency)
+		splits := make([]models.Split, len(input.MemberIDs))
+		for i, memberID := range input.MemberIDs {
+			splits[i] = models.Split{DebtorID: memberID, AmountDue: shares[i]}
+		}
+		return splits, nil, nil
+	}
+
+	if len(input.Splits) == 0 {
+		return nil, nil, errors.New("splits is required for " + splitType + " splits")
+	}
+	seen := make(map[uint]bool, len(input.Splits))
+	for _, split := range input.Splits {
+		if seen[split.MemberID] {
+			return nil, nil, errors.New("Each member can appear only once in splits")
+		}
+		seen[split.MemberID] = true
+	}
+
+	splits := make([]models.Split, len(input.Splits))
+	total := 0.0
+	if splitType == models.SplitTypePercentage {
+		for _, split := range input.Splits {
+			total += split.Percent
+		}
+		if math.Abs(total-100) > 1e-9 {
+			return nil, nil, fmt.Errorf("Split percentages must add up to 100 (got %v)", total)
+		}
+
+		// 端数処理モードに従って割合を金額に換算し、負担額の合計が支出額と一致するようにする
+		percents := make([]float64, len(input.Splits))
+		for i, split := range input.Splits {
+			percents[i] = split.Percent
+		}
+		shares := money.AllocateRounded(input.Amount, percents, mode, currency)
+		for i, split := range input.Splits {
+			percent := split.Percent
+			splits[i] = models.Split{
+				DebtorID:  split.MemberID,
+				AmountDue: shares[i],
+				Percent:   &percent,
+			}
+		}
+		return splits, nil, nil
+	}
+
+	for i, split := range input.Splits {
+		splits[i] = models.Split{DebtorID: split.MemberID, AmountDue: split.Amount}
+		total += split.Amount
+	}
+	if math.Abs(total-input.Amount) > math.Pow10(-utils.CurrencyMinorUnits(currency))/2 {
+		return nil, nil, fmt.Errorf("Split amounts must add up to the expense amount (got %v, expected %v)", total, input.Amount)
+	}
+	return splits, nil, nil
+}
+
+// itemizedSplits は品目ごとの負担者から各メンバーの負担額を計算します
+// 品目の金額はその品目の負担者で均等に分け、支出額と品目の合計の差（税・サービス料など）は各メンバーの小計に比例して配分します
+// 負担額は端数処理モードに従って配分し（money.AllocateRounded）、合計が支出額と一致するようにします
+func itemizedSplits(input AddExpenseInput, mode string, currency string) ([]models.Split, []models.ExpenseItem, error) {
+	if len(input.Items) == 0 {
+		return nil, nil, errors.New("items is required for itemized splits")
+	}
+
+	var memberIDs []uint
+	subtotals := map[uint]float64{}
+	itemsTotal := 0.0
+	items := make([]models.ExpenseItem, len(input.Items))
+	for i, item := range input.Items {
+		seen := make(map[uint]bool, len(item.MemberIDs))
+		ids := make([]string, len(item.MemberIDs))
+		for j, memberID := range item.MemberIDs {
+			if seen[memberID] {
+				return nil, nil, fmt.Errorf("Each member can appear only once in item %q", item.Name)
+			}
+			seen[memberID] = true
+			if _, ok := subtotals[memberID]; !ok {
+				memberIDs = append(memberIDs, memberID)
+			}
+			subtotals[memberID] += item.Price / float64(len(item.MemberIDs))
+			ids[j] = strconv.FormatUint(uint64(memberID), 10)
+		}
+		itemsTotal += item.Price
+		items[i] = models.ExpenseItem{
+			Name:      item.Name,
+			Price:     item.Price,
+			MemberIDs: strings.Join(ids, " "),
+		}
+	}
+
+	surcharge := input.Tax + input.Tip
+	if itemsTotal+surcharge-input.Amount > math.Pow10(-utils.CurrencyMinorUnits(currency))/2 {
+		return nil, nil, fmt.Errorf("Item prices plus tax and tip cannot exceed the expense amount (got %v, expected at most %v)", itemsTotal+surcharge, input.Amount)
+	}
+
+	// 品目の合計との差額（税金とチップを均等に分ける場合はそれらを除いた差額）は品目の小計に比例して分ける
+	proportional := input.Amount
+	equalShare := 0.0
+	if input.SurchargeSplit == models.SurchargeSplitEqual {
+		proportional -= surcharge
+		equalShare = surcharge / float64(len(memberIDs))
+	}
+
+	weights := make([]float64, len(memberIDs))
+	for i, memberID := range memberIDs {
+		weights[i] = subtotals[memberID]*proportional/itemsTotal + equalShare
+	}
+	shares := money.AllocateRounded(input.Amount, weights, mode, currency)
+	splits := make([]models.Split, len(memberIDs))
+	for i, memberID := range memberIDs {
+		splits[i] = models.Split{DebtorID: memberID, AmountDue: shares[i]}
+	}
+	return splits, items, nil
+}
+
+// visibleExpenses は他のメンバーの下書きを除外するスコープを返します（includeDrafts が false の場合は自分の下書きも除外します）
+func visibleExpenses(userID interface{}, includeDrafts bool) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if includeDrafts {
+			return db.Where("expenses.status <> ? OR expenses.created_by_id = ?", models.ExpenseStatusDraft, userID)
+		}
+		return db.Where("expenses.status <> ?", models.ExpenseStatusDraft)
+	}
+}
+
+// parseExpenseDate は支出の日付（YYYY-MM-DD）または日時（RFC3339）をパースします
+// 日時の場合は loc（グループのタイムゾーン）での日付を Date とし、日時を UTC で返します
+func parseExpenseDate(value string, loc *time.Location) (time.Time, *time.Time, error) {
+	if date, err := time.Parse("2006-01-02", value); err == nil {
+		return date, nil, nil
+	}
+	occurredAt, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, nil, errors.New("Invalid date format. Use YYYY-MM-DD or RFC3339")
+	}
+	utc := occurredAt.UTC()
+	return localDate(occurredAt, loc), &utc, nil
+}
+
+// expenseCurrency は支出を入力した通貨のコードを返します（省略した場合はグループの通貨）
+// グループの通貨と異なる場合は、換算レートの指定が必要です
+func expenseCurrency(input AddExpenseInput, groupCurrency string) (string, error) {
+	if input.Currency == "" {
+		return groupCurrency, nil
+	}
+	currency, ok := utils.NormalizeCurrency(input.Currency)
+	if !ok {
+		return "", errors.New("Unsupported currency")
+	}
+	if currency != groupCurrency && input.ExchangeRate == 0 {
+		return "", errors.New("exchangeRate is required when currency differs from the group currency")
+	}
+	return currency, nil
+}
+
+// setExpenseAmount は支出額（税金とチップを含む）を設定します
+// 支出を入力した通貨がグループの通貨と異なる場合は、入力時のレートで支出額・負担額・品目・税金・チップの金額をグループの通貨に換算し（負債はグループの通貨で計算する）、
+// 換算前の金額と通貨・レートを保存します。負担額は換算後の支出額を換算前の負担額の比で配分し、合計が換算後の支出額と一致するようにします
+// 返金の場合は、正の値で入力した金額を全て負の値にして保存します（負債計算で支払者と負担者の貸借が逆向きに動く）
+func setExpenseAmount(expense *models.Expense, input AddExpenseInput, currency, groupCurrency string, splits []models.Split, items []models.ExpenseItem) {
+	setConvertedAmount(expense, input, currency, groupCurrency, splits, items)
+
+	expense.Type = models.ExpenseTypeExpense
+	if input.Type == models.ExpenseTypeRefund {
+		expense.Type = models.ExpenseTypeRefund
+		negateExpenseAmounts(expense, splits, items)
+	}
+}
+
+// setConvertedAmount は支出額・負担額・品目・税金・チップの金額をグループの通貨で設定します
+func setConvertedAmount(expense *models.Expense, input AddExpenseInput, currency, groupCurrency string, splits []models.Split, items []models.ExpenseItem) {
+	expense.Tax = nil
+	expense.Tip = nil
+	expense.SurchargeSplit = ""
+	if input.Tax > 0 || input.Tip > 0 {
+		tax, tip := input.Tax, input.Tip
+		expense.Tax = &tax
+		expense.Tip = &tip
+		expense.SurchargeSplit = input.SurchargeSplit
+		if expense.SurchargeSplit == "" {
+			expense.SurchargeSplit = models.SurchargeSplitProportional
+		}
+	}
+
+	if currency == groupCurrency {
+		expense.Amount = input.Amount
+		expense.Currency = ""
+		expense.ForeignAmount = nil
+		expense.ExchangeRate = nil
+		return
+	}
+
+	rate := input.ExchangeRate
+	round := func(amount float64) float64 {
+		return money.Round(amount, groupCurrency)
+	}
+	foreignAmount := input.Amount
+	expense.Amount = round(input.Amount * rate)
+	expense.Currency = currency
+	expense.ForeignAmount = &foreignAmount
+	expense.ExchangeRate = &rate
+
+	foreignShares := make([]float64, len(splits))
+	for i := range splits {
+		foreignShares[i] = splits[i].AmountDue
+	}
+	for i, share := range money.Allocate(expense.Amount, foreignShares, groupCurrency) {
+		splits[i].AmountDue = share
+	}
+	for i := range items {
+		items[i].Price = round(items[i].Price * rate)
+	}
+	if expense.Tax != nil {
+		*expense.Tax = round(*expense.Tax * rate)
+		*expense.Tip = round(*expense.Tip * rate)
+	}
+}
+
+// negateExpenseAmounts は返金の支出額・換算前の金額・負担額・品目・税金・チップの金額を負の値にします
+func negateExpenseAmounts(expense *models.Expense, splits []models.Split, items []models.ExpenseItem) {
+	negate := func(amount float64) float64 {
+		if amount == 0 {
+			return 0 // -0 にしない
+		}
+		return -amount
+	}
+	expense.Amount = negate(expense.Amount)
+	if expense.ForeignAmount != nil {
+		*expense.ForeignAmount = negate(*expense.ForeignAmount)
+	}
+	if expense.Tax != nil {
+		*expense.Tax = negate(*expense.Tax)
+		*expense.Tip = negate(*expense.Tip)
+	}
+	for i := range splits {
+		splits[i].AmountDue = negate(splits[i].AmountDue)
+	}
+	for i := range items {
+		items[i].Price = negate(items[i].Price)
+	}
+}
+
+// resolveSplitType は支出の分け方を返します（省略時は Items があれば itemized、Splits があれば exact、どちらもなければ equal）
+func resolveSplitType(input AddExpenseInput) string {
+	if input.SplitType != "" {
+		return input.SplitType
+	}
+	if len(input.Items) > 0 {
+		return models.SplitTypeItemized
+	}
+	if len(input.Splits) > 0 {
+		return models.SplitTypeExact
+	}
+	return models.SplitTypeEqual
+}
+
+// expenseNonMemberFields はグループのメンバーでない支払者・負担者のIDを、指定された入力フィールドごとに返します
+// 負担者のフィールドは分け方に応じて memberIDs / splits / items のいずれかになります
+func expenseNonMemberFields(input AddExpenseInput, splits []models.Split, nonMembers []uint) gin.H {
+	invalid := make(map[uint]bool, len(nonMembers))
+	for _, id := range nonMembers {
+		invalid[id] = true
+	}
+
+	fields := gin.H{}
+	if invalid[input.PayerID] {
+		fields["payerID"] = []uint{input.PayerID}
+	}
+
+	var debtorIDs []uint
+	seen := make(map[uint]bool, len(nonMembers))
+	for _, split := range splits {
+		if invalid[split.DebtorID] && !seen[split.DebtorID] {
+			seen[split.DebtorID] = true
+			debtorIDs = append(debtorIDs, split.DebtorID)
+		}
+	}
+	if len(debtorIDs) > 0 {
+		field := "memberIDs"
+		switch resolveSplitType(input) {
+		case models.SplitTypeExact, models.SplitTypePercentage:
+			field = "splits"
+		case models.SplitTypeItemized:
+			field = "items"
+		}
+		fields[field] = debtorIDs
+	}
+	return fields
+}
+
+// splitDebtorIDs はSplitの負担者のIDを返します
+func splitDebtorIDs(splits []models.Split) []uint {
+	ids := make([]uint, len(splits))
+	for i, split := range splits {
+		ids[i] = split.DebtorID
+	}
+	return ids
+}
+
+// splitEqually は金額を人数で均等割りし、端数処理モードに従って各メンバーの負担額を返します
+func splitEqually(amount float64, count int, mode string, currency string) []float64 {
+	weights := make([]float64, count)
+	for i := range weights {
+		weights[i] = 1
+	}
+	return money.AllocateRounded(amount, weights, mode, currency)
+}
@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ito-system/clear-up-share/backend/database"
+	"github.com/ito-system/clear-up-share/backend/models"
+)
+
+// ExpenseMapItem は地図に表示する支出の形式
+type ExpenseMapItem struct {
+	ID          uint       `json:"id"`
+	Description string     `json:"description"`
+	Amount      float64    `json:"amount"`
+	Date        string     `json:"date"`
+	OccurredAt  *time.Time `json:"occurredAt,omitempty"`
+	PayerID     uint       `json:"payerID"`
+	CategoryID  *uint      `json:"categoryID"`
+	Latitude    float64    `json:"latitude"`
+	Longitude   float64    `json:"longitude"`
+	PlaceName   string     `json:"placeName,omitempty"`
+}
+
+// GetExpenseMap は場所（緯度・経度）が設定された支出を日付の古い順に取得します（旅行でどこにお金を使ったかの振り返り用）
+// from / to で日付の範囲、category でカテゴリ（none の場合は未分類）を絞り込めます。下書きは含めません
+// GET /api/v1/groups/:groupID/expenses/map?from=YYYY-MM-DD&to=YYYY-MM-DD&category=<categoryID|none>
+func GetExpenseMap(c *gin.Context) {
+	// パスパラメータからgroupIDを取得
+	groupIDStr := c.Param("groupID")
+	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// ユーザーがグループのメンバーであることを確認
+	var membership models.Membership
+	if err := database.DB.Preload("Group").Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	query := database.DB.Where("group_id = ? AND latitude IS NOT NULL AND longitude IS NOT NULL", groupID).Scopes(visibleExpenses(userID, false))
+	if value := c.Query("category"); value != "" {
+		filter, err := categoryFilter(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category"})
+			return
+		}
+		query = query.Scopes(filter)
+	}
+	from, err := parseOptionalDate(c.Query("from"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from date format. Use YYYY-MM-DD"})
+		return
+	}
+	if from != nil {
+		query = query.Where("date >= ?", *from)
+	}
+	to, err := parseOptionalDate(c.Query("to"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to date format. Use YYYY-MM-DD"})
+		return
+	}
+	if to != nil {
+		query = query.Where("date < ?", to.AddDate(0, 0, 1))
+	}
+
+	var expenses []models.Expense
+	if err := query.Order("date, COALESCE(occurred_at, created_at), id").Find(&expenses).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch expenses"})
+		return
+	}
+
+	items := make([]ExpenseMapItem, len(expenses))
+	for i, expense := range expenses {
+		items[i] = ExpenseMapItem{
+			ID:          expense.ID,
+			Description: expense.Description,
+			Amount:      expense.Amount,
+			Date:        expense.Date.Format("2006-01-02"),
+			OccurredAt:  expense.OccurredAt,
+			PayerID:     expense.PayerID,
+			CategoryID:  expense.CategoryID,
+			Latitude:    *expense.Latitude,
+			Longitude:   *expense.Longitude,
+			PlaceName:   expense.PlaceName,
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"groupID":  groupID,
+		"currency": membership.Group.Currency,
+		"expenses": items,
+	})
+}
@@ -0,0 +1,134 @@
+package handler
+
+import (
+	"encoding/csv"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ito-system/clear-up-share/backend/database"
+	"github.com/ito-system/clear-up-share/backend/models"
+	"github.com/ito-system/clear-up-share/backend/utils"
+)
+
+// 立替金の精算状況
+const (
+	reimbursementSelf         = "self"         // 自分の負担分
+	reimbursementReimbursed   = "reimbursed"   // 全額精算済み
+	reimbursementPartial      = "partial"      // 一部精算済み
+	reimbursementUnreimbursed = "unreimbursed" // 未精算
+)
+
+// ExportTaxYear は自分が支払った支出とその立替分の精算状況を、指定年（1月〜12月）についてCSVで出力します
+// 精算額は負担者から自分への清算を、古い支出の立替分から順に充当して求めます
+// GET /api/v1/users/me/exports/tax-year?year=2024
+func ExportTaxYear(c *gin.Context) {
+	// クエリパラメータから対象年を取得
+	year, err := strconv.Atoi(c.Query("year"))
+	if err != nil || year < 1900 || year > 9999 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid year"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	yearStart := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	yearEnd := yearStart.AddDate(1, 0, 0)
+
+	// 対象年末までに自分が支払った支出のSplitを古い順に取得（前年以前の立替分から精算を充当するため）
+	var splits []models.Split
+	if err := database.DB.Preload("Expense.Group").Preload("Debtor").
+		Joins("JOIN expenses ON expenses.id = splits.expense_id").
+		Where("expenses.payer_id = ? AND expenses.status = ? AND expenses.deleted_at IS NULL AND expenses.date < ?", userID, models.ExpenseStatusConfirmed, yearEnd).
+		Order("expenses.date, expenses.id, splits.id").
+		Find(&splits).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch expenses"})
+		return
+	}
+
+	// 負担者から自分への清算額をグループ・負担者ごとに集計
+	type debtorKey struct {
+		GroupID  uint
+		DebtorID uint
+	}
+
+	var settlements []models.Settlement
+	if err := database.DB.Where("receiver_id = ? AND status = ? AND voided_at IS NULL", userID, models.SettlementStatusConfirmed).Find(&settlements).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch settlements"})
+		return
+	}
+
+	remaining := make(map[debtorKey]float64)
+	for _, s := range settlements {
+		remaining[debtorKey{GroupID: s.GroupID, DebtorID: s.PayerID}] += s.Amount
+	}
+
+	// CSVを作成（Excelで文字化けしないようBOM付きUTF-8）
+	filename := fmt.Sprintf("tax-year-%d.csv", year)
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Status(http.StatusOK)
+	c.Writer.WriteString("\ufeff")
+
+	w := csv.NewWriter(c.Writer)
+	w.Write([]string{
+		"date", "group", "description", "currency", "expense_amount",
+		"debtor", "share", "reimbursed", "unreimbursed", "status",
+	})
+
+	for _, split := range splits {
+		expense := split.Expense
+		key := debtorKey{GroupID: expense.GroupID, DebtorID: split.DebtorID}
+
+		// 自分の負担分は精算の対象外、他のメンバーの立替分には清算額を充当
+		status := reimbursementSelf
+		reimbursed := 0.0
+		if split.DebtorID != userID.(uint) {
+			reimbursed = math.Min(remaining[key], split.AmountDue)
+			remaining[key] -= reimbursed
+
+			switch {
+			case reimbursed >= split.AmountDue:
+				status = reimbursementReimbursed
+			case reimbursed > 0:
+				status = reimbursementPartial
+			default:
+				status = reimbursementUnreimbursed
+			}
+		}
+
+		// 対象年の支出のみ出力
+		if expense.Date.Before(yearStart) {
+			continue
+		}
+
+		unreimbursed := 0.0
+		if status != reimbursementSelf {
+			unreimbursed = split.AmountDue - reimbursed
+		}
+
+		digits := utils.CurrencyMinorUnits(expense.Group.Currency)
+		w.Write([]string{
+			expense.Date.Format("2006-01-02"),
+			expense.Group.Name,
+			expense.Description,
+			expense.Group.Currency,
+			strconv.FormatFloat(expense.Amount, 'f', digits, 64),
+			split.Debtor.Username,
+			strconv.FormatFloat(split.AmountDue, 'f', digits, 64),
+			strconv.FormatFloat(reimbursed, 'f', digits, 64),
+			strconv.FormatFloat(unreimbursed, 'f', digits, 64),
+			status,
+		})
+	}
+
+	w.Flush()
+}
@@ -0,0 +1,366 @@
+package handler
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ito-system/clear-up-share/backend/database"
+	"github.com/ito-system/clear-up-share/backend/models"
+	"github.com/ito-system/clear-up-share/backend/utils"
+	"gorm.io/gorm"
+)
+
+// CreateForgivenessInput は債務免除の記録リクエストの入力形式
+type CreateForgivenessInput struct {
+	DebtorID   uint    `json:"debtorID" binding:"required"`
+	ReceiverID uint    `json:"receiverID" binding:"required"`
+	Amount     float64 `json:"amount" binding:"required,gt=0"`
+	Note       string  `json:"note" binding:"max=200"`
+}
+
+// forgivableAmount は債務者と債権者の現在の貸借額から免除できる上限額を返します
+// 免除によって債権者が債務者に、債務者が債権者に転じないようにするためのものです
+func forgivableAmount(db *gorm.DB, groupID, debtorID, receiverID uint) (float64, error) {
+	balances, err := groupBalances(db, groupID)
+	if err != nil {
+		return 0, err
+	}
+	return math.Max(0, math.Min(-balances[debtorID], balances[receiverID])), nil
+}
+
+// exceedsForgivable は免除額が上限を超えているかを通貨の最小単位の誤差を許容して判定します
+func exceedsForgivable(amount, limit float64, currency string) bool {
+	return amount-limit > math.Pow10(-utils.CurrencyMinorUnits(currency))/2
+}
+
+// CreateForgiveness は債権者が債務者への貸しの一部を免除した記録を作成します
+// 免除する本人以外が記録した場合は、本人が確認するまで負債計算に含めません
+// POST /api/v1/groups/:groupID/forgivenesses
+func CreateForgiveness(c *gin.Context) {
+	// パスパラメータからgroupIDを取得
+	groupIDStr := c.Param("groupID")
+	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// ユーザーがグループのメンバーであることを確認
+	var membership models.Membership
+	if err := database.DB.Preload("Group").Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	// アーカイブ済みのグループは読み取り専用
+	if membership.Group.ArchivedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Group is archived and read-only"})
+		return
+	}
+
+	// 清算を記録する権限があることを確認
+	if !hasPermission(membership.Role, PermRecordSettlement) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to record settlements"})
+		return
+	}
+
+	// リクエストボディをバインド
+	var input CreateForgivenessInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// DebtorとReceiverが同じでないことを確認
+	if input.DebtorID == input.ReceiverID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Debtor and receiver cannot be the same"})
+		return
+	}
+
+	// Debtorがグループのメンバーであることを確認
+	var debtorMembership models.Membership
+	if err := database.DB.Where("user_id = ? AND group_id = ?", input.DebtorID, groupID).First(&debtorMembership).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Debtor is not a member of this group"})
+		return
+	}
+
+	// Receiverがグループのメンバーであることを確認
+	var receiverMembership models.Membership
+	if err := database.DB.Where("user_id = ? AND group_id = ?", input.ReceiverID, groupID).First(&receiverMembership).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Receiver is not a member of this group"})
+		return
+	}
+
+	// 免除額が現在の貸借額を超えないことを確認
+	limit, err := forgivableAmount(database.DB, uint(groupID), input.DebtorID, input.ReceiverID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate balances"})
+		return
+	}
+	if exceedsForgivable(input.Amount, limit, membership.Group.Currency) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Amount exceeds the outstanding balance between debtor and receiver"})
+		return
+	}
+
+	// 免除する本人以外が記録した場合は本人の確認が必要（仮メンバーの扱いは代理入力の支出と同じ）
+	needsConfirmation, err := requiresPayerApproval(input.ReceiverID, userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Receiver not found"})
+		return
+	}
+
+	forgiveness := models.Forgiveness{
+		GroupID:     uint(groupID),
+		DebtorID:    input.DebtorID,
+		ReceiverID:  input.ReceiverID,
+		Amount:      input.Amount,
+		Note:        strings.TrimSpace(input.Note),
+		Status:      models.ForgivenessStatusConfirmed,
+		CreatedByID: userID.(uint),
+	}
+	action := models.ActivityDebtForgiven
+	if needsConfirmation {
+		forgiveness.Status = models.ForgivenessStatusPending
+		action = models.ActivityDebtForgivenessRequested
+	} else {
+		now := time.Now()
+		forgiveness.ConfirmedAt = &now
+	}
+
+	// トランザクション開始
+	tx := database.DB.Begin()
+
+	// 記録の完了まで債務者・債権者が退会・除名されないようにする
+	ok, err := lockGroupMembers(tx, forgiveness.GroupID, []uint{forgiveness.DebtorID, forgiveness.ReceiverID})
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check members"})
+		return
+	}
+	if !ok {
+		tx.Rollback()
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Debtor and receiver must belong to this group"})
+		return
+	}
+
+	if err := tx.Create(&forgiveness).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create forgiveness"})
+		return
+	}
+
+	// 免除する本人に確認を依頼
+	if needsConfirmation {
+		message := "You were asked to confirm forgiving " + strconv.FormatFloat(forgiveness.Amount, 'f', -1, 64) + " " + membership.Group.Currency
+		if err := notify(tx, forgiveness.ReceiverID, forgiveness.GroupID, models.NotificationForgivenessRequested, message, "forgiveness", forgiveness.ID); err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send notification"})
+			return
+		}
+	}
+
+	// アクティビティを記録
+	if err := recordActivity(tx, forgiveness.GroupID, userID.(uint), action, "forgiveness", forgiveness.ID, map[string]interface{}{
+		"debtorID":   forgiveness.DebtorID,
+		"receiverID": forgiveness.ReceiverID,
+		"amount":     forgiveness.Amount,
+		"note":       forgiveness.Note,
+	}); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record activity"})
+		return
+	}
+
+	tx.Commit()
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":     "Forgiveness recorded successfully",
+		"forgiveness": forgivenessResponse(forgiveness, membership.Group.Currency),
+	})
+}
+
+// ConfirmForgiveness は免除する本人が債務免除を確認し、負債計算に反映します
+// POST /api/v1/groups/:groupID/forgivenesses/:forgivenessID/confirm
+func ConfirmForgiveness(c *gin.Context) {
+	respondToForgiveness(c, true)
+}
+
+// DeclineForgiveness は免除する本人が債務免除を辞退し、記録を取り消します
+// POST /api/v1/groups/:groupID/forgivenesses/:forgivenessID/decline
+func DeclineForgiveness(c *gin.Context) {
+	respondToForgiveness(c, false)
+}
+
+// respondToForgiveness は確認待ちの債務免除の確認・辞退を処理します
+func respondToForgiveness(c *gin.Context, confirm bool) {
+	// パスパラメータからgroupIDとforgivenessIDを取得
+	groupIDStr := c.Param("groupID")
+	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	forgivenessIDStr := c.Param("forgivenessID")
+	forgivenessID, err := strconv.ParseUint(forgivenessIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid forgiveness ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// ユーザーがグループのメンバーであることを確認
+	var membership models.Membership
+	if err := database.DB.Preload("Group").Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	// アーカイブ済みのグループは読み取り専用
+	if membership.Group.ArchivedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Group is archived and read-only"})
+		return
+	}
+
+	// リーガルホールド中のグループでは記録を変更できない
+	if rejectIfLegalHold(c, membership.Group) {
+		return
+	}
+
+	var forgiveness models.Forgiveness
+	if err := database.DB.Where("id = ? AND group_id = ?", forgivenessID, groupID).First(&forgiveness).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Forgiveness not found"})
+		return
+	}
+
+	// 免除する本人のみ確認・辞退できる
+	if forgiveness.ReceiverID != userID.(uint) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the receiver can confirm this forgiveness"})
+		return
+	}
+
+	if forgiveness.Status != models.ForgivenessStatusPending {
+		c.JSON(http.StatusConflict, gin.H{"error": "Forgiveness is not pending confirmation"})
+		return
+	}
+
+	// 確認待ちの間に清算などで貸借が変わっている場合があるため再確認する
+	if confirm {
+		limit, err := forgivableAmount(database.DB, uint(groupID), forgiveness.DebtorID, forgiveness.ReceiverID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate balances"})
+			return
+		}
+		if exceedsForgivable(forgiveness.Amount, limit, membership.Group.Currency) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Amount exceeds the outstanding balance between debtor and receiver"})
+			return
+		}
+	}
+
+	// トランザクション開始
+	tx := database.DB.Begin()
+
+	// 確認の完了まで債務者・債権者が退会・除名されないようにする
+	if confirm {
+		ok, err := lockGroupMembers(tx, forgiveness.GroupID, []uint{forgiveness.DebtorID, forgiveness.ReceiverID})
+		if err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check members"})
+			return
+		}
+		if !ok {
+			tx.Rollback()
+			c.JSON(http.StatusConflict, gin.H{"error": "Debtor is no longer a member of this group"})
+			return
+		}
+	}
+
+	action := models.ActivityDebtForgivenessDeclined
+	if confirm {
+		now := time.Now()
+		forgiveness.Status = models.ForgivenessStatusConfirmed
+		forgiveness.ConfirmedAt = &now
+		action = models.ActivityDebtForgiven
+		if err := tx.Model(&forgiveness).Updates(map[string]interface{}{
+			"status":       forgiveness.Status,
+			"confirmed_at": forgiveness.ConfirmedAt,
+		}).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to confirm forgiveness"})
+			return
+		}
+	} else if err := tx.Delete(&forgiveness).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decline forgiveness"})
+		return
+	}
+
+	// 記録したユーザーに結果を通知
+	if confirm && forgiveness.CreatedByID != forgiveness.ReceiverID {
+		message := "Forgiving " + strconv.FormatFloat(forgiveness.Amount, 'f', -1, 64) + " " + membership.Group.Currency + " was confirmed"
+		if err := notify(tx, forgiveness.CreatedByID, forgiveness.GroupID, models.NotificationForgivenessConfirmed, message, "forgiveness", forgiveness.ID); err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send notification"})
+			return
+		}
+	}
+
+	// アクティビティを記録
+	if err := recordActivity(tx, forgiveness.GroupID, userID.(uint), action, "forgiveness", forgiveness.ID, map[string]interface{}{
+		"debtorID":   forgiveness.DebtorID,
+		"receiverID": forgiveness.ReceiverID,
+		"amount":     forgiveness.Amount,
+	}); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record activity"})
+		return
+	}
+
+	tx.Commit()
+
+	if !confirm {
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Forgiveness declined",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "Forgiveness confirmed",
+		"forgiveness": forgivenessResponse(forgiveness, membership.Group.Currency),
+	})
+}
+
+// forgivenessResponse は債務免除のレスポンス形式を構築します
+func forgivenessResponse(f models.Forgiveness, currency string) gin.H {
+	return gin.H{
+		"id":          f.ID,
+		"groupID":     f.GroupID,
+		"debtorID":    f.DebtorID,
+		"receiverID":  f.ReceiverID,
+		"amount":      f.Amount,
+		"currency":    currency,
+		"note":        f.Note,
+		"status":      f.Status,
+		"createdByID": f.CreatedByID,
+		"createdAt":   f.CreatedAt,
+		"confirmedAt": f.ConfirmedAt,
+	}
+}
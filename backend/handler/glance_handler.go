@@ -0,0 +1,164 @@
+package handler
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ito-system/clear-up-share/backend/database"
+	"github.com/ito-system/clear-up-share/backend/utils"
+)
+
+const (
+	glanceCacheTTL     = 60 * time.Second // グランス用レスポンスを保持する時間
+	glanceCacheMaxSize = 10000            // 保持するレスポンスの最大件数
+)
+
+// glanceResponse はウォッチのコンプリケーションやウィジェット向けの最小限のレスポンス
+type glanceResponse struct {
+	Net    map[string]float64 `json:"net"`    // 通貨ごとの貸借額の合計（正: 受け取る、負: 支払う）
+	Next   *glancePayment     `json:"next"`   // 次に支払うべきもの（支払うものがない場合は null）
+	Status string             `json:"status"` // 1行の状況説明
+	AsOf   time.Time          `json:"asOf"`   // 集計日時
+}
+
+// glancePayment は最も支払額が大きいグループの支払い
+type glancePayment struct {
+	GroupID  uint    `json:"groupID"`
+	Group    string  `json:"group"`
+	Amount   float64 `json:"amount"`
+	Currency string  `json:"currency"`
+}
+
+var (
+	glanceCacheMu sync.Mutex
+	glanceCache   = map[uint]glanceResponse{}
+)
+
+// GetMyGlance は認証ユーザーの貸借の概要（通貨ごとの合計・次の支払い・1行の状況）を返します
+// 頻繁に呼び出される前提のため、集計結果をユーザーごとに glanceCacheTTL の間保持します
+// GET /api/v1/users/me/glance
+func GetMyGlance(c *gin.Context) {
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	glanceCacheMu.Lock()
+	response, found := glanceCache[userID.(uint)]
+	glanceCacheMu.Unlock()
+
+	if !found || time.Since(response.AsOf) >= glanceCacheTTL {
+		var err error
+		response, err = buildGlance(userID.(uint))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate balances"})
+			return
+		}
+		storeGlance(userID.(uint), response)
+	}
+
+	maxAge := glanceCacheTTL - time.Since(response.AsOf)
+	c.Header("Cache-Control", fmt.Sprintf("private, max-age=%d", int(maxAge.Seconds())))
+	c.JSON(http.StatusOK, response)
+}
+
+// buildGlance はユーザーが所属するアーカイブされていないグループの貸借額を集計します
+func buildGlance(userID uint) (glanceResponse, error) {
+	type groupRow struct {
+		ID       uint
+		Name     string
+		Currency string
+	}
+
+	var groups []groupRow
+	if err := database.DB.Table("memberships").
+		Select("groups.id, groups.name, groups.currency").
+		Joins("JOIN groups ON groups.id = memberships.group_id AND groups.deleted_at IS NULL").
+		Where("memberships.user_id = ? AND memberships.deleted_at IS NULL AND groups.archived_at IS NULL", userID).
+		Order("groups.id").
+		Scan(&groups).Error; err != nil {
+		return glanceResponse{}, err
+	}
+
+	groupIDs := make([]uint, len(groups))
+	for i, g := range groups {
+		groupIDs[i] = g.ID
+	}
+	totals, err := groupsBalanceTotals(database.DB, groupIDs, userID)
+	if err != nil {
+		return glanceResponse{}, err
+	}
+
+	response := glanceResponse{
+		Net:  map[string]float64{},
+		AsOf: time.Now(),
+	}
+	owing := 0
+	for _, g := range groups {
+		balance := totals[g.ID].MyBalance
+		// 端数の誤差は貸借なしとして扱う
+		if math.Abs(balance) < math.Pow10(-utils.CurrencyMinorUnits(g.Currency))/2 {
+			continue
+		}
+		response.Net[g.Currency] += balance
+
+		if balance < 0 {
+			owing++
+			if response.Next == nil || -balance > response.Next.Amount {
+				response.Next = &glancePayment{
+					GroupID:  g.ID,
+					Group:    g.Name,
+					Amount:   -balance,
+					Currency: g.Currency,
+				}
+			}
+		}
+	}
+
+	switch {
+	case response.Next != nil:
+		response.Status = fmt.Sprintf("You owe %s in %s", formatGlanceAmount(response.Next.Amount, response.Next.Currency), response.Next.Group)
+		if owing > 1 {
+			response.Status += fmt.Sprintf(" (+%d more)", owing-1)
+		}
+	case len(response.Net) == 1:
+		for currency, amount := range response.Net {
+			response.Status = "You are owed " + formatGlanceAmount(amount, currency)
+		}
+	case len(response.Net) > 1:
+		response.Status = fmt.Sprintf("You are owed in %d currencies", len(response.Net))
+	default:
+		response.Status = "All settled up"
+	}
+
+	return response, nil
+}
+
+// formatGlanceAmount は金額を通貨の補助単位の桁数で表示用に整形します（例: "1500 JPY"）
+func formatGlanceAmount(amount float64, currency string) string {
+	return strconv.FormatFloat(amount, 'f', utils.CurrencyMinorUnits(currency), 64) + " " + currency
+}
+
+// storeGlance は集計結果を保持します（上限を超える場合は期限切れのものを削除します）
+func storeGlance(userID uint, response glanceResponse) {
+	glanceCacheMu.Lock()
+	defer glanceCacheMu.Unlock()
+
+	if len(glanceCache) >= glanceCacheMaxSize {
+		for id, cached := range glanceCache {
+			if time.Since(cached.AsOf) >= glanceCacheTTL {
+				delete(glanceCache, id)
+			}
+		}
+	}
+	if len(glanceCache) < glanceCacheMaxSize {
+		glanceCache[userID] = response
+	}
+}
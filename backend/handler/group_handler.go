@@ -1,26 +1,77 @@
 package handler
 
 import (
+	"errors"
+	"io"
 	"net/http"
 	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/ito-system/clear-up-share/backend/analytics"
 	"github.com/ito-system/clear-up-share/backend/database"
 	"github.com/ito-system/clear-up-share/backend/models"
+	"github.com/ito-system/clear-up-share/backend/money"
+	"github.com/ito-system/clear-up-share/backend/utils"
+	"gorm.io/gorm/clause"
 )
 
 // CreateGroupInput はグループ作成リクエストの入力形式
 type CreateGroupInput struct {
+	Name     string `json:"name" binding:"required"`
+	Currency string `json:"currency"`
+}
+
+// UpdateGroupInput はグループ更新リクエストの入力形式（指定されたフィールドのみ更新）
+type UpdateGroupInput struct {
+	Name        *string `json:"name" binding:"omitempty,min=1"`
+	Currency    *string `json:"currency"`
+	Description *string `json:"description" binding:"omitempty,max=500"`
+	StartDate   *string `json:"startDate"` // YYYY-MM-DD（空文字で解除）
+	EndDate     *string `json:"endDate"`   // YYYY-MM-DD（空文字で解除）
+	Location    *string `json:"location" binding:"omitempty,max=100"`
+}
+
+// UpdateAppearanceInput はグループの見た目設定リクエストの入力形式
+type UpdateAppearanceInput struct {
+	Icon  string `json:"icon" binding:"max=32"`
+	Color string `json:"color" binding:"omitempty,hexcolor,len=7"`
+}
+
+// PinGroupInput はグループのピン留めリクエストの入力形式
+type PinGroupInput struct {
+	Pinned *bool `json:"pinned" binding:"required"`
+}
+
+// CloneGroupInput はグループ複製リクエストの入力形式
+type CloneGroupInput struct {
 	Name string `json:"name" binding:"required"`
 }
 
+// UpdateMemberRoleInput はメンバーのロール変更リクエストの入力形式
+type UpdateMemberRoleInput struct {
+	Role string `json:"role" binding:"required"`
+}
+
 // AddSettlementInput は清算記録リクエストの入力形式
 type AddSettlementInput struct {
-	PayerID    uint    `json:"payerID" binding:"required"`
-	ReceiverID uint    `json:"receiverID" binding:"required"`
-	Amount     float64 `json:"amount" binding:"required,gt=0"`
+	PayerID          uint    `json:"payerID" binding:"required"`
+	ReceiverID       uint    `json:"receiverID" binding:"required"`
+	Amount           float64 `json:"amount" binding:"required,gt=0"` // currency を指定した場合はその通貨での金額
+	Date             string  `json:"date"`                           // 支払った日付（YYYY-MM-DD、省略時はグループのタイムゾーンでの今日）
+	Note             string  `json:"note" binding:"max=200"`
+	Method           string  `json:"method" binding:"omitempty,oneof=cash bank_transfer paypay other"` // 支払い方法（省略可）
+	ExpenseIDs       []uint  `json:"expenseIDs"`                                                       // 清算を充てる支出（指定した順に受取者が支払った支出の送金者の未払いの負担額に充てる、省略可）
+	Currency         string  `json:"currency"`                                                         // 支払った通貨（省略した場合はグループの通貨）
+	ExchangeRate     float64 `json:"exchangeRate" binding:"omitempty,gt=0"`                            // currency の1単位あたりのグループの通貨の金額
+	AllowOverpayment bool    `json:"allowOverpayment"`                                                 // 送金者の受取者への借りを超える額を意図して記録する場合に指定
+}
+
+// ConfirmSettlementInput は清算の受け取りの確認リクエストの入力形式
+type ConfirmSettlementInput struct {
+	AllowOverpayment bool `json:"allowOverpayment"` // 送金者の受取者への借りを超える場合も確認する場合に指定
 }
 
 // DebtSummary はメンバーごとの貸借額を表す形式
@@ -28,23 +79,99 @@ type DebtSummary struct {
 	UserID   uint    `json:"userID"`
 	Username string  `json:"username"`
 	Balance  float64 `json:"balance"`
+	Left     bool    `json:"left,omitempty"` // 貸借を残したまま退会・除名されたメンバー
+}
+
+// PairwiseDebtSummary は2人の間の差し引きの借りを表す形式（FromUserID が ToUserID に Amount を支払う）
+type PairwiseDebtSummary struct {
+	FromUserID uint    `json:"fromUserID"`
+	FromName   string  `json:"fromName"`
+	ToUserID   uint    `json:"toUserID"`
+	ToName     string  `json:"toName"`
+	Amount     float64 `json:"amount"`
+	Left       bool    `json:"left,omitempty"` // どちらかが貸借を残したまま退会・除名されている
 }
 
 // HistoryItem は履歴アイテムの統合形式
 type HistoryItem struct {
 	ID           uint      `json:"id"`
-	Type         string    `json:"type"` // "expense" または "settlement"
+	Type         string    `json:"type"` // "expense"、"settlement" または "forgiveness"
 	Date         time.Time `json:"date"`
-	Amount       float64   `json:"amount"`
+	Amount       float64   `json:"amount"`                // 返金の場合は負の値
+	ExpenseType  string    `json:"expenseType,omitempty"` // 支出の種類（expense / refund）
 	Description  string    `json:"description,omitempty"`
-	PayerID      uint      `json:"payerID"`
+	Status       string    `json:"status,omitempty"` // 支出・清算・債務免除のステータス（confirmed / pending / awaiting_payer / draft）
+	PayerID      uint      `json:"payerID"`          // 債務免除の場合は免除された債務者
 	PayerName    string    `json:"payerName"`
 	ReceiverID   uint      `json:"receiverID,omitempty"`
 	ReceiverName string    `json:"receiverName,omitempty"`
+	// 通貨移行で金額を併記する形式を選んだ場合の換算前の金額と通貨
+	OriginalAmount   *float64 `json:"originalAmount,omitempty"`
+	OriginalCurrency string   `json:"originalCurrency,omitempty"`
+	// グループの通貨と異なる通貨で入力した支出・支払った清算の換算前の金額・通貨と換算レート
+	ForeignAmount   *float64 `json:"foreignAmount,omitempty"`
+	ForeignCurrency string   `json:"foreignCurrency,omitempty"`
+	ExchangeRate    *float64 `json:"exchangeRate,omitempty"`
+	CategoryID      *uint    `json:"categoryID,omitempty"` // 支出のカテゴリ
+	Tags            []string `json:"tags,omitempty"`       // 支出のタグ
+	// 日時で入力した支出の支払日時
+	OccurredAt *time.Time `json:"occurredAt,omitempty"`
+	// 清算の支払い方法（cash / bank_transfer / paypay / other）
+	Method string `json:"method,omitempty"`
+	// 清算を充てた支出と額
+	Allocations []AllocationSummary `json:"allocations,omitempty"`
+	// 取り消した清算の取り消し日時（負債計算には含まれない）
+	VoidedAt *time.Time `json:"voidedAt,omitempty"`
+	// 複数の受取者にまとめて支払った清算の一括送金のID
+	BatchID *uint `json:"batchID,omitempty"`
+
+	day time.Time // 並び替えに使うグループのタイムゾーンでの日付
+	at  time.Time // 並び替えに使う同じ日付の中での日時
+}
+
+// groupLastActivitySQL はグループごとの最終更新日時（アクティビティ・支出・清算の最新日時）を集計するSQL
+const groupLastActivitySQL = `
+	SELECT group_id, MAX(t) AS last_activity_at FROM (
+		SELECT group_id, created_at AS t FROM activity_logs
+		UNION ALL
+		SELECT group_id, updated_at FROM expenses WHERE deleted_at IS NULL
+		UNION ALL
+		SELECT group_id, created_at FROM settlements WHERE deleted_at IS NULL
+	) activity GROUP BY group_id`
+
+// parseOptionalDate は YYYY-MM-DD 形式の日付をパースします（空文字の場合は nil）
+func parseOptionalDate(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	date, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return nil, err
+	}
+	return &date, nil
+}
+
+// formatOptionalDate は日付を YYYY-MM-DD 形式で返します（未設定の場合は nil）
+func formatOptionalDate(date *time.Time) interface{} {
+	if date == nil {
+		return nil
+	}
+	return date.Format("2006-01-02")
+}
+
+// groupSortColumns はグループ一覧の並び替えキーと対応する列・既定の並び順
+var groupSortColumns = map[string]struct {
+	Column string
+	Desc   bool
+}{
+	"lastActivity": {Column: "last_activity_at", Desc: true},
+	"name":         {Column: "groups.name", Desc: false},
+	"createdAt":    {Column: "groups.created_at", Desc: true},
 }
 
 // GetGroups はユーザーが所属するグループ一覧を取得します
-// GET /api/v1/groups
+// ピン留めしたグループは並び替えキーに関わらず先頭に表示されます
+// GET /api/v1/groups?page=&limit=&q=&sort=lastActivity|name|createdAt&order=asc|desc
 func GetGroups(c *gin.Context) {
 	// コンテキストからuserIDを取得
 	userID, exists := c.Get("userID")
@@ -53,31 +180,114 @@ func GetGroups(c *gin.Context) {
 		return
 	}
 
-	// ユーザーが所属するグループを取得
-	var memberships []models.Membership
-	if err := database.DB.Preload("Group").Where("user_id = ?", userID).Find(&memberships).Error; err != nil {
+	// includeArchived=true の場合のみアーカイブ済みグループを含める
+	includeArchived, _ := strconv.ParseBool(c.DefaultQuery("includeArchived", "false"))
+
+	// 並び替えキーと順序を決定
+	sortKey := c.DefaultQuery("sort", "lastActivity")
+	sortColumn, ok := groupSortColumns[sortKey]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid sort key"})
+		return
+	}
+	desc := sortColumn.Desc
+	switch c.Query("order") {
+	case "":
+	case "asc":
+		desc = false
+	case "desc":
+		desc = true
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid sort order"})
+		return
+	}
+	orderBy := sortColumn.Column
+	if desc {
+		orderBy += " DESC"
+	}
+
+	page, limit := parsePagination(c, 20, 100)
+
+	// ユーザーが所属するグループを最終更新日時と合わせて取得
+	query := database.DB.Table("memberships").
+		Joins("JOIN groups ON groups.id = memberships.group_id AND groups.deleted_at IS NULL").
+		Joins("LEFT JOIN ("+groupLastActivitySQL+") la ON la.group_id = groups.id").
+		Where("memberships.user_id = ? AND memberships.deleted_at IS NULL", userID)
+	if !includeArchived {
+		query = query.Where("groups.archived_at IS NULL")
+	}
+	if q := strings.TrimSpace(c.Query("q")); q != "" {
+		query = query.Where("LOWER(groups.name) LIKE ?", "%"+strings.ToLower(q)+"%")
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch groups"})
 		return
 	}
 
 	// レスポンス用のグループリストを構築
 	type GroupResponse struct {
-		ID      uint   `json:"id"`
-		Name    string `json:"name"`
-		OwnerID uint   `json:"ownerID"`
+		ID             uint        `json:"id"`
+		Name           string      `json:"name"`
+		OwnerID        uint        `json:"ownerID"`
+		Currency       string      `json:"currency"`
+		ArchivedAt     *time.Time  `json:"archivedAt,omitempty"`
+		Icon           string      `json:"icon"`
+		Color          string      `json:"color"`
+		Description    string      `json:"description"`
+		StartDate      *time.Time  `json:"-"`
+		EndDate        *time.Time  `json:"-"`
+		StartDay       interface{} `json:"startDate" gorm:"-"` // StartDate を YYYY-MM-DD 形式にしたもの
+		EndDay         interface{} `json:"endDate" gorm:"-"`   // EndDate を YYYY-MM-DD 形式にしたもの
+		Location       string      `json:"location"`
+		CreatedAt      time.Time   `json:"createdAt"`
+		LastActivityAt time.Time   `json:"lastActivityAt"`
+		MemberCount    int64       `json:"memberCount"`
+		TotalUnsettled float64     `json:"totalUnsettled"`
+		MyBalance      float64     `json:"myBalance"`
+		Pinned         bool        `json:"pinned"`
 	}
 
-	groups := make([]GroupResponse, len(memberships))
-	for i, m := range memberships {
-		groups[i] = GroupResponse{
-			ID:      m.Group.ID,
-			Name:    m.Group.Name,
-			OwnerID: m.Group.OwnerID,
-		}
+	groups := []GroupResponse{}
+	if err := query.
+		Select("groups.id, groups.name, groups.owner_id, groups.currency, groups.archived_at, groups.icon, groups.color, " +
+			"groups.description, groups.start_date, groups.end_date, groups.location, groups.created_at, " +
+			"COALESCE(la.last_activity_at, groups.created_at) AS last_activity_at, " +
+			"(SELECT COUNT(*) FROM memberships gm WHERE gm.group_id = groups.id AND gm.deleted_at IS NULL) AS member_count, " +
+			"memberships.pinned").
+		Order("memberships.pinned DESC").
+		Order(orderBy).
+		Order("groups.id").
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Scan(&groups).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch groups"})
+		return
+	}
+
+	// 表示するグループの貸借額をまとめて集計
+	groupIDs := make([]uint, len(groups))
+	for i, g := range groups {
+		groupIDs[i] = g.ID
+	}
+	totals, err := groupsBalanceTotals(database.DB, groupIDs, userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate balances"})
+		return
+	}
+	for i := range groups {
+		groups[i].TotalUnsettled = totals[groups[i].ID].TotalUnsettled
+		groups[i].MyBalance = totals[groups[i].ID].MyBalance
+		groups[i].StartDay = formatOptionalDate(groups[i].StartDate)
+		groups[i].EndDay = formatOptionalDate(groups[i].EndDate)
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"groups": groups,
+		"total":  total,
+		"page":   page,
+		"limit":  limit,
 	})
 }
 
@@ -90,6 +300,17 @@ func CreateGroup(c *gin.Context) {
 		return
 	}
 
+	// 通貨を検証（未指定の場合はデフォルト通貨）
+	currency := utils.DefaultCurrency
+	if input.Currency != "" {
+		code, ok := utils.NormalizeCurrency(input.Currency)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported currency code"})
+			return
+		}
+		currency = code
+	}
+
 	// コンテキストからuserIDを取得
 	userID, exists := c.Get("userID")
 	if !exists {
@@ -97,13 +318,20 @@ func CreateGroup(c *gin.Context) {
 		return
 	}
 
+	// 所属グループ数が上限に達していないことを確認
+	if err := checkGroupQuota(database.DB, userID.(uint)); err != nil {
+		respondQuotaError(c, err)
+		return
+	}
+
 	// トランザクションでグループとメンバーシップを作成
 	tx := database.DB.Begin()
 
 	// グループ作成
 	group := models.Group{
-		Name:    input.Name,
-		OwnerID: userID.(uint),
+		Name:     input.Name,
+		OwnerID:  userID.(uint),
+		Currency: currency,
 	}
 
 	if err := tx.Create(&group).Error; err != nil {
@@ -116,6 +344,7 @@ func CreateGroup(c *gin.Context) {
 	membership := models.Membership{
 		UserID:  userID.(uint),
 		GroupID: group.ID,
+		Role:    models.RoleOwner,
 	}
 
 	if err := tx.Create(&membership).Error; err != nil {
@@ -124,21 +353,34 @@ func CreateGroup(c *gin.Context) {
 		return
 	}
 
+	// アクティビティを記録
+	if err := recordActivity(tx, group.ID, userID.(uint), models.ActivityMemberJoined, "member", membership.UserID, nil); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record activity"})
+		return
+	}
+
 	tx.Commit()
 
+	analytics.Track(analytics.EventGroupCreated, userID.(uint), map[string]interface{}{
+		"groupId":  analytics.Anonymize("group", group.ID),
+		"currency": group.Currency,
+	})
+
 	c.JSON(http.StatusCreated, gin.H{
 		"message": "Group created successfully",
 		"group": gin.H{
-			"id":      group.ID,
-			"name":    group.Name,
-			"ownerID": group.OwnerID,
+			"id":       group.ID,
+			"name":     group.Name,
+			"ownerID":  group.OwnerID,
+			"currency": group.Currency,
 		},
 	})
 }
 
-// GetGroupHistory はグループの履歴を取得します
-// GET /api/v1/groups/:groupID/history
-func GetGroupHistory(c *gin.Context) {
+// UpdateGroup はグループの名前・通貨を更新します
+// PUT /api/v1/groups/:groupID
+func UpdateGroup(c *gin.Context) {
 	// パスパラメータからgroupIDを取得
 	groupIDStr := c.Param("groupID")
 	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
@@ -156,67 +398,121 @@ func GetGroupHistory(c *gin.Context) {
 
 	// ユーザーがグループのメンバーであることを確認
 	var membership models.Membership
-	if err := database.DB.Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+	if err := database.DB.Preload("Group").Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
 		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
 		return
 	}
 
-	// Expenseを取得（Payerをプリロード）
-	var expenses []models.Expense
-	if err := database.DB.Preload("Payer").Where("group_id = ?", groupID).Find(&expenses).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch expenses"})
+	// アーカイブ済みのグループは読み取り専用
+	if membership.Group.ArchivedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Group is archived and read-only"})
 		return
 	}
 
-	// Settlementを取得（Payer, Receiverをプリロード）
-	var settlements []models.Settlement
-	if err := database.DB.Preload("Payer").Preload("Receiver").Where("group_id = ?", groupID).Find(&settlements).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch settlements"})
+	// グループを管理する権限があることを確認
+	if !hasPermission(membership.Role, PermManageGroup) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to manage this group"})
 		return
 	}
 
-	// 履歴アイテムを統合
-	var history []HistoryItem
+	// リクエストボディをバインド
+	var input UpdateGroupInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	for _, e := range expenses {
-		history = append(history, HistoryItem{
-			ID:          e.ID,
-			Type:        "expense",
-			Date:        e.Date,
-			Amount:      e.Amount,
-			Description: e.Description,
-			PayerID:     e.PayerID,
-			PayerName:   e.Payer.Username,
-		})
+	// 指定されたフィールドのみ更新
+	group := membership.Group
+	updates := map[string]interface{}{}
+	if input.Name != nil {
+		group.Name = *input.Name
+		updates["name"] = group.Name
+	}
+	if input.Currency != nil {
+		code, ok := utils.NormalizeCurrency(*input.Currency)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported currency code"})
+			return
+		}
+		// 記録があるグループの通貨を変更すると過去の金額の意味が変わるため、通貨移行を使う
+		if code != group.Currency {
+			var records int64
+			if err := database.DB.Raw(`SELECT
+				(SELECT COUNT(*) FROM expenses WHERE group_id = @groupID) +
+				(SELECT COUNT(*) FROM settlements WHERE group_id = @groupID) +
+				(SELECT COUNT(*) FROM forgivenesses WHERE group_id = @groupID)`,
+				map[string]interface{}{"groupID": groupID},
+			).Scan(&records).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update group"})
+				return
+			}
+			if records > 0 {
+				c.JSON(http.StatusConflict, gin.H{"error": "Group has recorded amounts. Use the currency migration to change its currency"})
+				return
+			}
+		}
+		group.Currency = code
+		updates["currency"] = group.Currency
+	}
+	if input.Description != nil {
+		group.Description = strings.TrimSpace(*input.Description)
+		updates["description"] = group.Description
+	}
+	if input.Location != nil {
+		group.Location = strings.TrimSpace(*input.Location)
+		updates["location"] = group.Location
+	}
+	if input.StartDate != nil {
+		date, err := parseOptionalDate(*input.StartDate)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid start date format. Use YYYY-MM-DD"})
+			return
+		}
+		group.StartDate = date
+		updates["start_date"] = group.StartDate
+	}
+	if input.EndDate != nil {
+		date, err := parseOptionalDate(*input.EndDate)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid end date format. Use YYYY-MM-DD"})
+			return
+		}
+		group.EndDate = date
+		updates["end_date"] = group.EndDate
 	}
 
-	for _, s := range settlements {
-		history = append(history, HistoryItem{
-			ID:           s.ID,
-			Type:         "settlement",
-			Date:         s.CreatedAt,
-			Amount:       s.Amount,
-			PayerID:      s.PayerID,
-			PayerName:    s.Payer.Username,
-			ReceiverID:   s.ReceiverID,
-			ReceiverName: s.Receiver.Username,
-		})
+	// 終了日は開始日より前にできない
+	if group.StartDate != nil && group.EndDate != nil && group.EndDate.Before(*group.StartDate) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "End date cannot be before start date"})
+		return
 	}
 
-	// 日付で降順ソート（新しいものが先）
-	sort.Slice(history, func(i, j int) bool {
-		return history[i].Date.After(history[j].Date)
-	})
+	if len(updates) > 0 {
+		if err := database.DB.Model(&group).Updates(updates).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update group"})
+			return
+		}
+	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"groupID": groupID,
-		"history": history,
+		"message": "Group updated successfully",
+		"group": gin.H{
+			"id":          group.ID,
+			"name":        group.Name,
+			"ownerID":     group.OwnerID,
+			"currency":    group.Currency,
+			"description": group.Description,
+			"startDate":   formatOptionalDate(group.StartDate),
+			"endDate":     formatOptionalDate(group.EndDate),
+			"location":    group.Location,
+		},
 	})
 }
 
-// GetGroupMembers はグループのメンバー一覧を取得します
-// GET /api/v1/groups/:groupID/members
-func GetGroupMembers(c *gin.Context) {
+// CloneGroup はメンバーと設定を引き継いだ新しいグループを作成します（支出・清算は複製しません）
+// POST /api/v1/groups/:groupID/clone
+func CloneGroup(c *gin.Context) {
 	// パスパラメータからgroupIDを取得
 	groupIDStr := c.Param("groupID")
 	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
@@ -234,43 +530,182 @@ func GetGroupMembers(c *gin.Context) {
 
 	// ユーザーがグループのメンバーであることを確認
 	var membership models.Membership
-	if err := database.DB.Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+	if err := database.DB.Preload("Group").Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
 		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
 		return
 	}
 
-	// グループのメンバーを取得
+	// グループを管理する権限があることを確認
+	if !hasPermission(membership.Role, PermManageGroup) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to manage this group"})
+		return
+	}
+
+	// リクエストボディをバインド
+	var input CloneGroupInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 複製元のメンバーと設定を取得
 	var memberships []models.Membership
 	if err := database.DB.Preload("User").Where("group_id = ?", groupID).Find(&memberships).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch members"})
 		return
 	}
 
-	// レスポンス用のメンバーリストを構築
-	type MemberResponse struct {
-		ID       uint   `json:"id"`
-		Username string `json:"username"`
-		Email    string `json:"email"`
+	settings, err := loadGroupSettings(database.DB, uint(groupID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch settings"})
+		return
 	}
 
-	members := make([]MemberResponse, len(memberships))
-	for i, m := range memberships {
-		members[i] = MemberResponse{
-			ID:       m.User.ID,
-			Username: m.User.Username,
-			Email:    m.User.Email,
+	var categories []models.Category
+	if err := database.DB.Where("group_id = ?", groupID).Order("id").Find(&categories).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch categories"})
+		return
+	}
+
+	// 複製先グループのメンバー数が上限に収まることを確認
+	if err := checkQuota(QuotaMembersPerGroup, limitsForUser(database.DB, userID.(uint)).MaxMembersPerGroup, 0, int64(len(memberships))); err != nil {
+		respondQuotaError(c, err)
+		return
+	}
+
+	// トランザクションでグループ・メンバーシップ・設定を作成
+	tx := database.DB.Begin()
+
+	source := membership.Group
+	group := models.Group{
+		Name:     input.Name,
+		OwnerID:  userID.(uint),
+		Currency: source.Currency,
+		Icon:     source.Icon,
+		Color:    source.Color,
+		// 開始日・終了日は元のグループ固有のものなので引き継がない
+		Description: source.Description,
+		Location:    source.Location,
+	}
+
+	if err := tx.Create(&group).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create group"})
+		return
+	}
+
+	for _, m := range memberships {
+		// 複製した本人が新しいグループのオーナーになり、元のオーナーは管理者として引き継ぐ
+		role := m.Role
+		if m.UserID == userID.(uint) {
+			role = models.RoleOwner
+		} else if role == models.RoleOwner {
+			role = models.RoleAdmin
+		}
+
+		// 仮メンバーはグループごとに名前を変更できるよう新しい仮ユーザーとして複製
+		memberID := m.UserID
+		if m.User.IsPlaceholder {
+			placeholder := models.User{
+				Username:      m.User.Username,
+				IsPlaceholder: true,
+			}
+			if err := tx.Create(&placeholder).Error; err != nil {
+				tx.Rollback()
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create placeholder member"})
+				return
+			}
+			memberID = placeholder.ID
+		} else if err := checkGroupQuota(tx, memberID); err != nil {
+			// 各メンバーの所属グループ数が上限に達していないことを確認
+			tx.Rollback()
+			respondQuotaError(c, err)
+			return
+		}
+
+		clonedMembership := models.Membership{
+			UserID:   memberID,
+			GroupID:  group.ID,
+			Role:     role,
+			Nickname: m.Nickname,
+		}
+
+		if err := tx.Create(&clonedMembership).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add member"})
+			return
+		}
+
+		// アクティビティを記録
+		if err := recordActivity(tx, group.ID, userID.(uint), models.ActivityMemberJoined, "member", memberID, map[string]interface{}{
+			"clonedFrom": source.ID,
+		}); err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record activity"})
+			return
 		}
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"groupID": groupID,
-		"members": members,
+	// 保存済みの設定があれば引き継ぐ
+	if settings.ID != 0 {
+		clonedSettings := models.GroupSettings{
+			GroupID:                group.ID,
+			RoundingMode:           settings.RoundingMode,
+			AllowMemberEdit:        settings.AllowMemberEdit,
+			RequireExpenseApproval: settings.RequireExpenseApproval,
+			MonthStartDay:          settings.MonthStartDay,
+			WeekStartDay:           settings.WeekStartDay,
+			AllowLeaveWithBalance:  settings.AllowLeaveWithBalance,
+			Timezone:               settings.Timezone,
+			ReminderCadence:        settings.ReminderCadence,
+			ReminderThreshold:      settings.ReminderThreshold,
+			ClosingDay:             settings.ClosingDay,
+		}
+		// 次の締め日は複製したグループのタイムゾーンでの今日から求める（UpdateGroupSettings と同じ）
+		clonedSettings.NextClosingDate = nextClosingDate(groupToday(clonedSettings), clonedSettings.ClosingDay)
+
+		if err := tx.Create(&clonedSettings).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to copy settings"})
+			return
+		}
+	}
+
+	// 支出のカテゴリを引き継ぐ
+	for _, category := range categories {
+		clonedCategory := models.Category{
+			GroupID: group.ID,
+			Name:    category.Name,
+		}
+		if err := tx.Create(&clonedCategory).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to copy categories"})
+			return
+		}
+	}
+
+	tx.Commit()
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Group cloned successfully",
+		"group": gin.H{
+			"id":          group.ID,
+			"name":        group.Name,
+			"ownerID":     group.OwnerID,
+			"currency":    group.Currency,
+			"icon":        group.Icon,
+			"color":       group.Color,
+			"clonedFrom":  source.ID,
+			"memberCount": len(memberships),
+		},
 	})
 }
 
-// GetGroupDebts はグループの負債状態を計算します
-// GET /api/v1/groups/:groupID/debts
-func GetGroupDebts(c *gin.Context) {
+// GetGroupHistory はグループの履歴を、グループのタイムゾーンでの日付の新しい順（同じ日付の中では日時の新しい順）で取得します
+// category を指定した場合はそのカテゴリ（none の場合は未分類）、tag を指定した場合はそのタグが付いた支出だけを返します
+// 下書きは含めず、includeDrafts=true の場合は自分の下書きも含めます
+// GET /api/v1/groups/:groupID/history?affectsMe=true&category=<categoryID|none>&tag=<name>&includeDrafts=true
+func GetGroupHistory(c *gin.Context) {
 	// パスパラメータからgroupIDを取得
 	groupIDStr := c.Param("groupID")
 	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
@@ -288,92 +723,187 @@ func GetGroupDebts(c *gin.Context) {
 
 	// ユーザーがグループのメンバーであることを確認
 	var membership models.Membership
-	if err := database.DB.Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+	if err := database.DB.Preload("Group").Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
 		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
 		return
 	}
 
-	// グループのメンバーを取得
-	var memberships []models.Membership
-	if err := database.DB.Preload("User").Where("group_id = ?", groupID).Find(&memberships).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch members"})
-		return
+	// affectsMe=true の場合は自分が支払者・負担者・送金者・受取者であるものに絞り込む
+	affectsMe, _ := strconv.ParseBool(c.DefaultQuery("affectsMe", "false"))
+	// 下書きは includeDrafts=true の場合に自分のものだけを含める
+	includeDrafts, _ := strconv.ParseBool(c.DefaultQuery("includeDrafts", "false"))
+	expenseQuery := database.DB.Preload("Payer").Preload("Tags", orderTagsByName).Where("group_id = ?", groupID).Scopes(visibleExpenses(userID, includeDrafts))
+	settlementQuery := database.DB.Preload("Payer").Preload("Receiver").Preload("Allocations").Where("group_id = ?", groupID)
+	forgivenessQuery := database.DB.Preload("Debtor").Preload("Receiver").Where("group_id = ?", groupID)
+	if affectsMe {
+		expenseQuery = expenseQuery.Where(
+			"payer_id = ? OR id IN (SELECT expense_id FROM splits WHERE debtor_id = ? AND deleted_at IS NULL)",
+			userID, userID,
+		)
+		settlementQuery = settlementQuery.Where("payer_id = ? OR receiver_id = ?", userID, userID)
+		forgivenessQuery = forgivenessQuery.Where("debtor_id = ? OR receiver_id = ?", userID, userID)
 	}
 
-	// メンバー情報をマップに保存
-	memberMap := make(map[uint]string)
-	balances := make(map[uint]float64)
-	for _, m := range memberships {
-		memberMap[m.UserID] = m.User.Username
-		balances[m.UserID] = 0
+	// カテゴリ・タグで絞り込む場合、カテゴリやタグのない清算・債務免除は含めない
+	expensesOnly := false
+	if category := c.Query("category"); category != "" {
+		filter, err := categoryFilter(category)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category"})
+			return
+		}
+		expenseQuery = expenseQuery.Scopes(filter)
+		expensesOnly = true
+	}
+	if tag := c.Query("tag"); tag != "" {
+		expenseQuery = expenseQuery.Scopes(tagFilter(tag))
+		expensesOnly = true
 	}
 
-	// グループの全支出を取得
+	// Expenseを取得（Payerをプリロード）
 	var expenses []models.Expense
-	if err := database.DB.Where("group_id = ?", groupID).Find(&expenses).Error; err != nil {
+	if err := expenseQuery.Find(&expenses).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch expenses"})
 		return
 	}
 
-	// 支出IDのリストを作成
-	var expenseIDs []uint
-	for _, e := range expenses {
-		expenseIDs = append(expenseIDs, e.ID)
+	// Settlementを取得（Payer, Receiverをプリロード）
+	var settlements []models.Settlement
+	if !expensesOnly {
+		if err := settlementQuery.Find(&settlements).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch settlements"})
+			return
+		}
 	}
 
-	// 全てのSplitを取得
-	var splits []models.Split
-	if len(expenseIDs) > 0 {
-		if err := database.DB.Where("expense_id IN ?", expenseIDs).Find(&splits).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch splits"})
+	// 債務免除を取得（Debtor, Receiverをプリロード）
+	var forgivenesses []models.Forgiveness
+	if !expensesOnly {
+		if err := forgivenessQuery.Find(&forgivenesses).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch forgivenesses"})
 			return
 		}
 	}
 
-	// 支払額を集計（Expense.PayerIDごと）
-	for _, e := range expenses {
-		balances[e.PayerID] += e.Amount
+	// グループ内の表示名を取得（退会済みのユーザーはユーザー名を表示）
+	names, err := groupDisplayNames(database.DB, uint(groupID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch members"})
+		return
 	}
-
-	// 負担額を集計（Split.DebtorIDごと）
-	for _, s := range splits {
-		balances[s.DebtorID] -= s.AmountDue
+	displayName := func(user models.User) string {
+		if name, ok := names[user.ID]; ok {
+			return name
+		}
+		return user.Username
 	}
 
-	// 清算を考慮（Settlement）
-	var settlements []models.Settlement
-	if err := database.DB.Where("group_id = ?", groupID).Find(&settlements).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch settlements"})
+	// 債務免除の日付はグループのタイムゾーンで決める（清算は支払った日付を使う）
+	settings, err := loadGroupSettings(database.DB, uint(groupID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch group settings"})
 		return
 	}
+	loc := groupLocation(settings)
+
+	// 履歴アイテムを統合
+	var history []HistoryItem
+
+	for _, e := range expenses {
+		history = append(history, HistoryItem{
+			ID:               e.ID,
+			Type:             "expense",
+			Date:             e.Date,
+			Amount:           e.Amount,
+			ExpenseType:      e.Type,
+			Description:      e.Description,
+			Status:           e.Status,
+			PayerID:          e.PayerID,
+			PayerName:        displayName(e.Payer),
+			OriginalAmount:   e.OriginalAmount,
+			OriginalCurrency: e.OriginalCurrency,
+			ForeignAmount:    e.ForeignAmount,
+			ForeignCurrency:  e.Currency,
+			ExchangeRate:     e.ExchangeRate,
+			CategoryID:       e.CategoryID,
+			Tags:             tagNames(e.Tags),
+			OccurredAt:       e.OccurredAt,
+			day:              e.Date,
+			at:               expenseTime(e),
+		})
+	}
 
-	// 清算による調整
-	// Payerは送金した（＝支払った）ので、その分負債が減る（balanceが減る）
-	// Receiverは受け取った（＝受領した）ので、その分債権が減る（balanceが減る）
 	for _, s := range settlements {
-		balances[s.PayerID] -= s.Amount    // 送金者は支払ったので、受け取る権利が減る
-		balances[s.ReceiverID] += s.Amount // 受領者は受け取ったので、支払う義務が減る（balanceが増える）
+		history = append(history, HistoryItem{
+			ID:               s.ID,
+			Type:             "settlement",
+			Date:             s.Date,
+			Amount:           s.Amount,
+			Description:      s.Note,
+			Status:           s.Status,
+			Method:           s.Method,
+			Allocations:      allocationSummaries(s.Allocations),
+			PayerID:          s.PayerID,
+			PayerName:        displayName(s.Payer),
+			ReceiverID:       s.ReceiverID,
+			ReceiverName:     displayName(s.Receiver),
+			OriginalAmount:   s.OriginalAmount,
+			OriginalCurrency: s.OriginalCurrency,
+			ForeignAmount:    s.ForeignAmount,
+			ForeignCurrency:  s.Currency,
+			ExchangeRate:     s.ExchangeRate,
+			VoidedAt:         s.VoidedAt,
+			BatchID:          s.BatchID,
+			day:              s.Date,
+			at:               s.CreatedAt,
+		})
 	}
 
-	// DebtSummaryのリストを作成
-	var debts []DebtSummary
-	for userID, username := range memberMap {
-		debts = append(debts, DebtSummary{
-			UserID:   userID,
-			Username: username,
-			Balance:  balances[userID],
+	for _, f := range forgivenesses {
+		history = append(history, HistoryItem{
+			ID:               f.ID,
+			Type:             "forgiveness",
+			Date:             f.CreatedAt,
+			Amount:           f.Amount,
+			Description:      f.Note,
+			Status:           f.Status,
+			PayerID:          f.DebtorID,
+			PayerName:        displayName(f.Debtor),
+			ReceiverID:       f.ReceiverID,
+			ReceiverName:     displayName(f.Receiver),
+			OriginalAmount:   f.OriginalAmount,
+			OriginalCurrency: f.OriginalCurrency,
+			day:              localDate(f.CreatedAt, loc),
+			at:               f.CreatedAt,
 		})
 	}
 
+	// グループのタイムゾーンでの日付の降順、同じ日付の中では日時の降順でソート（新しいものが先）
+	sort.Slice(history, func(i, j int) bool {
+		if !history[i].day.Equal(history[j].day) {
+			return history[i].day.After(history[j].day)
+		}
+		return history[i].at.After(history[j].at)
+	})
+
 	c.JSON(http.StatusOK, gin.H{
-		"groupID": groupID,
-		"debts":   debts,
+		"groupID":  groupID,
+		"currency": membership.Group.Currency,
+		"history":  history,
 	})
 }
 
-// RecordSettlement は清算を記録します
-// POST /api/v1/groups/:groupID/settlements
-func RecordSettlement(c *gin.Context) {
+// expenseTime は同じ日付の支出・清算を並べるときの支出の日時を返します（日付のみで入力した場合は登録日時）
+func expenseTime(e models.Expense) time.Time {
+	if e.OccurredAt != nil {
+		return *e.OccurredAt
+	}
+	return e.CreatedAt
+}
+
+// GetGroupMembers はグループのメンバー一覧を取得します
+// GET /api/v1/groups/:groupID/members
+func GetGroupMembers(c *gin.Context) {
 	// パスパラメータからgroupIDを取得
 	groupIDStr := c.Param("groupID")
 	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
@@ -396,24 +926,347 @@ func RecordSettlement(c *gin.Context) {
 		return
 	}
 
-	// リクエストボディをバインド
-	var input AddSettlementInput
-	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	// グループのメンバーを取得
+	var memberships []models.Membership
+	if err := database.DB.Preload("User").Where("group_id = ?", groupID).Find(&memberships).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch members"})
 		return
 	}
 
-	// PayerとReceiverが同じでないことを確認
-	if input.PayerID == input.ReceiverID {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Payer and receiver cannot be the same"})
-		return
+	// レスポンス用のメンバーリストを構築
+	type MemberResponse struct {
+		ID            uint   `json:"id"`
+		Username      string `json:"username"`
+		Email         string `json:"email"`
+		Nickname      string `json:"nickname,omitempty"`
+		DisplayName   string `json:"displayName"`
+		Role          string `json:"role"`
+		IsPlaceholder bool   `json:"isPlaceholder"`
 	}
 
-	// PayerがグループのメンバーであることをPayerがグループのメンバーであることを確認
-	var payerMembership models.Membership
-	if err := database.DB.Where("user_id = ? AND group_id = ?", input.PayerID, groupID).First(&payerMembership).Error; err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Payer is not a member of this group"})
-		return
+	members := make([]MemberResponse, len(memberships))
+	for i, m := range memberships {
+		members[i] = MemberResponse{
+			ID:            m.User.ID,
+			Username:      m.User.Username,
+			Email:         m.User.Email,
+			Nickname:      m.Nickname,
+			DisplayName:   memberDisplayName(m),
+			Role:          m.Role,
+			IsPlaceholder: m.User.IsPlaceholder,
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"groupID": groupID,
+		"members": members,
+	})
+}
+
+// GetGroupDebts はグループの負債状態を計算します
+// GET /api/v1/groups/:groupID/debts
+func GetGroupDebts(c *gin.Context) {
+	// パスパラメータからgroupIDを取得
+	groupIDStr := c.Param("groupID")
+	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// ユーザーがグループのメンバーであることを確認
+	var membership models.Membership
+	if err := database.DB.Preload("Group").Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	// グループのメンバーを取得
+	var memberships []models.Membership
+	if err := database.DB.Preload("User").Where("group_id = ?", groupID).Find(&memberships).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch members"})
+		return
+	}
+
+	// メンバー情報をマップに保存
+	memberMap := make(map[uint]string)
+	for _, m := range memberships {
+		memberMap[m.UserID] = memberDisplayName(m)
+	}
+
+	// 支出・Split・清算から各メンバーの貸借額を集計（承認待ちの支出は含めない）
+	balances, err := groupBalances(database.DB, uint(groupID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate balances"})
+		return
+	}
+
+	// DebtSummaryのリストを作成
+	var debts []DebtSummary
+	for userID, username := range memberMap {
+		debts = append(debts, DebtSummary{
+			UserID:   userID,
+			Username: username,
+			Balance:  balances[userID],
+		})
+	}
+
+	// 貸借を残して退会したメンバーも含める（退会時に未精算の貸借を許可している場合）
+	var leftIDs []uint
+	for userID, balance := range balances {
+		if _, ok := memberMap[userID]; !ok && balance != 0 {
+			leftIDs = append(leftIDs, userID)
+		}
+	}
+	if len(leftIDs) > 0 {
+		var leftUsers []models.User
+		if err := database.DB.Where("id IN ?", leftIDs).Find(&leftUsers).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch members"})
+			return
+		}
+		for _, u := range leftUsers {
+			debts = append(debts, DebtSummary{
+				UserID:   u.ID,
+				Username: u.Username,
+				Balance:  balances[u.ID],
+				Left:     true,
+			})
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"groupID":  groupID,
+		"currency": membership.Group.Currency,
+		"debts":    debts,
+	})
+}
+
+// GetPairwiseDebts はグループ内の2人ずつの差し引きの借りを返します（誰に誰がいくら借りているか）
+// GET /api/v1/groups/:groupID/debts/pairwise
+func GetPairwiseDebts(c *gin.Context) {
+	// パスパラメータからgroupIDを取得
+	groupIDStr := c.Param("groupID")
+	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// ユーザーがグループのメンバーであることを確認
+	var membership models.Membership
+	if err := database.DB.Preload("Group").Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	// Split・清算・債務免除から2人ずつの借りを集計（承認待ちの支出は含めない）
+	pairs, err := groupPairwiseDebts(database.DB, uint(groupID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate debts"})
+		return
+	}
+
+	names, err := groupDisplayNames(database.DB, uint(groupID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch members"})
+		return
+	}
+
+	// 貸借を残して退会したメンバーはユーザー名で表示する
+	var leftIDs []uint
+	for _, p := range pairs {
+		for _, id := range []uint{p.FromUserID, p.ToUserID} {
+			if _, ok := names[id]; !ok {
+				leftIDs = append(leftIDs, id)
+			}
+		}
+	}
+	left := make(map[uint]bool, len(leftIDs))
+	if len(leftIDs) > 0 {
+		var leftUsers []models.User
+		if err := database.DB.Where("id IN ?", leftIDs).Find(&leftUsers).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch members"})
+			return
+		}
+		for _, u := range leftUsers {
+			names[u.ID] = u.Username
+			left[u.ID] = true
+		}
+	}
+
+	// 通貨の補助単位に丸めて差し引きが0になった組は除く
+	debts := []PairwiseDebtSummary{}
+	for _, p := range pairs {
+		amount := money.Round(p.Amount, membership.Group.Currency)
+		if amount == 0 {
+			continue
+		}
+		debts = append(debts, PairwiseDebtSummary{
+			FromUserID: p.FromUserID,
+			FromName:   names[p.FromUserID],
+			ToUserID:   p.ToUserID,
+			ToName:     names[p.ToUserID],
+			Amount:     amount,
+			Left:       left[p.FromUserID] || left[p.ToUserID],
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"groupID":  groupID,
+		"currency": membership.Group.Currency,
+		"debts":    debts,
+	})
+}
+
+// GetGroupSummary はグループのダッシュボード用の集計値を1回の呼び出しで返します
+// GET /api/v1/groups/:groupID/summary
+func GetGroupSummary(c *gin.Context) {
+	// パスパラメータからgroupIDを取得
+	groupIDStr := c.Param("groupID")
+	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// ユーザーがグループのメンバーであることを確認
+	var membership models.Membership
+	if err := database.DB.Preload("Group").Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	// 支出合計・件数・メンバー数・最終更新日時を1つのクエリで集計
+	var summary struct {
+		TotalSpend     float64
+		ExpenseCount   int64
+		MemberCount    int64
+		LastActivityAt *time.Time
+	}
+	err = database.DB.Raw(`
+		SELECT
+			(SELECT COALESCE(SUM(amount), 0) FROM expenses
+				WHERE group_id = @groupID AND status = @confirmed AND deleted_at IS NULL) AS total_spend,
+			(SELECT COUNT(*) FROM expenses
+				WHERE group_id = @groupID AND status = @confirmed AND deleted_at IS NULL) AS expense_count,
+			(SELECT COUNT(*) FROM memberships
+				WHERE group_id = @groupID AND deleted_at IS NULL) AS member_count,
+			(SELECT MAX(t) FROM (
+				SELECT created_at AS t FROM activity_logs WHERE group_id = @groupID
+				UNION ALL
+				SELECT updated_at FROM expenses WHERE group_id = @groupID AND deleted_at IS NULL
+				UNION ALL
+				SELECT created_at FROM settlements WHERE group_id = @groupID AND deleted_at IS NULL
+			) activity) AS last_activity_at`,
+		map[string]interface{}{
+			"groupID":   groupID,
+			"confirmed": models.ExpenseStatusConfirmed,
+		},
+	).Scan(&summary).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch summary"})
+		return
+	}
+
+	// 自分の貸借額を計算
+	balances, err := groupBalances(database.DB, uint(groupID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate balances"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"groupID":        groupID,
+		"name":           membership.Group.Name,
+		"currency":       membership.Group.Currency,
+		"description":    membership.Group.Description,
+		"startDate":      formatOptionalDate(membership.Group.StartDate),
+		"endDate":        formatOptionalDate(membership.Group.EndDate),
+		"location":       membership.Group.Location,
+		"totalSpend":     summary.TotalSpend,
+		"expenseCount":   summary.ExpenseCount,
+		"memberCount":    summary.MemberCount,
+		"lastActivityAt": summary.LastActivityAt,
+		"myBalance":      balances[userID.(uint)],
+	})
+}
+
+// RecordSettlement は清算を記録します
+// POST /api/v1/groups/:groupID/settlements
+func RecordSettlement(c *gin.Context) {
+	// パスパラメータからgroupIDを取得
+	groupIDStr := c.Param("groupID")
+	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// ユーザーがグループのメンバーであることを確認
+	var membership models.Membership
+	if err := database.DB.Preload("Group").Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	// アーカイブ済みのグループは読み取り専用
+	if membership.Group.ArchivedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Group is archived and read-only"})
+		return
+	}
+
+	// 清算を記録する権限があることを確認
+	if !hasPermission(membership.Role, PermRecordSettlement) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to record settlements"})
+		return
+	}
+
+	// リクエストボディをバインド
+	var input AddSettlementInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// PayerとReceiverが同じでないことを確認
+	if input.PayerID == input.ReceiverID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Payer and receiver cannot be the same"})
+		return
+	}
+
+	// PayerがグループのメンバーであることをPayerがグループのメンバーであることを確認
+	var payerMembership models.Membership
+	if err := database.DB.Where("user_id = ? AND group_id = ?", input.PayerID, groupID).First(&payerMembership).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Payer is not a member of this group"})
+		return
 	}
 
 	// ReceiverがグループのメンバーであることをReceiverがグループのメンバーであることを確認
@@ -423,26 +1276,121 @@ func RecordSettlement(c *gin.Context) {
 		return
 	}
 
+	// グループのポリシー設定を取得
+	settings, err := loadGroupSettings(database.DB, uint(groupID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch group settings"})
+		return
+	}
+
+	// 支払った日付を決定（省略時は今日、未来の日付は不可）
+	date := groupToday(settings)
+	if input.Date != "" {
+		date, err = time.Parse("2006-01-02", input.Date)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format. Use YYYY-MM-DD"})
+			return
+		}
+		if date.After(groupToday(settings)) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Settlement date cannot be in the future"})
+			return
+		}
+	}
+
 	// Settlementを作成
 	settlement := models.Settlement{
 		GroupID:    uint(groupID),
 		PayerID:    input.PayerID,
 		ReceiverID: input.ReceiverID,
-		Amount:     input.Amount,
+		Date:       date,
+		Note:       strings.TrimSpace(input.Note),
+		Method:     input.Method,
+		Status:     models.SettlementStatusConfirmed,
+	}
+	if err := setSettlementAmount(&settlement, input, membership.Group.Currency); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// トランザクション開始
+	tx := database.DB.Begin()
+
+	// 清算の完了まで送金者・受取者が退会・除名されず、同じ2人の清算が同時に記録されないようにする
+	ok, err := lockSettlementPair(tx, uint(groupID), input.PayerID, input.ReceiverID)
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check members"})
+		return
+	}
+	if !ok {
+		tx.Rollback()
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Payer and receiver must belong to this group"})
+		return
+	}
+
+	// 送金者の受取者への借りを超える清算は貸借を逆転させるため、allowOverpayment を指定しない限り記録しない
+	currency := membership.Group.Currency
+	currentDebt, overpayment, err := settlementOverpayment(tx, uint(groupID), input.PayerID, input.ReceiverID, settlement.Amount, currency, 0)
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate debts"})
+		return
+	}
+	if overpayment > 0 && !input.AllowOverpayment {
+		tx.Rollback()
+		respondSettlementOverpayment(c, currentDebt, overpayment, currency)
+		return
 	}
 
-	if err := database.DB.Create(&settlement).Error; err != nil {
+	if err := tx.Create(&settlement).Error; err != nil {
+		tx.Rollback()
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create settlement"})
 		return
 	}
 
+	// 指定された支出に清算を充てる
+	allocations, err := allocateSettlement(tx, settlement, input.ExpenseIDs, membership.Group.Currency)
+	if err != nil {
+		tx.Rollback()
+		var allocationErr *SettlementAllocationError
+		if errors.As(err, &allocationErr) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": allocationErr.Message, "expenseID": allocationErr.ExpenseID})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to allocate settlement"})
+		return
+	}
+
+	// アクティビティを記録
+	if err := recordActivity(tx, settlement.GroupID, userID.(uint), models.ActivitySettlementRecorded, "settlement", settlement.ID, map[string]interface{}{
+		"payerID":         settlement.PayerID,
+		"receiverID":      settlement.ReceiverID,
+		"amount":          settlement.Amount,
+		"note":            settlement.Note,
+		"method":          settlement.Method,
+		"expenseIDs":      input.ExpenseIDs,
+		"foreignAmount":   settlement.ForeignAmount,
+		"foreignCurrency": settlement.Currency,
+		"exchangeRate":    settlement.ExchangeRate,
+	}); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record activity"})
+		return
+	}
+
+	tx.Commit()
+
+	analytics.Track(analytics.EventSettlementRecorded, userID.(uint), map[string]interface{}{
+		"groupId": analytics.Anonymize("group", settlement.GroupID),
+	})
+
 	// Payer, Receiverの情報を取得してレスポンスに含める
 	var payer models.User
 	var receiver models.User
 	database.DB.First(&payer, input.PayerID)
 	database.DB.First(&receiver, input.ReceiverID)
 
-	c.JSON(http.StatusCreated, gin.H{
+	response := gin.H{
 		"message": "Settlement recorded successfully",
 		"settlement": gin.H{
 			"id":           settlement.ID,
@@ -452,7 +1400,710 @@ func RecordSettlement(c *gin.Context) {
 			"receiverID":   settlement.ReceiverID,
 			"receiverName": receiver.Username,
 			"amount":       settlement.Amount,
+			"currency":     membership.Group.Currency,
+			"date":         settlement.Date.Format("2006-01-02"),
+			"note":         settlement.Note,
+			"method":       settlement.Method,
+			"status":       settlement.Status,
+			"allocations":  allocationSummaries(allocations),
 			"createdAt":    settlement.CreatedAt,
+			// グループの通貨と異なる通貨で支払った場合の換算前の金額・通貨と換算レート
+			"foreignAmount":   settlement.ForeignAmount,
+			"foreignCurrency": settlement.Currency,
+			"exchangeRate":    settlement.ExchangeRate,
 		},
+	}
+	// allowOverpayment で借りを超えて記録した場合は、超えた額を警告として返す
+	if overpayment > 0 {
+		response["warning"] = "Settlement amount exceeds the payer's current debt to the receiver"
+		response["overpayment"] = money.FromMinor(overpayment, currency)
+	}
+	c.JSON(http.StatusCreated, response)
+}
+
+// setSettlementAmount は清算額をグループの通貨で設定します
+// グループの通貨と異なる通貨で支払った場合は、指定されたレートで換算し（負債はグループの通貨で計算する）、換算前の金額と通貨・レートを保存します
+func setSettlementAmount(settlement *models.Settlement, input AddSettlementInput, groupCurrency string) error {
+	currency := groupCurrency
+	if input.Currency != "" {
+		var ok bool
+		currency, ok = utils.NormalizeCurrency(input.Currency)
+		if !ok {
+			return errors.New("Unsupported currency")
+		}
+	}
+
+	if currency == groupCurrency {
+		settlement.Amount = input.Amount
+		return nil
+	}
+	if input.ExchangeRate == 0 {
+		return errors.New("exchangeRate is required when currency differs from the group currency")
+	}
+
+	foreignAmount := money.Round(input.Amount, currency)
+	rate := input.ExchangeRate
+	settlement.Amount = money.Round(foreignAmount*rate, groupCurrency)
+	if settlement.Amount <= 0 {
+		return errors.New("Converted amount must be greater than zero")
+	}
+	settlement.Currency = currency
+	settlement.ForeignAmount = &foreignAmount
+	settlement.ExchangeRate = &rate
+	return nil
+}
+
+// respondSettlementOverpayment は清算額が送金者の受取者への借りを超える場合のエラーを返します
+func respondSettlementOverpayment(c *gin.Context, currentDebt float64, overpayment int64, currency string) {
+	c.JSON(http.StatusConflict, gin.H{
+		"error":       "Settlement amount exceeds the payer's current debt to the receiver",
+		"currentDebt": currentDebt,
+		"overpayment": money.FromMinor(overpayment, currency),
+	})
+}
+
+// VoidSettlement は誤って記録した清算を取り消します（記録は取り消した日時とともに残し、負債計算には含めません）
+// 取り消しには理由が必要で、取り消す前の内容を理由とともに SettlementRevision に保存します
+// 送金者・受取者本人か、他のメンバーの支出を編集できる管理者のみ取り消せます
+// DELETE /api/v1/groups/:groupID/settlements/:settlementID
+func VoidSettlement(c *gin.Context) {
+	// パスパラメータからgroupIDとsettlementIDを取得
+	groupIDStr := c.Param("groupID")
+	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	settlementIDStr := c.Param("settlementID")
+	settlementID, err := strconv.ParseUint(settlementIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid settlement ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// ユーザーがグループのメンバーであることを確認
+	var membership models.Membership
+	if err := database.DB.Preload("Group").Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	// アーカイブ済みのグループは読み取り専用
+	if membership.Group.ArchivedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Group is archived and read-only"})
+		return
+	}
+
+	// リーガルホールド中のグループでは記録を取り消せない
+	if rejectIfLegalHold(c, membership.Group) {
+		return
+	}
+
+	// リクエストボディをバインド（取り消しの理由は必須）
+	var input VoidSettlementInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		if errors.Is(err, io.EOF) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Reason is required"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	reason := strings.TrimSpace(input.Reason)
+	if reason == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Reason is required"})
+		return
+	}
+
+	var settlement models.Settlement
+	if err := database.DB.Where("id = ? AND group_id = ?", settlementID, groupID).First(&settlement).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Settlement not found"})
+		return
+	}
+
+	// 清算を取り消す権限があることを確認
+	involved := settlement.PayerID == userID.(uint) || settlement.ReceiverID == userID.(uint)
+	if !(involved && hasPermission(membership.Role, PermRecordSettlement)) && !hasPermission(membership.Role, PermEditAnyExpense) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to void this settlement"})
+		return
+	}
+
+	if settlement.VoidedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Settlement is already voided"})
+		return
+	}
+
+	// トランザクション開始
+	tx := database.DB.Begin()
+
+	// 取り消すと貸借が変わるため、送金者・受取者が退会していないことを確認し、完了するまで退会・除名されないようにする
+	ok, err := lockGroupMembers(tx, uint(groupID), []uint{settlement.PayerID, settlement.ReceiverID})
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check members"})
+		return
+	}
+	if !ok {
+		tx.Rollback()
+		c.JSON(http.StatusConflict, gin.H{"error": "Payer or receiver has left this group"})
+		return
+	}
+
+	// 同時に編集された場合に取り消す前の内容を取り違えないよう、清算をロックして取得し直す
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&settlement, settlement.ID).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch settlement"})
+		return
+	}
+
+	// 同時に取り消された場合に二重に記録しない
+	voidedAt := time.Now()
+	result := tx.Model(&models.Settlement{}).
+		Where("id = ? AND voided_at IS NULL", settlement.ID).
+		Updates(map[string]interface{}{"voided_at": voidedAt, "voided_by_id": userID})
+	if result.Error != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to void settlement"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		tx.Rollback()
+		c.JSON(http.StatusConflict, gin.H{"error": "Settlement is already voided"})
+		return
+	}
+
+	// 取り消す前の内容を理由とともに保存する
+	revision, err := recordSettlementRevision(tx, settlement, userID.(uint), models.SettlementRevisionVoided, reason)
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record revision"})
+		return
+	}
+
+	// 精算依頼から支払った清算の場合は、依頼を支払い前に戻す
+	if err := tx.Model(&models.ReimbursementRequest{}).Where("settlement_id = ?", settlement.ID).Updates(map[string]interface{}{
+		"status":        models.ReimbursementStatusRequested,
+		"settlement_id": nil,
+		"paid_at":       nil,
+		"confirmed_at":  nil,
+	}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reopen reimbursement request"})
+		return
+	}
+
+	// アクティビティを記録
+	if err := recordActivity(tx, settlement.GroupID, userID.(uint), models.ActivitySettlementVoided, "settlement", settlement.ID, map[string]interface{}{
+		"payerID":    settlement.PayerID,
+		"receiverID": settlement.ReceiverID,
+		"amount":     settlement.Amount,
+		"reason":     reason,
+		"revisionID": revision.ID,
+	}); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record activity"})
+		return
+	}
+
+	tx.Commit()
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Settlement voided successfully",
+		"settlement": gin.H{
+			"id":         settlement.ID,
+			"groupID":    settlement.GroupID,
+			"payerID":    settlement.PayerID,
+			"receiverID": settlement.ReceiverID,
+			"amount":     settlement.Amount,
+			"currency":   membership.Group.Currency,
+			"date":       settlement.Date.Format("2006-01-02"),
+			"note":       settlement.Note,
+			"method":     settlement.Method,
+			"status":     settlement.Status,
+			"createdAt":  settlement.CreatedAt,
+			"voidedAt":   voidedAt,
+			"voidReason": reason,
+		},
+	})
+}
+
+// ConfirmSettlement は受取者が一括清算で記録した送金の受け取りを確認し、負債計算に反映します
+// POST /api/v1/groups/:groupID/settlements/:settlementID/confirm
+func ConfirmSettlement(c *gin.Context) {
+	// パスパラメータからgroupIDとsettlementIDを取得
+	groupIDStr := c.Param("groupID")
+	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	settlementIDStr := c.Param("settlementID")
+	settlementID, err := strconv.ParseUint(settlementIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid settlement ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// ユーザーがグループのメンバーであることを確認
+	var membership models.Membership
+	if err := database.DB.Preload("Group").Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	// アーカイブ済みのグループは読み取り専用
+	if membership.Group.ArchivedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Group is archived and read-only"})
+		return
+	}
+
+	var settlement models.Settlement
+	if err := database.DB.Where("id = ? AND group_id = ?", settlementID, groupID).First(&settlement).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Settlement not found"})
+		return
+	}
+
+	// 受取者本人のみ確認できる
+	if settlement.ReceiverID != userID.(uint) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the receiver can confirm this settlement"})
+		return
+	}
+
+	if settlement.Status != models.SettlementStatusPending || settlement.VoidedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Settlement is not pending confirmation"})
+		return
+	}
+
+	// リクエストボディをバインド（省略可）
+	var input ConfirmSettlementInput
+	if err := c.ShouldBindJSON(&input); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// トランザクション開始
+	tx := database.DB.Begin()
+
+	// 確認すると貸借が変わるため、送金者・受取者が退会していないことを確認し、完了するまで退会・除名されず、同じ2人の清算が同時に反映されないようにする
+	ok, err := lockSettlementPair(tx, uint(groupID), settlement.PayerID, settlement.ReceiverID)
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check members"})
+		return
+	}
+	if !ok {
+		tx.Rollback()
+		c.JSON(http.StatusConflict, gin.H{"error": "Payer or receiver has left this group"})
+		return
+	}
+
+	// 確認待ちの間に他の清算で借りが減っている場合は、allowOverpayment を指定しない限り確認しない
+	currency := membership.Group.Currency
+	currentDebt, overpayment, err := settlementOverpayment(tx, uint(groupID), settlement.PayerID, settlement.ReceiverID, settlement.Amount, currency, settlement.ID)
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate debts"})
+		return
+	}
+	if overpayment > 0 && !input.AllowOverpayment {
+		tx.Rollback()
+		respondSettlementOverpayment(c, currentDebt, overpayment, currency)
+		return
+	}
+
+	// 同時に確認・取り消しされた場合に二重に反映しない
+	result := tx.Model(&models.Settlement{}).
+		Where("id = ? AND status = ? AND voided_at IS NULL", settlement.ID, models.SettlementStatusPending).
+		Update("status", models.SettlementStatusConfirmed)
+	if result.Error != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to confirm settlement"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		tx.Rollback()
+		c.JSON(http.StatusConflict, gin.H{"error": "Settlement is not pending confirmation"})
+		return
+	}
+	settlement.Status = models.SettlementStatusConfirmed
+
+	// 送金者に受け取りの確認を通知
+	message := "Your payment of " + formatGlanceAmount(settlement.Amount, membership.Group.Currency) + " was confirmed"
+	if err := notify(tx, settlement.PayerID, settlement.GroupID, models.NotificationSettlementConfirmed, message, "settlement", settlement.ID); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send notification"})
+		return
+	}
+
+	// アクティビティを記録
+	if err := recordActivity(tx, settlement.GroupID, userID.(uint), models.ActivitySettlementConfirmed, "settlement", settlement.ID, map[string]interface{}{
+		"payerID":    settlement.PayerID,
+		"receiverID": settlement.ReceiverID,
+		"amount":     settlement.Amount,
+	}); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record activity"})
+		return
+	}
+
+	tx.Commit()
+
+	response := gin.H{
+		"message": "Settlement confirmed successfully",
+		"settlement": gin.H{
+			"id":         settlement.ID,
+			"groupID":    settlement.GroupID,
+			"payerID":    settlement.PayerID,
+			"receiverID": settlement.ReceiverID,
+			"amount":     settlement.Amount,
+			"currency":   membership.Group.Currency,
+			"date":       settlement.Date.Format("2006-01-02"),
+			"status":     settlement.Status,
+			"createdAt":  settlement.CreatedAt,
+		},
+	}
+	// allowOverpayment で借りを超えて確認した場合は、超えた額を警告として返す
+	if overpayment > 0 {
+		response["warning"] = "Settlement amount exceeds the payer's current debt to the receiver"
+		response["overpayment"] = money.FromMinor(overpayment, currency)
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// UpdateMemberRole はメンバーのロールを変更します
+// PUT /api/v1/groups/:groupID/members/:userID/role
+func UpdateMemberRole(c *gin.Context) {
+	// パスパラメータからgroupIDと対象のuserIDを取得
+	groupIDStr := c.Param("groupID")
+	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	targetIDStr := c.Param("userID")
+	targetID, err := strconv.ParseUint(targetIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// ユーザーがグループのメンバーであることを確認
+	var membership models.Membership
+	if err := database.DB.Preload("Group").Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	// アーカイブ済みのグループは読み取り専用
+	if membership.Group.ArchivedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Group is archived and read-only"})
+		return
+	}
+
+	// メンバーを管理する権限があることを確認
+	if !hasPermission(membership.Role, PermManageMembers) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to manage members"})
+		return
+	}
+
+	// リクエストボディをバインド
+	var input UpdateMemberRoleInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// ownerロールはグループ作成時のみ付与され、ここでは変更できない
+	if !isValidRole(input.Role) || input.Role == models.RoleOwner {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Role must be one of admin, member, viewer"})
+		return
+	}
+
+	// 対象メンバーのメンバーシップを取得
+	var target models.Membership
+	if err := database.DB.Where("user_id = ? AND group_id = ?", targetID, groupID).First(&target).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Member not found"})
+		return
+	}
+
+	if target.Role == models.RoleOwner {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot change the role of the group owner"})
+		return
+	}
+
+	// 仮メンバーはログインできないため、記録のための member 以外のロールは持たない
+	var targetUser models.User
+	if err := database.DB.Select("id", "is_placeholder").First(&targetUser, target.UserID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch member"})
+		return
+	}
+	if targetUser.IsPlaceholder {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot change the role of a placeholder member"})
+		return
+	}
+
+	// トランザクション開始
+	tx := database.DB.Begin()
+
+	previousRole := target.Role
+	target.Role = input.Role
+	if err := tx.Save(&target).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update role"})
+		return
+	}
+
+	// アクティビティを記録
+	if err := recordActivity(tx, target.GroupID, userID.(uint), models.ActivityMemberRoleChanged, "member", target.UserID, map[string]interface{}{
+		"from": previousRole,
+		"to":   target.Role,
+	}); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record activity"})
+		return
+	}
+
+	tx.Commit()
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Role updated successfully",
+		"member": gin.H{
+			"userID":  target.UserID,
+			"groupID": target.GroupID,
+			"role":    target.Role,
+		},
+	})
+}
+
+// ArchiveGroup はグループをアーカイブします
+// POST /api/v1/groups/:groupID/archive
+func ArchiveGroup(c *gin.Context) {
+	setGroupArchived(c, true)
+}
+
+// UnarchiveGroup はグループのアーカイブを解除します
+// POST /api/v1/groups/:groupID/unarchive
+func UnarchiveGroup(c *gin.Context) {
+	setGroupArchived(c, false)
+}
+
+// setGroupArchived はグループのアーカイブ状態を切り替えます
+func setGroupArchived(c *gin.Context, archived bool) {
+	// パスパラメータからgroupIDを取得
+	groupIDStr := c.Param("groupID")
+	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// ユーザーがグループのメンバーであることを確認
+	var membership models.Membership
+	if err := database.DB.Preload("Group").Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	// グループを管理する権限があることを確認
+	if !hasPermission(membership.Role, PermManageGroup) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to manage this group"})
+		return
+	}
+
+	group := membership.Group
+	if archived {
+		if group.ArchivedAt != nil {
+			c.JSON(http.StatusConflict, gin.H{"error": "Group is already archived"})
+			return
+		}
+		now := time.Now()
+		group.ArchivedAt = &now
+	} else {
+		if group.ArchivedAt == nil {
+			c.JSON(http.StatusConflict, gin.H{"error": "Group is not archived"})
+			return
+		}
+		group.ArchivedAt = nil
+	}
+
+	// トランザクション開始
+	tx := database.DB.Begin()
+
+	if err := tx.Model(&group).Update("archived_at", group.ArchivedAt).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update group"})
+		return
+	}
+
+	action := models.ActivityGroupUnarchived
+	if archived {
+		action = models.ActivityGroupArchived
+	}
+
+	// アクティビティを記録
+	if err := recordActivity(tx, group.ID, userID.(uint), action, "group", group.ID, nil); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record activity"})
+		return
+	}
+
+	tx.Commit()
+
+	message := "Group unarchived successfully"
+	if archived {
+		message = "Group archived successfully"
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": message,
+		"group": gin.H{
+			"id":         group.ID,
+			"name":       group.Name,
+			"ownerID":    group.OwnerID,
+			"archivedAt": group.ArchivedAt,
+		},
+	})
+}
+
+// UpdateGroupAppearance はグループのアイコンとカラーを更新します
+// PUT /api/v1/groups/:groupID/appearance
+func UpdateGroupAppearance(c *gin.Context) {
+	// パスパラメータからgroupIDを取得
+	groupIDStr := c.Param("groupID")
+	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// ユーザーがグループのメンバーであることを確認
+	var membership models.Membership
+	if err := database.DB.Preload("Group").Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	// アーカイブ済みのグループは読み取り専用
+	if membership.Group.ArchivedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Group is archived and read-only"})
+		return
+	}
+
+	// グループを管理する権限があることを確認
+	if !hasPermission(membership.Role, PermManageGroup) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to manage this group"})
+		return
+	}
+
+	// リクエストボディをバインド
+	var input UpdateAppearanceInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	group := membership.Group
+	group.Icon = input.Icon
+	group.Color = strings.ToUpper(input.Color)
+
+	if err := database.DB.Model(&group).Updates(map[string]interface{}{
+		"icon":  group.Icon,
+		"color": group.Color,
+	}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update appearance"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Appearance updated successfully",
+		"group": gin.H{
+			"id":    group.ID,
+			"name":  group.Name,
+			"icon":  group.Icon,
+			"color": group.Color,
+		},
+	})
+}
+
+// PinGroup は認証ユーザーのグループ一覧でグループをピン留め・解除します
+// ピン留めは各メンバー個人の設定で、他のメンバーには影響しません
+// PUT /api/v1/groups/:groupID/pin
+func PinGroup(c *gin.Context) {
+	// パスパラメータからgroupIDを取得
+	groupIDStr := c.Param("groupID")
+	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// ユーザーがグループのメンバーであることを確認
+	var membership models.Membership
+	if err := database.DB.Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	// リクエストボディをバインド
+	var input PinGroupInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := database.DB.Model(&membership).Update("pinned", *input.Pinned).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update pin"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Pin updated successfully",
+		"groupID": membership.GroupID,
+		"pinned":  membership.Pinned,
 	})
 }
@@ -0,0 +1,809 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ito-system/clear-up-share/backend/database"
+	"github.com/ito-system/clear-up-share/backend/models"
+	"github.com/ito-system/clear-up-share/backend/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	groupOrderPollInterval = time.Minute // 申告の締め切りを過ぎた注文を確認する間隔
+	groupOrderBatchSize    = 100         // 1回の確認で締める注文の最大数
+)
+
+// errGroupOrderPayerLeft は注文の支払者がグループを抜けたため支出を登録できないことを表します
+var errGroupOrderPayerLeft = errors.New("Payer is no longer a member of this group")
+
+// GroupOrderItemInput はまとめ買いの注文の1品目の入力形式
+type GroupOrderItemInput struct {
+	Name  string  `json:"name" binding:"required,max=100"`
+	Price float64 `json:"price" binding:"gt=0"`
+}
+
+// CreateGroupOrderInput はまとめ買いの注文の登録リクエストの入力形式
+type CreateGroupOrderInput struct {
+	Description   string                `json:"description" binding:"required"`
+	Amount        float64               `json:"amount" binding:"omitempty,gt=0"` // 支払額（省略した場合は品目の合計）
+	Date          string                `json:"date" binding:"required"`         // YYYY-MM-DD
+	ClaimDeadline string                `json:"claimDeadline"`                   // 申告の締め切り（RFC3339、省略した場合は支払者が締めるまで）
+	Items         []GroupOrderItemInput `json:"items" binding:"required,min=1,max=200,dive"`
+}
+
+// CreateGroupOrder はまとめ買いの支払者が品目を登録し、メンバーの申告を受け付ける注文を作成します
+// POST /api/v1/groups/:groupID/orders
+func CreateGroupOrder(c *gin.Context) {
+	// パスパラメータからgroupIDを取得
+	groupIDStr := c.Param("groupID")
+	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// ユーザーがグループのメンバーであることを確認
+	var membership models.Membership
+	if err := database.DB.Preload("Group").Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	// アーカイブ済みのグループは読み取り専用
+	if membership.Group.ArchivedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Group is archived and read-only"})
+		return
+	}
+
+	// 支出を追加する権限があることを確認
+	if !hasPermission(membership.Role, PermAddExpense) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to add expenses"})
+		return
+	}
+
+	// リクエストボディをバインド
+	var input CreateGroupOrderInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	date, err := time.Parse("2006-01-02", input.Date)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format. Use YYYY-MM-DD"})
+		return
+	}
+
+	var deadline *time.Time
+	if input.ClaimDeadline != "" {
+		parsed, err := time.Parse(time.RFC3339, input.ClaimDeadline)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid claimDeadline format. Use RFC3339"})
+			return
+		}
+		if !parsed.After(time.Now()) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "claimDeadline must be in the future"})
+			return
+		}
+		utc := parsed.UTC()
+		deadline = &utc
+	}
+
+	// 支払額は品目の合計以上（差額は税・送料などとして申告額に比例して分ける）
+	itemsTotal := 0.0
+	for _, item := range input.Items {
+		itemsTotal += item.Price
+	}
+	currency := membership.Group.Currency
+	amount := input.Amount
+	if amount == 0 {
+		amount = itemsTotal
+	}
+	if itemsTotal-amount > math.Pow10(-utils.CurrencyMinorUnits(currency))/2 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Item prices cannot exceed the order amount"})
+		return
+	}
+
+	order := models.GroupOrder{
+		GroupID:       uint(groupID),
+		PayerID:       userID.(uint),
+		Description:   input.Description,
+		Amount:        amount,
+		Date:          date,
+		ClaimDeadline: deadline,
+		Status:        models.GroupOrderStatusOpen,
+	}
+	for _, item := range input.Items {
+		order.Items = append(order.Items, models.GroupOrderItem{Name: item.Name, Price: item.Price})
+	}
+
+	// 注文と品目を作成
+	if err := database.DB.Create(&order).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create order"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Order created successfully",
+		"order":   groupOrderResponse(order, currency),
+	})
+}
+
+// GetGroupOrders はグループのまとめ買いの注文を新しい順に取得します
+// GET /api/v1/groups/:groupID/orders?status=open|closed
+func GetGroupOrders(c *gin.Context) {
+	// パスパラメータからgroupIDを取得
+	groupIDStr := c.Param("groupID")
+	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// ユーザーがグループのメンバーであることを確認
+	var membership models.Membership
+	if err := database.DB.Preload("Group").Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	query := database.DB.Preload("Items", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).Preload("Items.Claims").
+		Where("group_id = ?", groupID)
+	if status := c.Query("status"); status != "" {
+		if status != models.GroupOrderStatusOpen && status != models.GroupOrderStatusClosed {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status"})
+			return
+		}
+		query = query.Where("status = ?", status)
+	}
+
+	var orders []models.GroupOrder
+	if err := query.Order("created_at DESC, id DESC").Find(&orders).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch orders"})
+		return
+	}
+
+	responses := make([]gin.H, len(orders))
+	for i, order := range orders {
+		responses[i] = groupOrderResponse(order, membership.Group.Currency)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"groupID": groupID,
+		"orders":  responses,
+	})
+}
+
+// GetGroupOrder はまとめ買いの注文の品目・申告と、現在の申告で締めた場合の負担額を取得します
+// GET /api/v1/groups/:groupID/orders/:orderID
+func GetGroupOrder(c *gin.Context) {
+	// パスパラメータからgroupIDとorderIDを取得
+	groupIDStr := c.Param("groupID")
+	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	orderIDStr := c.Param("orderID")
+	orderID, err := strconv.ParseUint(orderIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// ユーザーがグループのメンバーであることを確認
+	var membership models.Membership
+	if err := database.DB.Preload("Group").Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	var order models.GroupOrder
+	if err := database.DB.Preload("Items", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).Preload("Items.Claims").
+		Where("id = ? AND group_id = ?", orderID, groupID).First(&order).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+		return
+	}
+
+	// グループのポリシー設定を取得
+	settings, err := loadGroupSettings(database.DB, uint(groupID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch group settings"})
+		return
+	}
+
+	// グループ内の表示名を取得（退会済みのユーザーはユーザー名を表示）
+	names, err := groupDisplayNames(database.DB, uint(groupID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch members"})
+		return
+	}
+
+	response := groupOrderResponse(order, membership.Group.Currency)
+
+	// 締める前は現在の申告で締めた場合の負担額を返す（申告のない品目とグループを抜けたメンバーの申告は支払者の分）
+	if order.Status == models.GroupOrderStatusOpen {
+		left := make(map[uint]bool)
+		for _, item := range order.Items {
+			for _, claim := range item.Claims {
+				if _, ok := names[claim.UserID]; !ok {
+					left[claim.UserID] = true
+				}
+			}
+		}
+		splits, _, err := itemizedSplits(groupOrderExpenseInput(order, left), settings.RoundingMode, membership.Group.Currency)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate shares"})
+			return
+		}
+		shares := make([]gin.H, len(splits))
+		for i, split := range splits {
+			shares[i] = gin.H{
+				"memberID":  split.DebtorID,
+				"username":  names[split.DebtorID],
+				"amountDue": split.AmountDue,
+			}
+		}
+		response["estimatedShares"] = shares
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"order": response,
+	})
+}
+
+// ClaimGroupOrderItem はまとめ買いの品目を自分の分として申告します（同じ品目を何度申告しても1件のまま）
+// POST /api/v1/groups/:groupID/orders/:orderID/items/:itemID/claim
+func ClaimGroupOrderItem(c *gin.Context) {
+	updateGroupOrderClaim(c, true)
+}
+
+// UnclaimGroupOrderItem はまとめ買いの品目の自分の申告を取り消します
+// DELETE /api/v1/groups/:groupID/orders/:orderID/items/:itemID/claim
+func UnclaimGroupOrderItem(c *gin.Context) {
+	updateGroupOrderClaim(c, false)
+}
+
+// updateGroupOrderClaim は受付中の注文の品目の申告・申告の取り消しを処理します
+func updateGroupOrderClaim(c *gin.Context, claim bool) {
+	// パスパラメータからgroupID・orderID・itemIDを取得
+	groupIDStr := c.Param("groupID")
+	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	orderIDStr := c.Param("orderID")
+	orderID, err := strconv.ParseUint(orderIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
+		return
+	}
+
+	itemIDStr := c.Param("itemID")
+	itemID, err := strconv.ParseUint(itemIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid item ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// ユーザーがグループのメンバーであることを確認
+	var membership models.Membership
+	if err := database.DB.Preload("Group").Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	// アーカイブ済みのグループは読み取り専用
+	if membership.Group.ArchivedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Group is archived and read-only"})
+		return
+	}
+
+	// 支出を追加する権限があることを確認（申告した品目は締めたときに自分の負担になる）
+	if !hasPermission(membership.Role, PermAddExpense) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to add expenses"})
+		return
+	}
+
+	// トランザクション開始
+	tx := database.DB.Begin()
+
+	// 注文を取得し、申告の完了まで締められないようにする
+	var order models.GroupOrder
+	if err := tx.Clauses(clause.Locking{Strength: "SHARE"}).Where("id = ? AND group_id = ?", orderID, groupID).First(&order).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+		return
+	}
+	if order.Status != models.GroupOrderStatusOpen || (order.ClaimDeadline != nil && !time.Now().Before(*order.ClaimDeadline)) {
+		tx.Rollback()
+		c.JSON(http.StatusConflict, gin.H{"error": "Claiming is closed for this order"})
+		return
+	}
+
+	var item models.GroupOrderItem
+	if err := tx.Where("id = ? AND order_id = ?", itemID, order.ID).First(&item).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusNotFound, gin.H{"error": "Item not found"})
+		return
+	}
+
+	if claim {
+		record := models.GroupOrderClaim{ItemID: item.ID, UserID: userID.(uint)}
+		if err := tx.Where("item_id = ? AND user_id = ?", item.ID, userID).FirstOrCreate(&record).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to claim item"})
+			return
+		}
+	} else if err := tx.Where("item_id = ? AND user_id = ?", item.ID, userID).Delete(&models.GroupOrderClaim{}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unclaim item"})
+		return
+	}
+
+	tx.Commit()
+
+	var claimerIDs []uint
+	if err := database.DB.Model(&models.GroupOrderClaim{}).Where("item_id = ?", item.ID).Order("id").Pluck("user_id", &claimerIDs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch claims"})
+		return
+	}
+
+	message := "Item claimed successfully"
+	if !claim {
+		message = "Item unclaimed successfully"
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": message,
+		"item": gin.H{
+			"id":        item.ID,
+			"name":      item.Name,
+			"price":     item.Price,
+			"claimedBy": claimerIDs,
+		},
+	})
+}
+
+// CloseGroupOrder は申告を締め、品目ごとの申告者から負担額を計算して品目ごとに分けた支出を登録します
+// 申告のない品目とグループを抜けたメンバーの申告は支払者の分として扱います（支払者または管理者のみ）
+// POST /api/v1/groups/:groupID/orders/:orderID/close
+func CloseGroupOrder(c *gin.Context) {
+	// パスパラメータからgroupIDとorderIDを取得
+	groupIDStr := c.Param("groupID")
+	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	orderIDStr := c.Param("orderID")
+	orderID, err := strconv.ParseUint(orderIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// ユーザーがグループのメンバーであることを確認
+	var membership models.Membership
+	if err := database.DB.Preload("Group").Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	// アーカイブ済みのグループは読み取り専用
+	if membership.Group.ArchivedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Group is archived and read-only"})
+		return
+	}
+
+	// グループのポリシー設定を取得
+	settings, err := loadGroupSettings(database.DB, uint(groupID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch group settings"})
+		return
+	}
+
+	// トランザクション開始
+	tx := database.DB.Begin()
+
+	// 注文を取得し、同時に締められないようにする
+	var order models.GroupOrder
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ? AND group_id = ?", orderID, groupID).First(&order).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+		return
+	}
+
+	// 注文の支払者または全ての支出を編集する権限（管理者）があることを確認
+	if order.PayerID != userID.(uint) && !hasPermission(membership.Role, PermEditAnyExpense) {
+		tx.Rollback()
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the payer or an admin can close this order"})
+		return
+	}
+	if order.Status != models.GroupOrderStatusOpen {
+		tx.Rollback()
+		c.JSON(http.StatusConflict, gin.H{"error": "Order is already closed"})
+		return
+	}
+
+	expense, err := finalizeGroupOrder(tx, &order, settings, membership.Group.Currency)
+	if err != nil {
+		tx.Rollback()
+		var quotaErr *QuotaExceededError
+		switch {
+		case errors.As(err, &quotaErr):
+			respondQuotaError(c, err)
+		case errors.Is(err, errGroupOrderPayerLeft):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to close order"})
+		}
+		return
+	}
+
+	tx.Commit()
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Order closed successfully",
+		"order":   groupOrderResponse(order, membership.Group.Currency),
+		"expense": expenseResponse(expense, membership.Group.Currency),
+	})
+}
+
+// DeleteGroupOrder は受付中のまとめ買いの注文を取り消します（支払者または管理者のみ）
+// DELETE /api/v1/groups/:groupID/orders/:orderID
+func DeleteGroupOrder(c *gin.Context) {
+	// パスパラメータからgroupIDとorderIDを取得
+	groupIDStr := c.Param("groupID")
+	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	orderIDStr := c.Param("orderID")
+	orderID, err := strconv.ParseUint(orderIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// ユーザーがグループのメンバーであることを確認
+	var membership models.Membership
+	if err := database.DB.Preload("Group").Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	// アーカイブ済みのグループは読み取り専用
+	if membership.Group.ArchivedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Group is archived and read-only"})
+		return
+	}
+
+	// リーガルホールド中のグループでは記録を変更できない
+	if rejectIfLegalHold(c, membership.Group) {
+		return
+	}
+
+	// トランザクション開始
+	tx := database.DB.Begin()
+
+	// 注文を取得し、同時に締められないようにする
+	var order models.GroupOrder
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ? AND group_id = ?", orderID, groupID).First(&order).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+		return
+	}
+
+	// 注文の支払者または全ての支出を編集する権限（管理者）があることを確認
+	if order.PayerID != userID.(uint) && !hasPermission(membership.Role, PermEditAnyExpense) {
+		tx.Rollback()
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the payer or an admin can delete this order"})
+		return
+	}
+
+	// 締めた注文は登録した支出として扱う
+	if order.Status != models.GroupOrderStatusOpen {
+		tx.Rollback()
+		c.JSON(http.StatusConflict, gin.H{"error": "Order is already closed. Delete its expense instead"})
+		return
+	}
+
+	// 申告・品目・注文を削除
+	itemIDs := tx.Model(&models.GroupOrderItem{}).Select("id").Where("order_id = ?", order.ID)
+	if err := tx.Where("item_id IN (?)", itemIDs).Delete(&models.GroupOrderClaim{}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete claims"})
+		return
+	}
+	if err := tx.Where("order_id = ?", order.ID).Delete(&models.GroupOrderItem{}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete items"})
+		return
+	}
+	if err := tx.Delete(&order).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete order"})
+		return
+	}
+
+	tx.Commit()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Order deleted successfully"})
+}
+
+// StartGroupOrderScheduler は申告の締め切りを過ぎたまとめ買いの注文を締めるワーカーを起動します
+func StartGroupOrderScheduler() {
+	go func() {
+		for {
+			closeDueGroupOrders(time.Now())
+			time.Sleep(groupOrderPollInterval)
+		}
+	}()
+}
+
+// closeDueGroupOrders は申告の締め切りを過ぎた受付中の注文をまとめて締めます（アーカイブ済みのグループは除く）
+func closeDueGroupOrders(now time.Time) {
+	var due []models.GroupOrder
+	if err := database.DB.
+		Where("status = ? AND claim_deadline <= ?", models.GroupOrderStatusOpen, now).
+		Where("group_id NOT IN (SELECT id FROM groups WHERE archived_at IS NOT NULL)").
+		Order("claim_deadline, id").
+		Limit(groupOrderBatchSize).
+		Find(&due).Error; err != nil {
+		log.Printf("Failed to fetch group orders: %v", err)
+		return
+	}
+
+	for _, order := range due {
+		if err := closeDueGroupOrder(order.ID, now); err != nil {
+			log.Printf("Failed to close group order %d: %v", order.ID, err)
+		}
+	}
+}
+
+// closeDueGroupOrder は締め切りを過ぎた注文を締めます（締められない場合は受付中のまま次回の確認で再試行します）
+func closeDueGroupOrder(orderID uint, now time.Time) error {
+	tx := database.DB.Begin()
+
+	// 複数のサーバーで動かしている場合や支払者が同時に締めた場合に二重に登録しない
+	var order models.GroupOrder
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).Preload("Group").First(&order, orderID).Error; err != nil {
+		tx.Rollback()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	if order.Status != models.GroupOrderStatusOpen || order.ClaimDeadline == nil || order.ClaimDeadline.After(now) {
+		tx.Rollback()
+		return nil
+	}
+
+	settings, err := loadGroupSettings(tx, order.GroupID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if _, err := finalizeGroupOrder(tx, &order, settings, order.Group.Currency); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+// finalizeGroupOrder は注文の申告から品目ごとに分けた支出を登録し、注文を締めます（order は tx で行ロックしておきます）
+// 支出は支払者が登録した場合と同じく、承認が必要なグループでは承認待ちになります
+func finalizeGroupOrder(tx *gorm.DB, order *models.GroupOrder, settings models.GroupSettings, currency string) (models.Expense, error) {
+	var expense models.Expense
+
+	var payer models.Membership
+	if err := tx.Where("user_id = ? AND group_id = ?", order.PayerID, order.GroupID).First(&payer).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return expense, errGroupOrderPayerLeft
+		}
+		return expense, err
+	}
+
+	var items []models.GroupOrderItem
+	if err := tx.Preload("Claims").Where("order_id = ?", order.ID).Order("id").Find(&items).Error; err != nil {
+		return expense, err
+	}
+	order.Items = items
+
+	// 支払者と申告者が完了するまで退会・除名されないようにし、グループを抜けたメンバーの申告は除く
+	userIDs := []uint{order.PayerID}
+	for _, item := range items {
+		for _, claim := range item.Claims {
+			userIDs = append(userIDs, claim.UserID)
+		}
+	}
+	nonMembers, err := lockGroupNonMembers(tx, order.GroupID, userIDs)
+	if err != nil {
+		return expense, err
+	}
+	left := make(map[uint]bool, len(nonMembers))
+	for _, id := range nonMembers {
+		if id == order.PayerID {
+			return expense, errGroupOrderPayerLeft
+		}
+		left[id] = true
+	}
+
+	// 今月の支出数が上限に達していないことを確認
+	if err := checkExpenseQuota(tx, order.GroupID, settings, 1); err != nil {
+		return expense, err
+	}
+
+	splits, expenseItems, err := itemizedSplits(groupOrderExpenseInput(*order, left), settings.RoundingMode, currency)
+	if err != nil {
+		return expense, err
+	}
+
+	// 承認が必要なグループでは、承認権限のないメンバーの支出は承認待ちになる
+	status := models.ExpenseStatusConfirmed
+	if settings.RequireExpenseApproval && !hasPermission(payer.Role, PermApproveExpense) {
+		status = models.ExpenseStatusPending
+	}
+
+	expense = models.Expense{
+		GroupID:     order.GroupID,
+		PayerID:     order.PayerID,
+		Amount:      order.Amount,
+		Type:        models.ExpenseTypeExpense,
+		Description: order.Description,
+		Date:        order.Date,
+		Status:      status,
+		CreatedByID: order.PayerID,
+	}
+	if err := tx.Create(&expense).Error; err != nil {
+		return expense, err
+	}
+	for _, split := range splits {
+		split.ExpenseID = expense.ID
+		if err := tx.Create(&split).Error; err != nil {
+			return expense, err
+		}
+	}
+	for _, item := range expenseItems {
+		item.ExpenseID = expense.ID
+		if err := tx.Create(&item).Error; err != nil {
+			return expense, err
+		}
+	}
+
+	if err := recordActivity(tx, expense.GroupID, order.PayerID, models.ActivityExpenseAdded, "expense", expense.ID, map[string]interface{}{
+		"description":  expense.Description,
+		"amount":       expense.Amount,
+		"payerID":      expense.PayerID,
+		"groupOrderID": order.ID,
+	}); err != nil {
+		return expense, err
+	}
+
+	now := time.Now()
+	order.Status = models.GroupOrderStatusClosed
+	order.ExpenseID = &expense.ID
+	order.ClosedAt = &now
+	if err := tx.Model(&models.GroupOrder{}).Where("id = ?", order.ID).Updates(map[string]interface{}{
+		"status":     order.Status,
+		"expense_id": order.ExpenseID,
+		"closed_at":  order.ClosedAt,
+	}).Error; err != nil {
+		return expense, err
+	}
+	return expense, nil
+}
+
+// groupOrderExpenseInput は注文の品目と申告から品目ごとに分ける支出の入力を組み立てます
+// 申告のない品目と left（グループを抜けたメンバー）の申告のみの品目は支払者の分とします
+func groupOrderExpenseInput(order models.GroupOrder, left map[uint]bool) AddExpenseInput {
+	input := AddExpenseInput{
+		Description: order.Description,
+		Amount:      order.Amount,
+		PayerID:     order.PayerID,
+		SplitType:   models.SplitTypeItemized,
+	}
+	for _, item := range order.Items {
+		var memberIDs []uint
+		for _, claim := range item.Claims {
+			if !left[claim.UserID] {
+				memberIDs = append(memberIDs, claim.UserID)
+			}
+		}
+		if len(memberIDs) == 0 {
+			memberIDs = []uint{order.PayerID}
+		}
+		input.Items = append(input.Items, ExpenseItemInput{Name: item.Name, Price: item.Price, MemberIDs: memberIDs})
+	}
+	return input
+}
+
+// groupOrderResponse はまとめ買いの注文のレスポンス形式を構築します
+func groupOrderResponse(order models.GroupOrder, currency string) gin.H {
+	items := make([]gin.H, len(order.Items))
+	for i, item := range order.Items {
+		claimedBy := make([]uint, len(item.Claims))
+		for j, claim := range item.Claims {
+			claimedBy[j] = claim.UserID
+		}
+		items[i] = gin.H{
+			"id":        item.ID,
+			"name":      item.Name,
+			"price":     item.Price,
+			"claimedBy": claimedBy,
+		}
+	}
+	return gin.H{
+		"id":            order.ID,
+		"groupID":       order.GroupID,
+		"payerID":       order.PayerID,
+		"description":   order.Description,
+		"amount":        order.Amount,
+		"currency":      currency,
+		"date":          order.Date.Format("2006-01-02"),
+		"claimDeadline": order.ClaimDeadline,
+		"status":        order.Status,
+		"expenseID":     order.ExpenseID,
+		"closedAt":      order.ClosedAt,
+		"items":         items,
+		"createdAt":     order.CreatedAt,
+	}
+}
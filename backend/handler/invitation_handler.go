@@ -0,0 +1,280 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ito-system/clear-up-share/backend/database"
+	"github.com/ito-system/clear-up-share/backend/models"
+)
+
+// CreateInvitationInput はグループ招待リクエストの入力形式
+type CreateInvitationInput struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// CreateInvitation はメールアドレス宛てにグループへの招待を作成します
+// POST /api/v1/groups/:groupID/invitations
+func CreateInvitation(c *gin.Context) {
+	// パスパラメータからgroupIDを取得
+	groupIDStr := c.Param("groupID")
+	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// ユーザーがグループのメンバーであることを確認
+	var membership models.Membership
+	if err := database.DB.Preload("Group").Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	// アーカイブ済みのグループは読み取り専用
+	if membership.Group.ArchivedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Group is archived and read-only"})
+		return
+	}
+
+	// メンバーを管理する権限があることを確認
+	if !hasPermission(membership.Role, PermManageMembers) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to manage members"})
+		return
+	}
+
+	// リクエストボディをバインド
+	var input CreateInvitationInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	email := strings.ToLower(strings.TrimSpace(input.Email))
+
+	// 既にメンバーであるユーザーは招待できない
+	var memberCount int64
+	if err := database.DB.Model(&models.Membership{}).
+		Joins("JOIN users ON users.id = memberships.user_id").
+		Where("memberships.group_id = ? AND LOWER(users.email) = ? AND users.is_placeholder = ?", groupID, email, false).
+		Count(&memberCount).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check membership"})
+		return
+	}
+	if memberCount > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "User is already a member of this group"})
+		return
+	}
+
+	// 同じメールアドレスへの保留中の招待は重複させない
+	var pendingCount int64
+	if err := database.DB.Model(&models.Invitation{}).
+		Where("group_id = ? AND email = ? AND status = ?", groupID, email, models.InvitationStatusPending).
+		Count(&pendingCount).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check invitations"})
+		return
+	}
+	if pendingCount > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "An invitation is already pending for this email"})
+		return
+	}
+
+	invitation := models.Invitation{
+		GroupID:   uint(groupID),
+		InviterID: userID.(uint),
+		Email:     email,
+		Status:    models.InvitationStatusPending,
+	}
+
+	if err := database.DB.Create(&invitation).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create invitation"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Invitation created successfully",
+		"invitation": gin.H{
+			"id":        invitation.ID,
+			"groupID":   invitation.GroupID,
+			"email":     invitation.Email,
+			"status":    invitation.Status,
+			"createdAt": invitation.CreatedAt,
+		},
+	})
+}
+
+// GetMyInvitations は認証ユーザーのメールアドレス宛ての保留中の招待一覧を取得します
+// GET /api/v1/users/me/invitations
+func GetMyInvitations(c *gin.Context) {
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var user models.User
+	if err := database.DB.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	var invitations []models.Invitation
+	if err := database.DB.Preload("Group").Preload("Inviter").
+		Where("email = ? AND status = ?", strings.ToLower(user.Email), models.InvitationStatusPending).
+		Order("created_at DESC").
+		Find(&invitations).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch invitations"})
+		return
+	}
+
+	// レスポンス用の招待リストを構築
+	type InvitationResponse struct {
+		ID          uint   `json:"id"`
+		GroupID     uint   `json:"groupID"`
+		GroupName   string `json:"groupName"`
+		InviterID   uint   `json:"inviterID"`
+		InviterName string `json:"inviterName"`
+		Status      string `json:"status"`
+	}
+
+	items := make([]InvitationResponse, len(invitations))
+	for i, inv := range invitations {
+		items[i] = InvitationResponse{
+			ID:          inv.ID,
+			GroupID:     inv.GroupID,
+			GroupName:   inv.Group.Name,
+			InviterID:   inv.InviterID,
+			InviterName: inv.Inviter.Username,
+			Status:      inv.Status,
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"invitations": items,
+	})
+}
+
+// AcceptInvitation は招待を承諾してグループに参加します
+// POST /api/v1/users/me/invitations/:invitationID/accept
+func AcceptInvitation(c *gin.Context) {
+	respondToInvitation(c, true)
+}
+
+// DeclineInvitation は招待を辞退します
+// POST /api/v1/users/me/invitations/:invitationID/decline
+func DeclineInvitation(c *gin.Context) {
+	respondToInvitation(c, false)
+}
+
+// respondToInvitation は招待の承諾・辞退を処理します
+func respondToInvitation(c *gin.Context, accept bool) {
+	// パスパラメータからinvitationIDを取得
+	invitationIDStr := c.Param("invitationID")
+	invitationID, err := strconv.ParseUint(invitationIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid invitation ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var user models.User
+	if err := database.DB.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	// 自分宛ての保留中の招待であることを確認
+	var invitation models.Invitation
+	if err := database.DB.Where("id = ? AND email = ?", invitationID, strings.ToLower(user.Email)).First(&invitation).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invitation not found"})
+		return
+	}
+
+	if invitation.Status != models.InvitationStatusPending {
+		c.JSON(http.StatusConflict, gin.H{"error": "Invitation has already been answered"})
+		return
+	}
+
+	if !accept {
+		if err := database.DB.Model(&invitation).Update("status", models.InvitationStatusDeclined).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decline invitation"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Invitation declined",
+		})
+		return
+	}
+
+	// トランザクションでメンバーシップ作成と招待の更新を行う
+	tx := database.DB.Begin()
+
+	var existing int64
+	if err := tx.Model(&models.Membership{}).Where("user_id = ? AND group_id = ?", user.ID, invitation.GroupID).Count(&existing).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check membership"})
+		return
+	}
+
+	if existing == 0 {
+		// 所属グループ数とグループのメンバー数が上限に達していないことを確認
+		if err := checkGroupQuota(tx, user.ID); err != nil {
+			tx.Rollback()
+			respondQuotaError(c, err)
+			return
+		}
+		if err := checkMemberQuota(tx, invitation.GroupID, 1); err != nil {
+			tx.Rollback()
+			respondQuotaError(c, err)
+			return
+		}
+
+		membership := models.Membership{
+			UserID:  user.ID,
+			GroupID: invitation.GroupID,
+			Role:    models.RoleMember,
+		}
+		if err := tx.Create(&membership).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to join group"})
+			return
+		}
+
+		// アクティビティを記録
+		if err := recordActivity(tx, invitation.GroupID, user.ID, models.ActivityMemberJoined, "member", user.ID, map[string]interface{}{
+			"invitationID": invitation.ID,
+		}); err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record activity"})
+			return
+		}
+	}
+
+	if err := tx.Model(&invitation).Update("status", models.InvitationStatusAccepted).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to accept invitation"})
+		return
+	}
+
+	tx.Commit()
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Invitation accepted",
+		"groupID": invitation.GroupID,
+	})
+}
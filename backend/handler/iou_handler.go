@@ -0,0 +1,711 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ito-system/clear-up-share/backend/database"
+	"github.com/ito-system/clear-up-share/backend/models"
+	"github.com/ito-system/clear-up-share/backend/money"
+	"github.com/ito-system/clear-up-share/backend/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// iouReminderInterval は同じ貸し借りの返済のリマインダーを再度送れるまでの間隔
+const iouReminderInterval = 24 * time.Hour
+
+// CreateIOUInput は個人間の貸し借りを記録するリクエストの入力形式
+type CreateIOUInput struct {
+	FriendEmail string  `json:"friendEmail" binding:"required,email"`             // 貸し借りの相手のメールアドレス
+	Direction   string  `json:"direction" binding:"required,oneof=lent borrowed"` // lent: 相手に貸した、borrowed: 相手から借りた
+	Amount      float64 `json:"amount" binding:"required,gt=0"`
+	Currency    string  `json:"currency"` // 省略時はデフォルト通貨
+	Description string  `json:"description" binding:"required,max=200"`
+	Date        string  `json:"date"` // 貸し借りした日付（YYYY-MM-DD、省略時は今日）
+}
+
+// SettleIOUInput は個人間の貸し借りの返済を記録するリクエストの入力形式
+type SettleIOUInput struct {
+	Amount *float64 `json:"amount" binding:"omitempty,gt=0"` // 省略時は残りの全額
+	Date   string   `json:"date"`                            // 返済した日付（YYYY-MM-DD、省略時は今日）
+	Method string   `json:"method" binding:"omitempty,oneof=cash bank_transfer paypay other"`
+	Note   string   `json:"note" binding:"max=200"`
+}
+
+// IOUPaymentResponse は個人間の貸し借りの返済の形式
+type IOUPaymentResponse struct {
+	ID           uint       `json:"id"`
+	Amount       float64    `json:"amount"`
+	Date         string     `json:"date"`
+	Method       string     `json:"method"`
+	Note         string     `json:"note"`
+	Status       string     `json:"status"` // confirmed / pending（貸した側の確認待ち）
+	RecordedByID uint       `json:"recordedByID"`
+	ConfirmedAt  *time.Time `json:"confirmedAt"`
+	CreatedAt    time.Time  `json:"createdAt"`
+}
+
+// IOUResponse は個人間の貸し借りの形式
+type IOUResponse struct {
+	ID             uint                 `json:"id"`
+	LenderID       uint                 `json:"lenderID"`
+	LenderName     string               `json:"lenderName"`
+	BorrowerID     uint                 `json:"borrowerID"`
+	BorrowerName   string               `json:"borrowerName"`
+	Amount         float64              `json:"amount"`
+	Currency       string               `json:"currency"`
+	Description    string               `json:"description"`
+	Date           string               `json:"date"`
+	Status         string               `json:"status"`
+	Paid           float64              `json:"paid"`      // 確定した返済の合計額
+	Pending        float64              `json:"pending"`   // 確認待ちの返済の合計額
+	Remaining      float64              `json:"remaining"` // 残りの額（確認待ちの返済は差し引かない）
+	SettledAt      *time.Time           `json:"settledAt"`
+	LastRemindedAt *time.Time           `json:"lastRemindedAt"`
+	CreatedAt      time.Time            `json:"createdAt"`
+	Payments       []IOUPaymentResponse `json:"payments,omitempty"`
+	Payment        *PaymentHints        `json:"payment,omitempty"` // 認証ユーザーが借りた側で、貸した側が送金先を設定している場合のみ
+}
+
+// IOUTotal は通貨ごとの個人間の貸し借りの残りの額の合計を表す形式
+type IOUTotal struct {
+	Currency string  `json:"currency"`
+	OwedToMe float64 `json:"owedToMe"` // 貸した残りの額
+	IOwe     float64 `json:"iOwe"`     // 借りた残りの額
+	Net      float64 `json:"net"`      // 正: 受け取る、負: 支払う
+}
+
+// iouAmounts は貸し借りの確定した返済・確認待ちの返済・残りの額を補助単位で返します
+func iouAmounts(iou models.IOU) (paid, pending, remaining int64) {
+	for _, p := range iou.Payments {
+		units := money.ToMinor(p.Amount, iou.Currency)
+		if p.Status == models.SettlementStatusPending {
+			pending += units
+		} else {
+			paid += units
+		}
+	}
+	return paid, pending, money.ToMinor(iou.Amount, iou.Currency) - paid
+}
+
+// iouResponse は個人間の貸し借りのレスポンス形式を返します（withPayments が true の場合は返済の一覧を含めます）
+func iouResponse(iou models.IOU, withPayments bool) IOUResponse {
+	paid, pending, remaining := iouAmounts(iou)
+	response := IOUResponse{
+		ID:             iou.ID,
+		LenderID:       iou.LenderID,
+		LenderName:     iou.Lender.Username,
+		BorrowerID:     iou.BorrowerID,
+		BorrowerName:   iou.Borrower.Username,
+		Amount:         iou.Amount,
+		Currency:       iou.Currency,
+		Description:    iou.Description,
+		Date:           iou.Date.Format("2006-01-02"),
+		Status:         iou.Status,
+		Paid:           money.FromMinor(paid, iou.Currency),
+		Pending:        money.FromMinor(pending, iou.Currency),
+		Remaining:      money.FromMinor(remaining, iou.Currency),
+		SettledAt:      iou.SettledAt,
+		LastRemindedAt: iou.LastRemindedAt,
+		CreatedAt:      iou.CreatedAt,
+	}
+	if withPayments {
+		response.Payments = make([]IOUPaymentResponse, len(iou.Payments))
+		for i, p := range iou.Payments {
+			response.Payments[i] = IOUPaymentResponse{
+				ID:           p.ID,
+				Amount:       p.Amount,
+				Date:         p.Date.Format("2006-01-02"),
+				Method:       p.Method,
+				Note:         p.Note,
+				Status:       p.Status,
+				RecordedByID: p.RecordedByID,
+				ConfirmedAt:  p.ConfirmedAt,
+				CreatedAt:    p.CreatedAt,
+			}
+		}
+	}
+	return response
+}
+
+// serverToday はサーバーのタイムゾーンでの今日の日付を返します（グループに属さない個人間の貸し借りの日付に使用）
+func serverToday() time.Time {
+	return localDate(time.Now(), time.Local)
+}
+
+// parseIOUDate は貸し借り・返済の日付を解析します（省略時は今日、今日より後の日付はエラー）
+func parseIOUDate(value string) (time.Time, string) {
+	today := serverToday()
+	if value == "" {
+		return today, ""
+	}
+	date, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return date, "Invalid date format. Use YYYY-MM-DD"
+	}
+	if date.After(today) {
+		return date, "Date cannot be in the future"
+	}
+	return date, ""
+}
+
+// loadIOU は認証ユーザーが当事者の貸し借りを返済の一覧と合わせて取得します
+func loadIOU(db *gorm.DB, iouID uint64, userID interface{}) (models.IOU, error) {
+	var iou models.IOU
+	err := db.Preload("Lender").Preload("Borrower").
+		Preload("Payments", func(db *gorm.DB) *gorm.DB { return db.Order("date, id") }).
+		Where("id = ? AND (lender_id = ? OR borrower_id = ?)", iouID, userID, userID).
+		First(&iou).Error
+	return iou, err
+}
+
+// CreateIOU はグループを作らずに、認証ユーザーとメールアドレスで指定した相手の間の1回限りの貸し借りを記録し、相手に通知します
+// POST /api/v1/iou
+func CreateIOU(c *gin.Context) {
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// リクエストボディをバインド
+	var input CreateIOUInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 通貨を検証（未指定の場合はデフォルト通貨）
+	currency := utils.DefaultCurrency
+	if input.Currency != "" {
+		code, ok := utils.NormalizeCurrency(input.Currency)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported currency code"})
+			return
+		}
+		currency = code
+	}
+	amount := money.Round(input.Amount, currency)
+	if amount <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Amount is too small for the currency"})
+		return
+	}
+
+	date, dateErr := parseIOUDate(input.Date)
+	if dateErr != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": dateErr})
+		return
+	}
+
+	// 相手を取得（仮メンバーと個人情報を削除したユーザーとは記録できない）
+	var friend models.User
+	if err := database.DB.Where("LOWER(email) = ? AND is_placeholder = ? AND anonymized_at IS NULL", utils.NormalizeEmail(input.FriendEmail), false).First(&friend).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if friend.ID == userID.(uint) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot record an IOU with yourself"})
+		return
+	}
+
+	var me models.User
+	if err := database.DB.First(&me, userID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user"})
+		return
+	}
+
+	iou := models.IOU{
+		LenderID:    me.ID,
+		BorrowerID:  friend.ID,
+		CreatedByID: me.ID,
+		Amount:      amount,
+		Currency:    currency,
+		Description: input.Description,
+		Date:        date,
+		Status:      models.IOUStatusOpen,
+		Lender:      me,
+		Borrower:    friend,
+	}
+	message := fmt.Sprintf("%s recorded that you owe %s for %s", me.Username, formatGlanceAmount(amount, currency), input.Description)
+	if input.Direction == "borrowed" {
+		iou.LenderID, iou.BorrowerID = friend.ID, me.ID
+		iou.Lender, iou.Borrower = friend, me
+		message = fmt.Sprintf("%s recorded that they owe you %s for %s", me.Username, formatGlanceAmount(amount, currency), input.Description)
+	}
+
+	// トランザクション開始
+	tx := database.DB.Begin()
+
+	if err := tx.Omit("Lender", "Borrower").Create(&iou).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record IOU"})
+		return
+	}
+
+	// 相手に通知
+	if err := notify(tx, friend.ID, 0, models.NotificationIOURecorded, message, "iou", iou.ID); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send notification"})
+		return
+	}
+
+	tx.Commit()
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "IOU recorded successfully",
+		"iou":     iouResponse(iou, true),
+	})
+}
+
+// GetIOUs は認証ユーザーが当事者の個人間の貸し借りの一覧と、通貨ごとの残りの額の合計を返します
+// GET /api/v1/iou?status=open|settled&friendID=
+func GetIOUs(c *gin.Context) {
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	query := database.DB.Preload("Lender").Preload("Borrower").Preload("Payments").
+		Where("lender_id = ? OR borrower_id = ?", userID, userID)
+
+	// ステータスで絞り込み（省略時は全て）
+	switch status := c.Query("status"); status {
+	case "":
+	case models.IOUStatusOpen, models.IOUStatusSettled:
+		query = query.Where("status = ?", status)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status. Use open or settled"})
+		return
+	}
+
+	// 相手で絞り込み
+	if friendIDStr := c.Query("friendID"); friendIDStr != "" {
+		friendID, err := strconv.ParseUint(friendIDStr, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid friend ID"})
+			return
+		}
+		query = query.Where("lender_id = ? OR borrower_id = ?", friendID, friendID)
+	}
+
+	var ious []models.IOU
+	if err := query.Order("date DESC, id DESC").Find(&ious).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch IOUs"})
+		return
+	}
+
+	// 通貨の異なる貸し借りは合算せず、通貨ごとに補助単位で合計する
+	type unitTotal struct{ owedToMe, iOwe int64 }
+	units := make(map[string]*unitTotal)
+	responses := make([]IOUResponse, len(ious))
+	for i, iou := range ious {
+		responses[i] = iouResponse(iou, false)
+		if iou.Status != models.IOUStatusOpen {
+			continue
+		}
+		_, _, remaining := iouAmounts(iou)
+		total, ok := units[iou.Currency]
+		if !ok {
+			total = &unitTotal{}
+			units[iou.Currency] = total
+		}
+		if iou.LenderID == userID.(uint) {
+			total.owedToMe += remaining
+		} else {
+			total.iOwe += remaining
+		}
+	}
+
+	totals := make([]IOUTotal, 0, len(units))
+	for currency, u := range units {
+		totals = append(totals, IOUTotal{
+			Currency: currency,
+			OwedToMe: money.FromMinor(u.owedToMe, currency),
+			IOwe:     money.FromMinor(u.iOwe, currency),
+			Net:      money.FromMinor(u.owedToMe-u.iOwe, currency),
+		})
+	}
+	sort.Slice(totals, func(i, j int) bool { return totals[i].Currency < totals[j].Currency })
+
+	c.JSON(http.StatusOK, gin.H{
+		"ious":   responses,
+		"totals": totals,
+	})
+}
+
+// GetIOU は個人間の貸し借りを返済の一覧と合わせて返します
+// 認証ユーザーが借りた側の場合は、清算の送金の提案と同じく貸した側の送金先を付けます
+// GET /api/v1/iou/:iouID
+func GetIOU(c *gin.Context) {
+	// パスパラメータからiouIDを取得
+	iouIDStr := c.Param("iouID")
+	iouID, err := strconv.ParseUint(iouIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid IOU ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	iou, err := loadIOU(database.DB, iouID, userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "IOU not found"})
+		return
+	}
+
+	response := iouResponse(iou, true)
+	if iou.Status == models.IOUStatusOpen && iou.BorrowerID == userID.(uint) {
+		var profile models.PayoutProfile
+		if err := database.DB.Where("user_id = ?", iou.LenderID).First(&profile).Error; err == nil {
+			hints := &PaymentHints{Methods: []string{}}
+			if profile.PayPayLink != "" {
+				hints.Methods = append(hints.Methods, models.SettlementMethodPayPay)
+				hints.PayPayLink = profile.PayPayLink
+			}
+			if hasBankAccount(profile) {
+				hints.Methods = append(hints.Methods, models.SettlementMethodBankTransfer)
+				hints.BankTransfer = &BankTransfer{
+					BankName:      profile.BankName,
+					BranchName:    profile.BranchName,
+					AccountType:   profile.AccountType,
+					AccountNumber: profile.AccountNumber,
+					AccountHolder: profile.AccountHolder,
+					Amount:        response.Remaining,
+				}
+			}
+			if len(hints.Methods) > 0 {
+				response.Payment = hints
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"iou": response,
+	})
+}
+
+// SettleIOU は個人間の貸し借りの返済を記録します（省略時は残りの全額）
+// 貸した側が記録した返済はすぐに確定し、借りた側が記録した返済は貸した側が確認するまで確認待ちになります
+// 確定した返済の合計が貸し借りの額に達すると、貸し借りは返済済み（settled）になります
+// POST /api/v1/iou/:iouID/settle
+func SettleIOU(c *gin.Context) {
+	// パスパラメータからiouIDを取得
+	iouIDStr := c.Param("iouID")
+	iouID, err := strconv.ParseUint(iouIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid IOU ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// リクエストボディをバインド（ボディの省略は残りの全額の返済として扱う）
+	var input SettleIOUInput
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	date, dateErr := parseIOUDate(input.Date)
+	if dateErr != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": dateErr})
+		return
+	}
+
+	// トランザクション開始
+	tx := database.DB.Begin()
+
+	// 同時に返済を記録して残りの額を超えないように、貸し借りをロックして取得する
+	iou, err := loadIOU(tx.Clauses(clause.Locking{Strength: "UPDATE"}), iouID, userID)
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusNotFound, gin.H{"error": "IOU not found"})
+		return
+	}
+	if iou.Status == models.IOUStatusSettled {
+		tx.Rollback()
+		c.JSON(http.StatusConflict, gin.H{"error": "IOU is already settled"})
+		return
+	}
+
+	// 確認待ちの返済を含めて残りの額を超えないことを確認
+	_, pending, remaining := iouAmounts(iou)
+	available := remaining - pending
+	if available <= 0 {
+		tx.Rollback()
+		c.JSON(http.StatusConflict, gin.H{"error": "The remaining amount is waiting for the lender's confirmation"})
+		return
+	}
+	units := available
+	if input.Amount != nil {
+		units = money.ToMinor(*input.Amount, iou.Currency)
+		if units <= 0 {
+			tx.Rollback()
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Amount is too small for the currency"})
+			return
+		}
+		if units > available {
+			tx.Rollback()
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":     "Amount exceeds the remaining balance",
+				"remaining": money.FromMinor(available, iou.Currency),
+			})
+			return
+		}
+	}
+
+	payment := models.IOUPayment{
+		IOUID:        iou.ID,
+		Amount:       money.FromMinor(units, iou.Currency),
+		Date:         date,
+		Method:       input.Method,
+		Note:         input.Note,
+		Status:       models.SettlementStatusConfirmed,
+		RecordedByID: userID.(uint),
+	}
+	byLender := iou.LenderID == userID.(uint)
+	if byLender {
+		now := time.Now()
+		payment.ConfirmedAt = &now
+	} else {
+		payment.Status = models.SettlementStatusPending
+	}
+	if err := tx.Create(&payment).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record payment"})
+		return
+	}
+	iou.Payments = append(iou.Payments, payment)
+
+	if err := settleIOUIfPaid(tx, &iou); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record payment"})
+		return
+	}
+
+	// 相手に通知（借りた側が記録した返済は、貸した側に受け取りの確認を依頼する）
+	amount := formatGlanceAmount(payment.Amount, iou.Currency)
+	if byLender {
+		message := fmt.Sprintf("%s recorded your repayment of %s for %s", iou.Lender.Username, amount, iou.Description)
+		err = notify(tx, iou.BorrowerID, 0, models.NotificationSettlementConfirmed, message, "iou", iou.ID)
+	} else {
+		message := fmt.Sprintf("%s says they repaid %s for %s. Please confirm you received it", iou.Borrower.Username, amount, iou.Description)
+		err = notify(tx, iou.LenderID, 0, models.NotificationSettlementPending, message, "iou", iou.ID)
+	}
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send notification"})
+		return
+	}
+
+	tx.Commit()
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Payment recorded successfully",
+		"iou":     iouResponse(iou, true),
+	})
+}
+
+// ConfirmIOUPayment は借りた側が記録した返済の受け取りを貸した側が確認し、返済を確定します
+// POST /api/v1/iou/:iouID/payments/:paymentID/confirm
+func ConfirmIOUPayment(c *gin.Context) {
+	// パスパラメータからiouIDとpaymentIDを取得
+	iouIDStr := c.Param("iouID")
+	iouID, err := strconv.ParseUint(iouIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid IOU ID"})
+		return
+	}
+
+	paymentIDStr := c.Param("paymentID")
+	paymentID, err := strconv.ParseUint(paymentIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payment ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// トランザクション開始
+	tx := database.DB.Begin()
+
+	// 同時に確認して二重に確定しないように、貸し借りをロックして取得する
+	iou, err := loadIOU(tx.Clauses(clause.Locking{Strength: "UPDATE"}), iouID, userID)
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusNotFound, gin.H{"error": "IOU not found"})
+		return
+	}
+
+	// 受け取りを確認できるのは貸した側のみ
+	if iou.LenderID != userID.(uint) {
+		tx.Rollback()
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the lender can confirm payments"})
+		return
+	}
+
+	index := -1
+	for i, p := range iou.Payments {
+		if p.ID == uint(paymentID) {
+			index = i
+		}
+	}
+	if index < 0 {
+		tx.Rollback()
+		c.JSON(http.StatusNotFound, gin.H{"error": "Payment not found"})
+		return
+	}
+	payment := &iou.Payments[index]
+	if payment.Status != models.SettlementStatusPending {
+		tx.Rollback()
+		c.JSON(http.StatusConflict, gin.H{"error": "Payment is already confirmed"})
+		return
+	}
+
+	now := time.Now()
+	payment.Status = models.SettlementStatusConfirmed
+	payment.ConfirmedAt = &now
+	if err := tx.Model(payment).Updates(map[string]interface{}{
+		"status":       payment.Status,
+		"confirmed_at": payment.ConfirmedAt,
+	}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to confirm payment"})
+		return
+	}
+
+	if err := settleIOUIfPaid(tx, &iou); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to confirm payment"})
+		return
+	}
+
+	// 借りた側に通知
+	message := fmt.Sprintf("%s confirmed your repayment of %s for %s", iou.Lender.Username, formatGlanceAmount(payment.Amount, iou.Currency), iou.Description)
+	if err := notify(tx, iou.BorrowerID, 0, models.NotificationSettlementConfirmed, message, "iou", iou.ID); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send notification"})
+		return
+	}
+
+	tx.Commit()
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Payment confirmed successfully",
+		"iou":     iouResponse(iou, true),
+	})
+}
+
+// RemindIOU は貸した側が借りた側に返済のリマインダーを送ります（同じ貸し借りには24時間に1回まで）
+// POST /api/v1/iou/:iouID/remind
+func RemindIOU(c *gin.Context) {
+	// パスパラメータからiouIDを取得
+	iouIDStr := c.Param("iouID")
+	iouID, err := strconv.ParseUint(iouIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid IOU ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// トランザクション開始
+	tx := database.DB.Begin()
+
+	// 同時に送って二重に通知しないように、貸し借りをロックして取得する
+	iou, err := loadIOU(tx.Clauses(clause.Locking{Strength: "UPDATE"}), iouID, userID)
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusNotFound, gin.H{"error": "IOU not found"})
+		return
+	}
+
+	// リマインダーを送れるのは貸した側のみ
+	if iou.LenderID != userID.(uint) {
+		tx.Rollback()
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the lender can send reminders"})
+		return
+	}
+	if iou.Status == models.IOUStatusSettled {
+		tx.Rollback()
+		c.JSON(http.StatusConflict, gin.H{"error": "IOU is already settled"})
+		return
+	}
+
+	now := time.Now()
+	if iou.LastRemindedAt != nil && now.Before(iou.LastRemindedAt.Add(iouReminderInterval)) {
+		tx.Rollback()
+		c.JSON(http.StatusTooManyRequests, gin.H{
+			"error":          "A reminder was already sent in the last 24 hours",
+			"nextReminderAt": iou.LastRemindedAt.Add(iouReminderInterval),
+		})
+		return
+	}
+
+	_, _, remaining := iouAmounts(iou)
+	amount := formatGlanceAmount(money.FromMinor(remaining, iou.Currency), iou.Currency)
+	message := fmt.Sprintf("Reminder from %s: you owe %s for %s", iou.Lender.Username, amount, iou.Description)
+	if err := notify(tx, iou.BorrowerID, 0, models.NotificationDebtReminder, message, "iou", iou.ID); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send reminder"})
+		return
+	}
+
+	iou.LastRemindedAt = &now
+	if err := tx.Model(&iou).Update("last_reminded_at", now).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send reminder"})
+		return
+	}
+
+	tx.Commit()
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Reminder sent successfully",
+		"iou":     iouResponse(iou, false),
+	})
+}
+
+// settleIOUIfPaid は確定した返済の合計が貸し借りの額に達した場合に、貸し借りを返済済みにします
+func settleIOUIfPaid(tx *gorm.DB, iou *models.IOU) error {
+	if _, _, remaining := iouAmounts(*iou); remaining > 0 {
+		return nil
+	}
+	now := time.Now()
+	iou.Status = models.IOUStatusSettled
+	iou.SettledAt = &now
+	return tx.Model(iou).Updates(map[string]interface{}{
+		"status":     iou.Status,
+		"settled_at": iou.SettledAt,
+	}).Error
+}
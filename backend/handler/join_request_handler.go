@@ -0,0 +1,509 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ito-system/clear-up-share/backend/database"
+	"github.com/ito-system/clear-up-share/backend/models"
+	"gorm.io/gorm"
+)
+
+// UpdateGroupVisibilityInput はグループの公開範囲変更リクエストの入力形式
+type UpdateGroupVisibilityInput struct {
+	Visibility     string `json:"visibility" binding:"required,oneof=private code"`
+	RegenerateCode bool   `json:"regenerateCode"` // 既存の参加コードを無効にして新しいコードを発行する
+}
+
+// CreateJoinRequestInput は参加申請リクエストの入力形式
+type CreateJoinRequestInput struct {
+	Code    string `json:"code" binding:"required"`
+	Message string `json:"message" binding:"max=200"`
+}
+
+// newJoinCode は参加申請用のコードを生成します（8文字の16進数を大文字にしたもの）
+func newJoinCode() (string, error) {
+	token, err := randomToken(4)
+	if err != nil {
+		return "", err
+	}
+	return strings.ToUpper(token), nil
+}
+
+// normalizeJoinCode は入力された参加コードを比較用に正規化します
+func normalizeJoinCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// UpdateGroupVisibility はグループの公開範囲を変更します
+// code にすると参加コードが発行され、コードを知っているユーザーが参加を申請できるようになります
+// PUT /api/v1/groups/:groupID/visibility
+func UpdateGroupVisibility(c *gin.Context) {
+	// パスパラメータからgroupIDを取得
+	groupIDStr := c.Param("groupID")
+	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// ユーザーがグループのメンバーであることを確認
+	var membership models.Membership
+	if err := database.DB.Preload("Group").Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	// アーカイブ済みのグループは読み取り専用
+	if membership.Group.ArchivedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Group is archived and read-only"})
+		return
+	}
+
+	// メンバーを管理する権限があることを確認
+	if !hasPermission(membership.Role, PermManageMembers) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to manage members"})
+		return
+	}
+
+	// リクエストボディをバインド
+	var input UpdateGroupVisibilityInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	group := membership.Group
+	updates := map[string]interface{}{
+		"visibility": input.Visibility,
+	}
+	if input.Visibility == models.GroupVisibilityCode {
+		// 初めて公開する場合と再発行を指定された場合のみ新しいコードを発行する
+		if group.JoinCode == "" || input.RegenerateCode {
+			code, err := newJoinCode()
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate join code"})
+				return
+			}
+			updates["join_code"] = code
+		}
+	} else {
+		// 非公開に戻した場合は古いコードで申請できないようにする
+		updates["join_code"] = ""
+	}
+
+	if err := database.DB.Model(&group).Updates(updates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update group visibility"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Group visibility updated successfully",
+		"group": gin.H{
+			"id":         group.ID,
+			"visibility": group.Visibility,
+			"joinCode":   group.JoinCode,
+		},
+	})
+}
+
+// LookupGroupByCode は参加コードからグループの基本情報を取得します
+// 参加申請の前に、申請先のグループを確認するために使います
+// GET /api/v1/groups/lookup?code=XXXXXXXX
+func LookupGroupByCode(c *gin.Context) {
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	code := normalizeJoinCode(c.Query("code"))
+	if code == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "code is required"})
+		return
+	}
+
+	// 参加コードで公開中のアクティブなグループのみ対象とする
+	var group models.Group
+	if err := database.DB.Where("join_code = ? AND visibility = ? AND archived_at IS NULL", code, models.GroupVisibilityCode).First(&group).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Group not found"})
+		return
+	}
+
+	var memberCount int64
+	if err := database.DB.Model(&models.Membership{}).Where("group_id = ?", group.ID).Count(&memberCount).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count members"})
+		return
+	}
+
+	var isMember int64
+	if err := database.DB.Model(&models.Membership{}).Where("group_id = ? AND user_id = ?", group.ID, userID).Count(&isMember).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check membership"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"group": gin.H{
+			"id":          group.ID,
+			"name":        group.Name,
+			"icon":        group.Icon,
+			"color":       group.Color,
+			"currency":    group.Currency,
+			"memberCount": memberCount,
+			"isMember":    isMember > 0,
+		},
+	})
+}
+
+// CreateJoinRequest は参加コードを使ってグループへの参加を申請します
+// 申請はメンバー管理権限を持つメンバーが承認するまで保留されます
+// POST /api/v1/groups/:groupID/join-requests
+func CreateJoinRequest(c *gin.Context) {
+	// パスパラメータからgroupIDを取得
+	groupIDStr := c.Param("groupID")
+	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// リクエストボディをバインド
+	var input CreateJoinRequestInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// コードが一致しない場合はグループの存在自体を明かさない
+	var group models.Group
+	if err := database.DB.Where("id = ? AND visibility = ? AND join_code = ?", groupID, models.GroupVisibilityCode, normalizeJoinCode(input.Code)).First(&group).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Group not found"})
+		return
+	}
+
+	if group.ArchivedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Group is archived and read-only"})
+		return
+	}
+
+	// 既にメンバーであるユーザーは申請できない
+	var memberCount int64
+	if err := database.DB.Model(&models.Membership{}).Where("user_id = ? AND group_id = ?", userID, groupID).Count(&memberCount).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check membership"})
+		return
+	}
+	if memberCount > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "You are already a member of this group"})
+		return
+	}
+
+	// 保留中の申請は重複させない
+	var pendingCount int64
+	if err := database.DB.Model(&models.JoinRequest{}).
+		Where("group_id = ? AND user_id = ? AND status = ?", groupID, userID, models.JoinRequestStatusPending).
+		Count(&pendingCount).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check join requests"})
+		return
+	}
+	if pendingCount > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "A join request is already pending for this group"})
+		return
+	}
+
+	var user models.User
+	if err := database.DB.First(&user, userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	// トランザクションで申請の作成と管理者への通知を行う
+	tx := database.DB.Begin()
+
+	request := models.JoinRequest{
+		GroupID: group.ID,
+		UserID:  user.ID,
+		Status:  models.JoinRequestStatusPending,
+		Message: strings.TrimSpace(input.Message),
+	}
+	if err := tx.Create(&request).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create join request"})
+		return
+	}
+
+	if err := notifyJoinRequestReviewers(tx, group, user, request); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create notification"})
+		return
+	}
+
+	tx.Commit()
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Join request submitted successfully",
+		"joinRequest": gin.H{
+			"id":        request.ID,
+			"groupID":   request.GroupID,
+			"status":    request.Status,
+			"createdAt": request.CreatedAt,
+		},
+	})
+}
+
+// notifyJoinRequestReviewers はメンバー管理権限を持つメンバーに参加申請を通知します
+func notifyJoinRequestReviewers(tx *gorm.DB, group models.Group, requester models.User, request models.JoinRequest) error {
+	var memberships []models.Membership
+	if err := tx.Where("group_id = ?", group.ID).Find(&memberships).Error; err != nil {
+		return err
+	}
+
+	message := requester.Username + " requested to join \"" + group.Name + "\""
+	for _, m := range memberships {
+		if !hasPermission(m.Role, PermManageMembers) {
+			continue
+		}
+		if err := notify(tx, m.UserID, group.ID, models.NotificationJoinRequested, message, "join_request", request.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetJoinRequests はグループへの保留中の参加申請一覧を取得します
+// GET /api/v1/groups/:groupID/join-requests
+func GetJoinRequests(c *gin.Context) {
+	// パスパラメータからgroupIDを取得
+	groupIDStr := c.Param("groupID")
+	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// ユーザーがグループのメンバーであることを確認
+	var membership models.Membership
+	if err := database.DB.Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	// メンバーを管理する権限があることを確認
+	if !hasPermission(membership.Role, PermManageMembers) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to manage members"})
+		return
+	}
+
+	var requests []models.JoinRequest
+	if err := database.DB.Preload("User").
+		Where("group_id = ? AND status = ?", groupID, models.JoinRequestStatusPending).
+		Order("created_at ASC").
+		Find(&requests).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch join requests"})
+		return
+	}
+
+	// レスポンス用の申請リストを構築
+	type JoinRequestResponse struct {
+		ID        uint      `json:"id"`
+		UserID    uint      `json:"userID"`
+		Username  string    `json:"username"`
+		Message   string    `json:"message"`
+		Status    string    `json:"status"`
+		CreatedAt time.Time `json:"createdAt"`
+	}
+
+	items := make([]JoinRequestResponse, len(requests))
+	for i, r := range requests {
+		items[i] = JoinRequestResponse{
+			ID:        r.ID,
+			UserID:    r.UserID,
+			Username:  r.User.Username,
+			Message:   r.Message,
+			Status:    r.Status,
+			CreatedAt: r.CreatedAt,
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"joinRequests": items,
+	})
+}
+
+// ApproveJoinRequest は参加申請を承認し、申請者をメンバーとして追加します
+// POST /api/v1/groups/:groupID/join-requests/:requestID/approve
+func ApproveJoinRequest(c *gin.Context) {
+	reviewJoinRequest(c, true)
+}
+
+// RejectJoinRequest は参加申請を却下します
+// POST /api/v1/groups/:groupID/join-requests/:requestID/reject
+func RejectJoinRequest(c *gin.Context) {
+	reviewJoinRequest(c, false)
+}
+
+// reviewJoinRequest は参加申請の承認・却下を処理します
+func reviewJoinRequest(c *gin.Context, approve bool) {
+	// パスパラメータからgroupIDとrequestIDを取得
+	groupIDStr := c.Param("groupID")
+	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	requestIDStr := c.Param("requestID")
+	requestID, err := strconv.ParseUint(requestIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid join request ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// ユーザーがグループのメンバーであることを確認
+	var membership models.Membership
+	if err := database.DB.Preload("Group").Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	// アーカイブ済みのグループは読み取り専用
+	if membership.Group.ArchivedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Group is archived and read-only"})
+		return
+	}
+
+	// メンバーを管理する権限があることを確認
+	if !hasPermission(membership.Role, PermManageMembers) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to manage members"})
+		return
+	}
+
+	var request models.JoinRequest
+	if err := database.DB.Where("id = ? AND group_id = ?", requestID, groupID).First(&request).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Join request not found"})
+		return
+	}
+
+	if request.Status != models.JoinRequestStatusPending {
+		c.JSON(http.StatusConflict, gin.H{"error": "Join request has already been reviewed"})
+		return
+	}
+
+	reviewerID := userID.(uint)
+	now := time.Now()
+	status := models.JoinRequestStatusRejected
+	notificationType := models.NotificationJoinRequestRejected
+	message := "Your request to join \"" + membership.Group.Name + "\" was declined"
+	if approve {
+		status = models.JoinRequestStatusApproved
+		notificationType = models.NotificationJoinRequestApproved
+		message = "Your request to join \"" + membership.Group.Name + "\" was approved"
+	}
+
+	// トランザクションでメンバーシップ作成と申請の更新を行う
+	tx := database.DB.Begin()
+
+	if approve {
+		var existing int64
+		if err := tx.Model(&models.Membership{}).Where("user_id = ? AND group_id = ?", request.UserID, groupID).Count(&existing).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check membership"})
+			return
+		}
+
+		if existing == 0 {
+			// 所属グループ数とグループのメンバー数が上限に達していないことを確認
+			if err := checkGroupQuota(tx, request.UserID); err != nil {
+				tx.Rollback()
+				respondQuotaError(c, err)
+				return
+			}
+			if err := checkMemberQuota(tx, uint(groupID), 1); err != nil {
+				tx.Rollback()
+				respondQuotaError(c, err)
+				return
+			}
+
+			newMembership := models.Membership{
+				UserID:  request.UserID,
+				GroupID: uint(groupID),
+				Role:    models.RoleMember,
+			}
+			if err := tx.Create(&newMembership).Error; err != nil {
+				tx.Rollback()
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add member"})
+				return
+			}
+
+			// アクティビティを記録
+			if err := recordActivity(tx, uint(groupID), reviewerID, models.ActivityMemberJoined, "member", request.UserID, map[string]interface{}{
+				"joinRequestID": request.ID,
+			}); err != nil {
+				tx.Rollback()
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record activity"})
+				return
+			}
+		}
+	}
+
+	if err := tx.Model(&request).Updates(map[string]interface{}{
+		"status":         status,
+		"reviewed_by_id": reviewerID,
+		"reviewed_at":    now,
+	}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update join request"})
+		return
+	}
+
+	// 申請者に結果を通知
+	if err := notify(tx, request.UserID, uint(groupID), notificationType, message, "group", uint(groupID)); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create notification"})
+		return
+	}
+
+	tx.Commit()
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Join request " + status,
+		"joinRequest": gin.H{
+			"id":         request.ID,
+			"groupID":    request.GroupID,
+			"userID":     request.UserID,
+			"status":     request.Status,
+			"reviewedAt": request.ReviewedAt,
+		},
+	})
+}
@@ -0,0 +1,310 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ito-system/clear-up-share/backend/database"
+	"github.com/ito-system/clear-up-share/backend/models"
+)
+
+// PlaceLegalHoldInput はリーガルホールド設定リクエストの入力形式
+type PlaceLegalHoldInput struct {
+	Reason string `json:"reason" binding:"required,max=500"`
+}
+
+// evidenceRecords は証拠用エクスポートに含めるグループの全記録（論理削除済みのものを含む）
+// 各レコードはデータベースの行をそのまま列名をキーにして出力します
+type evidenceRecords struct {
+	Group               []map[string]interface{} `json:"group"`
+	Settings            []map[string]interface{} `json:"settings"`
+	Members             []map[string]interface{} `json:"members"`
+	Users               []map[string]interface{} `json:"users"`
+	Expenses            []map[string]interface{} `json:"expenses"`
+	Splits              []map[string]interface{} `json:"splits"`
+	ExpenseItems        []map[string]interface{} `json:"expenseItems"`
+	ExpenseRevisions    []map[string]interface{} `json:"expenseRevisions"`
+	Receipts            []map[string]interface{} `json:"receipts"`
+	Categories          []map[string]interface{} `json:"categories"`
+	Tags                []map[string]interface{} `json:"tags"`
+	ExpenseTags         []map[string]interface{} `json:"expenseTags"`
+	RecurringExpenses   []map[string]interface{} `json:"recurringExpenses"`
+	Settlements         []map[string]interface{} `json:"settlements"`
+	SettlementRevisions []map[string]interface{} `json:"settlementRevisions"`
+	Forgivenesses       []map[string]interface{} `json:"forgivenesses"`
+	Invitations         []map[string]interface{} `json:"invitations"`
+	JoinRequests        []map[string]interface{} `json:"joinRequests"`
+	Activity            []map[string]interface{} `json:"activity"`
+}
+
+// rejectIfLegalHold はグループがリーガルホールド中の場合に 409 を返し、true を返します
+// ホールド中は新しい記録の追加はできますが、既存の支出・清算・債務免除などの記録の編集・削除はできません
+func rejectIfLegalHold(c *gin.Context, group models.Group) bool {
+	if group.LegalHoldAt == nil {
+		return false
+	}
+	c.JSON(http.StatusConflict, gin.H{"error": "Group is under legal hold"})
+	return true
+}
+
+// PlaceLegalHold はグループをリーガルホールドにし、記録の削除と保持期間ポリシーによる物理削除を止めます
+// POST /api/v1/groups/:groupID/legal-hold
+func PlaceLegalHold(c *gin.Context) {
+	setLegalHold(c, true)
+}
+
+// ReleaseLegalHold はグループのリーガルホールドを解除します
+// DELETE /api/v1/groups/:groupID/legal-hold
+func ReleaseLegalHold(c *gin.Context) {
+	setLegalHold(c, false)
+}
+
+// setLegalHold はリーガルホールドの設定・解除を処理します
+// 紛争中の記録を保全するためのものなので、アーカイブ済みのグループにも設定できます
+func setLegalHold(c *gin.Context, hold bool) {
+	// パスパラメータからgroupIDを取得
+	groupIDStr := c.Param("groupID")
+	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// ユーザーがグループのメンバーであることを確認
+	var membership models.Membership
+	if err := database.DB.Preload("Group").Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	// グループを管理する権限があることを確認
+	if !hasPermission(membership.Role, PermManageGroup) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to manage this group"})
+		return
+	}
+
+	group := membership.Group
+	updates := map[string]interface{}{}
+	if hold {
+		if group.LegalHoldAt != nil {
+			c.JSON(http.StatusConflict, gin.H{"error": "Group is already under legal hold"})
+			return
+		}
+
+		// リクエストボディをバインド
+		var input PlaceLegalHoldInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		now := time.Now()
+		holderID := userID.(uint)
+		group.LegalHoldAt = &now
+		group.LegalHoldByID = &holderID
+		group.LegalHoldReason = strings.TrimSpace(input.Reason)
+	} else {
+		if group.LegalHoldAt == nil {
+			c.JSON(http.StatusConflict, gin.H{"error": "Group is not under legal hold"})
+			return
+		}
+		group.LegalHoldAt = nil
+		group.LegalHoldByID = nil
+		group.LegalHoldReason = ""
+	}
+	updates["legal_hold_at"] = group.LegalHoldAt
+	updates["legal_hold_by_id"] = group.LegalHoldByID
+	updates["legal_hold_reason"] = group.LegalHoldReason
+
+	// トランザクション開始
+	tx := database.DB.Begin()
+
+	if err := tx.Model(&group).Updates(updates).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update group"})
+		return
+	}
+
+	action := models.ActivityLegalHoldReleased
+	var details map[string]interface{}
+	if hold {
+		action = models.ActivityLegalHoldPlaced
+		details = map[string]interface{}{"reason": group.LegalHoldReason}
+	}
+
+	// アクティビティを記録
+	if err := recordActivity(tx, group.ID, userID.(uint), action, "group", group.ID, details); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record activity"})
+		return
+	}
+
+	tx.Commit()
+
+	message := "Legal hold released successfully"
+	if hold {
+		message = "Legal hold placed successfully"
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": message,
+		"group": gin.H{
+			"id":              group.ID,
+			"name":            group.Name,
+			"legalHoldAt":     group.LegalHoldAt,
+			"legalHoldByID":   group.LegalHoldByID,
+			"legalHoldReason": group.LegalHoldReason,
+		},
+	})
+}
+
+// ExportGroupEvidence はグループの全記録（論理削除済みの支出・清算や変更履歴を含む）をJSONの証拠用バンドルとして出力します
+// records の SHA-256 ハッシュを manifest と X-ClearUp-Bundle-SHA256 ヘッダーに含め、改ざんの有無を確認できるようにします
+// GET /api/v1/groups/:groupID/legal-hold/export
+func ExportGroupEvidence(c *gin.Context) {
+	// パスパラメータからgroupIDを取得
+	groupIDStr := c.Param("groupID")
+	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// ユーザーがグループのメンバーであることを確認
+	var membership models.Membership
+	if err := database.DB.Preload("Group").Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	// グループを管理する権限があることを確認
+	if !hasPermission(membership.Role, PermManageGroup) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to manage this group"})
+		return
+	}
+
+	// 論理削除済みのものを含めて全ての記録を取得
+	var records evidenceRecords
+	queries := []struct {
+		dest  *[]map[string]interface{}
+		model interface{}
+		where string
+	}{
+		{&records.Group, &models.Group{}, "id = @groupID"},
+		{&records.Settings, &models.GroupSettings{}, "group_id = @groupID"},
+		{&records.Members, &models.Membership{}, "group_id = @groupID"},
+		{&records.Expenses, &models.Expense{}, "group_id = @groupID"},
+		{&records.Splits, &models.Split{}, "expense_id IN (SELECT id FROM expenses WHERE group_id = @groupID)"},
+		{&records.ExpenseItems, &models.ExpenseItem{}, "expense_id IN (SELECT id FROM expenses WHERE group_id = @groupID)"},
+		{&records.ExpenseRevisions, &models.ExpenseRevision{}, "expense_id IN (SELECT id FROM expenses WHERE group_id = @groupID)"},
+		{&records.Receipts, &models.Receipt{}, "expense_id IN (SELECT id FROM expenses WHERE group_id = @groupID)"},
+		{&records.Categories, &models.Category{}, "group_id = @groupID"},
+		{&records.Tags, &models.Tag{}, "group_id = @groupID"},
+		{&records.RecurringExpenses, &models.RecurringExpense{}, "group_id = @groupID"},
+		{&records.Settlements, &models.Settlement{}, "group_id = @groupID"},
+		{&records.SettlementRevisions, &models.SettlementRevision{}, "settlement_id IN (SELECT id FROM settlements WHERE group_id = @groupID)"},
+		{&records.Forgivenesses, &models.Forgiveness{}, "group_id = @groupID"},
+		{&records.Invitations, &models.Invitation{}, "group_id = @groupID"},
+		{&records.JoinRequests, &models.JoinRequest{}, "group_id = @groupID"},
+		{&records.Activity, &models.ActivityLog{}, "group_id = @groupID"},
+		// 記録に現れる全てのユーザー（退会済みのメンバーを含む）
+		{&records.Users, &models.User{}, `id IN (
+			SELECT user_id FROM memberships WHERE group_id = @groupID
+			UNION SELECT payer_id FROM expenses WHERE group_id = @groupID
+			UNION SELECT debtor_id FROM splits WHERE expense_id IN (SELECT id FROM expenses WHERE group_id = @groupID)
+			UNION SELECT payer_id FROM settlements WHERE group_id = @groupID
+			UNION SELECT receiver_id FROM settlements WHERE group_id = @groupID
+			UNION SELECT debtor_id FROM forgivenesses WHERE group_id = @groupID
+			UNION SELECT receiver_id FROM forgivenesses WHERE group_id = @groupID
+			UNION SELECT actor_id FROM activity_logs WHERE group_id = @groupID)`},
+	}
+	for _, q := range queries {
+		*q.dest = []map[string]interface{}{}
+		if err := database.DB.Unscoped().Model(q.model).
+			Where(q.where, map[string]interface{}{"groupID": groupID}).
+			Order("id").
+			Find(q.dest).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch records"})
+			return
+		}
+	}
+
+	// 支出とタグの中間テーブルには id がないので別に取得
+	records.ExpenseTags = []map[string]interface{}{}
+	if err := database.DB.Model(&models.ExpenseTag{}).
+		Where("expense_id IN (SELECT id FROM expenses WHERE group_id = ?)", groupID).
+		Order("expense_id, tag_id").
+		Find(&records.ExpenseTags).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch records"})
+		return
+	}
+
+	// パスワードハッシュは証拠として不要なので含めない
+	for _, user := range records.Users {
+		delete(user, "hashed_password")
+	}
+
+	data, err := json.Marshal(records)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build export"})
+		return
+	}
+	sum := sha256.Sum256(data)
+	digest := hex.EncodeToString(sum[:])
+
+	filename := fmt.Sprintf("group-%d-evidence-%s.json", groupID, time.Now().Format("20060102"))
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Header("X-ClearUp-Bundle-SHA256", digest)
+	c.JSON(http.StatusOK, gin.H{
+		"manifest": gin.H{
+			"groupID":      groupID,
+			"exportedAt":   time.Now(),
+			"exportedByID": userID,
+			"legalHoldAt":  membership.Group.LegalHoldAt,
+			"sha256":       digest,
+			"counts": gin.H{
+				"group":               len(records.Group),
+				"settings":            len(records.Settings),
+				"members":             len(records.Members),
+				"users":               len(records.Users),
+				"expenses":            len(records.Expenses),
+				"splits":              len(records.Splits),
+				"expenseItems":        len(records.ExpenseItems),
+				"expenseRevisions":    len(records.ExpenseRevisions),
+				"receipts":            len(records.Receipts),
+				"categories":          len(records.Categories),
+				"tags":                len(records.Tags),
+				"expenseTags":         len(records.ExpenseTags),
+				"recurringExpenses":   len(records.RecurringExpenses),
+				"settlements":         len(records.Settlements),
+				"settlementRevisions": len(records.SettlementRevisions),
+				"forgivenesses":       len(records.Forgivenesses),
+				"invitations":         len(records.Invitations),
+				"joinRequests":        len(records.JoinRequests),
+				"activity":            len(records.Activity),
+			},
+		},
+		"records": json.RawMessage(data),
+	})
+}
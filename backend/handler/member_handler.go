@@ -0,0 +1,522 @@
+package handler
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ito-system/clear-up-share/backend/database"
+	"github.com/ito-system/clear-up-share/backend/models"
+	"github.com/ito-system/clear-up-share/backend/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PlaceholderInput は仮メンバーの作成・名前変更リクエストの入力形式
+type PlaceholderInput struct {
+	Name string `json:"name" binding:"required,max=50"`
+}
+
+// UpdateNicknameInput はグループ内の表示名変更リクエストの入力形式（空文字でユーザー名表示に戻す）
+type UpdateNicknameInput struct {
+	Nickname string `json:"nickname" binding:"max=50"`
+}
+
+// memberDisplayName はグループ内での表示名（ニックネーム、未設定の場合はユーザー名）を返します
+func memberDisplayName(m models.Membership) string {
+	if m.Nickname != "" {
+		return m.Nickname
+	}
+	return m.User.Username
+}
+
+// groupDisplayNames はグループのメンバーの表示名をユーザーIDごとに返します
+func groupDisplayNames(db *gorm.DB, groupID uint) (map[uint]string, error) {
+	var memberships []models.Membership
+	if err := db.Preload("User").Where("group_id = ?", groupID).Find(&memberships).Error; err != nil {
+		return nil, err
+	}
+
+	names := make(map[uint]string, len(memberships))
+	for _, m := range memberships {
+		names[m.UserID] = memberDisplayName(m)
+	}
+	return names, nil
+}
+
+// lockGroupMembers は指定ユーザー全員がグループのメンバーであることを確認し、
+// トランザクションが終わるまでそのメンバーシップを共有ロックします
+// 貸借を変える操作と退会・除名が同時に実行されても、退会の判定後に退会者の貸借が変わらないようにするためのものです
+func lockGroupMembers(tx *gorm.DB, groupID uint, userIDs []uint) (bool, error) {
+	nonMembers, err := lockGroupNonMembers(tx, groupID, userIDs)
+	return len(nonMembers) == 0, err
+}
+
+// lockSettlementPair は lockGroupMembers と同様に送金者・受取者がグループのメンバーであることを確認し、
+// 2人のメンバーシップを排他ロックします
+// 同じ2人の清算の記録・編集・確認が同時に実行されても、借りを超えていないかの確認を1件ずつ行うためのものです
+func lockSettlementPair(tx *gorm.DB, groupID, payerID, receiverID uint) (bool, error) {
+	var memberships []models.Membership
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("group_id = ? AND user_id IN ?", groupID, []uint{payerID, receiverID}).
+		Order("user_id").
+		Find(&memberships).Error; err != nil {
+		return false, err
+	}
+	return len(memberships) == 2, nil
+}
+
+// lockGroupNonMembers は lockGroupMembers と同様にメンバーシップを共有ロックし、
+// 指定ユーザーのうちグループのメンバーでないユーザーのIDを指定された順に返します
+func lockGroupNonMembers(tx *gorm.DB, groupID uint, userIDs []uint) ([]uint, error) {
+	var memberships []models.Membership
+	if err := tx.Clauses(clause.Locking{Strength: "SHARE"}).
+		Where("group_id = ? AND user_id IN ?", groupID, userIDs).
+		Order("user_id").
+		Find(&memberships).Error; err != nil {
+		return nil, err
+	}
+
+	members := make(map[uint]bool, len(memberships))
+	for _, m := range memberships {
+		members[m.UserID] = true
+	}
+	var nonMembers []uint
+	for _, id := range userIDs {
+		if !members[id] {
+			members[id] = true // 重複して返さない
+			nonMembers = append(nonMembers, id)
+		}
+	}
+	return nonMembers, nil
+}
+
+// CreatePlaceholderMember はアカウントを持たない仮メンバーをグループに追加します
+// POST /api/v1/groups/:groupID/placeholders
+func CreatePlaceholderMember(c *gin.Context) {
+	// パスパラメータからgroupIDを取得
+	groupIDStr := c.Param("groupID")
+	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// ユーザーがグループのメンバーであることを確認
+	var membership models.Membership
+	if err := database.DB.Preload("Group").Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	// アーカイブ済みのグループは読み取り専用
+	if membership.Group.ArchivedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Group is archived and read-only"})
+		return
+	}
+
+	// メンバーを管理する権限があることを確認
+	if !hasPermission(membership.Role, PermManageMembers) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to manage members"})
+		return
+	}
+
+	// リクエストボディをバインド
+	var input PlaceholderInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// グループのメンバー数が上限に達していないことを確認
+	if err := checkMemberQuota(database.DB, uint(groupID), 1); err != nil {
+		respondQuotaError(c, err)
+		return
+	}
+
+	// トランザクションで仮ユーザーとメンバーシップを作成
+	tx := database.DB.Begin()
+
+	placeholder := models.User{
+		Username:      input.Name,
+		IsPlaceholder: true,
+	}
+
+	if err := tx.Create(&placeholder).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create placeholder member"})
+		return
+	}
+
+	placeholderMembership := models.Membership{
+		UserID:  placeholder.ID,
+		GroupID: uint(groupID),
+		Role:    models.RoleMember,
+	}
+
+	if err := tx.Create(&placeholderMembership).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add placeholder member"})
+		return
+	}
+
+	// アクティビティを記録
+	if err := recordActivity(tx, placeholderMembership.GroupID, userID.(uint), models.ActivityMemberJoined, "member", placeholder.ID, map[string]interface{}{
+		"placeholder": true,
+		"name":        placeholder.Username,
+	}); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record activity"})
+		return
+	}
+
+	tx.Commit()
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Placeholder member created successfully",
+		"member": gin.H{
+			"id":            placeholder.ID,
+			"username":      placeholder.Username,
+			"role":          placeholderMembership.Role,
+			"isPlaceholder": true,
+		},
+	})
+}
+
+// RenamePlaceholderMember は仮メンバーの名前を変更します
+// PUT /api/v1/groups/:groupID/placeholders/:userID
+func RenamePlaceholderMember(c *gin.Context) {
+	// パスパラメータからgroupIDと対象のuserIDを取得
+	groupIDStr := c.Param("groupID")
+	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	targetIDStr := c.Param("userID")
+	targetID, err := strconv.ParseUint(targetIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// ユーザーがグループのメンバーであることを確認
+	var membership models.Membership
+	if err := database.DB.Preload("Group").Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	// アーカイブ済みのグループは読み取り専用
+	if membership.Group.ArchivedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Group is archived and read-only"})
+		return
+	}
+
+	// メンバーを管理する権限があることを確認
+	if !hasPermission(membership.Role, PermManageMembers) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to manage members"})
+		return
+	}
+
+	// リクエストボディをバインド
+	var input PlaceholderInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 対象がこのグループの仮メンバーであることを確認
+	var target models.Membership
+	if err := database.DB.Preload("User").Where("user_id = ? AND group_id = ?", targetID, groupID).First(&target).Error; err != nil || !target.User.IsPlaceholder {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Placeholder member not found"})
+		return
+	}
+
+	placeholder := target.User
+	if err := database.DB.Model(&placeholder).Update("username", input.Name).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to rename placeholder member"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Placeholder member renamed successfully",
+		"member": gin.H{
+			"id":            placeholder.ID,
+			"username":      placeholder.Username,
+			"role":          target.Role,
+			"isPlaceholder": true,
+		},
+	})
+}
+
+// UpdateMemberNickname はグループ内でのメンバーの表示名を変更します
+// 自分の表示名はロールに関わらず変更でき、他のメンバーの表示名はメンバー管理権限が必要です
+// PUT /api/v1/groups/:groupID/members/:userID/nickname
+func UpdateMemberNickname(c *gin.Context) {
+	// パスパラメータからgroupIDと対象のuserIDを取得
+	groupIDStr := c.Param("groupID")
+	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	targetIDStr := c.Param("userID")
+	targetID, err := strconv.ParseUint(targetIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// ユーザーがグループのメンバーであることを確認
+	var membership models.Membership
+	if err := database.DB.Preload("Group").Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	// アーカイブ済みのグループは読み取り専用
+	if membership.Group.ArchivedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Group is archived and read-only"})
+		return
+	}
+
+	// 他のメンバーの表示名を変更する場合はメンバー管理権限が必要
+	if uint(targetID) != userID.(uint) && !hasPermission(membership.Role, PermManageMembers) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to manage members"})
+		return
+	}
+
+	// リクエストボディをバインド
+	var input UpdateNicknameInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 対象メンバーのメンバーシップを取得
+	var target models.Membership
+	if err := database.DB.Preload("User").Where("user_id = ? AND group_id = ?", targetID, groupID).First(&target).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Member not found"})
+		return
+	}
+
+	target.Nickname = strings.TrimSpace(input.Nickname)
+	if err := database.DB.Model(&target).Update("nickname", target.Nickname).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update nickname"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Nickname updated successfully",
+		"member": gin.H{
+			"userID":      target.UserID,
+			"groupID":     target.GroupID,
+			"username":    target.User.Username,
+			"nickname":    target.Nickname,
+			"displayName": memberDisplayName(target),
+		},
+	})
+}
+
+// LeaveGroup は認証ユーザーがグループから退会します
+// POST /api/v1/groups/:groupID/leave
+func LeaveGroup(c *gin.Context) {
+	// パスパラメータからgroupIDを取得
+	groupIDStr := c.Param("groupID")
+	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	removeMember(c, uint(groupID), userID.(uint), userID.(uint))
+}
+
+// RemoveMember はメンバーをグループから除名します
+// DELETE /api/v1/groups/:groupID/members/:userID
+func RemoveMember(c *gin.Context) {
+	// パスパラメータからgroupIDと対象のuserIDを取得
+	groupIDStr := c.Param("groupID")
+	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	targetIDStr := c.Param("userID")
+	targetID, err := strconv.ParseUint(targetIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// 自分自身は退会エンドポイントを使う
+	if uint(targetID) == userID.(uint) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Use the leave endpoint to leave the group"})
+		return
+	}
+
+	removeMember(c, uint(groupID), userID.(uint), uint(targetID))
+}
+
+// removeMember はメンバーの退会・除名を処理します
+// 未精算の貸借の判定は対象のメンバーシップを排他ロックした上で同じトランザクション内で行い、
+// 同時に追加された支出などが判定から漏れないようにします（lockGroupMembers を参照）
+func removeMember(c *gin.Context, groupID, actorID, targetID uint) {
+	// ユーザーがグループのメンバーであることを確認
+	var membership models.Membership
+	if err := database.DB.Preload("Group").Where("user_id = ? AND group_id = ?", actorID, groupID).First(&membership).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	// アーカイブ済みのグループは読み取り専用
+	if membership.Group.ArchivedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Group is archived and read-only"})
+		return
+	}
+
+	// リーガルホールド中のグループでは記録を削除できない
+	if rejectIfLegalHold(c, membership.Group) {
+		return
+	}
+
+	// 他のメンバーを除名するにはメンバーを管理する権限が必要
+	leaving := actorID == targetID
+	if !leaving && !hasPermission(membership.Role, PermManageMembers) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to manage members"})
+		return
+	}
+
+	// トランザクション開始
+	tx := database.DB.Begin()
+
+	// 対象のメンバーシップを排他ロックして取得
+	var target models.Membership
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("user_id = ? AND group_id = ?", targetID, groupID).First(&target).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusNotFound, gin.H{"error": "Member not found"})
+		return
+	}
+
+	// オーナーは退会・除名できない
+	if target.Role == models.RoleOwner {
+		tx.Rollback()
+		c.JSON(http.StatusConflict, gin.H{"error": "The group owner cannot leave or be removed"})
+		return
+	}
+
+	settings, err := loadGroupSettings(tx, groupID)
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch group settings"})
+		return
+	}
+
+	balances, err := groupBalances(tx, groupID)
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate balances"})
+		return
+	}
+	balance := balances[targetID]
+
+	// 許可されていない場合は未精算の貸借があるメンバーは退会・除名できない（通貨の最小単位未満の差は無視する）
+	if !settings.AllowLeaveWithBalance && math.Abs(balance) > math.Pow10(-utils.CurrencyMinorUnits(membership.Group.Currency))/2 {
+		tx.Rollback()
+		c.JSON(http.StatusConflict, gin.H{
+			"error":    "Member has an outstanding balance",
+			"balance":  balance,
+			"currency": membership.Group.Currency,
+		})
+		return
+	}
+
+	// 再参加できるようにメンバーシップは物理削除する（一意インデックス idx_user_group のため）
+	if err := tx.Unscoped().Delete(&target).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove member"})
+		return
+	}
+
+	// グループの通知設定も削除
+	if err := tx.Unscoped().Where("user_id = ? AND group_id = ?", targetID, groupID).Delete(&models.NotificationSetting{}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove member"})
+		return
+	}
+
+	// 除名されたメンバーに通知
+	action := models.ActivityMemberLeft
+	if !leaving {
+		action = models.ActivityMemberRemoved
+		message := "You were removed from \"" + membership.Group.Name + "\""
+		if err := notify(tx, targetID, groupID, models.NotificationMemberRemoved, message, "group", groupID); err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send notification"})
+			return
+		}
+	}
+
+	// アクティビティを記録
+	if err := recordActivity(tx, groupID, actorID, action, "member", targetID, map[string]interface{}{
+		"role":    target.Role,
+		"balance": balance,
+	}); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record activity"})
+		return
+	}
+
+	tx.Commit()
+
+	message := "Member removed successfully"
+	if leaving {
+		message = "Left the group successfully"
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": message,
+		"groupID": groupID,
+		"userID":  targetID,
+	})
+}
@@ -0,0 +1,163 @@
+package handler
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ito-system/clear-up-share/backend/database"
+	"github.com/ito-system/clear-up-share/backend/models"
+	"github.com/ito-system/clear-up-share/backend/money"
+)
+
+// 次に支払うメンバーを決めた理由
+const (
+	nextPayerReasonBalance  = "balance"   // 貸借額が最も少ない
+	nextPayerReasonLastPaid = "last_paid" // 貸借額が同じメンバーの中で最も長く支払っていない
+	nextPayerReasonUserID   = "user_id"   // 貸借額も最後に支払った日も同じで、ユーザーIDが小さい
+	nextPayerReasonOnly     = "only"      // 候補が1人だけ
+)
+
+// NextPayerCandidate は次に支払うメンバーの候補を表す形式
+type NextPayerCandidate struct {
+	UserID       uint    `json:"userID"`
+	Username     string  `json:"username"`
+	Balance      float64 `json:"balance"`      // 正: 受け取る、負: 支払う
+	LastPaidDate *string `json:"lastPaidDate"` // 最後に支出を支払った日付（支払ったことがない場合は null）
+}
+
+// GetNextPayer は清算の代わりに支払う人を交代して貸借を釣り合わせるグループのために、次に支払うメンバーを推薦します
+// 貸借額が最も少ない（最も多く借りている）メンバーを推薦し、同じ額の場合は最後に支出を支払った日が古いメンバー、
+// それも同じ場合はユーザーIDが小さいメンバーを推薦します。ranking は推薦する順に並べた全てのメンバーです
+// GET /api/v1/groups/:groupID/next-payer
+func GetNextPayer(c *gin.Context) {
+	// パスパラメータからgroupIDを取得
+	groupIDStr := c.Param("groupID")
+	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// ユーザーがグループのメンバーであることを確認
+	var membership models.Membership
+	if err := database.DB.Preload("Group").Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	balances, err := groupBalances(database.DB, uint(groupID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate balances"})
+		return
+	}
+
+	// 退会したメンバーは候補にしない
+	names, err := groupDisplayNames(database.DB, uint(groupID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch members"})
+		return
+	}
+
+	// メンバーごとに最後に支出を支払った日付を取得（承認待ち・下書きの支出は含めない）
+	type lastPaidRow struct {
+		PayerID  uint
+		LastPaid time.Time
+	}
+	var rows []lastPaidRow
+	if err := database.DB.Model(&models.Expense{}).
+		Select("payer_id, MAX(date) AS last_paid").
+		Where("group_id = ? AND status = ?", groupID, models.ExpenseStatusConfirmed).
+		Group("payer_id").
+		Scan(&rows).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch expenses"})
+		return
+	}
+	lastPaid := make(map[uint]time.Time, len(rows))
+	for _, r := range rows {
+		lastPaid[r.PayerID] = r.LastPaid
+	}
+
+	// 貸借額は補助単位で比べる（端数の誤差で順位が変わらないように）
+	currency := membership.Group.Currency
+	units := make(map[uint]int64, len(names))
+	ranking := make([]NextPayerCandidate, 0, len(names))
+	for memberID, name := range names {
+		units[memberID] = money.ToMinor(balances[memberID], currency)
+		candidate := NextPayerCandidate{
+			UserID:   memberID,
+			Username: name,
+			Balance:  money.FromMinor(units[memberID], currency),
+		}
+		if date, ok := lastPaid[memberID]; ok {
+			formatted := date.Format("2006-01-02")
+			candidate.LastPaidDate = &formatted
+		}
+		ranking = append(ranking, candidate)
+	}
+
+	// 支払ったことがないメンバーは最も長く支払っていないものとして扱う
+	paidBefore := func(a, b uint) (bool, bool) {
+		dateA, okA := lastPaid[a]
+		dateB, okB := lastPaid[b]
+		if okA != okB {
+			return !okA, true
+		}
+		if !dateA.Equal(dateB) {
+			return dateA.Before(dateB), true
+		}
+		return false, false
+	}
+	sort.Slice(ranking, func(i, j int) bool {
+		a, b := ranking[i].UserID, ranking[j].UserID
+		if units[a] != units[b] {
+			return units[a] < units[b]
+		}
+		if less, decided := paidBefore(a, b); decided {
+			return less
+		}
+		return a < b
+	})
+
+	if len(ranking) == 0 {
+		c.JSON(http.StatusOK, gin.H{
+			"groupID":   groupID,
+			"currency":  currency,
+			"nextPayer": nil,
+			"ranking":   ranking,
+		})
+		return
+	}
+
+	// 1位と2位の差がついた条件を推薦の理由として返す
+	reason := nextPayerReasonOnly
+	if len(ranking) > 1 {
+		a, b := ranking[0].UserID, ranking[1].UserID
+		_, decided := paidBefore(a, b)
+		switch {
+		case units[a] != units[b]:
+			reason = nextPayerReasonBalance
+		case decided:
+			reason = nextPayerReasonLastPaid
+		default:
+			reason = nextPayerReasonUserID
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"groupID":   groupID,
+		"currency":  currency,
+		"nextPayer": ranking[0],
+		"reason":    reason,
+		"ranking":   ranking,
+	})
+}
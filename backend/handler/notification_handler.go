@@ -0,0 +1,135 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ito-system/clear-up-share/backend/database"
+	"github.com/ito-system/clear-up-share/backend/models"
+	"gorm.io/gorm"
+)
+
+// notify はユーザー宛てのアプリ内通知を作成します
+// 変更操作と同じトランザクション (tx) 内で呼び出してください
+// ユーザーがグループの通知設定で通知の区分を無効にしている場合は何も作成しません
+func notify(tx *gorm.DB, userID, groupID uint, notificationType, message, targetType string, targetID uint) error {
+	enabled, err := notificationEnabled(tx, userID, groupID, notificationType)
+	if err != nil {
+		return err
+	}
+	if !enabled {
+		return nil
+	}
+
+	return tx.Create(&models.Notification{
+		UserID:     userID,
+		GroupID:    groupID,
+		Type:       notificationType,
+		Message:    message,
+		TargetType: targetType,
+		TargetID:   targetID,
+	}).Error
+}
+
+// GetMyNotifications は認証ユーザーの通知を新しい順に取得します
+// GET /api/v1/users/me/notifications?unread=true
+func GetMyNotifications(c *gin.Context) {
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	page, limit := parsePagination(c, 20, 100)
+
+	query := database.DB.Model(&models.Notification{}).Where("user_id = ?", userID)
+	if unread, _ := strconv.ParseBool(c.DefaultQuery("unread", "false")); unread {
+		query = query.Where("read_at IS NULL")
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count notifications"})
+		return
+	}
+
+	var notifications []models.Notification
+	if err := query.Order("created_at DESC, id DESC").
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Find(&notifications).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch notifications"})
+		return
+	}
+
+	// レスポンス用の通知リストを構築
+	type NotificationResponse struct {
+		ID         uint       `json:"id"`
+		GroupID    uint       `json:"groupID,omitempty"`
+		Type       string     `json:"type"`
+		Message    string     `json:"message"`
+		TargetType string     `json:"targetType,omitempty"`
+		TargetID   uint       `json:"targetID,omitempty"`
+		ReadAt     *time.Time `json:"readAt"`
+		CreatedAt  time.Time  `json:"createdAt"`
+	}
+
+	items := make([]NotificationResponse, len(notifications))
+	for i, n := range notifications {
+		items[i] = NotificationResponse{
+			ID:         n.ID,
+			GroupID:    n.GroupID,
+			Type:       n.Type,
+			Message:    n.Message,
+			TargetType: n.TargetType,
+			TargetID:   n.TargetID,
+			ReadAt:     n.ReadAt,
+			CreatedAt:  n.CreatedAt,
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"notifications": items,
+		"page":          page,
+		"limit":         limit,
+		"total":         total,
+	})
+}
+
+// MarkNotificationRead は通知を既読にします
+// POST /api/v1/users/me/notifications/:notificationID/read
+func MarkNotificationRead(c *gin.Context) {
+	// パスパラメータからnotificationIDを取得
+	notificationIDStr := c.Param("notificationID")
+	notificationID, err := strconv.ParseUint(notificationIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid notification ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	result := database.DB.Model(&models.Notification{}).
+		Where("id = ? AND user_id = ? AND read_at IS NULL", notificationID, userID).
+		Update("read_at", time.Now())
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update notification"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Notification not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Notification marked as read",
+	})
+}
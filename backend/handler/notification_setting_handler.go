@@ -0,0 +1,194 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ito-system/clear-up-share/backend/database"
+	"github.com/ito-system/clear-up-share/backend/models"
+	"gorm.io/gorm"
+)
+
+// UpdateNotificationSettingsInput は通知設定更新リクエストの入力形式（指定されたフィールドのみ更新）
+type UpdateNotificationSettingsInput struct {
+	NewExpense  *bool `json:"newExpense"`
+	Edits       *bool `json:"edits"`
+	Settlements *bool `json:"settlements"`
+	Reminders   *bool `json:"reminders"`
+}
+
+// notificationCategories は通知の種類とその種類が属する通知設定の区分
+// ここにない種類（参加申請など）は通知設定に関わらず常に通知されます
+var notificationCategories = map[string]string{
+	models.NotificationExpenseApprovalRequested: models.NotificationCategoryNewExpense,
+	models.NotificationExpenseApproved:          models.NotificationCategoryEdits,
+	models.NotificationForgivenessRequested:     models.NotificationCategorySettlements,
+	models.NotificationForgivenessConfirmed:     models.NotificationCategorySettlements,
+	models.NotificationReimbursementRequested:   models.NotificationCategorySettlements,
+	models.NotificationReimbursementPaid:        models.NotificationCategorySettlements,
+	models.NotificationSettlementPending:        models.NotificationCategorySettlements,
+	models.NotificationSettlementConfirmed:      models.NotificationCategorySettlements,
+	models.NotificationSettlementPeriodClosed:   models.NotificationCategorySettlements,
+	models.NotificationIOURecorded:              models.NotificationCategorySettlements,
+	models.NotificationBudgetThreshold:          models.NotificationCategoryNewExpense,
+	models.NotificationDebtReminder:             models.NotificationCategoryReminders,
+}
+
+// defaultNotificationSetting は設定が未保存のユーザーに適用される既定値（全て受け取る）を返します
+func defaultNotificationSetting(userID, groupID uint) models.NotificationSetting {
+	return models.NotificationSetting{
+		UserID:      userID,
+		GroupID:     groupID,
+		NewExpense:  true,
+		Edits:       true,
+		Settlements: true,
+		Reminders:   true,
+	}
+}
+
+// loadNotificationSetting はユーザーのグループごとの通知設定を取得します（未保存の場合は既定値）
+func loadNotificationSetting(db *gorm.DB, userID, groupID uint) (models.NotificationSetting, error) {
+	var setting models.NotificationSetting
+	err := db.Where("user_id = ? AND group_id = ?", userID, groupID).First(&setting).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return defaultNotificationSetting(userID, groupID), nil
+	}
+	return setting, err
+}
+
+// notificationEnabled は通知の種類がユーザーの通知設定で有効になっているかを判定します
+func notificationEnabled(db *gorm.DB, userID, groupID uint, notificationType string) (bool, error) {
+	category, ok := notificationCategories[notificationType]
+	if !ok || groupID == 0 {
+		return true, nil
+	}
+
+	setting, err := loadNotificationSetting(db, userID, groupID)
+	if err != nil {
+		return false, err
+	}
+
+	switch category {
+	case models.NotificationCategoryNewExpense:
+		return setting.NewExpense, nil
+	case models.NotificationCategoryEdits:
+		return setting.Edits, nil
+	case models.NotificationCategorySettlements:
+		return setting.Settlements, nil
+	case models.NotificationCategoryReminders:
+		return setting.Reminders, nil
+	}
+	return true, nil
+}
+
+// notificationSettingResponse は通知設定のレスポンス形式を返します
+func notificationSettingResponse(setting models.NotificationSetting) gin.H {
+	return gin.H{
+		"groupID":     setting.GroupID,
+		"newExpense":  setting.NewExpense,
+		"edits":       setting.Edits,
+		"settlements": setting.Settlements,
+		"reminders":   setting.Reminders,
+	}
+}
+
+// GetNotificationSettings は認証ユーザーのグループごとの通知設定を取得します
+// GET /api/v1/groups/:groupID/notification-settings
+func GetNotificationSettings(c *gin.Context) {
+	// パスパラメータからgroupIDを取得
+	groupIDStr := c.Param("groupID")
+	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// ユーザーがグループのメンバーであることを確認
+	var membership models.Membership
+	if err := database.DB.Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	setting, err := loadNotificationSetting(database.DB, userID.(uint), uint(groupID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch notification settings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"settings": notificationSettingResponse(setting),
+	})
+}
+
+// UpdateNotificationSettings は認証ユーザーのグループごとの通知設定を更新します
+// PUT /api/v1/groups/:groupID/notification-settings
+func UpdateNotificationSettings(c *gin.Context) {
+	// パスパラメータからgroupIDを取得
+	groupIDStr := c.Param("groupID")
+	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// ユーザーがグループのメンバーであることを確認
+	var membership models.Membership
+	if err := database.DB.Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	// リクエストボディをバインド
+	var input UpdateNotificationSettingsInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	setting, err := loadNotificationSetting(database.DB, userID.(uint), uint(groupID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch notification settings"})
+		return
+	}
+
+	// 指定されたフィールドのみ更新
+	if input.NewExpense != nil {
+		setting.NewExpense = *input.NewExpense
+	}
+	if input.Edits != nil {
+		setting.Edits = *input.Edits
+	}
+	if input.Settlements != nil {
+		setting.Settlements = *input.Settlements
+	}
+	if input.Reminders != nil {
+		setting.Reminders = *input.Reminders
+	}
+
+	if err := database.DB.Save(&setting).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update notification settings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Notification settings updated successfully",
+		"settings": notificationSettingResponse(setting),
+	})
+}
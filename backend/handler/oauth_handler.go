@@ -0,0 +1,474 @@
+package handler
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ito-system/clear-up-share/backend/database"
+	"github.com/ito-system/clear-up-share/backend/models"
+	"github.com/ito-system/clear-up-share/backend/utils"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// 認可コードとアクセストークンの有効期間
+const (
+	oauthCodeTTL        = 10 * time.Minute
+	oauthAccessTokenTTL = time.Hour
+)
+
+// RegisterOAuthClientInput はOAuthクライアント登録リクエストの入力形式
+type RegisterOAuthClientInput struct {
+	Name         string   `json:"name" binding:"required,max=100"`
+	RedirectURIs []string `json:"redirectURIs" binding:"required,min=1,dive,url"`
+}
+
+// AuthorizeInput はユーザーの同意（認可）リクエストの入力形式
+type AuthorizeInput struct {
+	ClientID      string `json:"clientID" binding:"required"`
+	RedirectURI   string `json:"redirectURI" binding:"required"`
+	Scope         string `json:"scope" binding:"omitempty,oneof=full read"`
+	GroupID       uint   `json:"groupID"`
+	State         string `json:"state"`
+	CodeChallenge string `json:"codeChallenge"` // PKCE (S256)
+	Approve       bool   `json:"approve"`
+}
+
+// randomToken は暗号論的に安全なランダム文字列を生成します
+func randomToken(bytes int) (string, error) {
+	b := make([]byte, bytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// hashToken は認可コードを保存用にハッシュ化します
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// redirectURIAllowed はリダイレクトURIがクライアントに登録済みかを判定します
+func redirectURIAllowed(client models.OAuthClient, redirectURI string) bool {
+	for _, uri := range strings.Fields(client.RedirectURIs) {
+		if uri == redirectURI {
+			return true
+		}
+	}
+	return false
+}
+
+// authenticateOAuthClient はクライアントIDとシークレットを検証します
+func authenticateOAuthClient(clientID, clientSecret string) (models.OAuthClient, bool) {
+	var client models.OAuthClient
+	if err := database.DB.Where("client_id = ?", clientID).First(&client).Error; err != nil {
+		return client, false
+	}
+	if bcrypt.CompareHashAndPassword([]byte(client.SecretHash), []byte(clientSecret)) != nil {
+		return client, false
+	}
+	return client, true
+}
+
+// RegisterOAuthClient はサードパーティアプリをOAuthクライアントとして登録します
+// クライアントシークレットはこのレスポンスでのみ返されます
+// POST /api/v1/oauth/clients
+func RegisterOAuthClient(c *gin.Context) {
+	var input RegisterOAuthClientInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// アプリ経由のトークンでは他のアプリを登録・認可できない
+	if c.GetUint("oauthGrantID") != 0 {
+		c.JSON(http.StatusForbidden, gin.H{"error": "OAuth tokens cannot manage OAuth authorizations"})
+		return
+	}
+
+	clientID, err := randomToken(16)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate client ID"})
+		return
+	}
+	clientSecret, err := randomToken(32)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate client secret"})
+		return
+	}
+	secretHash, err := bcrypt.GenerateFromPassword([]byte(clientSecret), bcrypt.DefaultCost)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash client secret"})
+		return
+	}
+
+	client := models.OAuthClient{
+		ClientID:     clientID,
+		SecretHash:   string(secretHash),
+		Name:         input.Name,
+		RedirectURIs: strings.Join(input.RedirectURIs, " "),
+		OwnerID:      userID.(uint),
+	}
+
+	if err := database.DB.Create(&client).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to register client"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "OAuth client registered successfully",
+		"client": gin.H{
+			"clientID":     client.ClientID,
+			"clientSecret": clientSecret,
+			"name":         client.Name,
+			"redirectURIs": input.RedirectURIs,
+		},
+	})
+}
+
+// GetOAuthConsent は同意画面の表示に必要な情報を返します
+// GET /api/v1/oauth/authorize?client_id=&redirect_uri=&scope=&group_id=&state=
+func GetOAuthConsent(c *gin.Context) {
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var client models.OAuthClient
+	if err := database.DB.Where("client_id = ?", c.Query("client_id")).First(&client).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown client"})
+		return
+	}
+
+	redirectURI := c.Query("redirect_uri")
+	if !redirectURIAllowed(client, redirectURI) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Redirect URI is not registered for this client"})
+		return
+	}
+
+	scope := c.DefaultQuery("scope", utils.ScopeRead)
+	if scope != utils.ScopeRead && scope != utils.ScopeFull {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Scope must be read or full"})
+		return
+	}
+
+	// グループを限定する場合はグループ名を同意画面に表示する
+	var group gin.H
+	if groupIDStr := c.Query("group_id"); groupIDStr != "" {
+		groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+			return
+		}
+		var membership models.Membership
+		if err := database.DB.Preload("Group").Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+			return
+		}
+		group = gin.H{"id": membership.Group.ID, "name": membership.Group.Name}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"client": gin.H{
+			"clientID": client.ClientID,
+			"name":     client.Name,
+		},
+		"scope":       scope,
+		"group":       group,
+		"redirectURI": redirectURI,
+		"state":       c.Query("state"),
+	})
+}
+
+// AuthorizeOAuthClient はユーザーの同意結果を受け取り、認可コード付きのリダイレクト先を返します
+// POST /api/v1/oauth/authorize
+func AuthorizeOAuthClient(c *gin.Context) {
+	var input AuthorizeInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// アプリ経由のトークンでは他のアプリを登録・認可できない
+	if c.GetUint("oauthGrantID") != 0 {
+		c.JSON(http.StatusForbidden, gin.H{"error": "OAuth tokens cannot manage OAuth authorizations"})
+		return
+	}
+
+	var client models.OAuthClient
+	if err := database.DB.Where("client_id = ?", input.ClientID).First(&client).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown client"})
+		return
+	}
+
+	if !redirectURIAllowed(client, input.RedirectURI) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Redirect URI is not registered for this client"})
+		return
+	}
+
+	redirect, err := url.Parse(input.RedirectURI)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid redirect URI"})
+		return
+	}
+	query := redirect.Query()
+	if input.State != "" {
+		query.Set("state", input.State)
+	}
+
+	// 拒否された場合はエラー付きでリダイレクトさせる
+	if !input.Approve {
+		query.Set("error", "access_denied")
+		redirect.RawQuery = query.Encode()
+		c.JSON(http.StatusOK, gin.H{"redirectTo": redirect.String()})
+		return
+	}
+
+	scope := input.Scope
+	if scope == "" {
+		scope = utils.ScopeRead
+	}
+
+	// グループを限定する場合はメンバーであることを確認
+	if input.GroupID != 0 {
+		var membership models.Membership
+		if err := database.DB.Where("user_id = ? AND group_id = ?", userID, input.GroupID).First(&membership).Error; err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+			return
+		}
+	}
+
+	code, err := randomToken(32)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate authorization code"})
+		return
+	}
+
+	authCode := models.OAuthAuthorizationCode{
+		CodeHash:      hashToken(code),
+		ClientID:      client.ID,
+		UserID:        userID.(uint),
+		RedirectURI:   input.RedirectURI,
+		Scope:         scope,
+		GroupID:       input.GroupID,
+		CodeChallenge: input.CodeChallenge,
+		ExpiresAt:     time.Now().Add(oauthCodeTTL),
+	}
+
+	if err := database.DB.Create(&authCode).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create authorization code"})
+		return
+	}
+
+	query.Set("code", code)
+	redirect.RawQuery = query.Encode()
+
+	c.JSON(http.StatusOK, gin.H{"redirectTo": redirect.String()})
+}
+
+// ExchangeOAuthToken は認可コードをアクセストークンに交換します（RFC 6749 トークンエンドポイント）
+// POST /api/v1/oauth/token
+func ExchangeOAuthToken(c *gin.Context) {
+	if c.PostForm("grant_type") != "authorization_code" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported_grant_type"})
+		return
+	}
+
+	client, ok := authenticateOAuthClient(c.PostForm("client_id"), c.PostForm("client_secret"))
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid_client"})
+		return
+	}
+
+	var authCode models.OAuthAuthorizationCode
+	if err := database.DB.Where("code_hash = ? AND client_id = ?", hashToken(c.PostForm("code")), client.ID).First(&authCode).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_grant"})
+		return
+	}
+
+	if authCode.UsedAt != nil || time.Now().After(authCode.ExpiresAt) || authCode.RedirectURI != c.PostForm("redirect_uri") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_grant"})
+		return
+	}
+
+	// PKCE: code_verifier の SHA-256 が code_challenge と一致することを確認
+	if authCode.CodeChallenge != "" {
+		sum := sha256.Sum256([]byte(c.PostForm("code_verifier")))
+		challenge := base64.RawURLEncoding.EncodeToString(sum[:])
+		if subtle.ConstantTimeCompare([]byte(challenge), []byte(authCode.CodeChallenge)) != 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_grant"})
+			return
+		}
+	}
+
+	// トランザクションで認可コードの使用済み化と権限付与の作成を行う
+	tx := database.DB.Begin()
+
+	// 同じコードの同時使用を防ぐため、未使用の場合のみ更新する
+	now := time.Now()
+	result := tx.Model(&models.OAuthAuthorizationCode{}).Where("id = ? AND used_at IS NULL", authCode.ID).Update("used_at", now)
+	if result.Error != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server_error"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		tx.Rollback()
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_grant"})
+		return
+	}
+
+	grant := models.OAuthGrant{
+		ClientID: client.ID,
+		UserID:   authCode.UserID,
+		Scope:    authCode.Scope,
+		GroupID:  authCode.GroupID,
+	}
+	if err := tx.Create(&grant).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server_error"})
+		return
+	}
+
+	token, err := utils.GenerateOAuthJWT(grant.UserID, grant.Scope, grant.GroupID, grant.ID, oauthAccessTokenTTL)
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server_error"})
+		return
+	}
+
+	tx.Commit()
+
+	c.JSON(http.StatusOK, gin.H{
+		"access_token": token,
+		"token_type":   "Bearer",
+		"expires_in":   int(oauthAccessTokenTTL.Seconds()),
+		"scope":        grant.Scope,
+	})
+}
+
+// RevokeOAuthToken はクライアントからのトークン取り消しを処理します（RFC 7009）
+// 不正なトークンが指定された場合も情報を漏らさないよう 200 を返します
+// POST /api/v1/oauth/revoke
+func RevokeOAuthToken(c *gin.Context) {
+	client, ok := authenticateOAuthClient(c.PostForm("client_id"), c.PostForm("client_secret"))
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid_client"})
+		return
+	}
+
+	claims, err := utils.ParseJWT(c.PostForm("token"))
+	if err == nil {
+		if grantID, ok := claims["grantID"].(float64); ok {
+			database.DB.Model(&models.OAuthGrant{}).
+				Where("id = ? AND client_id = ? AND revoked_at IS NULL", uint(grantID), client.ID).
+				Update("revoked_at", time.Now())
+		}
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// GetMyOAuthGrants は認証ユーザーがアプリに与えている権限の一覧を取得します
+// GET /api/v1/users/me/oauth/grants
+func GetMyOAuthGrants(c *gin.Context) {
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var grants []models.OAuthGrant
+	if err := database.DB.Preload("Client").
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Order("created_at DESC").
+		Find(&grants).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch grants"})
+		return
+	}
+
+	// レスポンス用の権限リストを構築
+	type GrantResponse struct {
+		ID         uint      `json:"id"`
+		ClientName string    `json:"clientName"`
+		Scope      string    `json:"scope"`
+		GroupID    uint      `json:"groupID,omitempty"`
+		CreatedAt  time.Time `json:"createdAt"`
+	}
+
+	items := make([]GrantResponse, len(grants))
+	for i, g := range grants {
+		items[i] = GrantResponse{
+			ID:         g.ID,
+			ClientName: g.Client.Name,
+			Scope:      g.Scope,
+			GroupID:    g.GroupID,
+			CreatedAt:  g.CreatedAt,
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"grants": items,
+	})
+}
+
+// RevokeMyOAuthGrant はユーザー自身がアプリへの権限付与を取り消します
+// DELETE /api/v1/users/me/oauth/grants/:grantID
+func RevokeMyOAuthGrant(c *gin.Context) {
+	// パスパラメータからgrantIDを取得
+	grantIDStr := c.Param("grantID")
+	grantID, err := strconv.ParseUint(grantIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid grant ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	result := database.DB.Model(&models.OAuthGrant{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", grantID, userID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke grant"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Grant not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Grant revoked successfully",
+	})
+}
@@ -0,0 +1,119 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ito-system/clear-up-share/backend/database"
+	"github.com/ito-system/clear-up-share/backend/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// OnboardingStep は初期設定の1ステップのレスポンス形式
+type OnboardingStep struct {
+	Key         string     `json:"key"`
+	Completed   bool       `json:"completed"`
+	CompletedAt *time.Time `json:"completedAt"`
+}
+
+// trackOnboarding はアクティビティに応じて関係するユーザーの初期設定のステップを完了にします
+// 進捗の行がまだないユーザーは、初めて取得したときに既存の記録から作成されるので更新しません
+func trackOnboarding(tx *gorm.DB, action string, actorID, targetID uint, details map[string]interface{}) error {
+	switch action {
+	case models.ActivityMemberJoined:
+		return markOnboardingStep(tx, "joined_group_at", targetID)
+	case models.ActivityExpenseAdded:
+		return markOnboardingStep(tx, "first_expense_at", actorID)
+	case models.ActivitySettlementRecorded:
+		payerID, _ := details["payerID"].(uint)
+		receiverID, _ := details["receiverID"].(uint)
+		return markOnboardingStep(tx, "first_settlement_at", payerID, receiverID)
+	}
+	return nil
+}
+
+// markOnboardingStep は未完了のステップに完了日時を記録します
+func markOnboardingStep(tx *gorm.DB, column string, userIDs ...uint) error {
+	return tx.Model(&models.OnboardingProgress{}).
+		Where("user_id IN ? AND "+column+" IS NULL", userIDs).
+		Update(column, time.Now()).Error
+}
+
+// loadOnboardingProgress はユーザーの初期設定の進捗を取得します
+// 行がない場合は、グループ・支出・清算の既存の記録から完了日時を求めて作成します
+func loadOnboardingProgress(db *gorm.DB, userID uint) (models.OnboardingProgress, error) {
+	var progress models.OnboardingProgress
+	err := db.Where("user_id = ?", userID).First(&progress).Error
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return progress, err
+	}
+
+	type firstTimes struct {
+		JoinedGroupAt     *time.Time
+		FirstExpenseAt    *time.Time
+		FirstSettlementAt *time.Time
+	}
+	var times firstTimes
+	if err := db.Raw(`SELECT
+		(SELECT MIN(created_at) FROM memberships WHERE user_id = @userID) AS joined_group_at,
+		(SELECT MIN(created_at) FROM expenses WHERE created_by_id = @userID) AS first_expense_at,
+		(SELECT MIN(created_at) FROM settlements WHERE payer_id = @userID OR receiver_id = @userID) AS first_settlement_at`,
+		map[string]interface{}{"userID": userID},
+	).Scan(&times).Error; err != nil {
+		return progress, err
+	}
+
+	progress = models.OnboardingProgress{
+		UserID:            userID,
+		JoinedGroupAt:     times.JoinedGroupAt,
+		FirstExpenseAt:    times.FirstExpenseAt,
+		FirstSettlementAt: times.FirstSettlementAt,
+	}
+	// 同時に作成された場合は先に作成された行を使う
+	if err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&progress).Error; err != nil {
+		return progress, err
+	}
+	err = db.Where("user_id = ?", userID).First(&progress).Error
+	return progress, err
+}
+
+// GetMyOnboarding は認証ユーザーの初期設定のステップ（メール確認・グループ参加・最初の支出・最初の清算）の完了状況を返します
+// GET /api/v1/users/me/onboarding
+func GetMyOnboarding(c *gin.Context) {
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	progress, err := loadOnboardingProgress(database.DB, userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch onboarding progress"})
+		return
+	}
+
+	steps := []OnboardingStep{
+		{Key: "verifiedEmail", CompletedAt: progress.EmailVerifiedAt},
+		{Key: "joinedGroup", CompletedAt: progress.JoinedGroupAt},
+		{Key: "firstExpense", CompletedAt: progress.FirstExpenseAt},
+		{Key: "firstSettlement", CompletedAt: progress.FirstSettlementAt},
+	}
+	completed := 0
+	for i := range steps {
+		steps[i].Completed = steps[i].CompletedAt != nil
+		if steps[i].Completed {
+			completed++
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"steps":     steps,
+		"completed": completed,
+		"total":     len(steps),
+		"done":      completed == len(steps),
+	})
+}
@@ -0,0 +1,273 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ito-system/clear-up-share/backend/analytics"
+	"github.com/ito-system/clear-up-share/backend/database"
+	"github.com/ito-system/clear-up-share/backend/models"
+	"github.com/ito-system/clear-up-share/backend/utils"
+	"gorm.io/gorm"
+)
+
+// paymentLinkTTL は送金の提案で発行する支払いリンクのトークンの有効期限
+const paymentLinkTTL = 7 * 24 * time.Hour
+
+// PaymentOptions は送金の提案の送金を支払うための受取者の送金先と、支払い後に清算を記録するためのトークン
+type PaymentOptions struct {
+	PayPayLink   string        `json:"paypayLink,omitempty"` // 受取者の PayPay の受け取りリンク（金額は送金者が入力する）
+	BankTransfer *BankTransfer `json:"bankTransfer,omitempty"`
+	Token        string        `json:"token"` // 支払った後に payment-callback に送ると清算を記録する
+	ExpiresAt    time.Time     `json:"expiresAt"`
+}
+
+// BankTransfer は受取者の振込先と振り込む額
+type BankTransfer struct {
+	BankName      string  `json:"bankName"`
+	BranchName    string  `json:"branchName"`
+	AccountType   string  `json:"accountType"`
+	AccountNumber string  `json:"accountNumber"`
+	AccountHolder string  `json:"accountHolder"`
+	Amount        float64 `json:"amount"`
+}
+
+// PaymentCallbackInput は支払いリンクから支払った後の清算の記録リクエストの入力形式
+type PaymentCallbackInput struct {
+	Token  string `json:"token" binding:"required"`
+	Method string `json:"method" binding:"required,oneof=paypay bank_transfer"`
+}
+
+// attachPaymentOptions は認証ユーザーが送金者の送金に、受取者の送金先と支払いリンクのトークンを付けます
+// 振込先などの個人情報は送金者本人にのみ返し、送金先を設定していない受取者への送金には付けません
+func attachPaymentOptions(db *gorm.DB, groupID, userID uint, transfers []SuggestedTransfer) error {
+	var receiverIDs []uint
+	for _, t := range transfers {
+		if t.PayerID == userID && !t.Left {
+			receiverIDs = append(receiverIDs, t.ReceiverID)
+		}
+	}
+	if len(receiverIDs) == 0 {
+		return nil
+	}
+
+	var profiles []models.PayoutProfile
+	if err := db.Where("user_id IN ?", receiverIDs).Find(&profiles).Error; err != nil {
+		return err
+	}
+	byUser := make(map[uint]models.PayoutProfile, len(profiles))
+	for _, p := range profiles {
+		byUser[p.UserID] = p
+	}
+
+	for i, t := range transfers {
+		profile, ok := byUser[t.ReceiverID]
+		if t.PayerID != userID || t.Left || !ok {
+			continue
+		}
+
+		nonce, err := randomToken(16)
+		if err != nil {
+			return err
+		}
+		token, err := utils.GeneratePaymentLinkJWT(utils.PaymentLinkClaims{
+			GroupID:    groupID,
+			PayerID:    t.PayerID,
+			ReceiverID: t.ReceiverID,
+			Amount:     t.Amount,
+			Nonce:      nonce,
+		}, paymentLinkTTL)
+		if err != nil {
+			return err
+		}
+
+		options := &PaymentOptions{
+			PayPayLink: profile.PayPayLink,
+			Token:      token,
+			ExpiresAt:  time.Now().Add(paymentLinkTTL),
+		}
+		if hasBankAccount(profile) {
+			options.BankTransfer = &BankTransfer{
+				BankName:      profile.BankName,
+				BranchName:    profile.BranchName,
+				AccountType:   profile.AccountType,
+				AccountNumber: profile.AccountNumber,
+				AccountHolder: profile.AccountHolder,
+				Amount:        t.Amount,
+			}
+		}
+		transfers[i].Payment = options
+	}
+	return nil
+}
+
+// RecordPaymentCallback は送金の提案の支払いリンクから支払った後に、リンクの送金を清算として記録します
+// 送金者が記録した場合は受取者が受け取りを確認するまで確認待ち、受取者が記録した場合は確定済みになります
+// POST /api/v1/groups/:groupID/settlements/payment-callback
+func RecordPaymentCallback(c *gin.Context) {
+	// パスパラメータからgroupIDを取得
+	groupIDStr := c.Param("groupID")
+	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// ユーザーがグループのメンバーであることを確認
+	var membership models.Membership
+	if err := database.DB.Preload("Group").Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	// アーカイブ済みのグループは読み取り専用
+	if membership.Group.ArchivedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Group is archived and read-only"})
+		return
+	}
+
+	// 清算を記録する権限があることを確認
+	if !hasPermission(membership.Role, PermRecordSettlement) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to record settlements"})
+		return
+	}
+
+	// リクエストボディをバインド
+	var input PaymentCallbackInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	link, err := utils.ParsePaymentLinkJWT(input.Token)
+	if err != nil || link.GroupID != uint(groupID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired payment link"})
+		return
+	}
+
+	// 送金者か受取者のみ記録できる
+	if link.PayerID != userID.(uint) && link.ReceiverID != userID.(uint) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the payer or receiver can record this payment"})
+		return
+	}
+
+	// グループのポリシー設定を取得
+	settings, err := loadGroupSettings(database.DB, uint(groupID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch group settings"})
+		return
+	}
+
+	status := models.SettlementStatusPending
+	if link.ReceiverID == userID.(uint) {
+		status = models.SettlementStatusConfirmed
+	}
+
+	settlement := models.Settlement{
+		GroupID:          uint(groupID),
+		PayerID:          link.PayerID,
+		ReceiverID:       link.ReceiverID,
+		Amount:           link.Amount,
+		Date:             groupToday(settings),
+		Method:           input.Method,
+		Status:           status,
+		PaymentLinkNonce: link.Nonce,
+	}
+
+	// トランザクション開始
+	tx := database.DB.Begin()
+
+	// 清算の完了まで送金者・受取者が退会・除名されないようにする
+	ok, err := lockGroupMembers(tx, uint(groupID), []uint{link.PayerID, link.ReceiverID})
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check members"})
+		return
+	}
+	if !ok {
+		tx.Rollback()
+		c.JSON(http.StatusConflict, gin.H{"error": "Payer or receiver has left this group"})
+		return
+	}
+
+	// 同じリンクから二重に記録しない
+	var recorded int64
+	if err := tx.Model(&models.Settlement{}).Where("payment_link_nonce = ?", link.Nonce).Count(&recorded).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch settlements"})
+		return
+	}
+	if recorded > 0 {
+		tx.Rollback()
+		c.JSON(http.StatusConflict, gin.H{"error": "This payment has already been recorded"})
+		return
+	}
+
+	if err := tx.Create(&settlement).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create settlement"})
+		return
+	}
+
+	// 送金者が記録した場合は受取者に受け取りの確認を依頼する
+	if status == models.SettlementStatusPending {
+		names, err := groupDisplayNames(tx, uint(groupID))
+		if err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch members"})
+			return
+		}
+		message := names[settlement.PayerID] + " paid you " + formatGlanceAmount(settlement.Amount, membership.Group.Currency) + ". Please confirm once you receive it"
+		if err := notify(tx, settlement.ReceiverID, settlement.GroupID, models.NotificationSettlementPending, message, "settlement", settlement.ID); err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send notification"})
+			return
+		}
+	}
+
+	// アクティビティを記録
+	if err := recordActivity(tx, settlement.GroupID, userID.(uint), models.ActivitySettlementRecorded, "settlement", settlement.ID, map[string]interface{}{
+		"payerID":     settlement.PayerID,
+		"receiverID":  settlement.ReceiverID,
+		"amount":      settlement.Amount,
+		"method":      settlement.Method,
+		"status":      settlement.Status,
+		"paymentLink": true,
+	}); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record activity"})
+		return
+	}
+
+	tx.Commit()
+
+	analytics.Track(analytics.EventSettlementRecorded, userID.(uint), map[string]interface{}{
+		"groupId":     analytics.Anonymize("group", settlement.GroupID),
+		"paymentLink": true,
+	})
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Settlement recorded successfully",
+		"settlement": gin.H{
+			"id":         settlement.ID,
+			"groupID":    settlement.GroupID,
+			"payerID":    settlement.PayerID,
+			"receiverID": settlement.ReceiverID,
+			"amount":     settlement.Amount,
+			"currency":   membership.Group.Currency,
+			"date":       settlement.Date.Format("2006-01-02"),
+			"method":     settlement.Method,
+			"status":     settlement.Status,
+			"createdAt":  settlement.CreatedAt,
+		},
+	})
+}
@@ -0,0 +1,161 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ito-system/clear-up-share/backend/database"
+	"github.com/ito-system/clear-up-share/backend/models"
+	"gorm.io/gorm"
+)
+
+// payPayLinkHosts は受け取りリンクとして受け付ける PayPay のホスト
+var payPayLinkHosts = map[string]bool{
+	"qr.paypay.ne.jp":  true,
+	"pay.paypay.ne.jp": true,
+}
+
+// UpdatePayoutProfileInput は送金先の設定リクエストの入力形式（PayPay の受け取りリンクと振込先の一方または両方を指定）
+type UpdatePayoutProfileInput struct {
+	PayPayLink    string `json:"paypayLink" binding:"max=500"`
+	BankName      string `json:"bankName" binding:"max=100"`
+	BranchName    string `json:"branchName" binding:"max=100"`
+	AccountType   string `json:"accountType" binding:"omitempty,oneof=ordinary checking"`
+	AccountNumber string `json:"accountNumber" binding:"omitempty,numeric,max=20"`
+	AccountHolder string `json:"accountHolder" binding:"max=100"`
+}
+
+// payoutProfileResponse は送金先のレスポンス形式を返します
+func payoutProfileResponse(profile models.PayoutProfile) gin.H {
+	return gin.H{
+		"paypayLink":    profile.PayPayLink,
+		"bankName":      profile.BankName,
+		"branchName":    profile.BranchName,
+		"accountType":   profile.AccountType,
+		"accountNumber": profile.AccountNumber,
+		"accountHolder": profile.AccountHolder,
+		"updatedAt":     profile.UpdatedAt,
+	}
+}
+
+// hasBankAccount は振込先の全ての項目が設定されているかを判定します
+func hasBankAccount(profile models.PayoutProfile) bool {
+	return profile.BankName != "" && profile.BranchName != "" && profile.AccountType != "" &&
+		profile.AccountNumber != "" && profile.AccountHolder != ""
+}
+
+// GetMyPayoutProfile は認証ユーザーの送金先を取得します
+// GET /api/v1/users/me/payout-profile
+func GetMyPayoutProfile(c *gin.Context) {
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var profile models.PayoutProfile
+	if err := database.DB.Where("user_id = ?", userID).First(&profile).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Payout profile not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch payout profile"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"payoutProfile": payoutProfileResponse(profile)})
+}
+
+// UpdateMyPayoutProfile は認証ユーザーの送金先を設定します（指定しなかった項目は削除されます）
+// PUT /api/v1/users/me/payout-profile
+func UpdateMyPayoutProfile(c *gin.Context) {
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// リクエストボディをバインド
+	var input UpdatePayoutProfileInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	profile := models.PayoutProfile{
+		PayPayLink:    strings.TrimSpace(input.PayPayLink),
+		BankName:      strings.TrimSpace(input.BankName),
+		BranchName:    strings.TrimSpace(input.BranchName),
+		AccountType:   input.AccountType,
+		AccountNumber: input.AccountNumber,
+		AccountHolder: strings.TrimSpace(input.AccountHolder),
+	}
+
+	// PayPay の受け取りリンクは PayPay のURLのみ受け付ける
+	if profile.PayPayLink != "" {
+		link, err := url.Parse(profile.PayPayLink)
+		if err != nil || link.Scheme != "https" || !payPayLinkHosts[link.Host] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "paypayLink must be a PayPay link (https://qr.paypay.ne.jp/...)"})
+			return
+		}
+	}
+
+	// 振込先は全ての項目を指定するか、全て省略する
+	bankFields := profile.BankName != "" || profile.BranchName != "" || profile.AccountType != "" ||
+		profile.AccountNumber != "" || profile.AccountHolder != ""
+	if bankFields && !hasBankAccount(profile) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "bankName, branchName, accountType, accountNumber and accountHolder are all required for a bank account"})
+		return
+	}
+	if profile.PayPayLink == "" && !bankFields {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Specify a PayPay link or a bank account"})
+		return
+	}
+
+	var saved models.PayoutProfile
+	if err := database.DB.Where(models.PayoutProfile{UserID: userID.(uint)}).FirstOrInit(&saved).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch payout profile"})
+		return
+	}
+	profile.Model = saved.Model
+	profile.UserID = userID.(uint)
+
+	if err := database.DB.Save(&profile).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update payout profile"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":       "Payout profile updated successfully",
+		"payoutProfile": payoutProfileResponse(profile),
+	})
+}
+
+// DeleteMyPayoutProfile は認証ユーザーの送金先を削除します
+// DELETE /api/v1/users/me/payout-profile
+func DeleteMyPayoutProfile(c *gin.Context) {
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// 口座番号などを残さないよう物理削除する
+	result := database.DB.Unscoped().Where("user_id = ?", userID).Delete(&models.PayoutProfile{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete payout profile"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Payout profile not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Payout profile deleted successfully"})
+}
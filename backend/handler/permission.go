@@ -0,0 +1,96 @@
+package handler
+
+import (
+	"github.com/ito-system/clear-up-share/backend/models"
+	"github.com/ito-system/clear-up-share/backend/utils"
+)
+
+// Permission はグループ内で実行できる操作を表します
+type Permission int
+
+const (
+	// PermViewGroup は履歴・メンバー・負債の閲覧権限
+	PermViewGroup Permission = iota
+	// PermAddExpense は支出の追加権限
+	PermAddExpense
+	// PermEditOwnExpense は自分が支払った支出の編集・削除権限
+	PermEditOwnExpense
+	// PermEditAnyExpense は他のメンバーの支出の編集・削除権限
+	PermEditAnyExpense
+	// PermRecordSettlement は清算の記録権限
+	PermRecordSettlement
+	// PermManageMembers はメンバーのロール変更権限
+	PermManageMembers
+	// PermManageGroup はグループ自体の設定変更・アーカイブ権限
+	PermManageGroup
+	// PermApproveExpense は承認待ち支出の承認権限
+	PermApproveExpense
+)
+
+// permissionNames はAPIで公開する権限名
+var permissionNames = map[Permission]string{
+	PermViewGroup:        "view_group",
+	PermAddExpense:       "add_expense",
+	PermEditOwnExpense:   "edit_own_expense",
+	PermEditAnyExpense:   "edit_any_expense",
+	PermRecordSettlement: "record_settlement",
+	PermManageMembers:    "manage_members",
+	PermManageGroup:      "manage_group",
+	PermApproveExpense:   "approve_expense",
+}
+
+// rolePermissions はロールごとに許可される操作の一覧
+var rolePermissions = map[string][]Permission{
+	models.RoleOwner: {
+		PermViewGroup, PermAddExpense, PermEditOwnExpense, PermEditAnyExpense,
+		PermRecordSettlement, PermManageMembers, PermManageGroup, PermApproveExpense,
+	},
+	models.RoleAdmin: {
+		PermViewGroup, PermAddExpense, PermEditOwnExpense, PermEditAnyExpense,
+		PermRecordSettlement, PermManageMembers, PermManageGroup, PermApproveExpense,
+	},
+	models.RoleMember: {
+		PermViewGroup, PermAddExpense, PermEditOwnExpense, PermRecordSettlement,
+	},
+	models.RoleViewer: {
+		PermViewGroup,
+	},
+}
+
+// hasPermission はロールが指定の操作を許可されているかを判定します
+func hasPermission(role string, perm Permission) bool {
+	for _, p := range rolePermissions[role] {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// isValidRole はロール名が定義済みのものかを判定します
+func isValidRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// expenseEditPermission は支出の編集・削除に必要な権限を返します
+// 自分が支払った支出と自分が登録した支出は PermEditOwnExpense、それ以外は PermEditAnyExpense が必要です
+func expenseEditPermission(expense models.Expense, userID uint) Permission {
+	if expense.PayerID == userID || expense.CreatedByID == userID {
+		return PermEditOwnExpense
+	}
+	return PermEditAnyExpense
+}
+
+// rolePermissionNames はロールに許可される操作の権限名を返します
+// 読み取り専用トークンの場合は閲覧権限のみを返します
+func rolePermissionNames(role, scope string) []string {
+	names := []string{}
+	for _, p := range rolePermissions[role] {
+		if scope == utils.ScopeRead && p != PermViewGroup {
+			continue
+		}
+		names = append(names, permissionNames[p])
+	}
+	return names
+}
@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ito-system/clear-up-share/backend/models"
+	"github.com/ito-system/clear-up-share/backend/utils"
+	"gorm.io/gorm"
+)
+
+// 利用上限の種類
+const (
+	QuotaGroupsPerUser    = "groups_per_user"
+	QuotaMembersPerGroup  = "members_per_group"
+	QuotaExpensesPerMonth = "expenses_per_month"
+)
+
+// QuotaExceededError は利用上限を超える操作であることを表します
+type QuotaExceededError struct {
+	Quota   string
+	Limit   int
+	Current int64
+}
+
+func (e *QuotaExceededError) Error() string {
+	return fmt.Sprintf("quota %s exceeded (%d/%d)", e.Quota, e.Current, e.Limit)
+}
+
+// limitsForUser はユーザーに適用される利用上限を返します
+// 課金プランを導入する際はここでユーザーのプランに応じた上限を返します
+func limitsForUser(db *gorm.DB, userID uint) utils.Limits {
+	return utils.DefaultLimits
+}
+
+// limitsForGroup はグループに適用される利用上限を返します
+// 課金プランを導入する際はここでグループ（オーナー）のプランに応じた上限を返します
+func limitsForGroup(db *gorm.DB, groupID uint) utils.Limits {
+	return utils.DefaultLimits
+}
+
+// checkQuota は現在の件数に追加分を加えても上限以内かを判定します
+func checkQuota(quota string, limit int, current, adding int64) error {
+	if limit > 0 && current+adding > int64(limit) {
+		return &QuotaExceededError{Quota: quota, Limit: limit, Current: current}
+	}
+	return nil
+}
+
+// checkGroupQuota はユーザーが新たにグループへ所属できるかを確認します
+func checkGroupQuota(db *gorm.DB, userID uint) error {
+	limit := limitsForUser(db, userID).MaxGroupsPerUser
+	if limit == 0 {
+		return nil
+	}
+
+	var count int64
+	if err := db.Model(&models.Membership{}).Where("user_id = ?", userID).Count(&count).Error; err != nil {
+		return err
+	}
+	return checkQuota(QuotaGroupsPerUser, limit, count, 1)
+}
+
+// checkMemberQuota はグループに adding 人のメンバーを追加できるかを確認します
+func checkMemberQuota(db *gorm.DB, groupID uint, adding int64) error {
+	limit := limitsForGroup(db, groupID).MaxMembersPerGroup
+	if limit == 0 {
+		return nil
+	}
+
+	var count int64
+	if err := db.Model(&models.Membership{}).Where("group_id = ?", groupID).Count(&count).Error; err != nil {
+		return err
+	}
+	return checkQuota(QuotaMembersPerGroup, limit, count, adding)
+}
+
+// checkExpenseQuota はグループの今月の期間（グループのタイムゾーンと月次開始日の設定に従う）に adding 件の支出を追加できるかを確認します（下書きは数えません）
+func checkExpenseQuota(db *gorm.DB, groupID uint, settings models.GroupSettings, adding int64) error {
+	limit := limitsForGroup(db, groupID).MaxExpensesPerMonth
+	if limit == 0 {
+		return nil
+	}
+
+	start, end := utils.MonthPeriod(time.Now().In(groupLocation(settings)), settings.MonthStartDay)
+
+	var count int64
+	if err := db.Model(&models.Expense{}).
+		Where("group_id = ? AND created_at >= ? AND created_at < ? AND status <> ?", groupID, start, end, models.ExpenseStatusDraft).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	return checkQuota(QuotaExpensesPerMonth, limit, count, adding)
+}
+
+// respondQuotaError は利用上限の確認結果をレスポンスとして返します
+func respondQuotaError(c *gin.Context, err error) {
+	var quotaErr *QuotaExceededError
+	if errors.As(err, &quotaErr) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Quota exceeded",
+			"quota":   quotaErr.Quota,
+			"limit":   quotaErr.Limit,
+			"current": quotaErr.Current,
+		})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check quota"})
+}
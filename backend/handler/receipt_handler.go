@@ -0,0 +1,246 @@
+package handler
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ito-system/clear-up-share/backend/database"
+	"github.com/ito-system/clear-up-share/backend/models"
+	"github.com/ito-system/clear-up-share/backend/storage"
+)
+
+const (
+	maxReceiptSize    = 10 << 20         // レシート1枚の最大サイズ（10MB）
+	receiptURLExpires = 15 * time.Minute // レシートのダウンロードURLの有効期間
+)
+
+// receiptContentTypes はレシートとして受け付けるファイルの種類と保存時の拡張子（請求書などのPDFも添付できます）
+var receiptContentTypes = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/webp":      ".webp",
+	"image/gif":       ".gif",
+	"application/pdf": ".pdf",
+}
+
+// ReceiptResponse はレシートのレスポンス形式
+type ReceiptResponse struct {
+	ID           uint      `json:"id"`
+	Filename     string    `json:"filename"`
+	ContentType  string    `json:"contentType"`
+	Size         int64     `json:"size"`
+	UploadedByID uint      `json:"uploadedByID"`
+	CreatedAt    time.Time `json:"createdAt"`
+	URL          string    `json:"url"`       // 署名付きのダウンロードURL
+	ExpiresAt    time.Time `json:"expiresAt"` // URLの有効期限
+}
+
+// signReceipts はレシートに署名付きのダウンロードURLを付けたレスポンスを作ります
+func signReceipts(receipts []models.Receipt) ([]ReceiptResponse, error) {
+	responses := make([]ReceiptResponse, len(receipts))
+	expiresAt := time.Now().Add(receiptURLExpires)
+	for i, receipt := range receipts {
+		url, err := storage.SignedURL(receipt.StorageKey, receiptURLExpires)
+		if err != nil {
+			return nil, err
+		}
+		responses[i] = ReceiptResponse{
+			ID:           receipt.ID,
+			Filename:     receipt.Filename,
+			ContentType:  receipt.ContentType,
+			Size:         receipt.Size,
+			UploadedByID: receipt.UploadedByID,
+			CreatedAt:    receipt.CreatedAt,
+			URL:          url,
+			ExpiresAt:    expiresAt,
+		}
+	}
+	return responses, nil
+}
+
+// UploadReceipt は支出にレシートの画像や請求書のPDFを添付します（multipart/form-data の file フィールド）
+// POST /api/v1/groups/:groupID/expenses/:expenseID/receipts
+func UploadReceipt(c *gin.Context) {
+	// パスパラメータからgroupIDとexpenseIDを取得
+	groupIDStr := c.Param("groupID")
+	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	expenseIDStr := c.Param("expenseID")
+	expenseID, err := strconv.ParseUint(expenseIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid expense ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// ユーザーがグループのメンバーであることを確認
+	var membership models.Membership
+	if err := database.DB.Preload("Group").Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	// アーカイブ済みのグループは読み取り専用
+	if membership.Group.ArchivedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Group is archived and read-only"})
+		return
+	}
+
+	// 既存のExpenseを取得
+	var expense models.Expense
+	if err := database.DB.Scopes(visibleExpenses(userID, true)).Where("id = ? AND group_id = ?", expenseID, groupID).First(&expense).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Expense not found"})
+		return
+	}
+
+	// 支出を編集できるメンバーはレシートも添付できる
+	if !hasPermission(membership.Role, expenseEditPermission(expense, userID.(uint))) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to edit this expense"})
+		return
+	}
+
+	// アップロードされたファイルを取得
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxReceiptSize+1<<20)
+	header, err := c.FormFile("file")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Receipt must be 10MB or smaller"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Receipt file is required"})
+		return
+	}
+	if header.Size > maxReceiptSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Receipt must be 10MB or smaller"})
+		return
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read receipt file"})
+		return
+	}
+	defer file.Close()
+
+	// ファイルの種類は送信された Content-Type ではなく内容から判定する
+	sniff := make([]byte, 512)
+	n, err := io.ReadFull(file, sniff)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read receipt file"})
+		return
+	}
+	contentType := http.DetectContentType(sniff[:n])
+	ext, ok := receiptContentTypes[contentType]
+	if !ok {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Receipt must be a JPEG, PNG, WebP or GIF image or a PDF"})
+		return
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read receipt file"})
+		return
+	}
+
+	// 推測されないランダムなキーで保存する
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store receipt"})
+		return
+	}
+	key := fmt.Sprintf("receipts/%d/%d/%s%s", groupID, expenseID, hex.EncodeToString(random), ext)
+	if err := storage.Put(c.Request.Context(), key, contentType, file, header.Size); err != nil {
+		log.Printf("Failed to store receipt %s: %v", key, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store receipt"})
+		return
+	}
+
+	receipt := models.Receipt{
+		ExpenseID:    expense.ID,
+		StorageKey:   key,
+		Filename:     filepath.Base(header.Filename),
+		ContentType:  contentType,
+		Size:         header.Size,
+		UploadedByID: userID.(uint),
+	}
+
+	// トランザクション開始
+	tx := database.DB.Begin()
+
+	if err := tx.Create(&receipt).Error; err != nil {
+		tx.Rollback()
+		storage.Delete(c.Request.Context(), key)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save receipt"})
+		return
+	}
+
+	// アクティビティを記録
+	if err := recordActivity(tx, expense.GroupID, userID.(uint), models.ActivityReceiptAdded, "expense", expense.ID, map[string]interface{}{
+		"receiptID": receipt.ID,
+		"filename":  receipt.Filename,
+	}); err != nil {
+		tx.Rollback()
+		storage.Delete(c.Request.Context(), key)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record activity"})
+		return
+	}
+
+	tx.Commit()
+
+	responses, err := signReceipts([]models.Receipt{receipt})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign receipt URL"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Receipt uploaded successfully",
+		"receipt": responses[0],
+	})
+}
+
+// DownloadFile はローカルに保存したファイルを署名付きURLで配信します（認証不要、署名と有効期限で保護）
+// GET /api/v1/files/*key?expires=...&signature=...
+func DownloadFile(c *gin.Context) {
+	file, err := storage.OpenSigned(c.Param("key"), c.Query("expires"), c.Query("signature"))
+	if err != nil {
+		if errors.Is(err, storage.ErrInvalidSignature) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Invalid or expired link"})
+			return
+		}
+		// S3 に保存している場合や、ファイルが削除済みの場合
+		c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+		return
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read file"})
+		return
+	}
+
+	c.Header("Cache-Control", "private, max-age="+strconv.Itoa(int(receiptURLExpires.Seconds())))
+	c.Header("X-Content-Type-Options", "nosniff")
+	// PDF に埋め込まれたスクリプトなどがこのオリジンで動かないようにする
+	c.Header("Content-Security-Policy", "sandbox")
+	http.ServeContent(c.Writer, c.Request, info.Name(), info.ModTime(), file)
+}
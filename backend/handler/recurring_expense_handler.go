@@ -0,0 +1,765 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ito-system/clear-up-share/backend/database"
+	"github.com/ito-system/clear-up-share/backend/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	recurringPollInterval = time.Hour // 定期的な支出の登録を確認する間隔
+	recurringBatchSize    = 100       // 1回の確認で処理する定期支出の最大数
+	recurringCatchUpLimit = 31        // 停止中に過ぎた回をまとめて登録する1回あたりの上限
+)
+
+// RecurringExpenseInput は定期的な支出の作成・更新リクエストの入力形式
+// 分け方は支出と同じですが、品目ごとに分ける itemized は使えません
+type RecurringExpenseInput struct {
+	Description string       `json:"description" binding:"required"`
+	Amount      float64      `json:"amount" binding:"required,gt=0"`
+	PayerID     uint         `json:"payerID" binding:"required"`
+	SplitType   string       `json:"splitType" binding:"omitempty,oneof=equal exact percentage"`
+	MemberIDs   []uint       `json:"memberIDs" binding:"required_without=Splits,omitempty,min=1"`
+	Splits      []SplitInput `json:"splits" binding:"omitempty,min=1,dive"`
+	CategoryID  *uint        `json:"categoryID"`
+	Cadence     string       `json:"cadence" binding:"required,oneof=weekly monthly yearly"`
+	StartDate   string       `json:"startDate" binding:"required"`
+	EndDate     string       `json:"endDate"`
+	Paused      bool         `json:"paused"`
+}
+
+// RecurringExpenseResponse は定期的な支出のレスポンス形式
+type RecurringExpenseResponse struct {
+	ID          uint         `json:"id"`
+	GroupID     uint         `json:"groupID"`
+	CreatedByID uint         `json:"createdByID"`
+	PayerID     uint         `json:"payerID"`
+	Amount      float64      `json:"amount"`
+	Description string       `json:"description"`
+	SplitType   string       `json:"splitType"`
+	MemberIDs   []uint       `json:"memberIDs"`
+	Splits      []SplitInput `json:"splits,omitempty"` // exact / percentage の場合の負担者ごとの負担額・割合
+	CategoryID  *uint        `json:"categoryID"`
+	Cadence     string       `json:"cadence"`
+	StartDate   string       `json:"startDate"`
+	EndDate     *string      `json:"endDate"`
+	NextRunDate *string      `json:"nextRunDate"` // 終了日を過ぎた場合は null
+	LastRunAt   *time.Time   `json:"lastRunAt"`
+	Paused      bool         `json:"paused"`
+	LastError   string       `json:"lastError,omitempty"`
+}
+
+// recurringOccurrence は開始日から数えて n 回目（0 が開始日）の日付を返します
+// 毎月・毎年の場合、その月に開始日と同じ日がなければ月末の日付にします
+func recurringOccurrence(start time.Time, cadence string, n int) time.Time {
+	switch cadence {
+	case models.CadenceWeekly:
+		return start.AddDate(0, 0, 7*n)
+	case models.CadenceYearly:
+		n *= 12
+	}
+	first := time.Date(start.Year(), start.Month()+time.Month(n), 1, 0, 0, 0, 0, start.Location())
+	lastDay := first.AddDate(0, 1, -1).Day()
+	day := start.Day()
+	if day > lastDay {
+		day = lastDay
+	}
+	return first.AddDate(0, 0, day-1)
+}
+
+// scheduleRecurringExpense は today 以降で最初の回を次に登録する回にします（過去の回はさかのぼって登録しません）
+func scheduleRecurringExpense(r *models.RecurringExpense, today time.Time) {
+	n := 0
+	for recurringOccurrence(r.StartDate, r.Cadence, n).Before(today) {
+		n++
+	}
+	r.RunCount = n
+	r.NextRunDate = recurringOccurrence(r.StartDate, r.Cadence, n)
+}
+
+// recurringToday は定期的な支出の日付と比較する今日の日付（UTC の0時）を返します
+func recurringToday(now time.Time) time.Time {
+	now = now.UTC()
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// expenseInput は入力を支出の負担額の計算に使う形式に変換します
+func (input RecurringExpenseInput) expenseInput() AddExpenseInput {
+	return AddExpenseInput{
+		Description: input.Description,
+		Amount:      input.Amount,
+		PayerID:     input.PayerID,
+		Date:        input.StartDate,
+		SplitType:   input.SplitType,
+		MemberIDs:   input.MemberIDs,
+		Splits:      input.Splits,
+		CategoryID:  input.CategoryID,
+	}
+}
+
+// recurringTemplateSplits は定期的な支出に保存されている負担者の指定を支出の入力形式に戻します
+func recurringTemplateSplits(r models.RecurringExpense) AddExpenseInput {
+	input := AddExpenseInput{
+		Description: r.Description,
+		Amount:      r.Amount,
+		PayerID:     r.PayerID,
+		SplitType:   r.SplitType,
+		CategoryID:  r.CategoryID,
+	}
+	input.MemberIDs, input.Splits = decodeSplitTemplate(r.SplitType, r.MemberIDs, r.Shares)
+	return input
+}
+
+// applyRecurringTemplate は計算済みの負担者ごとのSplitを定期的な支出に保存する形式にします
+func applyRecurringTemplate(r *models.RecurringExpense, splitType string, splits []models.Split) {
+	r.SplitType = splitType
+	r.MemberIDs, r.Shares = encodeSplitTemplate(splitType, splits)
+}
+
+// recurringExpenseResponse は定期的な支出をレスポンス形式に変換します
+func recurringExpenseResponse(r models.RecurringExpense) RecurringExpenseResponse {
+	template := recurringTemplateSplits(r)
+	response := RecurringExpenseResponse{
+		ID:          r.ID,
+		GroupID:     r.GroupID,
+		CreatedByID: r.CreatedByID,
+		PayerID:     r.PayerID,
+		Amount:      r.Amount,
+		Description: r.Description,
+		SplitType:   r.SplitType,
+		MemberIDs:   template.MemberIDs,
+		Splits:      template.Splits,
+		CategoryID:  r.CategoryID,
+		Cadence:     r.Cadence,
+		StartDate:   r.StartDate.Format("2006-01-02"),
+		LastRunAt:   r.LastRunAt,
+		Paused:      r.PausedAt != nil,
+		LastError:   r.LastError,
+	}
+	for _, split := range template.Splits {
+		response.MemberIDs = append(response.MemberIDs, split.MemberID)
+	}
+	if r.EndDate != nil {
+		endDate := r.EndDate.Format("2006-01-02")
+		response.EndDate = &endDate
+	}
+	if r.EndDate == nil || !r.NextRunDate.After(*r.EndDate) {
+		next := r.NextRunDate.Format("2006-01-02")
+		response.NextRunDate = &next
+	}
+	return response
+}
+
+// recurringEditPermission は定期的な支出の編集・削除に必要な権限を返します（自分が作成したものは PermEditOwnExpense）
+func recurringEditPermission(r models.RecurringExpense, userID uint) Permission {
+	if r.CreatedByID == userID {
+		return PermEditOwnExpense
+	}
+	return PermEditAnyExpense
+}
+
+// parseRecurringDates は開始日と終了日をパースします
+func parseRecurringDates(input RecurringExpenseInput) (time.Time, *time.Time, error) {
+	start, err := time.Parse("2006-01-02", input.StartDate)
+	if err != nil {
+		return time.Time{}, nil, errors.New("Invalid start date format. Use YYYY-MM-DD")
+	}
+	if input.EndDate == "" {
+		return start, nil, nil
+	}
+	end, err := time.Parse("2006-01-02", input.EndDate)
+	if err != nil {
+		return time.Time{}, nil, errors.New("Invalid end date format. Use YYYY-MM-DD")
+	}
+	if end.Before(start) {
+		return time.Time{}, nil, errors.New("End date must not be before start date")
+	}
+	return start, &end, nil
+}
+
+// GetRecurringExpenses はグループの定期的な支出の一覧を次に登録する日付の順に取得します
+// GET /api/v1/groups/:groupID/recurring-expenses
+func GetRecurringExpenses(c *gin.Context) {
+	// パスパラメータからgroupIDを取得
+	groupIDStr := c.Param("groupID")
+	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// ユーザーがグループのメンバーであることを確認
+	var membership models.Membership
+	if err := database.DB.Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	var recurring []models.RecurringExpense
+	if err := database.DB.Where("group_id = ?", groupID).Order("next_run_date, id").Find(&recurring).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch recurring expenses"})
+		return
+	}
+
+	responses := make([]RecurringExpenseResponse, len(recurring))
+	for i, r := range recurring {
+		responses[i] = recurringExpenseResponse(r)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"groupID":           groupID,
+		"recurringExpenses": responses,
+	})
+}
+
+// CreateRecurringExpense は定期的な支出を作成します
+// 開始日が過去の場合、今日より前の回はさかのぼって登録しません
+// POST /api/v1/groups/:groupID/recurring-expenses
+func CreateRecurringExpense(c *gin.Context) {
+	// パスパラメータからgroupIDを取得
+	groupIDStr := c.Param("groupID")
+	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// ユーザーがグループのメンバーであることを確認
+	var membership models.Membership
+	if err := database.DB.Preload("Group").Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	// アーカイブ済みのグループは読み取り専用
+	if membership.Group.ArchivedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Group is archived and read-only"})
+		return
+	}
+
+	// 支出を追加する権限があることを確認
+	if !hasPermission(membership.Role, PermAddExpense) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to add expenses"})
+		return
+	}
+
+	// リクエストボディをバインド
+	var input RecurringExpenseInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	start, end, err := parseRecurringDates(input)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// グループのポリシー設定を取得
+	settings, err := loadGroupSettings(database.DB, uint(groupID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch group settings"})
+		return
+	}
+
+	// 負担者ごとの負担額を決定（指定がなければ均等割り）
+	expenseInput := input.expenseInput()
+	splits, _, err := expenseSplits(expenseInput, settings.RoundingMode, membership.Group.Currency)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// トランザクション開始
+	tx := database.DB.Begin()
+
+	// 支払者と負担者がグループのメンバーであることを確認
+	nonMembers, err := lockGroupNonMembers(tx, uint(groupID), append([]uint{input.PayerID}, splitDebtorIDs(splits)...))
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check members"})
+		return
+	}
+	if len(nonMembers) > 0 {
+		tx.Rollback()
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":  "Payer and split members must belong to this group",
+			"fields": expenseNonMemberFields(expenseInput, splits, nonMembers),
+		})
+		return
+	}
+
+	// カテゴリがグループのものであることを確認
+	if err := lockExpenseCategory(tx, uint(groupID), input.CategoryID); err != nil {
+		tx.Rollback()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Category not found in this group"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check category"})
+		return
+	}
+
+	recurring := models.RecurringExpense{
+		GroupID:     uint(groupID),
+		CreatedByID: userID.(uint),
+		PayerID:     input.PayerID,
+		Amount:      input.Amount,
+		Description: input.Description,
+		CategoryID:  input.CategoryID,
+		Cadence:     input.Cadence,
+		StartDate:   start,
+		EndDate:     end,
+	}
+	applyRecurringTemplate(&recurring, resolveSplitType(expenseInput), splits)
+	scheduleRecurringExpense(&recurring, recurringToday(time.Now()))
+	if input.Paused {
+		now := time.Now()
+		recurring.PausedAt = &now
+	}
+
+	if err := tx.Create(&recurring).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create recurring expense"})
+		return
+	}
+
+	tx.Commit()
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":          "Recurring expense created successfully",
+		"recurringExpense": recurringExpenseResponse(recurring),
+	})
+}
+
+// UpdateRecurringExpense は定期的な支出を更新します
+// 開始日・周期を変更した場合や一時停止を解除した場合は、今日以降で最初の回から登録し直します
+// PUT /api/v1/groups/:groupID/recurring-expenses/:recurringID
+func UpdateRecurringExpense(c *gin.Context) {
+	// パスパラメータからgroupIDとrecurringIDを取得
+	groupIDStr := c.Param("groupID")
+	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	recurringIDStr := c.Param("recurringID")
+	recurringID, err := strconv.ParseUint(recurringIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid recurring expense ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// ユーザーがグループのメンバーであることを確認
+	var membership models.Membership
+	if err := database.DB.Preload("Group").Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	// アーカイブ済みのグループは読み取り専用
+	if membership.Group.ArchivedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Group is archived and read-only"})
+		return
+	}
+
+	// リーガルホールド中のグループでは記録を変更できない
+	if rejectIfLegalHold(c, membership.Group) {
+		return
+	}
+
+	// リクエストボディをバインド
+	var input RecurringExpenseInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	start, end, err := parseRecurringDates(input)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// グループのポリシー設定を取得
+	settings, err := loadGroupSettings(database.DB, uint(groupID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch group settings"})
+		return
+	}
+
+	// 負担者ごとの負担額を決定（指定がなければ均等割り）
+	expenseInput := input.expenseInput()
+	splits, _, err := expenseSplits(expenseInput, settings.RoundingMode, membership.Group.Currency)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// トランザクション開始
+	tx := database.DB.Begin()
+
+	// スケジューラーが登録中の場合は完了を待つ
+	var recurring models.RecurringExpense
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ? AND group_id = ?", recurringID, groupID).First(&recurring).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusNotFound, gin.H{"error": "Recurring expense not found"})
+		return
+	}
+
+	// 定期的な支出を編集する権限があることを確認
+	if !hasPermission(membership.Role, recurringEditPermission(recurring, userID.(uint))) {
+		tx.Rollback()
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to edit this recurring expense"})
+		return
+	}
+
+	// メンバーによる編集が許可されていない場合は、管理者のみ編集できる
+	if !settings.AllowMemberEdit && !hasPermission(membership.Role, PermEditAnyExpense) {
+		tx.Rollback()
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only admins can edit expenses in this group"})
+		return
+	}
+
+	// 支払者と負担者がグループのメンバーであることを確認
+	nonMembers, err := lockGroupNonMembers(tx, uint(groupID), append([]uint{input.PayerID}, splitDebtorIDs(splits)...))
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check members"})
+		return
+	}
+	if len(nonMembers) > 0 {
+		tx.Rollback()
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":  "Payer and split members must belong to this group",
+			"fields": expenseNonMemberFields(expenseInput, splits, nonMembers),
+		})
+		return
+	}
+
+	// カテゴリがグループのものであることを確認
+	if err := lockExpenseCategory(tx, uint(groupID), input.CategoryID); err != nil {
+		tx.Rollback()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Category not found in this group"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check category"})
+		return
+	}
+
+	reschedule := !recurring.StartDate.Equal(start) || recurring.Cadence != input.Cadence ||
+		(recurring.PausedAt != nil && !input.Paused)
+
+	recurring.PayerID = input.PayerID
+	recurring.Amount = input.Amount
+	recurring.Description = input.Description
+	recurring.CategoryID = input.CategoryID
+	recurring.Cadence = input.Cadence
+	recurring.StartDate = start
+	recurring.EndDate = end
+	applyRecurringTemplate(&recurring, resolveSplitType(expenseInput), splits)
+	if reschedule {
+		scheduleRecurringExpense(&recurring, recurringToday(time.Now()))
+	}
+	switch {
+	case input.Paused && recurring.PausedAt == nil:
+		now := time.Now()
+		recurring.PausedAt = &now
+	case !input.Paused:
+		recurring.PausedAt = nil
+		recurring.LastError = ""
+	}
+
+	if err := tx.Save(&recurring).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update recurring expense"})
+		return
+	}
+
+	tx.Commit()
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":          "Recurring expense updated successfully",
+		"recurringExpense": recurringExpenseResponse(recurring),
+	})
+}
+
+// DeleteRecurringExpense は定期的な支出を削除します（登録済みの支出は残ります）
+// DELETE /api/v1/groups/:groupID/recurring-expenses/:recurringID
+func DeleteRecurringExpense(c *gin.Context) {
+	// パスパラメータからgroupIDとrecurringIDを取得
+	groupIDStr := c.Param("groupID")
+	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	recurringIDStr := c.Param("recurringID")
+	recurringID, err := strconv.ParseUint(recurringIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid recurring expense ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// ユーザーがグループのメンバーであることを確認
+	var membership models.Membership
+	if err := database.DB.Preload("Group").Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	// アーカイブ済みのグループは読み取り専用
+	if membership.Group.ArchivedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Group is archived and read-only"})
+		return
+	}
+
+	// リーガルホールド中のグループでは記録を変更できない
+	if rejectIfLegalHold(c, membership.Group) {
+		return
+	}
+
+	var recurring models.RecurringExpense
+	if err := database.DB.Where("id = ? AND group_id = ?", recurringID, groupID).First(&recurring).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Recurring expense not found"})
+		return
+	}
+
+	// 定期的な支出を削除する権限があることを確認
+	if !hasPermission(membership.Role, recurringEditPermission(recurring, userID.(uint))) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to delete this recurring expense"})
+		return
+	}
+
+	if err := database.DB.Delete(&recurring).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete recurring expense"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Recurring expense deleted successfully"})
+}
+
+// StartRecurringScheduler は定期的な支出を登録日になったら支出として登録するワーカーを起動します
+func StartRecurringScheduler() {
+	go func() {
+		for {
+			runDueRecurringExpenses(time.Now())
+			time.Sleep(recurringPollInterval)
+		}
+	}()
+}
+
+// runDueRecurringExpenses は登録日を過ぎた定期的な支出をまとめて登録します（アーカイブ済みのグループは除く）
+func runDueRecurringExpenses(now time.Time) {
+	today := recurringToday(now)
+
+	var due []models.RecurringExpense
+	if err := database.DB.
+		Where("paused_at IS NULL AND next_run_date <= ? AND (end_date IS NULL OR next_run_date <= end_date)", today).
+		Where("group_id NOT IN (SELECT id FROM groups WHERE archived_at IS NOT NULL)").
+		Order("next_run_date, id").
+		Limit(recurringBatchSize).
+		Find(&due).Error; err != nil {
+		log.Printf("Failed to fetch recurring expenses: %v", err)
+		return
+	}
+
+	for _, r := range due {
+		if err := materializeRecurringExpense(r.ID, today); err != nil {
+			log.Printf("Failed to create expense from recurring expense %d: %v", r.ID, err)
+		}
+	}
+}
+
+// materializeRecurringExpense は定期的な支出の登録日を過ぎた回の Expense と Split を作成します
+// 支払者・負担者・作成者がグループを抜けた場合や負担額を計算できない場合は、一時停止して作成者に通知します
+func materializeRecurringExpense(recurringID uint, today time.Time) error {
+	tx := database.DB.Begin()
+
+	// 複数のサーバーで動かしている場合に同じ回を二重に登録しない
+	var r models.RecurringExpense
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).Preload("Group").First(&r, recurringID).Error; err != nil {
+		tx.Rollback()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	settings, err := loadGroupSettings(tx, r.GroupID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	pause := func(reason string) error {
+		now := time.Now()
+		r.PausedAt = &now
+		r.LastError = reason
+		message := "Recurring expense \"" + r.Description + "\" was paused: " + reason
+		return notify(tx, r.CreatedByID, r.GroupID, models.NotificationRecurringExpensePaused, message, "recurring_expense", r.ID)
+	}
+
+	var creator models.Membership
+	err = tx.Where("user_id = ? AND group_id = ?", r.CreatedByID, r.GroupID).First(&creator).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		tx.Rollback()
+		return err
+	}
+
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		if err := pause("its creator is no longer a member of this group"); err != nil {
+			tx.Rollback()
+			return err
+		}
+	case !hasPermission(creator.Role, PermAddExpense):
+		if err := pause("its creator can no longer add expenses"); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	for created := 0; r.PausedAt == nil && created < recurringCatchUpLimit; created++ {
+		if r.NextRunDate.After(today) || (r.EndDate != nil && r.NextRunDate.After(*r.EndDate)) {
+			break
+		}
+
+		// 今月の支出数が上限に達している場合は次回の確認まで待つ
+		if err := checkExpenseQuota(tx, r.GroupID, settings, 1); err != nil {
+			r.LastError = err.Error()
+			break
+		}
+
+		template := recurringTemplateSplits(r)
+		splits, _, err := expenseSplits(template, settings.RoundingMode, r.Group.Currency)
+		if err != nil {
+			if err := pause(err.Error()); err != nil {
+				tx.Rollback()
+				return err
+			}
+			break
+		}
+
+		nonMembers, err := lockGroupNonMembers(tx, r.GroupID, append([]uint{r.PayerID}, splitDebtorIDs(splits)...))
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		if len(nonMembers) > 0 {
+			if err := pause("its payer or split members are no longer in this group"); err != nil {
+				tx.Rollback()
+				return err
+			}
+			break
+		}
+
+		// 作成者が支出を登録した場合と同じく、承認が必要な場合は承認待ちにする
+		status := models.ExpenseStatusConfirmed
+		if settings.RequireExpenseApproval && !hasPermission(creator.Role, PermApproveExpense) {
+			status = models.ExpenseStatusPending
+		}
+		needsPayerApproval, err := requiresPayerApproval(r.PayerID, r.CreatedByID)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		if needsPayerApproval {
+			status = models.ExpenseStatusAwaitingPayer
+		}
+
+		expense := models.Expense{
+			GroupID:            r.GroupID,
+			PayerID:            r.PayerID,
+			Amount:             r.Amount,
+			Description:        r.Description,
+			Date:               r.NextRunDate,
+			Status:             status,
+			CreatedByID:        r.CreatedByID,
+			CategoryID:         r.CategoryID,
+			RecurringExpenseID: &r.ID,
+		}
+		if err := tx.Create(&expense).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+
+		for _, split := range splits {
+			split.ExpenseID = expense.ID
+			if err := tx.Create(&split).Error; err != nil {
+				tx.Rollback()
+				return err
+			}
+		}
+
+		if needsPayerApproval {
+			if err := notifyPayerApproval(tx, expense); err != nil {
+				tx.Rollback()
+				return err
+			}
+		}
+
+		if err := recordActivity(tx, expense.GroupID, r.CreatedByID, models.ActivityExpenseAdded, "expense", expense.ID, map[string]interface{}{
+			"description":        expense.Description,
+			"amount":             expense.Amount,
+			"payerID":            expense.PayerID,
+			"recurringExpenseID": r.ID,
+		}); err != nil {
+			tx.Rollback()
+			return err
+		}
+
+		now := time.Now()
+		r.RunCount++
+		r.NextRunDate = recurringOccurrence(r.StartDate, r.Cadence, r.RunCount)
+		r.LastRunAt = &now
+		r.LastError = ""
+	}
+
+	if err := tx.Omit("Group").Save(&r).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
@@ -0,0 +1,438 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ito-system/clear-up-share/backend/analytics"
+	"github.com/ito-system/clear-up-share/backend/database"
+	"github.com/ito-system/clear-up-share/backend/models"
+)
+
+// CreateReimbursementRequestInput は精算依頼の作成リクエストの入力形式
+type CreateReimbursementRequestInput struct {
+	DebtorID uint   `json:"debtorID" binding:"required"`
+	Note     string `json:"note" binding:"max=200"`
+}
+
+// CreateReimbursementRequest は支出の支払者が負担者に負担額の精算を依頼し、負担者に通知します
+// 同じ支出と負担者に未完了（requested / paid）の依頼がある場合は作成できません
+// POST /api/v1/groups/:groupID/expenses/:expenseID/reimbursement-requests
+func CreateReimbursementRequest(c *gin.Context) {
+	// パスパラメータからgroupIDとexpenseIDを取得
+	groupIDStr := c.Param("groupID")
+	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	expenseIDStr := c.Param("expenseID")
+	expenseID, err := strconv.ParseUint(expenseIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid expense ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// ユーザーがグループのメンバーであることを確認
+	var membership models.Membership
+	if err := database.DB.Preload("Group").Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	// アーカイブ済みのグループは読み取り専用
+	if membership.Group.ArchivedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Group is archived and read-only"})
+		return
+	}
+
+	// 清算を記録する権限があることを確認
+	if !hasPermission(membership.Role, PermRecordSettlement) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to record settlements"})
+		return
+	}
+
+	// リクエストボディをバインド
+	var input CreateReimbursementRequestInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Expenseを取得（負債計算に含まれる確定済みの支出のみ）
+	var expense models.Expense
+	if err := database.DB.Where("id = ? AND group_id = ? AND status = ?", expenseID, groupID, models.ExpenseStatusConfirmed).First(&expense).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Expense not found"})
+		return
+	}
+
+	// 支出の支払者のみ精算を依頼できる
+	if expense.PayerID != userID.(uint) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the payer can request reimbursement for this expense"})
+		return
+	}
+	if input.DebtorID == expense.PayerID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Debtor and requester cannot be the same"})
+		return
+	}
+
+	// 依頼額は負担者の負担額（返金など負担額が正でない場合は依頼できない）
+	var split models.Split
+	if err := database.DB.Where("expense_id = ? AND debtor_id = ?", expense.ID, input.DebtorID).First(&split).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Debtor has no share in this expense"})
+		return
+	}
+	if split.AmountDue <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Debtor owes nothing for this expense"})
+		return
+	}
+
+	// トランザクション開始
+	tx := database.DB.Begin()
+
+	// 依頼の完了まで依頼者・負担者が退会・除名されないようにする
+	ok, err := lockGroupMembers(tx, uint(groupID), []uint{expense.PayerID, input.DebtorID})
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check members"})
+		return
+	}
+	if !ok {
+		tx.Rollback()
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Debtor is not a member of this group"})
+		return
+	}
+
+	// 同じ支出と負担者に未完了の依頼がないことを確認
+	var open int64
+	if err := tx.Model(&models.ReimbursementRequest{}).
+		Where("expense_id = ? AND debtor_id = ? AND status IN ?", expense.ID, input.DebtorID, []string{models.ReimbursementStatusRequested, models.ReimbursementStatusPaid}).
+		Count(&open).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check reimbursement requests"})
+		return
+	}
+	if open > 0 {
+		tx.Rollback()
+		c.JSON(http.StatusConflict, gin.H{"error": "A reimbursement request for this expense is already open"})
+		return
+	}
+
+	request := models.ReimbursementRequest{
+		GroupID:     uint(groupID),
+		ExpenseID:   expense.ID,
+		RequesterID: expense.PayerID,
+		DebtorID:    input.DebtorID,
+		Amount:      split.AmountDue,
+		Note:        strings.TrimSpace(input.Note),
+		Status:      models.ReimbursementStatusRequested,
+	}
+	if err := tx.Create(&request).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create reimbursement request"})
+		return
+	}
+
+	// 負担者に精算を依頼
+	message := "You were asked to reimburse " + strconv.FormatFloat(request.Amount, 'f', -1, 64) + " " + membership.Group.Currency + " for \"" + expense.Description + "\""
+	if err := notify(tx, request.DebtorID, request.GroupID, models.NotificationReimbursementRequested, message, "reimbursement_request", request.ID); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send notification"})
+		return
+	}
+
+	// アクティビティを記録
+	if err := recordActivity(tx, request.GroupID, userID.(uint), models.ActivityReimbursementRequested, "reimbursement_request", request.ID, map[string]interface{}{
+		"expenseID": request.ExpenseID,
+		"debtorID":  request.DebtorID,
+		"amount":    request.Amount,
+	}); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record activity"})
+		return
+	}
+
+	tx.Commit()
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":              "Reimbursement requested successfully",
+		"reimbursementRequest": reimbursementRequestResponse(request, membership.Group.Currency),
+	})
+}
+
+// GetReimbursementRequests は自分が依頼した、または依頼された精算依頼を新しい順に取得します
+// GET /api/v1/groups/:groupID/reimbursement-requests?status=
+func GetReimbursementRequests(c *gin.Context) {
+	// パスパラメータからgroupIDを取得
+	groupIDStr := c.Param("groupID")
+	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// ユーザーがグループのメンバーであることを確認
+	var membership models.Membership
+	if err := database.DB.Preload("Group").Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	query := database.DB.Where("group_id = ? AND (requester_id = ? OR debtor_id = ?)", groupID, userID, userID)
+	if status := c.Query("status"); status != "" {
+		switch status {
+		case models.ReimbursementStatusRequested, models.ReimbursementStatusPaid, models.ReimbursementStatusConfirmed:
+			query = query.Where("status = ?", status)
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status"})
+			return
+		}
+	}
+
+	var requests []models.ReimbursementRequest
+	if err := query.Order("created_at DESC, id DESC").Find(&requests).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reimbursement requests"})
+		return
+	}
+
+	responses := make([]gin.H, len(requests))
+	for i, request := range requests {
+		responses[i] = reimbursementRequestResponse(request, membership.Group.Currency)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"groupID":               groupID,
+		"reimbursementRequests": responses,
+	})
+}
+
+// PayReimbursementRequest は依頼された負担者が依頼額を支払い、依頼者への清算を記録します
+// POST /api/v1/groups/:groupID/reimbursement-requests/:requestID/pay
+func PayReimbursementRequest(c *gin.Context) {
+	respondToReimbursementRequest(c, models.ReimbursementStatusPaid)
+}
+
+// ConfirmReimbursementRequest は依頼者が支払いの受け取りを確認し、精算依頼を完了します
+// POST /api/v1/groups/:groupID/reimbursement-requests/:requestID/confirm
+func ConfirmReimbursementRequest(c *gin.Context) {
+	respondToReimbursementRequest(c, models.ReimbursementStatusConfirmed)
+}
+
+// respondToReimbursementRequest は精算依頼を次のステータス（paid / confirmed）に進めます
+// paid にできるのは requested の依頼の負担者、confirmed にできるのは paid の依頼の依頼者のみです
+func respondToReimbursementRequest(c *gin.Context, next string) {
+	// パスパラメータからgroupIDとrequestIDを取得
+	groupIDStr := c.Param("groupID")
+	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	requestIDStr := c.Param("requestID")
+	requestID, err := strconv.ParseUint(requestIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid reimbursement request ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// ユーザーがグループのメンバーであることを確認
+	var membership models.Membership
+	if err := database.DB.Preload("Group").Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	// アーカイブ済みのグループは読み取り専用
+	if membership.Group.ArchivedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Group is archived and read-only"})
+		return
+	}
+
+	var request models.ReimbursementRequest
+	if err := database.DB.Where("id = ? AND group_id = ?", requestID, groupID).First(&request).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Reimbursement request not found"})
+		return
+	}
+
+	// 支払えるのは負担者、受け取りを確認できるのは依頼者のみ
+	current := models.ReimbursementStatusRequested
+	if next == models.ReimbursementStatusPaid {
+		if request.DebtorID != userID.(uint) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only the debtor can pay this reimbursement request"})
+			return
+		}
+		if !hasPermission(membership.Role, PermRecordSettlement) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to record settlements"})
+			return
+		}
+	} else {
+		current = models.ReimbursementStatusPaid
+		if request.RequesterID != userID.(uint) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only the requester can confirm this reimbursement request"})
+			return
+		}
+	}
+	if request.Status != current {
+		c.JSON(http.StatusConflict, gin.H{"error": "Reimbursement request is not " + current})
+		return
+	}
+
+	// トランザクション開始
+	tx := database.DB.Begin()
+
+	now := time.Now()
+	updates := map[string]interface{}{"status": next}
+	action := models.ActivityReimbursementConfirmed
+	var settlement models.Settlement
+	if next == models.ReimbursementStatusPaid {
+		// 支払いの完了まで依頼者・負担者が退会・除名されないようにする
+		ok, err := lockGroupMembers(tx, request.GroupID, []uint{request.DebtorID, request.RequesterID})
+		if err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check members"})
+			return
+		}
+		if !ok {
+			tx.Rollback()
+			c.JSON(http.StatusConflict, gin.H{"error": "Requester is no longer a member of this group"})
+			return
+		}
+
+		// 負担者から依頼者への清算を今日の日付で記録
+		settings, err := loadGroupSettings(tx, request.GroupID)
+		if err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch group settings"})
+			return
+		}
+		settlement = models.Settlement{
+			GroupID:    request.GroupID,
+			PayerID:    request.DebtorID,
+			ReceiverID: request.RequesterID,
+			Amount:     request.Amount,
+			Date:       groupToday(settings),
+		}
+		if err := tx.Create(&settlement).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create settlement"})
+			return
+		}
+		if err := recordActivity(tx, settlement.GroupID, userID.(uint), models.ActivitySettlementRecorded, "settlement", settlement.ID, map[string]interface{}{
+			"payerID":                settlement.PayerID,
+			"receiverID":             settlement.ReceiverID,
+			"amount":                 settlement.Amount,
+			"reimbursementRequestID": request.ID,
+		}); err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record activity"})
+			return
+		}
+
+		request.SettlementID = &settlement.ID
+		request.PaidAt = &now
+		updates["settlement_id"] = request.SettlementID
+		updates["paid_at"] = request.PaidAt
+		action = models.ActivityReimbursementPaid
+	} else {
+		request.ConfirmedAt = &now
+		updates["confirmed_at"] = request.ConfirmedAt
+	}
+
+	// ステータスを進める（同時に処理された場合は何もしない）
+	result := tx.Model(&models.ReimbursementRequest{}).Where("id = ? AND status = ?", request.ID, current).Updates(updates)
+	if result.Error != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update reimbursement request"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		tx.Rollback()
+		c.JSON(http.StatusConflict, gin.H{"error": "Reimbursement request is not " + current})
+		return
+	}
+	request.Status = next
+
+	// 依頼者に支払いを通知
+	if next == models.ReimbursementStatusPaid {
+		message := "A reimbursement of " + strconv.FormatFloat(request.Amount, 'f', -1, 64) + " " + membership.Group.Currency + " was paid and needs your confirmation"
+		if err := notify(tx, request.RequesterID, request.GroupID, models.NotificationReimbursementPaid, message, "reimbursement_request", request.ID); err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send notification"})
+			return
+		}
+	}
+
+	// アクティビティを記録
+	if err := recordActivity(tx, request.GroupID, userID.(uint), action, "reimbursement_request", request.ID, map[string]interface{}{
+		"expenseID":   request.ExpenseID,
+		"requesterID": request.RequesterID,
+		"debtorID":    request.DebtorID,
+		"amount":      request.Amount,
+	}); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record activity"})
+		return
+	}
+
+	tx.Commit()
+
+	if next == models.ReimbursementStatusPaid {
+		analytics.Track(analytics.EventSettlementRecorded, userID.(uint), map[string]interface{}{
+			"groupId": analytics.Anonymize("group", settlement.GroupID),
+		})
+	}
+
+	message := "Reimbursement request confirmed"
+	if next == models.ReimbursementStatusPaid {
+		message = "Reimbursement request paid"
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message":              message,
+		"reimbursementRequest": reimbursementRequestResponse(request, membership.Group.Currency),
+	})
+}
+
+// reimbursementRequestResponse は精算依頼のレスポンス形式を構築します
+func reimbursementRequestResponse(r models.ReimbursementRequest, currency string) gin.H {
+	return gin.H{
+		"id":           r.ID,
+		"groupID":      r.GroupID,
+		"expenseID":    r.ExpenseID,
+		"requesterID":  r.RequesterID,
+		"debtorID":     r.DebtorID,
+		"amount":       r.Amount,
+		"currency":     currency,
+		"note":         r.Note,
+		"status":       r.Status,
+		"settlementID": r.SettlementID,
+		"createdAt":    r.CreatedAt,
+		"paidAt":       r.PaidAt,
+		"confirmedAt":  r.ConfirmedAt,
+	}
+}
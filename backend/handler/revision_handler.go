@@ -0,0 +1,215 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ito-system/clear-up-share/backend/database"
+	"github.com/ito-system/clear-up-share/backend/models"
+	"gorm.io/gorm"
+)
+
+// ExpenseSnapshot は支出のある時点の内容（ExpenseRevision に保存する形式）
+type ExpenseSnapshot struct {
+	Description     string          `json:"description"`
+	Notes           string          `json:"notes,omitempty"`
+	Amount          float64         `json:"amount"`
+	Type            string          `json:"type"`
+	PayerID         uint            `json:"payerID"`
+	Date            string          `json:"date"`
+	OccurredAt      *time.Time      `json:"occurredAt,omitempty"`
+	Latitude        *float64        `json:"latitude,omitempty"`
+	Longitude       *float64        `json:"longitude,omitempty"`
+	PlaceName       string          `json:"placeName,omitempty"`
+	Status          string          `json:"status"`
+	CategoryID      *uint           `json:"categoryID"`
+	ForeignCurrency string          `json:"foreignCurrency,omitempty"`
+	ForeignAmount   *float64        `json:"foreignAmount,omitempty"`
+	ExchangeRate    *float64        `json:"exchangeRate,omitempty"`
+	Tax             *float64        `json:"tax,omitempty"`
+	Tip             *float64        `json:"tip,omitempty"`
+	SurchargeSplit  string          `json:"surchargeSplit,omitempty"`
+	Tags            []string        `json:"tags"`
+	Splits          []SnapshotSplit `json:"splits"`
+	Items           []SnapshotItem  `json:"items,omitempty"`
+}
+
+// SnapshotSplit はスナップショットの負担者ごとの負担額
+type SnapshotSplit struct {
+	MemberID  uint     `json:"memberID"`
+	AmountDue float64  `json:"amountDue"`
+	Percent   *float64 `json:"percent,omitempty"`
+}
+
+// SnapshotItem はスナップショットの品目
+type SnapshotItem struct {
+	Name      string  `json:"name"`
+	Price     float64 `json:"price"`
+	MemberIDs []uint  `json:"memberIDs"`
+}
+
+// RevisionResponse は支出の編集履歴の1件の形式
+type RevisionResponse struct {
+	ID         uint            `json:"id"`
+	EditorID   uint            `json:"editorID"`
+	EditorName string          `json:"editorName"`
+	EditedAt   time.Time       `json:"editedAt"`
+	Before     json.RawMessage `json:"before"` // 編集前の内容（ExpenseSnapshot）
+}
+
+// snapshotExpense は支出の現在の内容（負担額・品目・タグを含む）をスナップショットにします
+func snapshotExpense(tx *gorm.DB, expense models.Expense) (ExpenseSnapshot, error) {
+	snapshot := ExpenseSnapshot{
+		Description:     expense.Description,
+		Notes:           expense.Notes,
+		Amount:          expense.Amount,
+		Type:            expense.Type,
+		PayerID:         expense.PayerID,
+		Date:            expense.Date.Format("2006-01-02"),
+		OccurredAt:      expense.OccurredAt,
+		Latitude:        expense.Latitude,
+		Longitude:       expense.Longitude,
+		PlaceName:       expense.PlaceName,
+		Status:          expense.Status,
+		CategoryID:      expense.CategoryID,
+		ForeignCurrency: expense.Currency,
+		ForeignAmount:   expense.ForeignAmount,
+		ExchangeRate:    expense.ExchangeRate,
+		Tax:             expense.Tax,
+		Tip:             expense.Tip,
+		SurchargeSplit:  expense.SurchargeSplit,
+		Tags:            []string{},
+		Splits:          []SnapshotSplit{},
+	}
+
+	var tags []models.Tag
+	if err := tx.Model(&expense).Order("name").Association("Tags").Find(&tags); err != nil {
+		return snapshot, err
+	}
+	snapshot.Tags = tagNames(tags)
+
+	var splits []models.Split
+	if err := tx.Where("expense_id = ?", expense.ID).Order("id").Find(&splits).Error; err != nil {
+		return snapshot, err
+	}
+	for _, split := range splits {
+		snapshot.Splits = append(snapshot.Splits, SnapshotSplit{MemberID: split.DebtorID, AmountDue: split.AmountDue, Percent: split.Percent})
+	}
+
+	var items []models.ExpenseItem
+	if err := tx.Where("expense_id = ?", expense.ID).Order("id").Find(&items).Error; err != nil {
+		return snapshot, err
+	}
+	for _, item := range items {
+		memberIDs := []uint{}
+		for _, id := range strings.Fields(item.MemberIDs) {
+			if memberID, err := strconv.ParseUint(id, 10, 32); err == nil {
+				memberIDs = append(memberIDs, uint(memberID))
+			}
+		}
+		snapshot.Items = append(snapshot.Items, SnapshotItem{Name: item.Name, Price: item.Price, MemberIDs: memberIDs})
+	}
+	return snapshot, nil
+}
+
+// recordExpenseRevision は編集前の支出の内容を編集履歴に保存します（負担額・品目・タグを変更する前に呼び出します）
+func recordExpenseRevision(tx *gorm.DB, expense models.Expense, editorID uint) error {
+	snapshot, err := snapshotExpense(tx, expense)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	return tx.Create(&models.ExpenseRevision{
+		ExpenseID: expense.ID,
+		EditorID:  editorID,
+		Snapshot:  string(data),
+	}).Error
+}
+
+// GetExpenseRevisions は支出の編集履歴（誰がいつ編集したかと編集前の内容）を新しい順に取得します
+// current に現在の内容を返すので、各編集の変更内容は1つ新しい版（最新の編集は current）と比べて確認できます
+// GET /api/v1/groups/:groupID/expenses/:expenseID/revisions
+func GetExpenseRevisions(c *gin.Context) {
+	// パスパラメータからgroupIDとexpenseIDを取得
+	groupIDStr := c.Param("groupID")
+	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	expenseIDStr := c.Param("expenseID")
+	expenseID, err := strconv.ParseUint(expenseIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid expense ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// ユーザーがグループのメンバーであることを確認
+	var membership models.Membership
+	if err := database.DB.Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	// Expenseを取得
+	var expense models.Expense
+	if err := database.DB.Scopes(visibleExpenses(userID, true)).Where("id = ? AND group_id = ?", expenseID, groupID).First(&expense).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Expense not found"})
+		return
+	}
+
+	current, err := snapshotExpense(database.DB, expense)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch expense"})
+		return
+	}
+
+	var revisions []models.ExpenseRevision
+	if err := database.DB.Preload("Editor").Where("expense_id = ?", expense.ID).Order("created_at DESC, id DESC").Find(&revisions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch revisions"})
+		return
+	}
+
+	// グループ内の表示名を取得（退会済みのユーザーはユーザー名を表示）
+	names, err := groupDisplayNames(database.DB, uint(groupID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch members"})
+		return
+	}
+
+	responses := make([]RevisionResponse, len(revisions))
+	for i, revision := range revisions {
+		editorName, ok := names[revision.EditorID]
+		if !ok {
+			editorName = revision.Editor.Username
+		}
+		responses[i] = RevisionResponse{
+			ID:         revision.ID,
+			EditorID:   revision.EditorID,
+			EditorName: editorName,
+			EditedAt:   revision.CreatedAt,
+			Before:     json.RawMessage(revision.Snapshot),
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"expenseID": expense.ID,
+		"current":   current,
+		"revisions": responses,
+	})
+}
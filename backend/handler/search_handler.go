@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ito-system/clear-up-share/backend/database"
+	"github.com/ito-system/clear-up-share/backend/models"
+	"gorm.io/gorm"
+)
+
+// expenseSearchVector は支出の説明文の全文検索用の tsvector（database.InitDB で作成する GIN インデックスと同じ式）
+// 言語ごとの語幹処理はせず、空白や記号で区切った単語で一致させます
+const expenseSearchVector = "to_tsvector('simple', expenses.description)"
+
+// SearchResult は支出の検索結果の形式
+type SearchResult struct {
+	ID          uint      `json:"id"`
+	Description string    `json:"description"`
+	Amount      float64   `json:"amount"`
+	Date        string    `json:"date"`
+	PayerID     uint      `json:"payerID"`
+	Status      string    `json:"status"`
+	Rank        float64   `json:"rank"` // 一致の度合い（大きいほど上位）
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// SearchExpenses はグループの支出を説明文の全文検索で探し、一致の度合いの高い順に返します
+// q の単語を全て含む支出が対象です（例: "sushi dinner"、下書きは自分のもののみ）
+// GET /api/v1/groups/:groupID/search?q=&page=&limit=
+func SearchExpenses(c *gin.Context) {
+	// パスパラメータからgroupIDを取得
+	groupIDStr := c.Param("groupID")
+	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// ユーザーがグループのメンバーであることを確認
+	var membership models.Membership
+	if err := database.DB.Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	q := strings.TrimSpace(c.Query("q"))
+	if q == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Search query is required"})
+		return
+	}
+
+	page, limit := parsePagination(c, 20, 100)
+
+	query := database.DB.Model(&models.Expense{}).
+		Where("expenses.group_id = ?", groupID).
+		Scopes(visibleExpenses(userID, true)).
+		Where(expenseSearchVector+" @@ plainto_tsquery('simple', ?)", q)
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search expenses"})
+		return
+	}
+
+	var rows []struct {
+		models.Expense
+		Rank float64
+	}
+	if err := query.Session(&gorm.Session{}).
+		Select("expenses.*, ts_rank("+expenseSearchVector+", plainto_tsquery('simple', ?)) AS rank", q).
+		Order("rank DESC, date DESC, id DESC").
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Find(&rows).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search expenses"})
+		return
+	}
+
+	results := make([]SearchResult, len(rows))
+	for i, row := range rows {
+		results[i] = SearchResult{
+			ID:          row.ID,
+			Description: row.Description,
+			Amount:      row.Amount,
+			Date:        row.Date.Format("2006-01-02"),
+			PayerID:     row.PayerID,
+			Status:      row.Status,
+			Rank:        row.Rank,
+			CreatedAt:   row.CreatedAt,
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"groupID": groupID,
+		"query":   q,
+		"results": results,
+		"page":    page,
+		"limit":   limit,
+		"total":   total,
+	})
+}
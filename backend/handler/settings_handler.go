@@ -0,0 +1,245 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ito-system/clear-up-share/backend/database"
+	"github.com/ito-system/clear-up-share/backend/models"
+	"github.com/ito-system/clear-up-share/backend/utils"
+	"gorm.io/gorm"
+)
+
+// UpdateGroupSettingsInput はグループ設定更新リクエストの入力形式（指定されたフィールドのみ更新）
+type UpdateGroupSettingsInput struct {
+	RoundingMode           *string  `json:"roundingMode" binding:"omitempty,oneof=none round floor ceil"`
+	AllowMemberEdit        *bool    `json:"allowMemberEdit"`
+	RequireExpenseApproval *bool    `json:"requireExpenseApproval"`
+	MonthStartDay          *int     `json:"monthStartDay" binding:"omitempty,min=1,max=28"`
+	WeekStartDay           *int     `json:"weekStartDay" binding:"omitempty,min=0,max=6"`
+	AllowLeaveWithBalance  *bool    `json:"allowLeaveWithBalance"`
+	Timezone               *string  `json:"timezone"` // IANA タイムゾーン名（"Asia/Tokyo" など、空文字でサーバーのタイムゾーン）
+	ReminderCadence        *string  `json:"reminderCadence" binding:"omitempty,oneof=off weekly monthly"`
+	ReminderThreshold      *float64 `json:"reminderThreshold" binding:"omitempty,min=0"`
+	ClosingDay             *int     `json:"closingDay" binding:"omitempty,min=0,max=28"` // 0 で自動の締めを止める
+}
+
+// defaultGroupSettings は設定が未保存のグループに適用される既定値を返します
+func defaultGroupSettings(groupID uint) models.GroupSettings {
+	return models.GroupSettings{
+		GroupID:                groupID,
+		RoundingMode:           models.RoundingNone,
+		AllowMemberEdit:        true,
+		RequireExpenseApproval: false,
+		MonthStartDay:          1,
+		WeekStartDay:           int(time.Sunday),
+		AllowLeaveWithBalance:  false,
+		ReminderCadence:        models.ReminderCadenceOff,
+	}
+}
+
+// loadGroupSettings はグループ設定を取得します（未保存の場合は既定値）
+func loadGroupSettings(db *gorm.DB, groupID uint) (models.GroupSettings, error) {
+	var settings models.GroupSettings
+	err := db.Where("group_id = ?", groupID).First(&settings).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return defaultGroupSettings(groupID), nil
+	}
+	return settings, err
+}
+
+// groupLocation はグループのタイムゾーンを返します（未設定の場合はサーバーのタイムゾーン）
+func groupLocation(settings models.GroupSettings) *time.Location {
+	if settings.Timezone == "" {
+		return time.Local
+	}
+	loc, err := time.LoadLocation(settings.Timezone)
+	if err != nil {
+		return time.Local
+	}
+	return loc
+}
+
+// localDate は日時 t の loc での日付を、支出の日付と同じ形式（UTC の0時）で返します
+func localDate(t time.Time, loc *time.Location) time.Time {
+	local := t.In(loc)
+	return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// groupToday はグループのタイムゾーンでの今日の日付を返します
+func groupToday(settings models.GroupSettings) time.Time {
+	return localDate(time.Now(), groupLocation(settings))
+}
+
+// groupSettingsResponse はグループ設定のレスポンス形式を返します
+func groupSettingsResponse(settings models.GroupSettings) gin.H {
+	// 設定に基づく現在の月次・週次期間（グループのタイムゾーンでの日付、終了日は期間に含まれない）
+	now := time.Now().In(groupLocation(settings))
+	monthStart, monthEnd := utils.MonthPeriod(now, settings.MonthStartDay)
+	weekStart, weekEnd := utils.WeekPeriod(now, time.Weekday(settings.WeekStartDay))
+
+	return gin.H{
+		"groupID":                settings.GroupID,
+		"roundingMode":           settings.RoundingMode,
+		"allowMemberEdit":        settings.AllowMemberEdit,
+		"requireExpenseApproval": settings.RequireExpenseApproval,
+		"monthStartDay":          settings.MonthStartDay,
+		"weekStartDay":           settings.WeekStartDay,
+		"allowLeaveWithBalance":  settings.AllowLeaveWithBalance,
+		"timezone":               settings.Timezone,
+		"reminderCadence":        settings.ReminderCadence,
+		"reminderThreshold":      settings.ReminderThreshold,
+		"closingDay":             settings.ClosingDay,
+		"nextClosingDate":        formatOptionalDate(settings.NextClosingDate),
+		"currentMonth": gin.H{
+			"start": monthStart.Format("2006-01-02"),
+			"end":   monthEnd.Format("2006-01-02"),
+		},
+		"currentWeek": gin.H{
+			"start": weekStart.Format("2006-01-02"),
+			"end":   weekEnd.Format("2006-01-02"),
+		},
+	}
+}
+
+// GetGroupSettings はグループのポリシー設定を取得します
+// GET /api/v1/groups/:groupID/settings
+func GetGroupSettings(c *gin.Context) {
+	// パスパラメータからgroupIDを取得
+	groupIDStr := c.Param("groupID")
+	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// ユーザーがグループのメンバーであることを確認
+	var membership models.Membership
+	if err := database.DB.Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	settings, err := loadGroupSettings(database.DB, uint(groupID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch settings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"settings": groupSettingsResponse(settings),
+	})
+}
+
+// UpdateGroupSettings はグループのポリシー設定を更新します
+// PUT /api/v1/groups/:groupID/settings
+func UpdateGroupSettings(c *gin.Context) {
+	// パスパラメータからgroupIDを取得
+	groupIDStr := c.Param("groupID")
+	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// ユーザーがグループのメンバーであることを確認
+	var membership models.Membership
+	if err := database.DB.Preload("Group").Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	// アーカイブ済みのグループは読み取り専用
+	if membership.Group.ArchivedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Group is archived and read-only"})
+		return
+	}
+
+	// グループを管理する権限があることを確認
+	if !hasPermission(membership.Role, PermManageGroup) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to manage this group"})
+		return
+	}
+
+	// リクエストボディをバインド
+	var input UpdateGroupSettingsInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	settings, err := loadGroupSettings(database.DB, uint(groupID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch settings"})
+		return
+	}
+
+	// 指定されたフィールドのみ更新
+	if input.RoundingMode != nil {
+		settings.RoundingMode = *input.RoundingMode
+	}
+	if input.AllowMemberEdit != nil {
+		settings.AllowMemberEdit = *input.AllowMemberEdit
+	}
+	if input.RequireExpenseApproval != nil {
+		settings.RequireExpenseApproval = *input.RequireExpenseApproval
+	}
+	if input.MonthStartDay != nil {
+		settings.MonthStartDay = *input.MonthStartDay
+	}
+	if input.WeekStartDay != nil {
+		settings.WeekStartDay = *input.WeekStartDay
+	}
+	if input.AllowLeaveWithBalance != nil {
+		settings.AllowLeaveWithBalance = *input.AllowLeaveWithBalance
+	}
+	if input.Timezone != nil {
+		// "Local" はサーバーごとに異なるため受け付けない
+		if _, err := time.LoadLocation(*input.Timezone); err != nil || *input.Timezone == "Local" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid timezone"})
+			return
+		}
+		settings.Timezone = *input.Timezone
+	}
+	if input.ReminderCadence != nil {
+		settings.ReminderCadence = *input.ReminderCadence
+	}
+	if input.ReminderThreshold != nil {
+		settings.ReminderThreshold = *input.ReminderThreshold
+	}
+	if input.ClosingDay != nil {
+		settings.ClosingDay = *input.ClosingDay
+	}
+
+	// 締め日またはタイムゾーンを変更した場合は、次に自動で締める日付を計算し直す
+	if input.ClosingDay != nil || input.Timezone != nil {
+		settings.NextClosingDate = nextClosingDate(groupToday(settings), settings.ClosingDay)
+	}
+
+	if err := database.DB.Save(&settings).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update settings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Settings updated successfully",
+		"settings": groupSettingsResponse(settings),
+	})
+}
@@ -0,0 +1,231 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ito-system/clear-up-share/backend/database"
+	"github.com/ito-system/clear-up-share/backend/models"
+	"github.com/ito-system/clear-up-share/backend/money"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// allocatedSharesSQL は支出・負担者ごとに、取り消していない清算で支払った額の合計を集計するSQL
+const allocatedSharesSQL = `
+	SELECT settlement_allocations.expense_id, settlements.payer_id AS debtor_id, SUM(settlement_allocations.amount) AS allocated
+	FROM settlement_allocations
+	JOIN settlements ON settlements.id = settlement_allocations.settlement_id AND settlements.deleted_at IS NULL AND settlements.voided_at IS NULL
+	WHERE settlement_allocations.deleted_at IS NULL
+	GROUP BY settlement_allocations.expense_id, settlements.payer_id`
+
+// SettlementAllocationError は清算を指定された支出に充てられないことを表します
+type SettlementAllocationError struct {
+	ExpenseID uint
+	Message   string
+}
+
+func (e *SettlementAllocationError) Error() string {
+	return e.Message
+}
+
+// AllocationSummary は清算を充てた支出と額を表す形式
+type AllocationSummary struct {
+	ExpenseID uint    `json:"expenseID"`
+	Amount    float64 `json:"amount"`
+}
+
+// OpenExpenseShare は清算がまだ充てられていない支出の負担額を表す形式
+type OpenExpenseShare struct {
+	ExpenseID   uint      `json:"expenseID"`
+	Description string    `json:"description"`
+	Date        time.Time `json:"date"`
+	PayerID     uint      `json:"payerID"`
+	PayerName   string    `json:"payerName"`
+	DebtorID    uint      `json:"debtorID"`
+	DebtorName  string    `json:"debtorName"`
+	AmountDue   float64   `json:"amountDue"`
+	Allocated   float64   `json:"allocated"` // これまでの清算で支払った額
+	Open        float64   `json:"open"`      // 未払いの額
+}
+
+// allocationSummaries は清算を充てた支出の一覧をレスポンス形式に変換します
+func allocationSummaries(allocations []models.SettlementAllocation) []AllocationSummary {
+	summaries := make([]AllocationSummary, len(allocations))
+	for i, a := range allocations {
+		summaries[i] = AllocationSummary{ExpenseID: a.ExpenseID, Amount: a.Amount}
+	}
+	return summaries
+}
+
+// allocateSettlement は清算の額を指定された順に支出の送金者の未払いの負担額に充て、SettlementAllocation を作成します
+// 支出は受取者が支払った確定済みのもので、送金者の負担額が残っている必要があります
+// 全ての支出に充てても残った額は支出に充てずに貸借の清算として扱います
+func allocateSettlement(tx *gorm.DB, settlement models.Settlement, expenseIDs []uint, currency string) ([]models.SettlementAllocation, error) {
+	allocations := []models.SettlementAllocation{}
+	remaining := money.ToMinor(settlement.Amount, currency)
+	seen := make(map[uint]bool, len(expenseIDs))
+
+	for _, expenseID := range expenseIDs {
+		if seen[expenseID] {
+			return nil, &SettlementAllocationError{ExpenseID: expenseID, Message: "Duplicate expense ID"}
+		}
+		seen[expenseID] = true
+
+		if remaining == 0 {
+			return nil, &SettlementAllocationError{ExpenseID: expenseID, Message: "Settlement amount does not cover all listed expenses"}
+		}
+
+		var expense models.Expense
+		if err := tx.Where("id = ? AND group_id = ? AND status = ?", expenseID, settlement.GroupID, models.ExpenseStatusConfirmed).First(&expense).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, &SettlementAllocationError{ExpenseID: expenseID, Message: "Expense not found"}
+			}
+			return nil, err
+		}
+		if expense.PayerID != settlement.ReceiverID {
+			return nil, &SettlementAllocationError{ExpenseID: expenseID, Message: "Expense was not paid by the receiver"}
+		}
+
+		// 同じ負担額に同時に清算を充てないよう、送金者の負担額をロックする
+		var split models.Split
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("expense_id = ? AND debtor_id = ? AND amount_due > 0", expense.ID, settlement.PayerID).
+			First(&split).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, &SettlementAllocationError{ExpenseID: expenseID, Message: "Payer has no share in this expense"}
+			}
+			return nil, err
+		}
+
+		var allocated float64
+		if err := tx.Raw("SELECT COALESCE(SUM(allocated), 0) FROM ("+allocatedSharesSQL+") AS allocated_shares WHERE expense_id = ? AND debtor_id = ?",
+			expense.ID, settlement.PayerID).Scan(&allocated).Error; err != nil {
+			return nil, err
+		}
+
+		open := money.ToMinor(split.AmountDue, currency) - money.ToMinor(allocated, currency)
+		if open <= 0 {
+			return nil, &SettlementAllocationError{ExpenseID: expenseID, Message: "Payer's share of this expense is already settled"}
+		}
+		if open > remaining {
+			open = remaining
+		}
+		remaining -= open
+
+		allocation := models.SettlementAllocation{
+			SettlementID: settlement.ID,
+			ExpenseID:    expense.ID,
+			Amount:       money.FromMinor(open, currency),
+		}
+		if err := tx.Create(&allocation).Error; err != nil {
+			return nil, err
+		}
+		allocations = append(allocations, allocation)
+	}
+	return allocations, nil
+}
+
+// GetOpenExpenses は確定済みの支出のうち、清算がまだ充てられていない負担額の一覧を日付の新しい順に返します
+// ?debtorID= で負担者、?payerID= で支払者を絞り込めます
+// GET /api/v1/groups/:groupID/expenses/open
+func GetOpenExpenses(c *gin.Context) {
+	// パスパラメータからgroupIDを取得
+	groupIDStr := c.Param("groupID")
+	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// ユーザーがグループのメンバーであることを確認
+	var membership models.Membership
+	if err := database.DB.Preload("Group").Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	query := database.DB.Model(&models.Split{}).
+		Select("expenses.id AS expense_id, expenses.description, expenses.date, expenses.payer_id, splits.debtor_id, splits.amount_due, COALESCE(allocated_shares.allocated, 0) AS allocated").
+		Joins("JOIN expenses ON expenses.id = splits.expense_id AND expenses.deleted_at IS NULL").
+		Joins("LEFT JOIN ("+allocatedSharesSQL+") AS allocated_shares ON allocated_shares.expense_id = splits.expense_id AND allocated_shares.debtor_id = splits.debtor_id").
+		Where("expenses.group_id = ? AND expenses.status = ?", groupID, models.ExpenseStatusConfirmed).
+		Where("splits.debtor_id <> expenses.payer_id AND splits.amount_due > 0")
+
+	for _, filter := range []struct{ param, column string }{
+		{"debtorID", "splits.debtor_id"},
+		{"payerID", "expenses.payer_id"},
+	} {
+		value := c.Query(filter.param)
+		if value == "" {
+			continue
+		}
+		id, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + filter.param})
+			return
+		}
+		query = query.Where(filter.column+" = ?", id)
+	}
+
+	var rows []OpenExpenseShare
+	if err := query.Order("expenses.date DESC, expenses.id DESC, splits.debtor_id").Scan(&rows).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch open expenses"})
+		return
+	}
+
+	names, err := groupDisplayNames(database.DB, uint(groupID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch members"})
+		return
+	}
+
+	// 退会したメンバーはユーザー名で表示する
+	var leftIDs []uint
+	for _, row := range rows {
+		for _, id := range []uint{row.PayerID, row.DebtorID} {
+			if _, ok := names[id]; !ok {
+				leftIDs = append(leftIDs, id)
+			}
+		}
+	}
+	if len(leftIDs) > 0 {
+		var leftUsers []models.User
+		if err := database.DB.Where("id IN ?", leftIDs).Find(&leftUsers).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch members"})
+			return
+		}
+		for _, u := range leftUsers {
+			names[u.ID] = u.Username
+		}
+	}
+
+	currency := membership.Group.Currency
+	open := []OpenExpenseShare{}
+	for _, row := range rows {
+		units := money.ToMinor(row.AmountDue, currency) - money.ToMinor(row.Allocated, currency)
+		if units <= 0 {
+			continue
+		}
+		row.Open = money.FromMinor(units, currency)
+		row.PayerName = names[row.PayerID]
+		row.DebtorName = names[row.DebtorID]
+		open = append(open, row)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"groupID":  groupID,
+		"currency": currency,
+		"expenses": open,
+	})
+}
@@ -0,0 +1,362 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ito-system/clear-up-share/backend/analytics"
+	"github.com/ito-system/clear-up-share/backend/database"
+	"github.com/ito-system/clear-up-share/backend/models"
+	"github.com/ito-system/clear-up-share/backend/money"
+	"gorm.io/gorm"
+)
+
+// SettlementBatchReceiverInput は一括送金の受取者ごとの入力形式
+type SettlementBatchReceiverInput struct {
+	ReceiverID uint    `json:"receiverID" binding:"required"`
+	Amount     float64 `json:"amount" binding:"required,gt=0"` // currency を指定した場合はその通貨での金額
+	ExpenseIDs []uint  `json:"expenseIDs"`                     // この受取者への清算を充てる支出（省略可）
+}
+
+// AddSettlementBatchInput は複数の受取者にまとめて支払った清算を記録するリクエストの入力形式
+// 日付・メモ・支払い方法・通貨は全ての受取者への清算に共通です
+type AddSettlementBatchInput struct {
+	PayerID      uint                           `json:"payerID" binding:"required"`
+	Receivers    []SettlementBatchReceiverInput `json:"receivers" binding:"required,min=1,max=50,dive"`
+	Date         string                         `json:"date"` // 支払った日付（YYYY-MM-DD、省略時はグループのタイムゾーンでの今日）
+	Note         string                         `json:"note" binding:"max=200"`
+	Method       string                         `json:"method" binding:"omitempty,oneof=cash bank_transfer paypay other"`
+	Currency     string                         `json:"currency"` // 支払った通貨（省略した場合はグループの通貨）
+	ExchangeRate float64                        `json:"exchangeRate" binding:"omitempty,gt=0"`
+}
+
+// SettlementBatchItem は一括送金に含まれる受取者ごとの清算の形式
+type SettlementBatchItem struct {
+	ID              uint                `json:"id"`
+	ReceiverID      uint                `json:"receiverID"`
+	ReceiverName    string              `json:"receiverName"`
+	Amount          float64             `json:"amount"`
+	ForeignAmount   *float64            `json:"foreignAmount,omitempty"`
+	ForeignCurrency string              `json:"foreignCurrency,omitempty"`
+	ExchangeRate    *float64            `json:"exchangeRate,omitempty"`
+	Status          string              `json:"status"`
+	Allocations     []AllocationSummary `json:"allocations"`
+	VoidedAt        *time.Time          `json:"voidedAt,omitempty"` // 個別に取り消した清算
+}
+
+// SettlementBatchResponse は一括送金の形式
+type SettlementBatchResponse struct {
+	ID          uint                  `json:"id"`
+	GroupID     uint                  `json:"groupID"`
+	PayerID     uint                  `json:"payerID"`
+	PayerName   string                `json:"payerName"`
+	TotalAmount float64               `json:"totalAmount"` // 取り消していない清算の合計額
+	Currency    string                `json:"currency"`
+	Date        string                `json:"date"`
+	Note        string                `json:"note"`
+	Method      string                `json:"method"`
+	Settlements []SettlementBatchItem `json:"settlements"`
+	CreatedAt   time.Time             `json:"createdAt"`
+}
+
+// RecordSettlementBatch は1人の送金者が複数の受取者にまとめて支払った清算を1つのトランザクションで記録します
+// 受取者ごとの清算を SettlementBatch に紐付けて作成し、1回の支払いとして返します（いずれかの清算を記録できない場合は何も記録しません）
+// POST /api/v1/groups/:groupID/settlement-batches
+func RecordSettlementBatch(c *gin.Context) {
+	// パスパラメータからgroupIDを取得
+	groupIDStr := c.Param("groupID")
+	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// ユーザーがグループのメンバーであることを確認
+	var membership models.Membership
+	if err := database.DB.Preload("Group").Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	// アーカイブ済みのグループは読み取り専用
+	if membership.Group.ArchivedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Group is archived and read-only"})
+		return
+	}
+
+	// 清算を記録する権限があることを確認
+	if !hasPermission(membership.Role, PermRecordSettlement) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to record settlements"})
+		return
+	}
+
+	// リクエストボディをバインド
+	var input AddSettlementBatchInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 受取者は送金者以外で、同じ受取者を重複して指定できない
+	seen := make(map[uint]bool, len(input.Receivers))
+	for _, r := range input.Receivers {
+		if r.ReceiverID == input.PayerID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Payer and receiver cannot be the same"})
+			return
+		}
+		if seen[r.ReceiverID] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Each receiver can only be specified once", "receiverID": r.ReceiverID})
+			return
+		}
+		seen[r.ReceiverID] = true
+	}
+
+	// グループのポリシー設定を取得
+	settings, err := loadGroupSettings(database.DB, uint(groupID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch group settings"})
+		return
+	}
+
+	// 支払った日付を決定（省略時は今日、未来の日付は不可）
+	date := groupToday(settings)
+	if input.Date != "" {
+		date, err = time.Parse("2006-01-02", input.Date)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format. Use YYYY-MM-DD"})
+			return
+		}
+		if date.After(groupToday(settings)) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Settlement date cannot be in the future"})
+			return
+		}
+	}
+
+	// 受取者ごとのSettlementを作成（異なる通貨で支払った場合は同じレートで換算する）
+	settlements := make([]models.Settlement, len(input.Receivers))
+	for i, r := range input.Receivers {
+		settlements[i] = models.Settlement{
+			GroupID:    uint(groupID),
+			PayerID:    input.PayerID,
+			ReceiverID: r.ReceiverID,
+			Date:       date,
+			Note:       strings.TrimSpace(input.Note),
+			Method:     input.Method,
+			Status:     models.SettlementStatusConfirmed,
+		}
+		amountInput := AddSettlementInput{Amount: r.Amount, Currency: input.Currency, ExchangeRate: input.ExchangeRate}
+		if err := setSettlementAmount(&settlements[i], amountInput, membership.Group.Currency); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "receiverID": r.ReceiverID})
+			return
+		}
+	}
+
+	// トランザクション開始
+	tx := database.DB.Begin()
+
+	// 清算の完了まで送金者・受取者が退会・除名されないようにする
+	partyIDs := []uint{input.PayerID}
+	for _, r := range input.Receivers {
+		partyIDs = append(partyIDs, r.ReceiverID)
+	}
+	nonMembers, err := lockGroupNonMembers(tx, uint(groupID), partyIDs)
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check members"})
+		return
+	}
+	if len(nonMembers) > 0 {
+		tx.Rollback()
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Payer and receivers must belong to this group", "userIDs": nonMembers})
+		return
+	}
+
+	batch := models.SettlementBatch{
+		GroupID:     uint(groupID),
+		PayerID:     input.PayerID,
+		CreatedByID: userID.(uint),
+	}
+	if err := tx.Create(&batch).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create settlement"})
+		return
+	}
+
+	for i := range settlements {
+		settlement := &settlements[i]
+		settlement.BatchID = &batch.ID
+		if err := tx.Create(settlement).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create settlement"})
+			return
+		}
+
+		// 指定された支出に清算を充てる
+		expenseIDs := input.Receivers[i].ExpenseIDs
+		allocations, err := allocateSettlement(tx, *settlement, expenseIDs, membership.Group.Currency)
+		if err != nil {
+			tx.Rollback()
+			var allocationErr *SettlementAllocationError
+			if errors.As(err, &allocationErr) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": allocationErr.Message, "expenseID": allocationErr.ExpenseID, "receiverID": settlement.ReceiverID})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to allocate settlement"})
+			return
+		}
+		settlement.Allocations = allocations
+
+		// アクティビティを記録
+		if err := recordActivity(tx, settlement.GroupID, userID.(uint), models.ActivitySettlementRecorded, "settlement", settlement.ID, map[string]interface{}{
+			"payerID":         settlement.PayerID,
+			"receiverID":      settlement.ReceiverID,
+			"amount":          settlement.Amount,
+			"note":            settlement.Note,
+			"method":          settlement.Method,
+			"expenseIDs":      expenseIDs,
+			"foreignAmount":   settlement.ForeignAmount,
+			"foreignCurrency": settlement.Currency,
+			"exchangeRate":    settlement.ExchangeRate,
+			"batchID":         batch.ID,
+		}); err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record activity"})
+			return
+		}
+	}
+
+	names, err := groupDisplayNames(tx, uint(groupID))
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch members"})
+		return
+	}
+
+	tx.Commit()
+
+	analytics.Track(analytics.EventSettlementRecorded, userID.(uint), map[string]interface{}{
+		"groupId":   analytics.Anonymize("group", uint(groupID)),
+		"batch":     true,
+		"transfers": len(settlements),
+	})
+
+	batch.Settlements = settlements
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Settlements recorded successfully",
+		"batch":   settlementBatchResponse(batch, names, membership.Group.Currency),
+	})
+}
+
+// GetSettlementBatch は複数の受取者にまとめて支払った清算を1回の支払いとして取得します
+// GET /api/v1/groups/:groupID/settlement-batches/:batchID
+func GetSettlementBatch(c *gin.Context) {
+	// パスパラメータからgroupIDとbatchIDを取得
+	groupIDStr := c.Param("groupID")
+	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	batchIDStr := c.Param("batchID")
+	batchID, err := strconv.ParseUint(batchIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid batch ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// ユーザーがグループのメンバーであることを確認
+	var membership models.Membership
+	if err := database.DB.Preload("Group").Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	var batch models.SettlementBatch
+	if err := database.DB.Preload("Payer").
+		Preload("Settlements", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+		Preload("Settlements.Receiver").Preload("Settlements.Allocations").
+		Where("id = ? AND group_id = ?", batchID, groupID).First(&batch).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Settlement batch not found"})
+		return
+	}
+
+	// 退会したメンバーはユーザー名で表示する
+	names, err := groupDisplayNames(database.DB, uint(groupID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch members"})
+		return
+	}
+	users := []models.User{batch.Payer}
+	for _, s := range batch.Settlements {
+		users = append(users, s.Receiver)
+	}
+	for _, u := range users {
+		if _, ok := names[u.ID]; !ok {
+			names[u.ID] = u.Username
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"batch": settlementBatchResponse(batch, names, membership.Group.Currency),
+	})
+}
+
+// settlementBatchResponse は一括送金のレスポンス形式を返します
+// 日付・メモ・支払い方法は全ての清算で共通のため、最初の清算の値を返します
+func settlementBatchResponse(batch models.SettlementBatch, names map[uint]string, currency string) SettlementBatchResponse {
+	response := SettlementBatchResponse{
+		ID:          batch.ID,
+		GroupID:     batch.GroupID,
+		PayerID:     batch.PayerID,
+		PayerName:   names[batch.PayerID],
+		Currency:    currency,
+		Settlements: make([]SettlementBatchItem, len(batch.Settlements)),
+		CreatedAt:   batch.CreatedAt,
+	}
+	if len(batch.Settlements) > 0 {
+		first := batch.Settlements[0]
+		response.Date = first.Date.Format("2006-01-02")
+		response.Note = first.Note
+		response.Method = first.Method
+	}
+
+	var total int64
+	for i, s := range batch.Settlements {
+		response.Settlements[i] = SettlementBatchItem{
+			ID:              s.ID,
+			ReceiverID:      s.ReceiverID,
+			ReceiverName:    names[s.ReceiverID],
+			Amount:          s.Amount,
+			ForeignAmount:   s.ForeignAmount,
+			ForeignCurrency: s.Currency,
+			ExchangeRate:    s.ExchangeRate,
+			Status:          s.Status,
+			Allocations:     allocationSummaries(s.Allocations),
+			VoidedAt:        s.VoidedAt,
+		}
+		if s.VoidedAt == nil {
+			total += money.ToMinor(s.Amount, currency)
+		}
+	}
+	response.TotalAmount = money.FromMinor(total, currency)
+	return response
+}
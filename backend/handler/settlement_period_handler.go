@@ -0,0 +1,396 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ito-system/clear-up-share/backend/database"
+	"github.com/ito-system/clear-up-share/backend/models"
+	"github.com/ito-system/clear-up-share/backend/money"
+	"gorm.io/gorm"
+)
+
+// CloseSettlementPeriodInput は清算期間を締めるリクエストの入力形式
+type CloseSettlementPeriodInput struct {
+	EndDate string `json:"endDate" binding:"required"` // 期間の最終日（YYYY-MM-DD）
+}
+
+// SettlementPeriodResponse は清算期間の形式
+type SettlementPeriodResponse struct {
+	ID           uint      `json:"id"`
+	EndDate      string    `json:"endDate"`
+	ClosedByID   uint      `json:"closedByID"`
+	ClosedByName string    `json:"closedByName"`
+	ExpenseCount int       `json:"expenseCount"`
+	ClosedAt     time.Time `json:"closedAt"`
+	Automatic    bool      `json:"automatic"`
+	// 締めた時点の貸借額と送金の提案（自動で締める機能より前に締めた期間にはない）
+	Balances  json.RawMessage `json:"balances,omitempty"`
+	Transfers json.RawMessage `json:"transfers,omitempty"`
+}
+
+// GetSettlementPeriods はグループの締めた清算期間を新しい順に取得します
+// GET /api/v1/groups/:groupID/settlement-periods
+func GetSettlementPeriods(c *gin.Context) {
+	// パスパラメータからgroupIDを取得
+	groupIDStr := c.Param("groupID")
+	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// ユーザーがグループのメンバーであることを確認
+	var membership models.Membership
+	if err := database.DB.Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	var periods []models.SettlementPeriod
+	if err := database.DB.Preload("ClosedBy").Where("group_id = ?", groupID).Order("end_date DESC, id DESC").Find(&periods).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch settlement periods"})
+		return
+	}
+
+	// グループ内の表示名を取得（退会済みのユーザーはユーザー名を表示）
+	names, err := groupDisplayNames(database.DB, uint(groupID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch members"})
+		return
+	}
+
+	responses := make([]SettlementPeriodResponse, len(periods))
+	for i, period := range periods {
+		responses[i] = settlementPeriodResponse(period, names)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"groupID": groupID,
+		"periods": responses,
+	})
+}
+
+// CloseSettlementPeriod は全員の清算が済んだ期間を締め、最終日以前の確定済みの支出をロックします
+// ロックした支出は UnlockExpense で管理者がロックを解除するまで編集・削除できず、清算後に過去の貸借が変わらないようにします
+// POST /api/v1/groups/:groupID/settlement-periods
+func CloseSettlementPeriod(c *gin.Context) {
+	// パスパラメータからgroupIDを取得
+	groupIDStr := c.Param("groupID")
+	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// ユーザーがグループのメンバーであることを確認
+	var membership models.Membership
+	if err := database.DB.Preload("Group").Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	// アーカイブ済みのグループは読み取り専用
+	if membership.Group.ArchivedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Group is archived and read-only"})
+		return
+	}
+
+	// グループを管理する権限があることを確認
+	if !hasPermission(membership.Role, PermManageGroup) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to manage this group"})
+		return
+	}
+
+	// リクエストボディをバインド
+	var input CloseSettlementPeriodInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// グループのポリシー設定を取得
+	settings, err := loadGroupSettings(database.DB, uint(groupID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch group settings"})
+		return
+	}
+
+	// 期間の最終日を確認（グループのタイムゾーンでの今日より後の日付は締められない）
+	endDate, err := time.Parse("2006-01-02", input.EndDate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid endDate format. Use YYYY-MM-DD"})
+		return
+	}
+	if endDate.After(groupToday(settings)) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "endDate cannot be in the future"})
+		return
+	}
+
+	// トランザクション開始
+	tx := database.DB.Begin()
+
+	period, _, err := closeSettlementPeriod(tx, membership.Group, endDate, userID.(uint), false)
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to close settlement period"})
+		return
+	}
+
+	tx.Commit()
+
+	names, err := groupDisplayNames(database.DB, uint(groupID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch members"})
+		return
+	}
+	database.DB.First(&period.ClosedBy, period.ClosedByID)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Settlement period closed successfully",
+		"period":  settlementPeriodResponse(period, names),
+	})
+}
+
+// UnlockExpense は清算期間を締めてロックした支出のロックを解除し、編集・削除できるようにします（管理者のみ）
+// POST /api/v1/groups/:groupID/expenses/:expenseID/unlock
+func UnlockExpense(c *gin.Context) {
+	// パスパラメータからgroupIDとexpenseIDを取得
+	groupIDStr := c.Param("groupID")
+	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	expenseIDStr := c.Param("expenseID")
+	expenseID, err := strconv.ParseUint(expenseIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid expense ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// ユーザーがグループのメンバーであることを確認
+	var membership models.Membership
+	if err := database.DB.Preload("Group").Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	// アーカイブ済みのグループは読み取り専用
+	if membership.Group.ArchivedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Group is archived and read-only"})
+		return
+	}
+
+	// 全ての支出を編集する権限（管理者）があることを確認
+	if !hasPermission(membership.Role, PermEditAnyExpense) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only admins can unlock expenses"})
+		return
+	}
+
+	// Expenseを取得
+	var expense models.Expense
+	if err := database.DB.Where("id = ? AND group_id = ?", expenseID, groupID).First(&expense).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Expense not found"})
+		return
+	}
+	if expense.SettlementPeriodID == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Expense is not locked"})
+		return
+	}
+	periodID := *expense.SettlementPeriodID
+
+	// トランザクション開始
+	tx := database.DB.Begin()
+
+	// ロックを解除（同時に解除された場合は何もしない）
+	result := tx.Model(&models.Expense{}).Where("id = ? AND settlement_period_id IS NOT NULL", expense.ID).Update("settlement_period_id", gorm.Expr("NULL"))
+	if result.Error != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unlock expense"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		tx.Rollback()
+		c.JSON(http.StatusConflict, gin.H{"error": "Expense is not locked"})
+		return
+	}
+
+	// アクティビティを記録
+	if err := recordActivity(tx, expense.GroupID, userID.(uint), models.ActivityExpenseUnlocked, "expense", expense.ID, map[string]interface{}{
+		"description":        expense.Description,
+		"settlementPeriodID": periodID,
+	}); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record activity"})
+		return
+	}
+
+	tx.Commit()
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Expense unlocked successfully",
+		"expense": gin.H{
+			"id":      expense.ID,
+			"groupID": expense.GroupID,
+			"locked":  false,
+		},
+	})
+}
+
+// settlementPeriodResponse は清算期間のレスポンス形式を返します
+func settlementPeriodResponse(period models.SettlementPeriod, names map[uint]string) SettlementPeriodResponse {
+	closedByName := period.ClosedBy.Username
+	if name, ok := names[period.ClosedByID]; ok {
+		closedByName = name
+	}
+	response := SettlementPeriodResponse{
+		ID:           period.ID,
+		EndDate:      period.EndDate.Format("2006-01-02"),
+		ClosedByID:   period.ClosedByID,
+		ClosedByName: closedByName,
+		ExpenseCount: period.ExpenseCount,
+		ClosedAt:     period.CreatedAt,
+		Automatic:    period.Automatic,
+	}
+	if period.Balances != "" {
+		response.Balances = json.RawMessage(period.Balances)
+	}
+	if period.Transfers != "" {
+		response.Transfers = json.RawMessage(period.Transfers)
+	}
+	return response
+}
+
+// closeSettlementPeriod は最終日以前の確定済みでまだロックされていない支出をロックして清算期間を締めます
+// 締めた時点の各メンバーの貸借額と送金の提案を期間に保存し、送金の提案を返します
+func closeSettlementPeriod(tx *gorm.DB, group models.Group, endDate time.Time, closedByID uint, automatic bool) (models.SettlementPeriod, []SuggestedTransfer, error) {
+	period := models.SettlementPeriod{
+		GroupID:    group.ID,
+		EndDate:    endDate,
+		ClosedByID: closedByID,
+		Automatic:  automatic,
+	}
+	if err := tx.Create(&period).Error; err != nil {
+		return period, nil, err
+	}
+
+	// 最終日以前の確定済みでまだロックされていない支出をロック（承認待ち・下書きは負債に含まれないためロックしない）
+	result := tx.Model(&models.Expense{}).
+		Where("group_id = ? AND date < ? AND status = ? AND settlement_period_id IS NULL", group.ID, endDate.AddDate(0, 0, 1), models.ExpenseStatusConfirmed).
+		Update("settlement_period_id", period.ID)
+	if result.Error != nil {
+		return period, nil, result.Error
+	}
+	period.ExpenseCount = int(result.RowsAffected)
+
+	// 締めた時点の貸借額と送金の提案を保存する
+	balances, err := groupBalances(tx, group.ID)
+	if err != nil {
+		return period, nil, err
+	}
+	names, err := groupDisplayNames(tx, group.ID)
+	if err != nil {
+		return period, nil, err
+	}
+
+	// 貸借を残して退会したメンバーはユーザー名で表示する
+	var leftIDs []uint
+	for userID, balance := range balances {
+		if _, ok := names[userID]; !ok && balance != 0 {
+			leftIDs = append(leftIDs, userID)
+		}
+	}
+	left := make(map[uint]bool, len(leftIDs))
+	if len(leftIDs) > 0 {
+		var leftUsers []models.User
+		if err := tx.Where("id IN ?", leftIDs).Find(&leftUsers).Error; err != nil {
+			return period, nil, err
+		}
+		for _, u := range leftUsers {
+			names[u.ID] = u.Username
+			left[u.ID] = true
+		}
+	}
+
+	debts := []DebtSummary{}
+	for userID, name := range names {
+		debts = append(debts, DebtSummary{
+			UserID:   userID,
+			Username: name,
+			Balance:  money.Round(balances[userID], group.Currency),
+			Left:     left[userID],
+		})
+	}
+	sort.Slice(debts, func(i, j int) bool { return debts[i].UserID < debts[j].UserID })
+
+	transfers := suggestTransfers(balances, group.Currency)
+	for i := range transfers {
+		transfers[i].PayerName = names[transfers[i].PayerID]
+		transfers[i].ReceiverName = names[transfers[i].ReceiverID]
+		transfers[i].Left = left[transfers[i].PayerID] || left[transfers[i].ReceiverID]
+	}
+
+	balancesJSON, err := json.Marshal(debts)
+	if err != nil {
+		return period, nil, err
+	}
+	transfersJSON, err := json.Marshal(transfers)
+	if err != nil {
+		return period, nil, err
+	}
+	period.Balances = string(balancesJSON)
+	period.Transfers = string(transfersJSON)
+
+	if err := tx.Model(&period).Updates(map[string]interface{}{
+		"expense_count": period.ExpenseCount,
+		"balances":      period.Balances,
+		"transfers":     period.Transfers,
+	}).Error; err != nil {
+		return period, nil, err
+	}
+
+	// アクティビティを記録
+	if err := recordActivity(tx, period.GroupID, closedByID, models.ActivitySettlementPeriodClosed, "settlement_period", period.ID, map[string]interface{}{
+		"endDate":      period.EndDate.Format("2006-01-02"),
+		"expenseCount": period.ExpenseCount,
+		"automatic":    automatic,
+	}); err != nil {
+		return period, nil, err
+	}
+	return period, transfers, nil
+}
+
+// respondExpenseLocked は締めた清算期間に含まれる支出を変更しようとした場合のレスポンスを返します
+func respondExpenseLocked(c *gin.Context, expense models.Expense) {
+	c.JSON(http.StatusConflict, gin.H{
+		"error":              "Expense is locked by a closed settlement period. An admin must unlock it first",
+		"settlementPeriodID": expense.SettlementPeriodID,
+	})
+}
@@ -0,0 +1,238 @@
+package handler
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ito-system/clear-up-share/backend/database"
+	"github.com/ito-system/clear-up-share/backend/models"
+	"github.com/ito-system/clear-up-share/backend/utils"
+)
+
+// PaymentHints は受取者が受け付けている支払い方法と送金先
+type PaymentHints struct {
+	Methods      []string      `json:"methods"`              // 受取者が送金先を設定している支払い方法（paypay / bank_transfer / card）
+	PayPayLink   string        `json:"paypayLink,omitempty"` // 受取者の PayPay の受け取りリンク
+	BankTransfer *BankTransfer `json:"bankTransfer,omitempty"`
+}
+
+// SettlementPlanItem は清算計画の1件の送金を表す形式
+type SettlementPlanItem struct {
+	PayerID       uint         `json:"payerID"`
+	PayerName     string       `json:"payerName"`
+	PayerEmail    string       `json:"payerEmail,omitempty"` // 仮メンバーと個人情報を削除したユーザーにはない
+	ReceiverID    uint         `json:"receiverID"`
+	ReceiverName  string       `json:"receiverName"`
+	ReceiverEmail string       `json:"receiverEmail,omitempty"`
+	Amount        float64      `json:"amount"`
+	Left          bool         `json:"left,omitempty"` // 送金者か受取者が退会・除名されている
+	Hints         PaymentHints `json:"hints"`
+}
+
+// GetSettlementPlan は現在の送金の提案に、メンバーの連絡先と受取者の送金先を付けた清算計画を JSON または CSV で返します
+// 幹事がグループのチャットに貼り付けて清算をお願いするためのもので、JSON には貼り付け用の文章（text）も含めます
+// GET /api/v1/groups/:groupID/settlements/plan?format=csv|json
+func GetSettlementPlan(c *gin.Context) {
+	// パスパラメータからgroupIDを取得
+	groupIDStr := c.Param("groupID")
+	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	// 出力形式を確認（既定は JSON）
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "csv" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid format. Use csv or json"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// ユーザーがグループのメンバーであることを確認
+	var membership models.Membership
+	if err := database.DB.Preload("Group").Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	// グループのポリシー設定を取得
+	settings, err := loadGroupSettings(database.DB, uint(groupID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch group settings"})
+		return
+	}
+
+	transfers, err := namedSuggestedTransfers(database.DB, membership.Group)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate balances"})
+		return
+	}
+
+	// 送金者・受取者の連絡先と、受取者の送金先を取得
+	var userIDs, receiverIDs []uint
+	for _, t := range transfers {
+		userIDs = append(userIDs, t.PayerID, t.ReceiverID)
+		receiverIDs = append(receiverIDs, t.ReceiverID)
+	}
+	emails := make(map[uint]string)
+	profiles := make(map[uint]models.PayoutProfile)
+	cardEnabled := make(map[uint]bool)
+	if len(transfers) > 0 {
+		var users []models.User
+		if err := database.DB.Where("id IN ?", userIDs).Find(&users).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch members"})
+			return
+		}
+		for _, u := range users {
+			if !u.IsPlaceholder && u.AnonymizedAt == nil {
+				emails[u.ID] = u.Email
+			}
+		}
+
+		var payoutProfiles []models.PayoutProfile
+		if err := database.DB.Where("user_id IN ?", receiverIDs).Find(&payoutProfiles).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch payout profiles"})
+			return
+		}
+		for _, p := range payoutProfiles {
+			profiles[p.UserID] = p
+		}
+
+		var stripeAccounts []models.StripeAccount
+		if err := database.DB.Where("user_id IN ? AND charges_enabled = ?", receiverIDs, true).Find(&stripeAccounts).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch payout profiles"})
+			return
+		}
+		for _, a := range stripeAccounts {
+			cardEnabled[a.UserID] = true
+		}
+	}
+
+	items := make([]SettlementPlanItem, len(transfers))
+	for i, t := range transfers {
+		items[i] = SettlementPlanItem{
+			PayerID:       t.PayerID,
+			PayerName:     t.PayerName,
+			PayerEmail:    emails[t.PayerID],
+			ReceiverID:    t.ReceiverID,
+			ReceiverName:  t.ReceiverName,
+			ReceiverEmail: emails[t.ReceiverID],
+			Amount:        t.Amount,
+			Left:          t.Left,
+			Hints:         PaymentHints{Methods: []string{}},
+		}
+
+		// 退会したメンバーとの清算は記録できないため送金先を付けない
+		if t.Left {
+			continue
+		}
+		hints := &items[i].Hints
+		if profile, ok := profiles[t.ReceiverID]; ok {
+			if profile.PayPayLink != "" {
+				hints.Methods = append(hints.Methods, models.SettlementMethodPayPay)
+				hints.PayPayLink = profile.PayPayLink
+			}
+			if hasBankAccount(profile) {
+				hints.Methods = append(hints.Methods, models.SettlementMethodBankTransfer)
+				hints.BankTransfer = &BankTransfer{
+					BankName:      profile.BankName,
+					BranchName:    profile.BranchName,
+					AccountType:   profile.AccountType,
+					AccountNumber: profile.AccountNumber,
+					AccountHolder: profile.AccountHolder,
+					Amount:        t.Amount,
+				}
+			}
+		}
+		if cardEnabled[t.ReceiverID] {
+			hints.Methods = append(hints.Methods, models.SettlementMethodCard)
+		}
+	}
+
+	group := membership.Group
+	asOf := groupToday(settings).Format("2006-01-02")
+
+	if format == "csv" {
+		// CSVを作成（Excelで文字化けしないようBOM付きUTF-8）
+		filename := fmt.Sprintf("settlement-plan-%d-%s.csv", group.ID, asOf)
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+		c.Status(http.StatusOK)
+		c.Writer.WriteString("\ufeff")
+
+		w := csv.NewWriter(c.Writer)
+		w.Write([]string{
+			"payer", "payer_email", "receiver", "receiver_email", "currency", "amount", "methods",
+			"paypay_link", "bank_name", "branch_name", "account_type", "account_number", "account_holder",
+		})
+
+		digits := utils.CurrencyMinorUnits(group.Currency)
+		for _, item := range items {
+			var bank BankTransfer
+			if item.Hints.BankTransfer != nil {
+				bank = *item.Hints.BankTransfer
+			}
+			w.Write([]string{
+				item.PayerName,
+				item.PayerEmail,
+				item.ReceiverName,
+				item.ReceiverEmail,
+				group.Currency,
+				strconv.FormatFloat(item.Amount, 'f', digits, 64),
+				strings.Join(item.Hints.Methods, " "),
+				item.Hints.PayPayLink,
+				bank.BankName,
+				bank.BranchName,
+				bank.AccountType,
+				bank.AccountNumber,
+				bank.AccountHolder,
+			})
+		}
+
+		w.Flush()
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"groupID":   group.ID,
+		"groupName": group.Name,
+		"currency":  group.Currency,
+		"asOf":      asOf,
+		"transfers": items,
+		"text":      settlementPlanText(group, asOf, items),
+	})
+}
+
+// settlementPlanText はグループのチャットに貼り付けるための清算計画の文章を返します
+func settlementPlanText(group models.Group, asOf string, items []SettlementPlanItem) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Settlement plan for %s (as of %s)\n", group.Name, asOf)
+	if len(items) == 0 {
+		b.WriteString("Everyone is settled up.\n")
+		return b.String()
+	}
+	for _, item := range items {
+		fmt.Fprintf(&b, "- %s pays %s %s\n", item.PayerName, item.ReceiverName, formatGlanceAmount(item.Amount, group.Currency))
+		if item.Hints.PayPayLink != "" {
+			fmt.Fprintf(&b, "  PayPay: %s\n", item.Hints.PayPayLink)
+		}
+		if bank := item.Hints.BankTransfer; bank != nil {
+			fmt.Fprintf(&b, "  Bank: %s %s %s %s %s\n", bank.BankName, bank.BranchName, bank.AccountType, bank.AccountNumber, bank.AccountHolder)
+		}
+		if item.Left {
+			b.WriteString("  (one of them has left the group)\n")
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,212 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ito-system/clear-up-share/backend/database"
+	"github.com/ito-system/clear-up-share/backend/models"
+	"github.com/ito-system/clear-up-share/backend/money"
+	"github.com/ito-system/clear-up-share/backend/pdf"
+)
+
+// 領収書の余白と行の高さ（ポイント）
+const (
+	receiptMargin     = 56.0
+	receiptLineHeight = 18.0
+)
+
+// settlementMethodLabels は領収書に表示する支払い方法の名前
+var settlementMethodLabels = map[string]string{
+	models.SettlementMethodCash:         "Cash",
+	models.SettlementMethodBankTransfer: "Bank transfer",
+	models.SettlementMethodPayPay:       "PayPay",
+	models.SettlementMethodCard:         "Card",
+	models.SettlementMethodOther:        "Other",
+}
+
+// receiptWriter は領収書を上から順に描画し、ページの下端に達したら改ページします
+type receiptWriter struct {
+	doc *pdf.Document
+	y   float64
+}
+
+// next は次の行の位置を返します
+func (w *receiptWriter) next(height float64) float64 {
+	if w.y-height < receiptMargin {
+		w.doc.AddPage()
+		w.y = pdf.PageHeight - receiptMargin
+	}
+	w.y -= height
+	return w.y
+}
+
+// field は項目名と値を1行に描画します（値が長い場合は折り返します）
+func (w *receiptWriter) field(label, value string) {
+	lines := pdf.Wrap(value, 11, pdf.PageWidth-receiptMargin*2-120)
+	for i, line := range lines {
+		y := w.next(receiptLineHeight)
+		if i == 0 {
+			w.doc.Text(receiptMargin, y, 11, label)
+		}
+		w.doc.Text(receiptMargin+120, y, 11, line)
+	}
+}
+
+// GetSettlementReceipt は確定済みの清算の領収書（送金者・受取者・金額・日付・グループ・清算を充てた支出）を PDF で返します
+// シェアハウスの会計や立替経費の精算の記録として保存するためのものです
+// GET /api/v1/groups/:groupID/settlements/:settlementID/receipt
+func GetSettlementReceipt(c *gin.Context) {
+	// パスパラメータからgroupIDとsettlementIDを取得
+	groupIDStr := c.Param("groupID")
+	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	settlementIDStr := c.Param("settlementID")
+	settlementID, err := strconv.ParseUint(settlementIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid settlement ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// ユーザーがグループのメンバーであることを確認
+	var membership models.Membership
+	if err := database.DB.Preload("Group").Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	var settlement models.Settlement
+	if err := database.DB.Preload("Payer").Preload("Receiver").Preload("Allocations.Expense").
+		Where("id = ? AND group_id = ?", settlementID, groupID).First(&settlement).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Settlement not found"})
+		return
+	}
+
+	// 受け取りが確認されていない清算や取り消した清算の領収書は発行しない
+	if settlement.VoidedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Settlement has been voided"})
+		return
+	}
+	if settlement.Status != models.SettlementStatusConfirmed {
+		c.JSON(http.StatusConflict, gin.H{"error": "Settlement is not confirmed yet"})
+		return
+	}
+
+	// グループのポリシー設定を取得
+	settings, err := loadGroupSettings(database.DB, uint(groupID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch group settings"})
+		return
+	}
+
+	// 退会したメンバーはユーザー名で表示する
+	names, err := groupDisplayNames(database.DB, uint(groupID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch members"})
+		return
+	}
+	for _, u := range []models.User{settlement.Payer, settlement.Receiver} {
+		if _, ok := names[u.ID]; !ok {
+			names[u.ID] = u.Username
+		}
+	}
+
+	group := membership.Group
+	currency := group.Currency
+	receiptNumber := fmt.Sprintf("S-%d-%06d", group.ID, settlement.ID)
+
+	doc := pdf.New("Settlement receipt " + receiptNumber)
+	w := &receiptWriter{doc: doc, y: pdf.PageHeight - receiptMargin}
+	right := pdf.PageWidth - receiptMargin
+
+	y := w.next(20)
+	doc.Text(receiptMargin, y, 20, "Settlement Receipt")
+	doc.TextRight(right, y, 10, "No. "+receiptNumber)
+	y = w.next(14)
+	doc.TextRight(right, y, 10, "Issued "+groupToday(settings).Format("2006-01-02"))
+	y = w.next(12)
+	doc.Line(receiptMargin, y, right, y)
+
+	y = w.next(36)
+	doc.Text(receiptMargin, y, 11, "Amount")
+	doc.Text(receiptMargin+120, y, 18, formatGlanceAmount(settlement.Amount, currency))
+	w.next(6)
+
+	if settlement.ForeignAmount != nil && settlement.ExchangeRate != nil {
+		w.field("Paid as", fmt.Sprintf("%s (1 %s = %s %s)", formatGlanceAmount(*settlement.ForeignAmount, settlement.Currency),
+			settlement.Currency, strconv.FormatFloat(*settlement.ExchangeRate, 'f', -1, 64), currency))
+	}
+	w.field("Group", group.Name)
+	w.field("Date", settlement.Date.Format("2006-01-02"))
+	w.field("Paid by", names[settlement.PayerID])
+	w.field("Paid to", names[settlement.ReceiverID])
+	if label, ok := settlementMethodLabels[settlement.Method]; ok {
+		w.field("Method", label)
+	}
+	if settlement.Note != "" {
+		w.field("Note", settlement.Note)
+	}
+	if settlement.PaymentReference != "" {
+		w.field("Reference", settlement.PaymentReference)
+	}
+
+	// 清算を充てた支出
+	w.next(12)
+	y = w.next(receiptLineHeight)
+	doc.Text(receiptMargin, y, 13, "Covered expenses")
+	if len(settlement.Allocations) == 0 {
+		y = w.next(receiptLineHeight)
+		doc.Text(receiptMargin, y, 10, "This payment was applied to the overall balance between the payer and the receiver.")
+	} else {
+		columns := []float64{receiptMargin, receiptMargin + 80, right - 110, right}
+		y = w.next(receiptLineHeight)
+		doc.Text(columns[0], y, 9, "Date")
+		doc.Text(columns[1], y, 9, "Description")
+		doc.TextRight(columns[2], y, 9, "Expense total")
+		doc.TextRight(columns[3], y, 9, "Applied")
+		y = w.next(6)
+		doc.Line(receiptMargin, y, right, y)
+
+		var covered int64
+		for _, a := range settlement.Allocations {
+			y = w.next(receiptLineHeight)
+			doc.Text(columns[0], y, 10, a.Expense.Date.Format("2006-01-02"))
+			doc.Text(columns[1], y, 10, pdf.Truncate(a.Expense.Description, 10, columns[2]-columns[1]-100))
+			doc.TextRight(columns[2], y, 10, formatGlanceAmount(a.Expense.Amount, currency))
+			doc.TextRight(columns[3], y, 10, formatGlanceAmount(a.Amount, currency))
+			covered += money.ToMinor(a.Amount, currency)
+		}
+
+		y = w.next(6)
+		doc.Line(receiptMargin, y, right, y)
+		y = w.next(receiptLineHeight)
+		doc.Text(columns[1], y, 10, "Total applied to expenses")
+		doc.TextRight(columns[3], y, 10, formatGlanceAmount(money.FromMinor(covered, currency), currency))
+		if rest := money.ToMinor(settlement.Amount, currency) - covered; rest > 0 {
+			y = w.next(receiptLineHeight)
+			doc.Text(columns[1], y, 10, "Applied to the overall balance")
+			doc.TextRight(columns[3], y, 10, formatGlanceAmount(money.FromMinor(rest, currency), currency))
+		}
+	}
+
+	w.next(24)
+	y = w.next(12)
+	doc.Text(receiptMargin, y, 8, "Generated by ClearUp. This receipt records a settlement between group members.")
+
+	filename := fmt.Sprintf("settlement-%d-receipt.pdf", settlement.ID)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Data(http.StatusOK, "application/pdf", doc.Bytes())
+}
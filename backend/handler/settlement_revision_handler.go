@@ -0,0 +1,450 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ito-system/clear-up-share/backend/database"
+	"github.com/ito-system/clear-up-share/backend/models"
+	"github.com/ito-system/clear-up-share/backend/money"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// UpdateSettlementInput は清算の編集リクエストの入力形式（指定されたフィールドのみ更新、理由は必須）
+type UpdateSettlementInput struct {
+	Amount           *float64 `json:"amount" binding:"omitempty,gt=0"` // 異なる通貨で支払った清算の場合はその通貨での金額（記録時のレートで換算）
+	Date             *string  `json:"date"`                            // 支払った日付（YYYY-MM-DD）
+	Note             *string  `json:"note" binding:"omitempty,max=200"`
+	Method           *string  `json:"method" binding:"omitempty,oneof=cash bank_transfer paypay other"`
+	Reason           string   `json:"reason" binding:"required,max=500"` // 編集の理由
+	AllowOverpayment bool     `json:"allowOverpayment"`                  // 送金者の受取者への借りを超える金額に意図して変える場合に指定
+}
+
+// VoidSettlementInput は清算の取り消しリクエストの入力形式
+type VoidSettlementInput struct {
+	Reason string `json:"reason" binding:"required,max=500"` // 取り消しの理由
+}
+
+// SettlementSnapshot は清算のある時点の内容（SettlementRevision に保存する形式）
+type SettlementSnapshot struct {
+	Amount          float64  `json:"amount"`
+	Date            string   `json:"date"`
+	Note            string   `json:"note"`
+	Method          string   `json:"method"`
+	Status          string   `json:"status"`
+	ForeignCurrency string   `json:"foreignCurrency,omitempty"`
+	ForeignAmount   *float64 `json:"foreignAmount,omitempty"`
+	ExchangeRate    *float64 `json:"exchangeRate,omitempty"`
+}
+
+// SettlementRevisionResponse は清算の変更履歴の1件の形式
+type SettlementRevisionResponse struct {
+	ID         uint            `json:"id"`
+	Action     string          `json:"action"` // edited / voided
+	Reason     string          `json:"reason"`
+	EditorID   uint            `json:"editorID"`
+	EditorName string          `json:"editorName"`
+	EditedAt   time.Time       `json:"editedAt"`
+	Before     json.RawMessage `json:"before"` // 変更前の内容（SettlementSnapshot）
+}
+
+// snapshotSettlement は清算の現在の内容をスナップショットにします
+func snapshotSettlement(settlement models.Settlement) SettlementSnapshot {
+	return SettlementSnapshot{
+		Amount:          settlement.Amount,
+		Date:            settlement.Date.Format("2006-01-02"),
+		Note:            settlement.Note,
+		Method:          settlement.Method,
+		Status:          settlement.Status,
+		ForeignCurrency: settlement.Currency,
+		ForeignAmount:   settlement.ForeignAmount,
+		ExchangeRate:    settlement.ExchangeRate,
+	}
+}
+
+// recordSettlementRevision は変更前の清算の内容を理由とともに変更履歴に保存します（清算を更新する前に呼び出します）
+func recordSettlementRevision(tx *gorm.DB, settlement models.Settlement, editorID uint, action, reason string) (models.SettlementRevision, error) {
+	data, err := json.Marshal(snapshotSettlement(settlement))
+	if err != nil {
+		return models.SettlementRevision{}, err
+	}
+	revision := models.SettlementRevision{
+		SettlementID: settlement.ID,
+		EditorID:     editorID,
+		Action:       action,
+		Reason:       reason,
+		Snapshot:     string(data),
+	}
+	err = tx.Create(&revision).Error
+	return revision, err
+}
+
+// settlementRevisionResponses は清算の変更履歴をレスポンス形式に変換します
+func settlementRevisionResponses(revisions []models.SettlementRevision, names map[uint]string) []SettlementRevisionResponse {
+	responses := make([]SettlementRevisionResponse, len(revisions))
+	for i, revision := range revisions {
+		editorName, ok := names[revision.EditorID]
+		if !ok {
+			editorName = revision.Editor.Username
+		}
+		responses[i] = SettlementRevisionResponse{
+			ID:         revision.ID,
+			Action:     revision.Action,
+			Reason:     revision.Reason,
+			EditorID:   revision.EditorID,
+			EditorName: editorName,
+			EditedAt:   revision.CreatedAt,
+			Before:     json.RawMessage(revision.Snapshot),
+		}
+	}
+	return responses
+}
+
+// settlementDetailResponse は清算の詳細のレスポンス形式を返します
+func settlementDetailResponse(settlement models.Settlement, names map[uint]string, currency string) gin.H {
+	return gin.H{
+		"id":           settlement.ID,
+		"groupID":      settlement.GroupID,
+		"payerID":      settlement.PayerID,
+		"payerName":    names[settlement.PayerID],
+		"receiverID":   settlement.ReceiverID,
+		"receiverName": names[settlement.ReceiverID],
+		"amount":       settlement.Amount,
+		"currency":     currency,
+		"date":         settlement.Date.Format("2006-01-02"),
+		"note":         settlement.Note,
+		"method":       settlement.Method,
+		"status":       settlement.Status,
+		"allocations":  allocationSummaries(settlement.Allocations),
+		"batchID":      settlement.BatchID,
+		"createdAt":    settlement.CreatedAt,
+		"updatedAt":    settlement.UpdatedAt,
+		"voidedAt":     settlement.VoidedAt,
+		"voidedByID":   settlement.VoidedByID,
+		// グループの通貨と異なる通貨で支払った場合の換算前の金額・通貨と換算レート
+		"foreignAmount":   settlement.ForeignAmount,
+		"foreignCurrency": settlement.Currency,
+		"exchangeRate":    settlement.ExchangeRate,
+	}
+}
+
+// settlementNames はグループ内の表示名に、退会した送金者・受取者のユーザー名を加えて返します
+func settlementNames(db *gorm.DB, groupID uint, settlement models.Settlement) (map[uint]string, error) {
+	names, err := groupDisplayNames(db, groupID)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range []uint{settlement.PayerID, settlement.ReceiverID} {
+		if _, ok := names[id]; ok {
+			continue
+		}
+		var user models.User
+		if err := db.Unscoped().First(&user, id).Error; err == nil {
+			names[id] = user.Username
+		}
+	}
+	return names, nil
+}
+
+// GetSettlement は清算の詳細と、編集・取り消しの変更履歴（理由と変更前の内容、新しい順）を取得します
+// GET /api/v1/groups/:groupID/settlements/:settlementID
+func GetSettlement(c *gin.Context) {
+	// パスパラメータからgroupIDとsettlementIDを取得
+	groupIDStr := c.Param("groupID")
+	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	settlementIDStr := c.Param("settlementID")
+	settlementID, err := strconv.ParseUint(settlementIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid settlement ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// ユーザーがグループのメンバーであることを確認
+	var membership models.Membership
+	if err := database.DB.Preload("Group").Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	var settlement models.Settlement
+	if err := database.DB.Preload("Allocations").Where("id = ? AND group_id = ?", settlementID, groupID).First(&settlement).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Settlement not found"})
+		return
+	}
+
+	var revisions []models.SettlementRevision
+	if err := database.DB.Preload("Editor").Where("settlement_id = ?", settlement.ID).Order("created_at DESC, id DESC").Find(&revisions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch revisions"})
+		return
+	}
+
+	// 退会したメンバーはユーザー名で表示する
+	names, err := settlementNames(database.DB, uint(groupID), settlement)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch members"})
+		return
+	}
+
+	response := settlementDetailResponse(settlement, names, membership.Group.Currency)
+	response["revisions"] = settlementRevisionResponses(revisions, names)
+	c.JSON(http.StatusOK, gin.H{"settlement": response})
+}
+
+// UpdateSettlement は記録済みの清算の金額・日付・メモ・支払い方法を編集します
+// 編集には理由が必要で、編集前の内容を理由とともに SettlementRevision に保存します
+// 送金者・受取者本人か、他のメンバーの支出を編集できる管理者のみ編集できます
+// PUT /api/v1/groups/:groupID/settlements/:settlementID
+func UpdateSettlement(c *gin.Context) {
+	// パスパラメータからgroupIDとsettlementIDを取得
+	groupIDStr := c.Param("groupID")
+	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	settlementIDStr := c.Param("settlementID")
+	settlementID, err := strconv.ParseUint(settlementIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid settlement ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// ユーザーがグループのメンバーであることを確認
+	var membership models.Membership
+	if err := database.DB.Preload("Group").Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	// アーカイブ済みのグループは読み取り専用
+	if membership.Group.ArchivedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Group is archived and read-only"})
+		return
+	}
+
+	// リーガルホールド中のグループでは記録を変更できない
+	if rejectIfLegalHold(c, membership.Group) {
+		return
+	}
+
+	// リクエストボディをバインド
+	var input UpdateSettlementInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	reason := strings.TrimSpace(input.Reason)
+	if reason == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Reason is required"})
+		return
+	}
+	if input.Amount == nil && input.Date == nil && input.Note == nil && input.Method == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No changes specified"})
+		return
+	}
+
+	var settlement models.Settlement
+	if err := database.DB.Where("id = ? AND group_id = ?", settlementID, groupID).First(&settlement).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Settlement not found"})
+		return
+	}
+
+	// 清算を編集する権限があることを確認（取り消しと同じ）
+	involved := settlement.PayerID == userID.(uint) || settlement.ReceiverID == userID.(uint)
+	if !(involved && hasPermission(membership.Role, PermRecordSettlement)) && !hasPermission(membership.Role, PermEditAnyExpense) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to edit this settlement"})
+		return
+	}
+
+	// グループのポリシー設定を取得
+	settings, err := loadGroupSettings(database.DB, uint(groupID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch group settings"})
+		return
+	}
+
+	updates := map[string]interface{}{}
+	if input.Date != nil {
+		// 支払った日付（未来の日付は不可）
+		date, err := time.Parse("2006-01-02", *input.Date)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format. Use YYYY-MM-DD"})
+			return
+		}
+		if date.After(groupToday(settings)) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Settlement date cannot be in the future"})
+			return
+		}
+		updates["date"] = date
+	}
+	if input.Note != nil {
+		updates["note"] = strings.TrimSpace(*input.Note)
+	}
+	if input.Method != nil {
+		updates["method"] = *input.Method
+	}
+
+	// トランザクション開始
+	tx := database.DB.Begin()
+
+	// 同時に編集・取り消された場合に変更前の内容を取り違えないよう、清算をロックして取得し直す
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&settlement, settlement.ID).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch settlement"})
+		return
+	}
+	if settlement.VoidedAt != nil {
+		tx.Rollback()
+		c.JSON(http.StatusConflict, gin.H{"error": "Settlement has been voided"})
+		return
+	}
+
+	amount := settlement.Amount
+	var currentDebt float64
+	var overpayment int64
+	if input.Amount != nil {
+		// 金額を変えると貸借が変わるため、送金者・受取者が退会していないことを確認し、完了するまで退会・除名されず、同じ2人の清算が同時に変わらないようにする
+		ok, err := lockSettlementPair(tx, uint(groupID), settlement.PayerID, settlement.ReceiverID)
+		if err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check members"})
+			return
+		}
+		if !ok {
+			tx.Rollback()
+			c.JSON(http.StatusConflict, gin.H{"error": "Payer or receiver has left this group"})
+			return
+		}
+
+		// 支出・精算依頼に充てた清算やカード決済の清算は、充てた額や決済額と合わなくなるため金額を変えられない
+		if settlement.PaymentReference != "" {
+			tx.Rollback()
+			c.JSON(http.StatusConflict, gin.H{"error": "The amount of a card payment cannot be changed"})
+			return
+		}
+		var allocated, reimbursements int64
+		if err := tx.Model(&models.SettlementAllocation{}).Where("settlement_id = ?", settlement.ID).Count(&allocated).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch allocations"})
+			return
+		}
+		if err := tx.Model(&models.ReimbursementRequest{}).Where("settlement_id = ?", settlement.ID).Count(&reimbursements).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reimbursement requests"})
+			return
+		}
+		if allocated > 0 || reimbursements > 0 {
+			tx.Rollback()
+			c.JSON(http.StatusConflict, gin.H{"error": "Settlement has been applied to expenses. Void it and record it again to change the amount"})
+			return
+		}
+
+		// 異なる通貨で支払った清算は、記録時のレートで換算し直す
+		changed := settlement
+		amountInput := AddSettlementInput{Amount: *input.Amount}
+		if settlement.Currency != "" && settlement.ExchangeRate != nil {
+			amountInput.Currency = settlement.Currency
+			amountInput.ExchangeRate = *settlement.ExchangeRate
+		}
+		if err := setSettlementAmount(&changed, amountInput, membership.Group.Currency); err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		amount = changed.Amount
+
+		// 増額して送金者の受取者への借りを超えると貸借が逆転するため、allowOverpayment を指定しない限り変えない（減額は常に可能）
+		if amount > settlement.Amount {
+			currentDebt, overpayment, err = settlementOverpayment(tx, uint(groupID), settlement.PayerID, settlement.ReceiverID, amount, membership.Group.Currency, settlement.ID)
+			if err != nil {
+				tx.Rollback()
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate debts"})
+				return
+			}
+			if overpayment > 0 && !input.AllowOverpayment {
+				tx.Rollback()
+				respondSettlementOverpayment(c, currentDebt, overpayment, membership.Group.Currency)
+				return
+			}
+		}
+
+		updates["amount"] = amount
+		updates["foreign_amount"] = changed.ForeignAmount
+	}
+
+	// 変更前の内容を理由とともに保存する
+	revision, err := recordSettlementRevision(tx, settlement, userID.(uint), models.SettlementRevisionEdited, reason)
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record revision"})
+		return
+	}
+
+	// アクティビティを記録
+	if err := recordActivity(tx, settlement.GroupID, userID.(uint), models.ActivitySettlementEdited, "settlement", settlement.ID, map[string]interface{}{
+		"payerID":        settlement.PayerID,
+		"receiverID":     settlement.ReceiverID,
+		"amount":         amount,
+		"previousAmount": settlement.Amount,
+		"reason":         reason,
+		"revisionID":     revision.ID,
+	}); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record activity"})
+		return
+	}
+
+	if err := tx.Model(&settlement).Updates(updates).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update settlement"})
+		return
+	}
+
+	tx.Commit()
+
+	if err := database.DB.Preload("Allocations").First(&settlement, settlement.ID).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch settlement"})
+		return
+	}
+	names, err := settlementNames(database.DB, uint(groupID), settlement)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch members"})
+		return
+	}
+
+	response := gin.H{
+		"message":    "Settlement updated successfully",
+		"settlement": settlementDetailResponse(settlement, names, membership.Group.Currency),
+	}
+	// allowOverpayment で借りを超える金額に変えた場合は、超えた額を警告として返す
+	if overpayment > 0 {
+		response["warning"] = "Settlement amount exceeds the payer's current debt to the receiver"
+		response["overpayment"] = money.FromMinor(overpayment, membership.Group.Currency)
+	}
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,359 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ito-system/clear-up-share/backend/analytics"
+	"github.com/ito-system/clear-up-share/backend/database"
+	"github.com/ito-system/clear-up-share/backend/models"
+	"github.com/ito-system/clear-up-share/backend/money"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SuggestedTransfer は全員の貸借を0にするための送金の提案（AddSettlementInput と同じ形式で清算を記録できます）
+type SuggestedTransfer struct {
+	PayerID      uint            `json:"payerID"`
+	PayerName    string          `json:"payerName"`
+	ReceiverID   uint            `json:"receiverID"`
+	ReceiverName string          `json:"receiverName"`
+	Amount       float64         `json:"amount"`
+	Left         bool            `json:"left,omitempty"`    // 送金者か受取者が退会・除名されている（再参加するまで清算を記録できない）
+	Payment      *PaymentOptions `json:"payment,omitempty"` // 認証ユーザーが送金者で、受取者が送金先を設定している場合のみ
+}
+
+// balanceParty は送金の提案を計算するときの1人分の残りの貸借額（補助単位）
+type balanceParty struct {
+	userID uint
+	units  int64
+}
+
+// suggestTransfers は貸借額から、債務者から債権者への送金の一覧を作ります
+// 残りの額が最も大きい債務者と債権者の間で少ない方の額を送金する処理を繰り返すため、送金の件数は貸借のある人数より少なくなります
+// 誤差が出ないよう通貨の補助単位の整数で計算し、同じ額の場合はユーザーIDの小さい順に選びます
+func suggestTransfers(balances map[uint]float64, currency string) []SuggestedTransfer {
+	var debtors, creditors []balanceParty
+	for userID, balance := range balances {
+		units := money.ToMinor(balance, currency)
+		switch {
+		case units < 0:
+			debtors = append(debtors, balanceParty{userID: userID, units: -units})
+		case units > 0:
+			creditors = append(creditors, balanceParty{userID: userID, units: units})
+		}
+	}
+
+	largestFirst := func(parties []balanceParty) func(i, j int) bool {
+		return func(i, j int) bool {
+			if parties[i].units != parties[j].units {
+				return parties[i].units > parties[j].units
+			}
+			return parties[i].userID < parties[j].userID
+		}
+	}
+
+	transfers := []SuggestedTransfer{}
+	for len(debtors) > 0 && len(creditors) > 0 {
+		sort.Slice(debtors, largestFirst(debtors))
+		sort.Slice(creditors, largestFirst(creditors))
+
+		units := debtors[0].units
+		if creditors[0].units < units {
+			units = creditors[0].units
+		}
+		transfers = append(transfers, SuggestedTransfer{
+			PayerID:    debtors[0].userID,
+			ReceiverID: creditors[0].userID,
+			Amount:     money.FromMinor(units, currency),
+		})
+
+		debtors[0].units -= units
+		creditors[0].units -= units
+		if debtors[0].units == 0 {
+			debtors = debtors[1:]
+		}
+		if creditors[0].units == 0 {
+			creditors = creditors[1:]
+		}
+	}
+	return transfers
+}
+
+// namedSuggestedTransfers はグループの現在の貸借額から送金の提案を計算し、送金者・受取者の表示名を付けて返します
+// 貸借を残して退会したメンバーはユーザー名で表示し、その送金には Left を付けます
+func namedSuggestedTransfers(db *gorm.DB, group models.Group) ([]SuggestedTransfer, error) {
+	balances, err := groupBalances(db, group.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	names, err := groupDisplayNames(db, group.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	transfers := suggestTransfers(balances, group.Currency)
+
+	var leftIDs []uint
+	for _, t := range transfers {
+		for _, id := range []uint{t.PayerID, t.ReceiverID} {
+			if _, ok := names[id]; !ok {
+				leftIDs = append(leftIDs, id)
+			}
+		}
+	}
+	left := make(map[uint]bool, len(leftIDs))
+	if len(leftIDs) > 0 {
+		var leftUsers []models.User
+		if err := db.Where("id IN ?", leftIDs).Find(&leftUsers).Error; err != nil {
+			return nil, err
+		}
+		for _, u := range leftUsers {
+			names[u.ID] = u.Username
+			left[u.ID] = true
+		}
+	}
+
+	for i := range transfers {
+		transfers[i].PayerName = names[transfers[i].PayerID]
+		transfers[i].ReceiverName = names[transfers[i].ReceiverID]
+		transfers[i].Left = left[transfers[i].PayerID] || left[transfers[i].ReceiverID]
+	}
+	return transfers, nil
+}
+
+// GetSettlementSuggestions は負債情報と同じ貸借額から、全員の貸借を0にするための少ない件数の送金の一覧を返します
+// GET /api/v1/groups/:groupID/settlements/suggestions
+func GetSettlementSuggestions(c *gin.Context) {
+	// パスパラメータからgroupIDを取得
+	groupIDStr := c.Param("groupID")
+	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// ユーザーがグループのメンバーであることを確認
+	var membership models.Membership
+	if err := database.DB.Preload("Group").Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	// 支出・Split・清算から各メンバーの貸借額を集計（承認待ちの支出は含めない）
+	transfers, err := namedSuggestedTransfers(database.DB, membership.Group)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate balances"})
+		return
+	}
+
+	// アーカイブ済みのグループでは清算を記録できないため支払いリンクを付けない
+	if membership.Group.ArchivedAt == nil {
+		if err := attachPaymentOptions(database.DB, uint(groupID), userID.(uint), transfers); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create payment links"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"groupID":   groupID,
+		"currency":  membership.Group.Currency,
+		"transfers": transfers,
+	})
+}
+
+// SettleAll は送金の提案の全ての送金を受取者の確認待ちの清算として1つのトランザクションで記録し、送金者と受取者に通知します
+// 旅行の終わりなどにまとめて清算するためのもので、受取者が受け取りを確認した清算から負債計算に反映されます
+// POST /api/v1/groups/:groupID/settlements/settle-all
+func SettleAll(c *gin.Context) {
+	// パスパラメータからgroupIDを取得
+	groupIDStr := c.Param("groupID")
+	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// ユーザーがグループのメンバーであることを確認
+	var membership models.Membership
+	if err := database.DB.Preload("Group").Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	// アーカイブ済みのグループは読み取り専用
+	if membership.Group.ArchivedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Group is archived and read-only"})
+		return
+	}
+
+	// 清算を記録する権限があることを確認
+	if !hasPermission(membership.Role, PermRecordSettlement) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to record settlements"})
+		return
+	}
+
+	// グループのポリシー設定を取得
+	settings, err := loadGroupSettings(database.DB, uint(groupID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch group settings"})
+		return
+	}
+
+	// トランザクション開始
+	tx := database.DB.Begin()
+
+	// 同時に一括清算された場合に同じ送金を二重に記録しない
+	var group models.Group
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&group, groupID).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch group"})
+		return
+	}
+
+	// 確認待ちの清算は貸借額に含まれないため、残っている間は一括清算できない
+	var pending int64
+	if err := tx.Model(&models.Settlement{}).
+		Where("group_id = ? AND status = ? AND voided_at IS NULL", groupID, models.SettlementStatusPending).
+		Count(&pending).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch settlements"})
+		return
+	}
+	if pending > 0 {
+		tx.Rollback()
+		c.JSON(http.StatusConflict, gin.H{"error": "Settlements from a previous settle-all are still pending confirmation"})
+		return
+	}
+
+	balances, err := groupBalances(tx, uint(groupID))
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate balances"})
+		return
+	}
+
+	transfers := suggestTransfers(balances, group.Currency)
+	if len(transfers) == 0 {
+		tx.Rollback()
+		c.JSON(http.StatusBadRequest, gin.H{"error": "All balances are already settled"})
+		return
+	}
+
+	// 送金者・受取者が全員グループに所属していることを確認し、完了するまで退会・除名されないようにする
+	var partyIDs []uint
+	for _, t := range transfers {
+		partyIDs = append(partyIDs, t.PayerID, t.ReceiverID)
+	}
+	nonMembers, err := lockGroupNonMembers(tx, uint(groupID), partyIDs)
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check members"})
+		return
+	}
+	if len(nonMembers) > 0 {
+		tx.Rollback()
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "Some members with outstanding balances have left this group",
+			"userIDs": nonMembers,
+		})
+		return
+	}
+
+	names, err := groupDisplayNames(tx, uint(groupID))
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch members"})
+		return
+	}
+
+	settlements := make([]gin.H, 0, len(transfers))
+	for _, t := range transfers {
+		settlement := models.Settlement{
+			GroupID:    uint(groupID),
+			PayerID:    t.PayerID,
+			ReceiverID: t.ReceiverID,
+			Amount:     t.Amount,
+			Date:       groupToday(settings),
+			Status:     models.SettlementStatusPending,
+		}
+		if err := tx.Create(&settlement).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create settlement"})
+			return
+		}
+
+		// 送金者には支払いを、受取者には受け取りの確認を依頼する
+		amount := formatGlanceAmount(settlement.Amount, group.Currency)
+		if err := notify(tx, settlement.PayerID, settlement.GroupID, models.NotificationSettlementPending,
+			fmt.Sprintf("Please pay %s to %s to settle up", amount, names[settlement.ReceiverID]), "settlement", settlement.ID); err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send notification"})
+			return
+		}
+		if err := notify(tx, settlement.ReceiverID, settlement.GroupID, models.NotificationSettlementPending,
+			fmt.Sprintf("%s will pay you %s. Please confirm once you receive it", names[settlement.PayerID], amount), "settlement", settlement.ID); err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send notification"})
+			return
+		}
+
+		// アクティビティを記録
+		if err := recordActivity(tx, settlement.GroupID, userID.(uint), models.ActivitySettlementRecorded, "settlement", settlement.ID, map[string]interface{}{
+			"payerID":    settlement.PayerID,
+			"receiverID": settlement.ReceiverID,
+			"amount":     settlement.Amount,
+			"status":     settlement.Status,
+			"settleAll":  true,
+		}); err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record activity"})
+			return
+		}
+
+		settlements = append(settlements, gin.H{
+			"id":           settlement.ID,
+			"groupID":      settlement.GroupID,
+			"payerID":      settlement.PayerID,
+			"payerName":    names[settlement.PayerID],
+			"receiverID":   settlement.ReceiverID,
+			"receiverName": names[settlement.ReceiverID],
+			"amount":       settlement.Amount,
+			"currency":     group.Currency,
+			"date":         settlement.Date.Format("2006-01-02"),
+			"status":       settlement.Status,
+			"createdAt":    settlement.CreatedAt,
+		})
+	}
+
+	tx.Commit()
+
+	analytics.Track(analytics.EventSettlementRecorded, userID.(uint), map[string]interface{}{
+		"groupId":   analytics.Anonymize("group", uint(groupID)),
+		"settleAll": true,
+		"transfers": len(settlements),
+	})
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":     "Settlements recorded successfully",
+		"settlements": settlements,
+	})
+}
@@ -0,0 +1,157 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ito-system/clear-up-share/backend/database"
+	"github.com/ito-system/clear-up-share/backend/models"
+)
+
+// SimulatedExpense はシミュレーション用の仮の支出
+type SimulatedExpense struct {
+	Description string  `json:"description"`
+	Amount      float64 `json:"amount" binding:"required,gt=0"`
+	PayerID     uint    `json:"payerID" binding:"required"`
+	MemberIDs   []uint  `json:"memberIDs"`
+	NewMembers  []int   `json:"newMembers"` // 負担者に含める仮メンバー（SimulateInput.NewMembers のインデックス）
+}
+
+// SimulateInput はシミュレーションリクエストの入力形式
+type SimulateInput struct {
+	NewMembers []string           `json:"newMembers" binding:"dive,required,max=50"` // 仮に参加させるメンバーの名前
+	Expenses   []SimulatedExpense `json:"expenses" binding:"required,min=1,dive"`
+}
+
+// SimulatedBalance はシミュレーション結果のメンバーごとの貸借額
+type SimulatedBalance struct {
+	UserID           uint    `json:"userID,omitempty"`
+	Username         string  `json:"username"`
+	Hypothetical     bool    `json:"hypothetical,omitempty"`
+	CurrentBalance   float64 `json:"currentBalance"`
+	SimulatedBalance float64 `json:"simulatedBalance"`
+	Change           float64 `json:"change"`
+}
+
+// SimulateGroup は仮の支出・メンバーを加えた場合の貸借額を計算します（データは保存しません）
+// POST /api/v1/groups/:groupID/simulate
+func SimulateGroup(c *gin.Context) {
+	// パスパラメータからgroupIDを取得
+	groupIDStr := c.Param("groupID")
+	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// ユーザーがグループのメンバーであることを確認
+	var membership models.Membership
+	if err := database.DB.Preload("Group").Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	// リクエストボディをバインド
+	var input SimulateInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// グループのメンバーを取得
+	var memberships []models.Membership
+	if err := database.DB.Preload("User").Where("group_id = ?", groupID).Find(&memberships).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch members"})
+		return
+	}
+
+	memberMap := make(map[uint]string)
+	for _, m := range memberships {
+		memberMap[m.UserID] = m.User.Username
+	}
+
+	settings, err := loadGroupSettings(database.DB, uint(groupID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch group settings"})
+		return
+	}
+
+	// 現在の貸借額を取得
+	current, err := groupBalances(database.DB, uint(groupID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate balances"})
+		return
+	}
+
+	// 仮の支出を順に反映（仮メンバーの貸借額はインデックスごとに保持）
+	simulated := make(map[uint]float64, len(current))
+	for id, balance := range current {
+		simulated[id] = balance
+	}
+	newBalances := make([]float64, len(input.NewMembers))
+
+	for _, e := range input.Expenses {
+		if _, ok := memberMap[e.PayerID]; !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Payer is not a member of this group"})
+			return
+		}
+
+		count := len(e.MemberIDs) + len(e.NewMembers)
+		if count == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Each expense needs at least one member"})
+			return
+		}
+
+		shares := splitEqually(e.Amount, count, settings.RoundingMode, membership.Group.Currency)
+		simulated[e.PayerID] += e.Amount
+
+		for i, memberID := range e.MemberIDs {
+			if _, ok := memberMap[memberID]; !ok {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Split member is not a member of this group"})
+				return
+			}
+			simulated[memberID] -= shares[i]
+		}
+		for i, index := range e.NewMembers {
+			if index < 0 || index >= len(input.NewMembers) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid new member index"})
+				return
+			}
+			newBalances[index] -= shares[len(e.MemberIDs)+i]
+		}
+	}
+
+	// 結果を作成
+	var balances []SimulatedBalance
+	for _, m := range memberships {
+		balances = append(balances, SimulatedBalance{
+			UserID:           m.UserID,
+			Username:         memberDisplayName(m),
+			CurrentBalance:   current[m.UserID],
+			SimulatedBalance: simulated[m.UserID],
+			Change:           simulated[m.UserID] - current[m.UserID],
+		})
+	}
+	for i, name := range input.NewMembers {
+		balances = append(balances, SimulatedBalance{
+			Username:         name,
+			Hypothetical:     true,
+			SimulatedBalance: newBalances[i],
+			Change:           newBalances[i],
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"groupID":  groupID,
+		"currency": membership.Group.Currency,
+		"balances": balances,
+	})
+}
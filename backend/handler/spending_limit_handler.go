@@ -0,0 +1,330 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ito-system/clear-up-share/backend/database"
+	"github.com/ito-system/clear-up-share/backend/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// spendingLimitPollInterval はメンバーごとの負担額の上限を確認する間隔
+const spendingLimitPollInterval = 15 * time.Minute
+
+// spendingLimitBatchSize は1回の確認でまとめて取得する上限の件数
+const spendingLimitBatchSize = 100
+
+// UpdateSpendingLimitInput は負担額の上限の設定リクエストの入力形式
+type UpdateSpendingLimitInput struct {
+	MonthlyLimit float64 `json:"monthlyLimit" binding:"required,gt=0"`
+}
+
+// memberMonthlyShare は期間内の確定済みの支出でのメンバーの負担額の合計を返します（返金の負担額は差し引かれます）
+func memberMonthlyShare(db *gorm.DB, groupID, userID uint, start, end time.Time) (float64, error) {
+	var share float64
+	err := db.Model(&models.Split{}).
+		Select("COALESCE(SUM(splits.amount_due), 0)").
+		Joins("JOIN expenses ON expenses.id = splits.expense_id AND expenses.deleted_at IS NULL").
+		Where("expenses.group_id = ? AND expenses.status = ? AND expenses.date >= ? AND expenses.date < ?",
+			groupID, models.ExpenseStatusConfirmed, start, end).
+		Where("splits.debtor_id = ?", userID).
+		Scan(&share).Error
+	return share, err
+}
+
+// spendingLimitResponse はメンバーの負担額の上限と今月の負担額のレスポンス形式を返します（上限が未設定の場合 limit は nil）
+func spendingLimitResponse(limit *models.SpendingLimit, month string, start, end time.Time, share float64) gin.H {
+	response := gin.H{
+		"month":     month,
+		"startDate": start,
+		"endDate":   end,
+		"spent":     share,
+		"limit":     nil,
+		"exceeded":  false,
+	}
+	if limit != nil {
+		response["limit"] = limit.MonthlyLimit
+		response["exceeded"] = share > limit.MonthlyLimit
+	}
+	return response
+}
+
+// GetSpendingLimit は認証ユーザーのグループでの負担額の上限と今月の負担額を取得します
+// GET /api/v1/groups/:groupID/spending-limit
+func GetSpendingLimit(c *gin.Context) {
+	// パスパラメータからgroupIDを取得
+	groupIDStr := c.Param("groupID")
+	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// ユーザーがグループのメンバーであることを確認
+	var membership models.Membership
+	if err := database.DB.Preload("Group").Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	var limit *models.SpendingLimit
+	var saved models.SpendingLimit
+	err = database.DB.Where("user_id = ? AND group_id = ?", userID, groupID).First(&saved).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch spending limit"})
+		return
+	}
+	if err == nil {
+		limit = &saved
+	}
+
+	// グループのポリシー設定を取得
+	settings, err := loadGroupSettings(database.DB, uint(groupID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch group settings"})
+		return
+	}
+
+	month := budgetMonth(groupToday(settings), settings)
+	start, end, _ := budgetPeriod(month, settings)
+	share, err := memberMonthlyShare(database.DB, uint(groupID), userID.(uint), start, end)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate spending"})
+		return
+	}
+
+	response := spendingLimitResponse(limit, month, start, end, share)
+	response["groupID"] = groupID
+	response["currency"] = membership.Group.Currency
+	c.JSON(http.StatusOK, response)
+}
+
+// UpdateSpendingLimit は認証ユーザーのグループでの負担額の上限を設定します
+// 上限を変更すると、今月すでに通知していても新しい上限を超えた時点で改めて通知します
+// PUT /api/v1/groups/:groupID/spending-limit
+func UpdateSpendingLimit(c *gin.Context) {
+	// パスパラメータからgroupIDを取得
+	groupIDStr := c.Param("groupID")
+	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// ユーザーがグループのメンバーであることを確認
+	var membership models.Membership
+	if err := database.DB.Preload("Group").Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	// アーカイブ済みのグループは読み取り専用
+	if membership.Group.ArchivedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Group is archived and read-only"})
+		return
+	}
+
+	// リーガルホールド中のグループでは記録を変更できない
+	if rejectIfLegalHold(c, membership.Group) {
+		return
+	}
+
+	// リクエストボディをバインド
+	var input UpdateSpendingLimitInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var limit models.SpendingLimit
+	if err := database.DB.Where(models.SpendingLimit{UserID: userID.(uint), GroupID: uint(groupID)}).FirstOrInit(&limit).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch spending limit"})
+		return
+	}
+	limit.MonthlyLimit = input.MonthlyLimit
+	limit.NotifiedMonth = ""
+
+	if err := database.DB.Save(&limit).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update spending limit"})
+		return
+	}
+
+	// グループのポリシー設定を取得
+	settings, err := loadGroupSettings(database.DB, uint(groupID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch group settings"})
+		return
+	}
+
+	month := budgetMonth(groupToday(settings), settings)
+	start, end, _ := budgetPeriod(month, settings)
+	share, err := memberMonthlyShare(database.DB, uint(groupID), userID.(uint), start, end)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate spending"})
+		return
+	}
+
+	response := spendingLimitResponse(&limit, month, start, end, share)
+	response["groupID"] = groupID
+	response["currency"] = membership.Group.Currency
+	c.JSON(http.StatusOK, gin.H{
+		"message":       "Spending limit updated successfully",
+		"spendingLimit": response,
+	})
+}
+
+// DeleteSpendingLimit は認証ユーザーのグループでの負担額の上限を解除します
+// DELETE /api/v1/groups/:groupID/spending-limit
+func DeleteSpendingLimit(c *gin.Context) {
+	// パスパラメータからgroupIDを取得
+	groupIDStr := c.Param("groupID")
+	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// ユーザーがグループのメンバーであることを確認
+	var membership models.Membership
+	if err := database.DB.Preload("Group").Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	// アーカイブ済みのグループは読み取り専用
+	if membership.Group.ArchivedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Group is archived and read-only"})
+		return
+	}
+
+	// リーガルホールド中のグループでは記録を変更できない
+	if rejectIfLegalHold(c, membership.Group) {
+		return
+	}
+
+	result := database.DB.Where("user_id = ? AND group_id = ?", userID, groupID).Delete(&models.SpendingLimit{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete spending limit"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Spending limit not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Spending limit deleted successfully"})
+}
+
+// StartSpendingLimitScheduler はメンバーごとの負担額の上限を定期的に確認するバックグラウンド処理を開始します
+func StartSpendingLimitScheduler() {
+	go func() {
+		for {
+			checkSpendingLimits()
+			time.Sleep(spendingLimitPollInterval)
+		}
+	}()
+}
+
+// checkSpendingLimits はグループに所属している全てのメンバーの上限を確認します（アーカイブ済み・削除済みのグループは除く）
+func checkSpendingLimits() {
+	var limits []models.SpendingLimit
+	err := database.DB.
+		Where("EXISTS (SELECT 1 FROM memberships WHERE memberships.user_id = spending_limits.user_id AND memberships.group_id = spending_limits.group_id AND memberships.deleted_at IS NULL)").
+		Where("group_id IN (SELECT id FROM groups WHERE archived_at IS NULL AND deleted_at IS NULL)").
+		FindInBatches(&limits, spendingLimitBatchSize, func(batch *gorm.DB, _ int) error {
+			for _, limit := range limits {
+				if err := checkSpendingLimit(limit.ID); err != nil {
+					log.Printf("Failed to check spending limit %d: %v", limit.ID, err)
+				}
+			}
+			return nil
+		}).Error
+	if err != nil {
+		log.Printf("Failed to fetch spending limits: %v", err)
+	}
+}
+
+// checkSpendingLimit はメンバーの今月の負担額が上限を超えていれば、その月にまだ通知していない場合に限り本人に通知します
+func checkSpendingLimit(limitID uint) error {
+	tx := database.DB.Begin()
+
+	// 複数のサーバーで動かしている場合に二重に通知しない
+	var limit models.SpendingLimit
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).Preload("Group").First(&limit, limitID).Error; err != nil {
+		tx.Rollback()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	// 一覧の取得後にアーカイブ・削除されたグループには通知しない
+	if limit.Group.ID == 0 || limit.Group.ArchivedAt != nil {
+		tx.Rollback()
+		return nil
+	}
+
+	settings, err := loadGroupSettings(tx, limit.GroupID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	month := budgetMonth(groupToday(settings), settings)
+	if limit.NotifiedMonth == month {
+		tx.Rollback()
+		return nil
+	}
+
+	start, end, _ := budgetPeriod(month, settings)
+	share, err := memberMonthlyShare(tx, limit.GroupID, limit.UserID, start, end)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if share <= limit.MonthlyLimit {
+		tx.Rollback()
+		return nil
+	}
+
+	if err := tx.Model(&limit).Omit("Group").Update("notified_month", month).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	message := fmt.Sprintf("Your share of expenses this month (%s) exceeded your limit of %s", formatGlanceAmount(share, limit.Group.Currency), formatGlanceAmount(limit.MonthlyLimit, limit.Group.Currency))
+	if err := notify(tx, limit.UserID, limit.GroupID, models.NotificationSpendingLimitExceeded, message, "spending_limit", limit.ID); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
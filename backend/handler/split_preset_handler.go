@@ -0,0 +1,429 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ito-system/clear-up-share/backend/database"
+	"github.com/ito-system/clear-up-share/backend/models"
+	"gorm.io/gorm"
+)
+
+// SplitPresetInput は分け方のプリセットの作成・更新リクエストの入力形式
+// 支出の金額によらず使えるよう、分け方は均等割り（equal）か割合（percentage）のみ指定できます
+type SplitPresetInput struct {
+	Name      string       `json:"name" binding:"required,max=50"`
+	SplitType string       `json:"splitType" binding:"omitempty,oneof=equal percentage"`
+	MemberIDs []uint       `json:"memberIDs" binding:"required_without=Splits,omitempty,min=1"`
+	Splits    []SplitInput `json:"splits" binding:"omitempty,min=1,dive"`
+}
+
+// SplitPresetResponse は分け方のプリセットのレスポンス形式
+type SplitPresetResponse struct {
+	ID          uint         `json:"id"`
+	GroupID     uint         `json:"groupID"`
+	Name        string       `json:"name"`
+	CreatedByID uint         `json:"createdByID"`
+	SplitType   string       `json:"splitType"`
+	MemberIDs   []uint       `json:"memberIDs"`
+	Splits      []SplitInput `json:"splits,omitempty"` // percentage の場合の負担者ごとの割合
+}
+
+// encodeSplitTemplate は計算済みのSplitを保存する形式（負担者のIDと負担額・割合をスペース区切り）にします
+// 均等割りの場合は負担額・割合を保存しません
+func encodeSplitTemplate(splitType string, splits []models.Split) (memberIDs string, shares string) {
+	ids := make([]string, len(splits))
+	values := make([]string, len(splits))
+	for i, split := range splits {
+		ids[i] = strconv.FormatUint(uint64(split.DebtorID), 10)
+		switch splitType {
+		case models.SplitTypeExact:
+			values[i] = strconv.FormatFloat(split.AmountDue, 'f', -1, 64)
+		case models.SplitTypePercentage:
+			if split.Percent != nil {
+				values[i] = strconv.FormatFloat(*split.Percent, 'f', -1, 64)
+			}
+		}
+	}
+	if splitType == models.SplitTypeEqual {
+		return strings.Join(ids, " "), ""
+	}
+	return strings.Join(ids, " "), strings.Join(values, " ")
+}
+
+// decodeSplitTemplate は保存されている負担者の指定を支出の入力形式（equal は MemberIDs、それ以外は Splits）に戻します
+func decodeSplitTemplate(splitType, memberIDs, shares string) ([]uint, []SplitInput) {
+	var ids []uint
+	var splits []SplitInput
+	values := strings.Fields(shares)
+	for i, id := range strings.Fields(memberIDs) {
+		memberID, err := strconv.ParseUint(id, 10, 32)
+		if err != nil {
+			continue
+		}
+		if splitType == models.SplitTypeEqual {
+			ids = append(ids, uint(memberID))
+			continue
+		}
+		split := SplitInput{MemberID: uint(memberID)}
+		if i < len(values) {
+			share, _ := strconv.ParseFloat(values[i], 64)
+			if splitType == models.SplitTypePercentage {
+				split.Percent = share
+			} else {
+				split.Amount = share
+			}
+		}
+		splits = append(splits, split)
+	}
+	return ids, splits
+}
+
+// applySplitPreset は支出の入力で presetID が指定されていれば、プリセットの分け方を入力に反映します
+// プリセットがグループにない場合は gorm.ErrRecordNotFound を返します
+func applySplitPreset(db *gorm.DB, groupID uint, input *AddExpenseInput) error {
+	if input.PresetID == nil {
+		return nil
+	}
+	var preset models.SplitPreset
+	if err := db.Where("id = ? AND group_id = ?", *input.PresetID, groupID).First(&preset).Error; err != nil {
+		return err
+	}
+	input.SplitType = preset.SplitType
+	input.MemberIDs, input.Splits = decodeSplitTemplate(preset.SplitType, preset.MemberIDs, preset.Shares)
+	input.Items = nil
+	return nil
+}
+
+// respondSplitPresetError はプリセットを反映できなかった理由をレスポンスとして返します
+func respondSplitPresetError(c *gin.Context, err error) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Split preset not found in this group"})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch split preset"})
+}
+
+// splitPresetResponse は分け方のプリセットをレスポンス形式に変換します
+func splitPresetResponse(preset models.SplitPreset) SplitPresetResponse {
+	memberIDs, splits := decodeSplitTemplate(preset.SplitType, preset.MemberIDs, preset.Shares)
+	for _, split := range splits {
+		memberIDs = append(memberIDs, split.MemberID)
+	}
+	if memberIDs == nil {
+		memberIDs = []uint{}
+	}
+	return SplitPresetResponse{
+		ID:          preset.ID,
+		GroupID:     preset.GroupID,
+		Name:        preset.Name,
+		CreatedByID: preset.CreatedByID,
+		SplitType:   preset.SplitType,
+		MemberIDs:   memberIDs,
+		Splits:      splits,
+	}
+}
+
+// bindSplitPreset は入力を確認し、負担者がグループのメンバーであればプリセットに反映します
+// 入力に誤りがある場合はレスポンスを返して false を返します
+func bindSplitPreset(c *gin.Context, groupID uint, groupCurrency string, preset *models.SplitPreset) bool {
+	// リクエストボディをバインド
+	var input SplitPresetInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	name := strings.TrimSpace(input.Name)
+	if name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Split preset name is required"})
+		return false
+	}
+
+	// 支出と同じ計算で割合の合計や負担者の重複を確認する（金額は割合の確認用）
+	expenseInput := AddExpenseInput{
+		Amount:    100,
+		SplitType: input.SplitType,
+		MemberIDs: input.MemberIDs,
+		Splits:    input.Splits,
+	}
+	if expenseInput.SplitType == "" && len(input.Splits) > 0 {
+		expenseInput.SplitType = models.SplitTypePercentage
+	}
+	splits, _, err := expenseSplits(expenseInput, models.RoundingNone, groupCurrency)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+
+	// 負担者がグループのメンバーであることを確認
+	nonMembers, err := lockGroupNonMembers(database.DB, groupID, splitDebtorIDs(splits))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check members"})
+		return false
+	}
+	if len(nonMembers) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":  "Split members must belong to this group",
+			"fields": expenseNonMemberFields(expenseInput, splits, nonMembers),
+		})
+		return false
+	}
+
+	// 同じ名前のプリセットは作成しない
+	var count int64
+	if err := database.DB.Model(&models.SplitPreset{}).Where("group_id = ? AND name = ? AND id <> ?", groupID, name, preset.ID).Count(&count).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check split presets"})
+		return false
+	}
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Split preset already exists"})
+		return false
+	}
+
+	preset.Name = name
+	preset.SplitType = resolveSplitType(expenseInput)
+	preset.MemberIDs, preset.Shares = encodeSplitTemplate(preset.SplitType, splits)
+	return true
+}
+
+// GetSplitPresets はグループの分け方のプリセット一覧を名前順に取得します
+// GET /api/v1/groups/:groupID/split-presets
+func GetSplitPresets(c *gin.Context) {
+	// パスパラメータからgroupIDを取得
+	groupIDStr := c.Param("groupID")
+	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// ユーザーがグループのメンバーであることを確認
+	var membership models.Membership
+	if err := database.DB.Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	var presets []models.SplitPreset
+	if err := database.DB.Where("group_id = ?", groupID).Order("name").Find(&presets).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch split presets"})
+		return
+	}
+
+	responses := make([]SplitPresetResponse, len(presets))
+	for i, preset := range presets {
+		responses[i] = splitPresetResponse(preset)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"groupID": groupID,
+		"presets": responses,
+	})
+}
+
+// CreateSplitPreset はグループに分け方のプリセットを追加します
+// POST /api/v1/groups/:groupID/split-presets
+func CreateSplitPreset(c *gin.Context) {
+	// パスパラメータからgroupIDを取得
+	groupIDStr := c.Param("groupID")
+	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// ユーザーがグループのメンバーであることを確認
+	var membership models.Membership
+	if err := database.DB.Preload("Group").Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	// アーカイブ済みのグループは読み取り専用
+	if membership.Group.ArchivedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Group is archived and read-only"})
+		return
+	}
+
+	// 支出を追加できるメンバーはプリセットも追加できる
+	if !hasPermission(membership.Role, PermAddExpense) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to add split presets"})
+		return
+	}
+
+	preset := models.SplitPreset{
+		GroupID:     uint(groupID),
+		CreatedByID: userID.(uint),
+	}
+	if !bindSplitPreset(c, uint(groupID), membership.Group.Currency, &preset) {
+		return
+	}
+
+	if err := database.DB.Create(&preset).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create split preset"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Split preset created successfully",
+		"preset":  splitPresetResponse(preset),
+	})
+}
+
+// UpdateSplitPreset は分け方のプリセットを更新します（登録済みの支出の負担額は変わりません）
+// PUT /api/v1/groups/:groupID/split-presets/:presetID
+func UpdateSplitPreset(c *gin.Context) {
+	// パスパラメータからgroupIDとpresetIDを取得
+	groupIDStr := c.Param("groupID")
+	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	presetIDStr := c.Param("presetID")
+	presetID, err := strconv.ParseUint(presetIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid split preset ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// ユーザーがグループのメンバーであることを確認
+	var membership models.Membership
+	if err := database.DB.Preload("Group").Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	// アーカイブ済みのグループは読み取り専用
+	if membership.Group.ArchivedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Group is archived and read-only"})
+		return
+	}
+
+	// リーガルホールド中のグループでは記録を変更できない
+	if rejectIfLegalHold(c, membership.Group) {
+		return
+	}
+
+	var preset models.SplitPreset
+	if err := database.DB.Where("id = ? AND group_id = ?", presetID, groupID).First(&preset).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Split preset not found"})
+		return
+	}
+
+	// 自分が作成したプリセットは PermEditOwnExpense、他のメンバーのものは PermEditAnyExpense が必要
+	if !hasPermission(membership.Role, splitPresetEditPermission(preset, userID.(uint))) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to edit this split preset"})
+		return
+	}
+
+	if !bindSplitPreset(c, uint(groupID), membership.Group.Currency, &preset) {
+		return
+	}
+
+	if err := database.DB.Save(&preset).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update split preset"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Split preset updated successfully",
+		"preset":  splitPresetResponse(preset),
+	})
+}
+
+// DeleteSplitPreset は分け方のプリセットを削除します（登録済みの支出の負担額は変わりません）
+// DELETE /api/v1/groups/:groupID/split-presets/:presetID
+func DeleteSplitPreset(c *gin.Context) {
+	// パスパラメータからgroupIDとpresetIDを取得
+	groupIDStr := c.Param("groupID")
+	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	presetIDStr := c.Param("presetID")
+	presetID, err := strconv.ParseUint(presetIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid split preset ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// ユーザーがグループのメンバーであることを確認
+	var membership models.Membership
+	if err := database.DB.Preload("Group").Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	// アーカイブ済みのグループは読み取り専用
+	if membership.Group.ArchivedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Group is archived and read-only"})
+		return
+	}
+
+	// リーガルホールド中のグループでは記録を変更できない
+	if rejectIfLegalHold(c, membership.Group) {
+		return
+	}
+
+	var preset models.SplitPreset
+	if err := database.DB.Where("id = ? AND group_id = ?", presetID, groupID).First(&preset).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Split preset not found"})
+		return
+	}
+
+	// プリセットを削除する権限があることを確認
+	if !hasPermission(membership.Role, splitPresetEditPermission(preset, userID.(uint))) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to delete this split preset"})
+		return
+	}
+
+	if err := database.DB.Delete(&preset).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete split preset"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Split preset deleted successfully"})
+}
+
+// splitPresetEditPermission は分け方のプリセットの編集・削除に必要な権限を返します（自分が作成したものは PermEditOwnExpense）
+func splitPresetEditPermission(preset models.SplitPreset, userID uint) Permission {
+	if preset.CreatedByID == userID {
+		return PermEditOwnExpense
+	}
+	return PermEditAnyExpense
+}
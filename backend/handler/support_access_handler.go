@@ -0,0 +1,271 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ito-system/clear-up-share/backend/database"
+	"github.com/ito-system/clear-up-share/backend/models"
+	"github.com/ito-system/clear-up-share/backend/utils"
+)
+
+// サポートでのなりすましトークンの最大有効期間（閲覧権限の期限の方が早い場合はその期限まで）
+const supportTokenTTL = time.Hour
+
+// supportAccessLogLimit は閲覧権限の一覧で返すアクセス記録の件数
+const supportAccessLogLimit = 100
+
+// GrantSupportAccessInput はサポート担当への閲覧権限の付与リクエストの入力形式
+type GrantSupportAccessInput struct {
+	Hours  int    `json:"hours" binding:"required,min=1,max=72"` // 閲覧を許可する時間
+	Reason string `json:"reason" binding:"max=500"`
+}
+
+// StartSupportImpersonationInput はサポート担当のなりすましリクエストの入力形式
+type StartSupportImpersonationInput struct {
+	Email string `json:"email" binding:"required,email"` // 閲覧するユーザーのメールアドレス
+}
+
+// SupportAccessGrantResponse はサポート担当への閲覧権限を表す形式
+type SupportAccessGrantResponse struct {
+	ID        uint       `json:"id"`
+	Reason    string     `json:"reason"`
+	ExpiresAt time.Time  `json:"expiresAt"`
+	RevokedAt *time.Time `json:"revokedAt"`
+	Active    bool       `json:"active"`
+	CreatedAt time.Time  `json:"createdAt"`
+}
+
+// SupportAccessLogResponse はサポート担当によるアクセスの記録を表す形式
+type SupportAccessLogResponse struct {
+	GrantID   uint      `json:"grantID"`
+	AdminName string    `json:"adminName"`
+	Method    string    `json:"method"`
+	Path      string    `json:"path"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// supportAccessGrantResponse はサポート担当への閲覧権限をレスポンスの形式にします
+func supportAccessGrantResponse(grant models.SupportAccessGrant, now time.Time) SupportAccessGrantResponse {
+	return SupportAccessGrantResponse{
+		ID:        grant.ID,
+		Reason:    grant.Reason,
+		ExpiresAt: grant.ExpiresAt,
+		RevokedAt: grant.RevokedAt,
+		Active:    grant.RevokedAt == nil && grant.ExpiresAt.After(now),
+		CreatedAt: grant.CreatedAt,
+	}
+}
+
+// GrantSupportAccess は残高の問い合わせの調査などのために、サポート担当の管理者に期限付きでグループの閲覧を許可します
+// POST /api/v1/users/me/support-access
+func GrantSupportAccess(c *gin.Context) {
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// リクエストボディをバインド
+	var input GrantSupportAccessInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	now := time.Now()
+	grant := models.SupportAccessGrant{
+		UserID:    userID.(uint),
+		Reason:    strings.TrimSpace(input.Reason),
+		ExpiresAt: now.Add(time.Duration(input.Hours) * time.Hour),
+	}
+	if err := database.DB.Create(&grant).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to grant support access"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Support access granted successfully",
+		"grant":   supportAccessGrantResponse(grant, now),
+	})
+}
+
+// GetMySupportAccess は認証ユーザーがサポート担当に与えた閲覧権限と、サポート担当によるアクセスの記録を新しい順に取得します
+// GET /api/v1/users/me/support-access
+func GetMySupportAccess(c *gin.Context) {
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var grants []models.SupportAccessGrant
+	if err := database.DB.Where("user_id = ?", userID).Order("created_at DESC").Find(&grants).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch support access"})
+		return
+	}
+
+	var logs []models.SupportAccessLog
+	if err := database.DB.Preload("Admin").Where("user_id = ?", userID).
+		Order("created_at DESC, id DESC").Limit(supportAccessLogLimit).Find(&logs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch support access logs"})
+		return
+	}
+
+	now := time.Now()
+	grantItems := make([]SupportAccessGrantResponse, len(grants))
+	for i, g := range grants {
+		grantItems[i] = supportAccessGrantResponse(g, now)
+	}
+	logItems := make([]SupportAccessLogResponse, len(logs))
+	for i, l := range logs {
+		logItems[i] = SupportAccessLogResponse{
+			GrantID:   l.GrantID,
+			AdminName: l.Admin.Username,
+			Method:    l.Method,
+			Path:      l.Path,
+			CreatedAt: l.CreatedAt,
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"grants": grantItems,
+		"logs":   logItems,
+	})
+}
+
+// RevokeSupportAccess はサポート担当への閲覧権限を取り消します（発行済みのなりすましトークンもすぐに使えなくなります）
+// DELETE /api/v1/users/me/support-access/:grantID
+func RevokeSupportAccess(c *gin.Context) {
+	// パスパラメータからgrantIDを取得
+	grantIDStr := c.Param("grantID")
+	grantID, err := strconv.ParseUint(grantIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid grant ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	result := database.DB.Model(&models.SupportAccessGrant{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", grantID, userID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke support access"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Grant not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Support access revoked successfully",
+	})
+}
+
+// StartSupportImpersonation はサポート担当の管理者が、閲覧を許可したユーザーになりすます読み取り専用トークンを発行します
+// トークンはグループのルートの閲覧（GET）にのみ使え、アクセスは全てユーザーが確認できる記録に残ります
+// POST /api/v1/support/impersonate
+func StartSupportImpersonation(c *gin.Context) {
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// 権限を絞ったトークン・OAuth2トークン・なりすましトークンからはなりすましできない
+	if c.GetString("tokenScope") != utils.ScopeFull || c.GetUint("tokenGroupID") != 0 || c.GetUint("oauthGrantID") != 0 ||
+		c.GetBool("tokenExchanged") || c.GetUint("supportGrantID") != 0 {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Support access requires a full login token"})
+		return
+	}
+
+	// サポート担当の管理者であることを確認
+	var admin models.User
+	if !utils.IsSupportAdmin(userID.(uint)) || database.DB.First(&admin, userID).Error != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a support admin"})
+		return
+	}
+
+	// リクエストボディをバインド
+	var input StartSupportImpersonationInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var user models.User
+	if err := database.DB.Where("LOWER(email) = ? AND is_placeholder = ?", utils.NormalizeEmail(input.Email), false).First(&user).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if user.ID == admin.ID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot impersonate yourself"})
+		return
+	}
+
+	// ユーザーが閲覧を許可していることを確認（複数ある場合は期限が最も遅いもの）
+	now := time.Now()
+	var grant models.SupportAccessGrant
+	if err := database.DB.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", user.ID, now).
+		Order("expires_at DESC").First(&grant).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "User has not granted support access"})
+		return
+	}
+
+	expiresAt := now.Add(supportTokenTTL)
+	if grant.ExpiresAt.Before(expiresAt) {
+		expiresAt = grant.ExpiresAt
+	}
+
+	// トランザクション開始
+	tx := database.DB.Begin()
+
+	// なりすましの開始も記録し、ユーザーに通知する
+	if err := tx.Create(&models.SupportAccessLog{
+		GrantID: grant.ID,
+		AdminID: admin.ID,
+		UserID:  user.ID,
+		Method:  c.Request.Method,
+		Path:    c.Request.URL.RequestURI(),
+	}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record support access"})
+		return
+	}
+	message := admin.Username + " from support started viewing your groups"
+	if err := notify(tx, user.ID, 0, models.NotificationSupportAccessStarted, message, "support_access", grant.ID); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send notification"})
+		return
+	}
+
+	token, err := utils.GenerateSupportJWT(user.ID, admin.ID, grant.ID, expiresAt.Sub(now))
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	tx.Commit()
+
+	c.JSON(http.StatusOK, gin.H{
+		"token":     token,
+		"scope":     utils.ScopeRead,
+		"userID":    user.ID,
+		"grantID":   grant.ID,
+		"expiresAt": expiresAt,
+	})
+}
@@ -0,0 +1,123 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ito-system/clear-up-share/backend/database"
+	"github.com/ito-system/clear-up-share/backend/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// TagResponse はタグのレスポンス形式
+type TagResponse struct {
+	Name         string `json:"name"`
+	ExpenseCount int64  `json:"expenseCount"` // タグが付いた支出の件数（削除済みの支出を除く）
+}
+
+// normalizeTags はタグ名の前後の空白を除いて小文字に揃え、空のものと重複を取り除きます
+func normalizeTags(names []string) []string {
+	seen := make(map[string]bool, len(names))
+	tags := []string{}
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		tags = append(tags, name)
+	}
+	return tags
+}
+
+// resolveTags はグループのタグを名前から取得し、まだないものは作成します
+func resolveTags(tx *gorm.DB, groupID uint, names []string) ([]models.Tag, error) {
+	tags := []models.Tag{}
+	if len(names) == 0 {
+		return tags, nil
+	}
+
+	created := make([]models.Tag, len(names))
+	for i, name := range names {
+		created[i] = models.Tag{GroupID: groupID, Name: name}
+	}
+	// 同時に作成された場合は先に作成されたタグを使う
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&created).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Where("group_id = ? AND name IN ?", groupID, names).Order("name").Find(&tags).Error; err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+// tagNames はタグの名前の一覧を返します
+func tagNames(tags []models.Tag) []string {
+	names := make([]string, len(tags))
+	for i, tag := range tags {
+		names[i] = tag.Name
+	}
+	return names
+}
+
+// orderTagsByName は支出のタグを名前順にプリロードします
+func orderTagsByName(db *gorm.DB) *gorm.DB {
+	return db.Order("tags.name")
+}
+
+// tagFilter は ?tag= の値からそのタグが付いた支出に絞り込む条件を作ります
+func tagFilter(name string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where(
+			"id IN (SELECT expense_tags.expense_id FROM expense_tags JOIN tags ON tags.id = expense_tags.tag_id WHERE tags.name = ?)",
+			strings.ToLower(strings.TrimSpace(name)),
+		)
+	}
+}
+
+// GetTags はグループのタグ一覧を使用回数の多い順に取得します
+// GET /api/v1/groups/:groupID/tags
+func GetTags(c *gin.Context) {
+	// パスパラメータからgroupIDを取得
+	groupIDStr := c.Param("groupID")
+	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// ユーザーがグループのメンバーであることを確認
+	var membership models.Membership
+	if err := database.DB.Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	tags := []TagResponse{}
+	if err := database.DB.Model(&models.Tag{}).
+		Select("tags.name, COUNT(expenses.id) AS expense_count").
+		Joins("LEFT JOIN expense_tags ON expense_tags.tag_id = tags.id").
+		Joins("LEFT JOIN expenses ON expenses.id = expense_tags.expense_id AND expenses.deleted_at IS NULL AND expenses.status <> ?", models.ExpenseStatusDraft).
+		Where("tags.group_id = ?", groupID).
+		Group("tags.id, tags.name").
+		Order("expense_count DESC, tags.name").
+		Scan(&tags).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tags"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"groupID": groupID,
+		"tags":    tags,
+	})
+}
@@ -0,0 +1,256 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ito-system/clear-up-share/backend/database"
+	"github.com/ito-system/clear-up-share/backend/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// TrashItem はゴミ箱の支出の形式
+type TrashItem struct {
+	ID          uint      `json:"id"`
+	Description string    `json:"description"`
+	Amount      float64   `json:"amount"`
+	Date        string    `json:"date"`
+	PayerID     uint      `json:"payerID"`
+	Status      string    `json:"status"`
+	DeletedAt   time.Time `json:"deletedAt"`
+}
+
+// GetTrash はグループの削除済み（ゴミ箱の）支出を削除日時の新しい順に取得します
+// 削除済みの支出は負債計算に含まれず、保持期間ポリシーで物理削除されるまで RestoreExpense で復元できます
+// GET /api/v1/groups/:groupID/expenses/trash?page=&limit=
+func GetTrash(c *gin.Context) {
+	// パスパラメータからgroupIDを取得
+	groupIDStr := c.Param("groupID")
+	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// ユーザーがグループのメンバーであることを確認
+	var membership models.Membership
+	if err := database.DB.Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	page, limit := parsePagination(c, 20, 100)
+
+	query := database.DB.Unscoped().Model(&models.Expense{}).
+		Where("expenses.group_id = ? AND expenses.deleted_at IS NOT NULL", groupID).
+		Scopes(visibleExpenses(userID, true))
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count deleted expenses"})
+		return
+	}
+
+	var expenses []models.Expense
+	if err := query.Session(&gorm.Session{}).
+		Order("deleted_at DESC, id DESC").
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Find(&expenses).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch deleted expenses"})
+		return
+	}
+
+	items := make([]TrashItem, len(expenses))
+	for i, expense := range expenses {
+		items[i] = TrashItem{
+			ID:          expense.ID,
+			Description: expense.Description,
+			Amount:      expense.Amount,
+			Date:        expense.Date.Format("2006-01-02"),
+			PayerID:     expense.PayerID,
+			Status:      expense.Status,
+			DeletedAt:   expense.DeletedAt.Time,
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"groupID":  groupID,
+		"expenses": items,
+		"page":     page,
+		"limit":    limit,
+		"total":    total,
+	})
+}
+
+// RestoreExpense はゴミ箱の支出を、削除したときの負担額・品目・レシートとともに復元します
+// 削除と同じ権限が必要で、支払者や負担者が退会している場合は復元できません
+// POST /api/v1/groups/:groupID/expenses/:expenseID/restore
+func RestoreExpense(c *gin.Context) {
+	// パスパラメータからgroupIDとexpenseIDを取得
+	groupIDStr := c.Param("groupID")
+	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	expenseIDStr := c.Param("expenseID")
+	expenseID, err := strconv.ParseUint(expenseIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid expense ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// ユーザーがグループのメンバーであることを確認
+	var membership models.Membership
+	if err := database.DB.Preload("Group").Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	// アーカイブ済みのグループは読み取り専用
+	if membership.Group.ArchivedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Group is archived and read-only"})
+		return
+	}
+
+	// リーガルホールド中のグループでは記録を変更できない
+	if rejectIfLegalHold(c, membership.Group) {
+		return
+	}
+
+	// グループのポリシー設定を取得
+	settings, err := loadGroupSettings(database.DB, uint(groupID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch group settings"})
+		return
+	}
+
+	// トランザクション開始
+	tx := database.DB.Begin()
+
+	// ゴミ箱の支出を取得し、同時に復元されないようにする
+	var expense models.Expense
+	if err := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).
+		Scopes(visibleExpenses(userID, true)).
+		Where("id = ? AND group_id = ? AND deleted_at IS NOT NULL", expenseID, groupID).
+		First(&expense).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusNotFound, gin.H{"error": "Deleted expense not found"})
+		return
+	}
+
+	// 支出を復元する権限があることを確認（削除と同じ権限）
+	if !hasPermission(membership.Role, expenseEditPermission(expense, userID.(uint))) {
+		tx.Rollback()
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to restore this expense"})
+		return
+	}
+
+	// メンバーによる編集が許可されていない場合は、管理者のみ復元できる
+	if !settings.AllowMemberEdit && !hasPermission(membership.Role, PermEditAnyExpense) {
+		tx.Rollback()
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only admins can restore expenses in this group"})
+		return
+	}
+
+	// 支出と同時に削除されたSplitのみを対象にする（編集で置き換えられた古いSplitは復元しない）
+	var splits []models.Split
+	if err := tx.Unscoped().Scopes(deletedWithExpense("splits", expense)).Find(&splits).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch splits"})
+		return
+	}
+
+	// 削除した後に支払者や負担者が退会していないことを確認
+	nonMembers, err := lockGroupNonMembers(tx, uint(groupID), append([]uint{expense.PayerID}, splitDebtorIDs(splits)...))
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check members"})
+		return
+	}
+	if len(nonMembers) > 0 {
+		tx.Rollback()
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "Payer or split members have left this group",
+			"userIDs": nonMembers,
+		})
+		return
+	}
+
+	// 関連するレコードを復元
+	if err := tx.Unscoped().Model(&models.Split{}).Scopes(deletedWithExpense("splits", expense)).Update("deleted_at", nil).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore splits"})
+		return
+	}
+	if err := tx.Unscoped().Model(&models.ExpenseItem{}).Scopes(deletedWithExpense("expense_items", expense)).Update("deleted_at", nil).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore expense items"})
+		return
+	}
+	if err := tx.Unscoped().Model(&models.Receipt{}).Scopes(deletedWithExpense("receipts", expense)).Update("deleted_at", nil).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore receipts"})
+		return
+	}
+
+	// Expenseを復元
+	if err := tx.Unscoped().Model(&expense).Update("deleted_at", nil).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore expense"})
+		return
+	}
+
+	// アクティビティを記録（他のメンバーに見えない下書きの復元は記録しない）
+	if expense.Status != models.ExpenseStatusDraft {
+		if err := recordActivity(tx, expense.GroupID, userID.(uint), models.ActivityExpenseRestored, "expense", expense.ID, map[string]interface{}{
+			"description": expense.Description,
+			"amount":      expense.Amount,
+		}); err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record activity"})
+			return
+		}
+	}
+
+	tx.Commit()
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Expense restored successfully",
+		"expense": gin.H{
+			"id":      expense.ID,
+			"groupID": expense.GroupID,
+			"status":  expense.Status,
+		},
+	})
+}
+
+// deletedWithExpense は支出と同時に論理削除された関連レコード（table の expense_id が支出のもの）を絞り込みます
+// DeleteExpense は関連レコードを支出と同じ日時で削除しますが、以前に削除した支出は関連レコードの削除日時がわずかに早いため、
+// 支出の削除までの1秒以内に最後に削除されたレコードを対象にします
+func deletedWithExpense(table string, expense models.Expense) func(db *gorm.DB) *gorm.DB {
+	deletedAt := expense.DeletedAt.Time
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("expense_id = ? AND deleted_at = (SELECT MAX(deleted_at) FROM "+table+" WHERE expense_id = ? AND deleted_at BETWEEN ? AND ?)",
+			expense.ID, expense.ID, deletedAt.Add(-time.Second), deletedAt)
+	}
+}
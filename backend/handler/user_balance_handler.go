@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ito-system/clear-up-share/backend/database"
+	"github.com/ito-system/clear-up-share/backend/money"
+)
+
+// MyGroupBalance は所属するグループでの認証ユーザーの貸借額を表す形式
+type MyGroupBalance struct {
+	GroupID  uint    `json:"groupID"`
+	Name     string  `json:"name"`
+	Currency string  `json:"currency"`
+	Balance  float64 `json:"balance"`            // 正: 受け取る、負: 支払う
+	Archived bool    `json:"archived,omitempty"` // アーカイブ済みのグループ（貸借は合計に含める）
+}
+
+// CurrencyBalance は通貨ごとの貸借額の合計を表す形式
+type CurrencyBalance struct {
+	Currency string  `json:"currency"`
+	Balance  float64 `json:"balance"`
+}
+
+// GetMyBalances は認証ユーザーが所属する全てのグループでの貸借額と、通貨ごとの合計を返します
+// 貸借額は支出・清算ごとに計算せず、全グループ分を1つの集計SQLで求めます
+// GET /api/v1/users/me/balances
+func GetMyBalances(c *gin.Context) {
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	type groupRow struct {
+		ID       uint
+		Name     string
+		Currency string
+		Archived bool
+	}
+
+	var groups []groupRow
+	if err := database.DB.Table("memberships").
+		Select("groups.id, groups.name, groups.currency, groups.archived_at IS NOT NULL AS archived").
+		Joins("JOIN groups ON groups.id = memberships.group_id AND groups.deleted_at IS NULL").
+		Where("memberships.user_id = ? AND memberships.deleted_at IS NULL", userID).
+		Order("groups.id").
+		Scan(&groups).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch groups"})
+		return
+	}
+
+	groupIDs := make([]uint, len(groups))
+	for i, g := range groups {
+		groupIDs[i] = g.ID
+	}
+	totals, err := groupsBalanceTotals(database.DB, groupIDs, userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to calculate balances"})
+		return
+	}
+
+	// 通貨の異なるグループの貸借は合算せず、通貨ごとに補助単位で合計する
+	balances := make([]MyGroupBalance, len(groups))
+	units := make(map[string]int64)
+	for i, g := range groups {
+		balance := money.Round(totals[g.ID].MyBalance, g.Currency)
+		balances[i] = MyGroupBalance{
+			GroupID:  g.ID,
+			Name:     g.Name,
+			Currency: g.Currency,
+			Balance:  balance,
+			Archived: g.Archived,
+		}
+		units[g.Currency] += money.ToMinor(balance, g.Currency)
+	}
+
+	total := make([]CurrencyBalance, 0, len(units))
+	for currency, u := range units {
+		total = append(total, CurrencyBalance{Currency: currency, Balance: money.FromMinor(u, currency)})
+	}
+	sort.Slice(total, func(i, j int) bool { return total[i].Currency < total[j].Currency })
+
+	c.JSON(http.StatusOK, gin.H{
+		"groups": balances,
+		"total":  total,
+	})
+}
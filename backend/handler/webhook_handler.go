@@ -0,0 +1,418 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ito-system/clear-up-share/backend/database"
+	"github.com/ito-system/clear-up-share/backend/models"
+	"github.com/ito-system/clear-up-share/backend/utils"
+	"github.com/ito-system/clear-up-share/backend/webhook"
+	"gorm.io/gorm"
+)
+
+// webhookEvents はWebhookで購読できるイベント（アクティビティの種類）
+var webhookEvents = map[string]bool{
+	models.ActivityExpenseAdded:        true,
+	models.ActivityExpenseEdited:       true,
+	models.ActivityExpenseDeleted:      true,
+	models.ActivityExpenseApproved:     true,
+	models.ActivityExpenseRestored:     true,
+	models.ActivitySettlementRecorded:  true,
+	models.ActivitySettlementVoided:    true,
+	models.ActivitySettlementConfirmed: true,
+	models.ActivitySettlementEdited:    true,
+	models.ActivityDebtForgiven:        true,
+}
+
+// CreateWebhookInput はWebhook登録リクエストの入力形式
+type CreateWebhookInput struct {
+	URL    string   `json:"url" binding:"required,url,max=2048"`
+	Events []string `json:"events" binding:"required,min=1"`
+}
+
+// UpdateWebhookInput はWebhook更新リクエストの入力形式（指定されたフィールドのみ更新）
+type UpdateWebhookInput struct {
+	URL    *string  `json:"url" binding:"omitempty,url,max=2048"`
+	Events []string `json:"events" binding:"omitempty,min=1"`
+	Active *bool    `json:"active"`
+}
+
+// WebhookPayload はWebhookで送信するJSONの形式
+type WebhookPayload struct {
+	Event      string                 `json:"event"`
+	GroupID    uint                   `json:"groupID"`
+	ActorID    uint                   `json:"actorID"`
+	TargetType string                 `json:"targetType"`
+	TargetID   uint                   `json:"targetID"`
+	Details    map[string]interface{} `json:"details,omitempty"`
+	OccurredAt time.Time              `json:"occurredAt"`
+}
+
+// validWebhookURL はWebhookの送信先が http / https のURLで、内部アドレスを指していないかを判定します
+// ホスト名が内部アドレスに解決される場合は、送信時に接続先のアドレスで拒否されます
+func validWebhookURL(raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return false
+	}
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return webhook.IsPublicAddress(addr)
+	}
+	return host != "localhost" && !strings.HasSuffix(host, ".localhost")
+}
+
+// validWebhookEvents は購読するイベントが全て定義済みのものかを判定します
+func validWebhookEvents(events []string) bool {
+	for _, event := range events {
+		if !webhookEvents[event] {
+			return false
+		}
+	}
+	return true
+}
+
+// webhookSubscribes はWebhookが指定のイベントを購読しているかを判定します
+func webhookSubscribes(webhook models.Webhook, event string) bool {
+	for _, e := range strings.Fields(webhook.Events) {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// webhookResponse はWebhookのレスポンス形式を返します（シークレットは含めません）
+func webhookResponse(webhook models.Webhook) gin.H {
+	return gin.H{
+		"id":        webhook.ID,
+		"groupID":   webhook.GroupID,
+		"url":       webhook.URL,
+		"events":    strings.Fields(webhook.Events),
+		"active":    webhook.Active,
+		"createdAt": webhook.CreatedAt,
+	}
+}
+
+// enqueueWebhooks はイベントを購読している有効なWebhookへの配信を送信待ちに登録します
+// 変更操作と同じトランザクション (tx) 内で呼び出してください
+func enqueueWebhooks(tx *gorm.DB, payload WebhookPayload) error {
+	if !webhookEvents[payload.Event] || !utils.FeatureEnabled(utils.FeatureWebhooks) {
+		return nil
+	}
+
+	var webhooks []models.Webhook
+	if err := tx.Where("group_id = ? AND active = ?", payload.GroupID, true).Find(&webhooks).Error; err != nil {
+		return err
+	}
+
+	var body []byte
+	for _, webhook := range webhooks {
+		if !webhookSubscribes(webhook, payload.Event) {
+			continue
+		}
+
+		if body == nil {
+			b, err := json.Marshal(payload)
+			if err != nil {
+				return err
+			}
+			body = b
+		}
+
+		if err := tx.Create(&models.WebhookDelivery{
+			WebhookID:     webhook.ID,
+			Event:         payload.Event,
+			Payload:       string(body),
+			NextAttemptAt: payload.OccurredAt,
+		}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetWebhooks はグループのWebhook一覧を取得します
+// GET /api/v1/groups/:groupID/webhooks
+func GetWebhooks(c *gin.Context) {
+	// パスパラメータからgroupIDを取得
+	groupIDStr := c.Param("groupID")
+	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// ユーザーがグループのメンバーであることを確認
+	var membership models.Membership
+	if err := database.DB.Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	// グループを管理する権限があることを確認
+	if !hasPermission(membership.Role, PermManageGroup) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to manage this group"})
+		return
+	}
+
+	var webhooks []models.Webhook
+	if err := database.DB.Where("group_id = ?", groupID).Order("id").Find(&webhooks).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch webhooks"})
+		return
+	}
+
+	items := make([]gin.H, len(webhooks))
+	for i, webhook := range webhooks {
+		items[i] = webhookResponse(webhook)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"groupID":  groupID,
+		"webhooks": items,
+	})
+}
+
+// CreateWebhook はグループにWebhookを登録します（署名用シークレットはこのレスポンスでのみ返します）
+// POST /api/v1/groups/:groupID/webhooks
+func CreateWebhook(c *gin.Context) {
+	// パスパラメータからgroupIDを取得
+	groupIDStr := c.Param("groupID")
+	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// ユーザーがグループのメンバーであることを確認
+	var membership models.Membership
+	if err := database.DB.Preload("Group").Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	// アーカイブ済みのグループは読み取り専用
+	if membership.Group.ArchivedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Group is archived and read-only"})
+		return
+	}
+
+	// グループを管理する権限があることを確認
+	if !hasPermission(membership.Role, PermManageGroup) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to manage this group"})
+		return
+	}
+
+	// リクエストボディをバインド
+	var input CreateWebhookInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !validWebhookURL(input.URL) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Webhook URL must be a public http or https URL"})
+		return
+	}
+
+	if !validWebhookEvents(input.Events) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported webhook event"})
+		return
+	}
+
+	secret, err := randomToken(32)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate webhook secret"})
+		return
+	}
+
+	webhook := models.Webhook{
+		GroupID:     uint(groupID),
+		URL:         input.URL,
+		Secret:      secret,
+		Events:      strings.Join(input.Events, " "),
+		Active:      true,
+		CreatedByID: userID.(uint),
+	}
+
+	if err := database.DB.Create(&webhook).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create webhook"})
+		return
+	}
+
+	response := webhookResponse(webhook)
+	response["secret"] = webhook.Secret
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Webhook created successfully",
+		"webhook": response,
+	})
+}
+
+// UpdateWebhook はWebhookの送信先・イベント・有効状態を更新します
+// PUT /api/v1/groups/:groupID/webhooks/:webhookID
+func UpdateWebhook(c *gin.Context) {
+	// パスパラメータからgroupIDとwebhookIDを取得
+	groupIDStr := c.Param("groupID")
+	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	webhookIDStr := c.Param("webhookID")
+	webhookID, err := strconv.ParseUint(webhookIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// ユーザーがグループのメンバーであることを確認
+	var membership models.Membership
+	if err := database.DB.Preload("Group").Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	// アーカイブ済みのグループは読み取り専用
+	if membership.Group.ArchivedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Group is archived and read-only"})
+		return
+	}
+
+	// グループを管理する権限があることを確認
+	if !hasPermission(membership.Role, PermManageGroup) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to manage this group"})
+		return
+	}
+
+	// リクエストボディをバインド
+	var input UpdateWebhookInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var webhook models.Webhook
+	if err := database.DB.Where("id = ? AND group_id = ?", webhookID, groupID).First(&webhook).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Webhook not found"})
+		return
+	}
+
+	// 指定されたフィールドのみ更新
+	if input.URL != nil {
+		if !validWebhookURL(*input.URL) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Webhook URL must be a public http or https URL"})
+			return
+		}
+		webhook.URL = *input.URL
+	}
+	if input.Events != nil {
+		if !validWebhookEvents(input.Events) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported webhook event"})
+			return
+		}
+		webhook.Events = strings.Join(input.Events, " ")
+	}
+	if input.Active != nil {
+		webhook.Active = *input.Active
+	}
+
+	if err := database.DB.Save(&webhook).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update webhook"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Webhook updated successfully",
+		"webhook": webhookResponse(webhook),
+	})
+}
+
+// DeleteWebhook はWebhookを削除します（送信待ちの配信は送信されません）
+// DELETE /api/v1/groups/:groupID/webhooks/:webhookID
+func DeleteWebhook(c *gin.Context) {
+	// パスパラメータからgroupIDとwebhookIDを取得
+	groupIDStr := c.Param("groupID")
+	groupID, err := strconv.ParseUint(groupIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group ID"})
+		return
+	}
+
+	webhookIDStr := c.Param("webhookID")
+	webhookID, err := strconv.ParseUint(webhookIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook ID"})
+		return
+	}
+
+	// コンテキストからuserIDを取得
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// ユーザーがグループのメンバーであることを確認
+	var membership models.Membership
+	if err := database.DB.Preload("Group").Where("user_id = ? AND group_id = ?", userID, groupID).First(&membership).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a member of this group"})
+		return
+	}
+
+	// アーカイブ済みのグループは読み取り専用
+	if membership.Group.ArchivedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Group is archived and read-only"})
+		return
+	}
+
+	// グループを管理する権限があることを確認
+	if !hasPermission(membership.Role, PermManageGroup) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to manage this group"})
+		return
+	}
+
+	var webhook models.Webhook
+	if err := database.DB.Where("id = ? AND group_id = ?", webhookID, groupID).First(&webhook).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Webhook not found"})
+		return
+	}
+
+	if err := database.DB.Delete(&webhook).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete webhook"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Webhook deleted successfully",
+	})
+}
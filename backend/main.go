@@ -1,15 +1,28 @@
 package main
 
 import (
+	"encoding/json"
+	"flag"
+	"fmt"
 	"log"
+	_ "time/tzdata" // 実行用イメージにタイムゾーンデータがなくてもグループのタイムゾーンを読み込めるようにする
 
+	"github.com/ito-system/clear-up-share/backend/analytics"
 	"github.com/ito-system/clear-up-share/backend/database"
+	"github.com/ito-system/clear-up-share/backend/handler"
+	"github.com/ito-system/clear-up-share/backend/retention"
 	"github.com/ito-system/clear-up-share/backend/router"
+	"github.com/ito-system/clear-up-share/backend/storage"
+	"github.com/ito-system/clear-up-share/backend/stripe"
 	"github.com/ito-system/clear-up-share/backend/utils"
+	"github.com/ito-system/clear-up-share/backend/webhook"
 	"github.com/joho/godotenv"
 )
 
 func main() {
+	retentionReport := flag.Bool("retention-report", false, "print the retention policy dry-run report and exit")
+	flag.Parse()
+
 	// .envファイルを読み込む（存在しない場合は無視）
 	if err := godotenv.Load("../.env"); err != nil {
 		log.Println("No .env file found, using environment variables or defaults")
@@ -18,9 +31,60 @@ func main() {
 	// JWTシークレットを初期化
 	utils.InitJWT()
 
+	// 利用上限・無効化する機能・サポート担当の管理者を初期化
+	utils.InitLimits()
+	utils.InitFeatures()
+	utils.InitSupportAdmins()
+
+	// レシートなどのファイルの保存先を初期化
+	storage.Init()
+
+	// アプリ内のカード決済を初期化（STRIPE_SECRET_KEY 設定時のみ）
+	stripe.Init()
+
 	// データベース初期化
 	database.InitDB()
 
+	// -retention-report 指定時は保持期間ポリシーの対象件数を出力して終了
+	if *retentionReport {
+		report, err := retention.Run(database.DB, retention.LoadPolicy(), true)
+		if err != nil {
+			log.Fatal("Failed to run retention policy:", err)
+		}
+		out, _ := json.MarshalIndent(report, "", "  ")
+		fmt.Println(string(out))
+		return
+	}
+
+	// データベースの死活確認を開始（障害時は縮退運転に切り替える）
+	database.StartHealthCheck()
+
+	// 利用状況の収集を初期化（ANALYTICS_SINK 設定時のみ）
+	analytics.Init()
+
+	// 保持期間ポリシーの定期実行を開始（RETENTION_MODE 設定時のみ）
+	retention.StartScheduler()
+
+	// 定期的な支出の登録を開始
+	handler.StartRecurringScheduler()
+
+	// まとめ買いの注文の締め切り処理を開始
+	handler.StartGroupOrderScheduler()
+
+	// メンバーごとの負担額の上限の確認を開始
+	handler.StartSpendingLimitScheduler()
+
+	// 未精算のリマインダーの送信を開始
+	handler.StartDebtReminderScheduler()
+
+	// 清算期間の毎月の自動締めを開始
+	handler.StartClosingScheduler()
+
+	// Webhook配信ワーカーを起動
+	if utils.FeatureEnabled(utils.FeatureWebhooks) {
+		webhook.StartWorker()
+	}
+
 	// ルーター設定
 	r := router.SetupRouter()
 
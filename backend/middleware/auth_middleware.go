@@ -2,10 +2,13 @@ package middleware
 
 import (
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
+	"github.com/ito-system/clear-up-share/backend/database"
+	"github.com/ito-system/clear-up-share/backend/models"
 	"github.com/ito-system/clear-up-share/backend/utils"
 )
 
@@ -28,39 +31,118 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		// トークンを検証
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, jwt.ErrSignatureInvalid
-			}
-			return utils.JWTSecret, nil
-		})
-
-		if err != nil || !token.Valid {
+		// トークンを検証してクレームを取得
+		claims, err := utils.ParseJWT(tokenString)
+		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
 			c.Abort()
 			return
 		}
 
 		// クレームからuserIDを取得
-		claims, ok := token.Claims.(jwt.MapClaims)
+		userIDFloat, ok := claims["userID"].(float64)
 		if !ok {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims"})
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid userID in token"})
 			c.Abort()
 			return
 		}
 
-		userIDFloat, ok := claims["userID"].(float64)
+		userID := uint(userIDFloat)
+
+		// スコープを取得（スコープ導入前のトークンは全権限として扱う）
+		scope, ok := claims["scope"].(string)
 		if !ok {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid userID in token"})
+			scope = utils.ScopeFull
+		}
+
+		// 読み取り専用トークンでは書き込み操作を拒否
+		if scope == utils.ScopeRead && c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Token scope does not allow write access"})
 			c.Abort()
 			return
 		}
 
-		userID := uint(userIDFloat)
+		// グループ限定トークンでは対象グループ以外へのアクセスを拒否
+		var tokenGroupID uint
+		if groupIDFloat, ok := claims["groupID"].(float64); ok {
+			tokenGroupID = uint(groupIDFloat)
+			if c.Param("groupID") != strconv.FormatUint(uint64(tokenGroupID), 10) {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Token is restricted to a different group"})
+				c.Abort()
+				return
+			}
+		}
+
+		// OAuth2トークンは権限付与が取り消されていないことを確認
+		var oauthGrantID uint
+		if grantIDFloat, ok := claims["grantID"].(float64); ok {
+			oauthGrantID = uint(grantIDFloat)
+			if !utils.FeatureEnabled(utils.FeatureOAuth) {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Third-party app access is disabled"})
+				c.Abort()
+				return
+			}
+			var grant models.OAuthGrant
+			if err := database.DB.Where("id = ? AND user_id = ? AND revoked_at IS NULL", oauthGrantID, userID).First(&grant).Error; err != nil {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Token has been revoked"})
+				c.Abort()
+				return
+			}
+		}
+
+		// サポートでのなりすましトークンは、ユーザーの閲覧権限が有効な間だけグループの閲覧に使える
+		var supportGrantID, impersonatorID uint
+		if grantIDFloat, ok := claims["supportGrantID"].(float64); ok {
+			supportGrantID = uint(grantIDFloat)
+			impersonatorIDFloat, _ := claims["impersonatorID"].(float64)
+			impersonatorID = uint(impersonatorIDFloat)
+
+			var grant models.SupportAccessGrant
+			if err := database.DB.Where("id = ? AND user_id = ? AND revoked_at IS NULL AND expires_at > ?", supportGrantID, userID, time.Now()).First(&grant).Error; err != nil {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Support access has been revoked or has expired"})
+				c.Abort()
+				return
+			}
+			if !utils.IsSupportAdmin(impersonatorID) {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Support access has been revoked or has expired"})
+				c.Abort()
+				return
+			}
+			if !strings.HasPrefix(c.FullPath(), "/api/v1/groups") {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Support access is limited to viewing groups"})
+				c.Abort()
+				return
+			}
+
+			// 全てのアクセスを記録する（記録できない場合はアクセスさせない）
+			path := c.Request.URL.RequestURI()
+			if len(path) > 500 {
+				path = path[:500]
+			}
+			if err := database.DB.Create(&models.SupportAccessLog{
+				GrantID: supportGrantID,
+				AdminID: impersonatorID,
+				UserID:  userID,
+				Method:  c.Request.Method,
+				Path:    path,
+			}).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record support access"})
+				c.Abort()
+				return
+			}
+		}
+
+		// 交換で発行されたトークンかどうか
+		exchanged, _ := claims["exchanged"].(bool)
 
-		// userIDをコンテキストに設定
+		// userIDとトークンの権限範囲をコンテキストに設定
 		c.Set("userID", userID)
+		c.Set("tokenScope", scope)
+		c.Set("tokenGroupID", tokenGroupID)
+		c.Set("oauthGrantID", oauthGrantID)
+		c.Set("tokenExchanged", exchanged)
+		c.Set("supportGrantID", supportGrantID)
+		c.Set("impersonatorID", impersonatorID)
 		c.Next()
 	}
 }
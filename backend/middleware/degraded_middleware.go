@@ -0,0 +1,156 @@
+package middleware
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ito-system/clear-up-share/backend/database"
+	"github.com/ito-system/clear-up-share/backend/utils"
+)
+
+const (
+	retryAfterSeconds = 30   // 縮退運転中に再試行を促す秒数
+	staleCacheMaxSize = 1000 // 保持するレスポンスの最大件数
+)
+
+// staleResponse は縮退運転中に返すために保持しているレスポンス
+type staleResponse struct {
+	ContentType string
+	Body        []byte
+	CachedAt    time.Time
+}
+
+var (
+	staleCacheMu sync.RWMutex
+	staleCache   = map[string]staleResponse{}
+)
+
+// cachingWriter はレスポンスボディを記録するためのResponseWriter
+type cachingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *cachingWriter) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+// DegradedMode はデータベースの縮退運転中に書き込みを 503 で拒否するミドルウェア
+// cachedPaths に指定したルート（例: "/api/v1/groups/:groupID/debts"）は、通常運転中の最新のレスポンスを
+// ユーザーごとに保持しておき、縮退運転中はそれを返します
+func DegradedMode(cachedPaths ...string) gin.HandlerFunc {
+	cached := make(map[string]bool, len(cachedPaths))
+	for _, path := range cachedPaths {
+		cached[path] = true
+	}
+
+	return func(c *gin.Context) {
+		cacheable := c.Request.Method == http.MethodGet && cached[c.FullPath()]
+
+		if !database.Degraded() {
+			if !cacheable {
+				c.Next()
+				return
+			}
+
+			// 正常なレスポンスを保持
+			writer := &cachingWriter{ResponseWriter: c.Writer}
+			c.Writer = writer
+			c.Next()
+
+			userID, exists := c.Get("userID")
+			if exists && writer.Status() == http.StatusOK {
+				storeStaleResponse(staleCacheKey(userID.(uint), c.Request.URL.RequestURI()), staleResponse{
+					ContentType: writer.Header().Get("Content-Type"),
+					Body:        writer.body.Bytes(),
+					CachedAt:    time.Now(),
+				})
+			}
+			return
+		}
+
+		// 保持しているレスポンスがあれば返す（データベースを使わずにトークンを検証）
+		if cacheable {
+			if userID, ok := userIDFromToken(c); ok {
+				staleCacheMu.RLock()
+				response, found := staleCache[staleCacheKey(userID, c.Request.URL.RequestURI())]
+				staleCacheMu.RUnlock()
+
+				if found {
+					c.Header("X-ClearUp-Stale", "true")
+					c.Header("X-ClearUp-Cached-At", response.CachedAt.UTC().Format(time.RFC3339))
+					c.Data(http.StatusOK, response.ContentType, response.Body)
+					c.Abort()
+					return
+				}
+			}
+		}
+
+		c.Header("Retry-After", strconv.Itoa(retryAfterSeconds))
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":      "Service is temporarily read-only due to a database outage",
+			"retryAfter": retryAfterSeconds,
+		})
+		c.Abort()
+	}
+}
+
+// staleCacheKey はユーザーとリクエストURLごとのキャッシュキーを返します
+func staleCacheKey(userID uint, uri string) string {
+	return fmt.Sprintf("%d %s", userID, uri)
+}
+
+// storeStaleResponse はレスポンスを保持します（上限を超える場合は最も古いものを削除します）
+func storeStaleResponse(key string, response staleResponse) {
+	staleCacheMu.Lock()
+	defer staleCacheMu.Unlock()
+
+	if _, exists := staleCache[key]; !exists && len(staleCache) >= staleCacheMaxSize {
+		var oldestKey string
+		var oldest time.Time
+		for k, v := range staleCache {
+			if oldestKey == "" || v.CachedAt.Before(oldest) {
+				oldestKey, oldest = k, v.CachedAt
+			}
+		}
+		delete(staleCache, oldestKey)
+	}
+	staleCache[key] = response
+}
+
+// userIDFromToken はAuthorizationヘッダーのトークンを検証し、userIDを返します
+// OAuth2トークンとサポートでのなりすましトークンは、取り消し状態の確認やアクセスの記録に
+// データベースが必要なため対象外とします
+func userIDFromToken(c *gin.Context) (uint, bool) {
+	tokenString := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	claims, err := utils.ParseJWT(tokenString)
+	if err != nil {
+		return 0, false
+	}
+
+	if _, isOAuth := claims["grantID"]; isOAuth {
+		return 0, false
+	}
+	if _, isSupport := claims["supportGrantID"]; isSupport {
+		return 0, false
+	}
+
+	if groupIDFloat, ok := claims["groupID"].(float64); ok {
+		if c.Param("groupID") != strconv.FormatUint(uint64(groupIDFloat), 10) {
+			return 0, false
+		}
+	}
+
+	userIDFloat, ok := claims["userID"].(float64)
+	if !ok {
+		return 0, false
+	}
+	return uint(userIDFloat), true
+}
@@ -0,0 +1,20 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ito-system/clear-up-share/backend/utils"
+)
+
+// RequireFeature は機能が無効化されている場合にルートを 404 として扱うミドルウェア
+func RequireFeature(name string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !utils.FeatureEnabled(name) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "This feature is disabled"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -7,60 +7,820 @@ import (
 )
 
 // User はアプリケーションのユーザーを表します
+// IsPlaceholder が true のユーザーはログインできない仮メンバーで、特定のグループ内でのみ使われます
+// 仮メンバーは Username / Email の一意制約の対象外です
 type User struct {
 	gorm.Model
-	Username       string `gorm:"uniqueIndex;not null"`
-	Email          string `gorm:"uniqueIndex;not null"`
-	HashedPassword string `gorm:"not null"`
+	Username       string     `gorm:"uniqueIndex:idx_users_registered_username,where:is_placeholder = false;not null"`
+	Email          string     `gorm:"uniqueIndex:idx_users_registered_email,where:is_placeholder = false;not null"`
+	HashedPassword string     `gorm:"not null"`
+	IsPlaceholder  bool       `gorm:"not null;default:false"`
+	LastLoginAt    *time.Time // 最終ログイン日時（保持期間ポリシーで非アクティブなアカウントの判定に使用）
+	AnonymizedAt   *time.Time // 保持期間ポリシーにより個人情報を削除した日時
 }
 
 // Group は支出を共有するグループを表します
 type Group struct {
 	gorm.Model
-	Name    string `gorm:"not null"`
-	OwnerID uint   `gorm:"not null"`
-	Owner   User   `gorm:"foreignKey:OwnerID"`
+	Name            string     `gorm:"not null"`
+	OwnerID         uint       `gorm:"not null"`
+	Currency        string     `gorm:"size:3;not null;default:JPY"` // ISO 4217 通貨コード
+	ArchivedAt      *time.Time // nil の場合はアクティブ
+	Icon            string     // アイコン名または絵文字
+	Color           string     // #RRGGBB 形式のテーマカラー
+	Description     string     // グループの説明
+	StartDate       *time.Time // 旅行などの開始日
+	EndDate         *time.Time // 旅行などの終了日
+	Location        string     // 旅行先などの場所
+	LegalHoldAt     *time.Time // nil 以外の場合はリーガルホールド中（記録の削除・物理削除を禁止）
+	LegalHoldByID   *uint      // リーガルホールドを設定したユーザー
+	LegalHoldReason string     // リーガルホールドの理由
+	Visibility      string     `gorm:"not null;default:private"`                               // 参加方法（private / code）
+	JoinCode        string     `gorm:"uniqueIndex:idx_groups_join_code,where:join_code <> ''"` // 参加申請用のコード
+	Owner           User       `gorm:"foreignKey:OwnerID"`
 }
 
+// グループの公開範囲
+const (
+	GroupVisibilityPrivate = "private" // 招待されたユーザーのみ参加できる
+	GroupVisibilityCode    = "code"    // 参加コードを知っているユーザーが参加を申請できる
+)
+
+// メンバーシップのロール
+const (
+	RoleOwner  = "owner"
+	RoleAdmin  = "admin"
+	RoleMember = "member"
+	RoleViewer = "viewer"
+)
+
 // Membership はユーザーとグループの関連を表します
 type Membership struct {
 	gorm.Model
-	UserID  uint  `gorm:"uniqueIndex:idx_user_group;not null"`
-	GroupID uint  `gorm:"uniqueIndex:idx_user_group;not null"`
-	User    User  `gorm:"foreignKey:UserID"`
-	Group   Group `gorm:"foreignKey:GroupID"`
+	UserID   uint   `gorm:"uniqueIndex:idx_user_group;not null"`
+	GroupID  uint   `gorm:"uniqueIndex:idx_user_group;not null"`
+	Role     string `gorm:"not null;default:member"`
+	Nickname string `gorm:"size:50"`                // グループ内での表示名（空の場合はユーザー名を表示）
+	Pinned   bool   `gorm:"not null;default:false"` // グループ一覧で先頭に表示する
+	User     User   `gorm:"foreignKey:UserID"`
+	Group    Group  `gorm:"foreignKey:GroupID"`
 }
 
+// 参加申請のステータス
+const (
+	JoinRequestStatusPending  = "pending"
+	JoinRequestStatusApproved = "approved"
+	JoinRequestStatusRejected = "rejected"
+)
+
+// JoinRequest は参加コードを使ったグループへの参加申請を表します
+type JoinRequest struct {
+	gorm.Model
+	GroupID      uint   `gorm:"index;not null"`
+	UserID       uint   `gorm:"index;not null"`
+	Status       string `gorm:"not null;default:pending"`
+	Message      string // 申請者からのメッセージ
+	ReviewedByID *uint  // 承認・却下したユーザー
+	ReviewedAt   *time.Time
+	Group        Group `gorm:"foreignKey:GroupID"`
+	User         User  `gorm:"foreignKey:UserID"`
+}
+
+// 招待のステータス
+const (
+	InvitationStatusPending  = "pending"
+	InvitationStatusAccepted = "accepted"
+	InvitationStatusDeclined = "declined"
+)
+
+// Invitation はメールアドレス宛てのグループ招待を表します
+type Invitation struct {
+	gorm.Model
+	GroupID   uint   `gorm:"index;not null"`
+	InviterID uint   `gorm:"not null"`
+	Email     string `gorm:"index;not null"`
+	Status    string `gorm:"not null;default:pending"`
+	Group     Group  `gorm:"foreignKey:GroupID"`
+	Inviter   User   `gorm:"foreignKey:InviterID"`
+}
+
+// 端数処理モード
+const (
+	RoundingNone  = "none"
+	RoundingRound = "round"
+	RoundingFloor = "floor"
+	RoundingCeil  = "ceil"
+)
+
+// GroupSettings はグループごとのポリシー設定を表します
+type GroupSettings struct {
+	gorm.Model
+	GroupID                uint       `gorm:"uniqueIndex;not null"`
+	RoundingMode           string     `gorm:"not null"`
+	AllowMemberEdit        bool       `gorm:"not null"`
+	RequireExpenseApproval bool       `gorm:"not null"`
+	MonthStartDay          int        `gorm:"not null;default:1"`     // 月次集計期間の開始日（1〜28）
+	WeekStartDay           int        `gorm:"not null;default:0"`     // 週次集計期間の開始曜日（0=日曜〜6=土曜）
+	AllowLeaveWithBalance  bool       `gorm:"not null;default:false"` // 未精算の貸借があるメンバーの退会・除名を許可する
+	Timezone               string     `gorm:"size:64"`                // IANA タイムゾーン名（空の場合はサーバーのタイムゾーン）
+	ReminderCadence        string     `gorm:"not null;default:off"`   // 未精算のリマインダーを送る間隔（off / weekly / monthly）
+	ReminderThreshold      float64    `gorm:"not null;default:0"`     // この額を超える借りがあるメンバーにリマインダーを送る
+	ClosingDay             int        `gorm:"not null;default:0"`     // 清算期間を自動で締める日（1〜28、0の場合は締めない）
+	NextClosingDate        *time.Time // 次に清算期間を自動で締める日付（グループのタイムゾーンでの日付）
+	Group                  Group      `gorm:"foreignKey:GroupID"`
+}
+
+// GroupSettings.ReminderCadence の値
+const (
+	ReminderCadenceOff     = "off"
+	ReminderCadenceWeekly  = "weekly"
+	ReminderCadenceMonthly = "monthly"
+)
+
+// 支出のステータス
+const (
+	ExpenseStatusConfirmed     = "confirmed"
+	ExpenseStatusPending       = "pending"        // 管理者の承認待ち（負債計算に含めない）
+	ExpenseStatusAwaitingPayer = "awaiting_payer" // 代理入力された支出の支払者による承認待ち（負債計算に含めない）
+	ExpenseStatusDraft         = "draft"          // 登録者が作成途中の下書き（負債計算・履歴に含めず、登録者本人のみ参照できる）
+)
+
+// 支出の種類
+const (
+	ExpenseTypeExpense = "expense"
+	ExpenseTypeRefund  = "refund" // 返金・キャッシュバック（Amount・負担額・品目の金額を負の値で保存し、支払者と負担者の貸借を逆向きに動かす）
+)
+
 // Expense はグループ内の支出を表します
 type Expense struct {
 	gorm.Model
-	GroupID     uint      `gorm:"not null"`
-	PayerID     uint      `gorm:"not null"`
-	Amount      float64   `gorm:"not null"`
-	Description string    `gorm:"not null"`
-	Date        time.Time `gorm:"not null"`
-	Group       Group     `gorm:"foreignKey:GroupID"`
-	Payer       User      `gorm:"foreignKey:PayerID"`
+	GroupID            uint       `gorm:"not null"`
+	PayerID            uint       `gorm:"not null"`
+	Amount             float64    `gorm:"not null"`
+	Type               string     `gorm:"size:20;not null;default:expense"` // expense / refund
+	Description        string     `gorm:"not null"`
+	Notes              string     `gorm:"type:text"` // 支出のメモ（長文可）
+	Date               time.Time  `gorm:"not null"`
+	OccurredAt         *time.Time // 日時（RFC3339）で入力した場合の支払日時（Date はグループのタイムゾーンでの日付）
+	Latitude           *float64   // 支払った場所の緯度（Longitude と同時に設定）
+	Longitude          *float64   // 支払った場所の経度
+	PlaceName          string     `gorm:"size:100"` // 支払った場所の名前（店名など）
+	Status             string     `gorm:"not null;default:confirmed"`
+	CreatedByID        uint       `gorm:"not null;default:0"` // 支出を登録したユーザー（代理入力の場合は支払者と異なる）
+	ApprovedByID       *uint      // 承認したユーザー（承認不要で確定した場合は nil）
+	OriginalAmount     *float64   // 通貨移行前の金額（併記する形式で移行した場合のみ）
+	OriginalCurrency   string     `gorm:"size:3"` // 通貨移行前の通貨コード（併記する形式で移行した場合のみ）
+	Currency           string     `gorm:"size:3"` // 支出を入力した通貨（グループの通貨で入力した場合は空）
+	ForeignAmount      *float64   // Currency で入力した換算前の金額
+	ExchangeRate       *float64   // 入力時の換算レート（Currency の1単位あたりのグループの通貨の金額）
+	Tax                *float64   // 品目ごとに分けた支出の税金（Amount に含まれる）
+	Tip                *float64   // 品目ごとに分けた支出のチップ・サービス料（Amount に含まれる）
+	SurchargeSplit     string     `gorm:"size:20"` // 税金とチップの分け方（proportional / equal、税金とチップがない場合は空）
+	CategoryID         *uint      `gorm:"index"`   // 支出のカテゴリ（nil の場合は未分類）
+	RecurringExpenseID *uint      `gorm:"index"`   // 定期的な支出から自動で登録した場合の元の定期支出
+	SettlementPeriodID *uint      `gorm:"index"`   // 支出をロックした清算期間（nil の場合はロックされていない）
+	Group              Group      `gorm:"foreignKey:GroupID"`
+	Payer              User       `gorm:"foreignKey:PayerID"`
+	Tags               []Tag      `gorm:"many2many:expense_tags"`
+}
+
+// 定期的な支出の周期
+const (
+	CadenceWeekly  = "weekly"
+	CadenceMonthly = "monthly" // 開始日と同じ日（その月にない場合は月末）
+	CadenceYearly  = "yearly"
+)
+
+// RecurringExpense は家賃やサブスクリプションなど、周期ごとに自動で登録する支出のテンプレートを表します
+// NextRunDate になるとスケジューラーが Expense と Split を作成し、RunCount 回目の次の日付に進めます
+type RecurringExpense struct {
+	gorm.Model
+	GroupID     uint       `gorm:"index;not null"`
+	CreatedByID uint       `gorm:"not null"` // 作成したユーザー（登録される支出の登録者になる）
+	PayerID     uint       `gorm:"not null"`
+	Amount      float64    `gorm:"not null"`
+	Description string     `gorm:"not null"`
+	SplitType   string     `gorm:"not null;default:equal"` // equal / exact / percentage
+	MemberIDs   string     `gorm:"not null"`               // 負担者のID（スペース区切り）
+	Shares      string     // exact の場合は負担額、percentage の場合は割合（MemberIDs と同じ順にスペース区切り）
+	CategoryID  *uint      `gorm:"index"`
+	Cadence     string     `gorm:"not null"`
+	StartDate   time.Time  `gorm:"not null"`
+	EndDate     *time.Time // この日より後は登録しない（nil の場合は無期限）
+	NextRunDate time.Time  `gorm:"index;not null"`
+	RunCount    int        `gorm:"not null;default:0"` // 開始日から数えた次に登録する回（0 が開始日）
+	LastRunAt   *time.Time
+	PausedAt    *time.Time // nil 以外の場合は一時停止中
+	LastError   string     // 自動で一時停止した理由など
+	Group       Group      `gorm:"foreignKey:GroupID"`
+}
+
+// SplitPreset はグループでよく使う支出の分け方（「家賃: A 60%、B 40%」など）を表します
+// 支出の登録時に presetID で指定すると、その負担者と割合で負担額を計算します
+type SplitPreset struct {
+	gorm.Model
+	GroupID     uint   `gorm:"uniqueIndex:idx_split_preset_group_name,where:deleted_at IS NULL;not null"`
+	Name        string `gorm:"uniqueIndex:idx_split_preset_group_name,where:deleted_at IS NULL;size:50;not null"`
+	CreatedByID uint   `gorm:"not null"`
+	SplitType   string `gorm:"not null;default:equal"` // equal / percentage
+	MemberIDs   string `gorm:"not null"`               // 負担者のID（スペース区切り）
+	Shares      string // percentage の場合の割合（MemberIDs と同じ順にスペース区切り）
+	Group       Group  `gorm:"foreignKey:GroupID"`
+}
+
+// Category はグループ内の支出のカテゴリ（食費・光熱費など）を表します
+type Category struct {
+	gorm.Model
+	GroupID uint   `gorm:"uniqueIndex:idx_category_group_name,where:deleted_at IS NULL;not null"`
+	Name    string `gorm:"uniqueIndex:idx_category_group_name,where:deleted_at IS NULL;size:50;not null"`
+	Group   Group  `gorm:"foreignKey:GroupID"`
+}
+
+// Budget はグループのカテゴリごとの月次予算を表します
+// Month は "YYYY-MM" 形式で、グループの月の開始日からの1か月間（utils.MonthPeriod）を表します
+type Budget struct {
+	gorm.Model
+	GroupID    uint     `gorm:"uniqueIndex:idx_budget_group_category_month,where:deleted_at IS NULL;not null"`
+	CategoryID uint     `gorm:"uniqueIndex:idx_budget_group_category_month,where:deleted_at IS NULL;not null"`
+	Month      string   `gorm:"uniqueIndex:idx_budget_group_category_month,where:deleted_at IS NULL;size:7;not null"`
+	Amount     float64  `gorm:"not null"` // グループの基準通貨での予算額
+	Group      Group    `gorm:"foreignKey:GroupID"`
+	Category   Category `gorm:"foreignKey:CategoryID"`
+}
+
+// Tag はグループ内で支出に付ける自由なタグを表します（名前は小文字に揃えて保存します）
+type Tag struct {
+	gorm.Model
+	GroupID uint   `gorm:"uniqueIndex:idx_tag_group_name;not null"`
+	Name    string `gorm:"uniqueIndex:idx_tag_group_name;size:30;not null"`
+	Group   Group  `gorm:"foreignKey:GroupID"`
 }
 
-// Split は支出の均等割り負債を表します
+// ExpenseTag は支出とタグの中間テーブル（Expense.Tags の many2many で使われます）
+type ExpenseTag struct {
+	ExpenseID uint `gorm:"primaryKey"`
+	TagID     uint `gorm:"primaryKey"`
+}
+
+// 支出の分け方
+const (
+	SplitTypeEqual      = "equal"      // 均等割り
+	SplitTypeExact      = "exact"      // 負担者ごとの金額を指定
+	SplitTypePercentage = "percentage" // 負担者ごとの割合を指定
+	SplitTypeItemized   = "itemized"   // 品目ごとに負担者を指定
+	SplitTypePersonal   = "personal"   // 支払者が全額を負担（共有カードで払った個人的な買い物など、他のメンバーの貸借は変わらない）
+)
+
+// 品目ごとに分けた支出の税金とチップの分け方
+const (
+	SurchargeSplitProportional = "proportional" // 負担者ごとの品目の小計に比例
+	SurchargeSplitEqual        = "equal"        // 品目の負担者で均等割り
+)
+
+// Split は支出の負担者ごとの負担額を表します
 type Split struct {
 	gorm.Model
-	ExpenseID uint    `gorm:"not null"`
-	DebtorID  uint    `gorm:"not null"`
-	AmountDue float64 `gorm:"not null"`
+	ExpenseID uint     `gorm:"not null"`
+	DebtorID  uint     `gorm:"not null"`
+	AmountDue float64  `gorm:"not null"`
+	Percent   *float64 // 割合で分けた場合の負担者の割合（%）
+	Expense   Expense  `gorm:"foreignKey:ExpenseID"`
+	Debtor    User     `gorm:"foreignKey:DebtorID"`
+}
+
+// ExpenseItem は品目ごとに分けた支出の1品目を表します
+// 品目の金額は MemberIDs の負担者で均等に分け、支出額との差（税・サービス料など）は負担者の小計に比例して配分します
+type ExpenseItem struct {
+	gorm.Model
+	ExpenseID uint    `gorm:"index;not null"`
+	Name      string  `gorm:"not null"`
+	Price     float64 `gorm:"not null"`
+	MemberIDs string  `gorm:"not null"` // 品目の負担者のID（スペース区切り）
 	Expense   Expense `gorm:"foreignKey:ExpenseID"`
-	Debtor    User    `gorm:"foreignKey:DebtorID"`
 }
 
+// ExpenseRevision は支出を編集する前の内容のスナップショットを表します（編集のたびに1件作成します）
+type ExpenseRevision struct {
+	gorm.Model
+	ExpenseID uint    `gorm:"index;not null"`
+	EditorID  uint    `gorm:"not null"`           // 編集したユーザー
+	Snapshot  string  `gorm:"type:text;not null"` // 編集前の支出・負担額・品目・タグのJSON
+	Expense   Expense `gorm:"foreignKey:ExpenseID"`
+	Editor    User    `gorm:"foreignKey:EditorID"`
+}
+
+// ExpenseAcknowledgement はメンバーが支出を確認済み（内容に問題なし）としたことを表します
+// 支出を編集すると確認は取り消されます
+type ExpenseAcknowledgement struct {
+	gorm.Model
+	ExpenseID uint    `gorm:"uniqueIndex:idx_expense_ack_user,where:deleted_at IS NULL;not null"`
+	UserID    uint    `gorm:"uniqueIndex:idx_expense_ack_user,where:deleted_at IS NULL;not null"`
+	Expense   Expense `gorm:"foreignKey:ExpenseID"`
+	User      User    `gorm:"foreignKey:UserID"`
+}
+
+// Receipt は支出に添付したレシートの画像を表します（ファイル本体は storage パッケージの保存先に保存します）
+type Receipt struct {
+	gorm.Model
+	ExpenseID    uint    `gorm:"index;not null"`
+	StorageKey   string  `gorm:"uniqueIndex;not null"`
+	Filename     string  `gorm:"not null"` // アップロード時のファイル名
+	ContentType  string  `gorm:"not null"`
+	Size         int64   `gorm:"not null"`
+	UploadedByID uint    `gorm:"not null"`
+	Expense      Expense `gorm:"foreignKey:ExpenseID"`
+}
+
+// まとめ買いの注文のステータス
+const (
+	GroupOrderStatusOpen   = "open"   // 品目の申告を受付中
+	GroupOrderStatusClosed = "closed" // 申告を締めて支出を登録済み
+)
+
+// GroupOrder はまとめ買いの支払者が登録した品目を、各メンバーが自分の分として申告する注文を表します
+// 申告を締めると品目ごとの申告者から負担額を計算し、品目ごとに分けた支出（ExpenseID）を登録します
+type GroupOrder struct {
+	gorm.Model
+	GroupID       uint       `gorm:"index;not null"`
+	PayerID       uint       `gorm:"not null"` // 注文を登録した支払者
+	Description   string     `gorm:"not null"`
+	Amount        float64    `gorm:"not null"` // 支払額（品目の合計との差額は税・送料などとして申告額に比例して分ける）
+	Date          time.Time  `gorm:"not null"`
+	ClaimDeadline *time.Time // 申告の締め切り（過ぎるとスケジューラーが締める、nil の場合は支払者が締めるまで受け付ける）
+	Status        string     `gorm:"not null;default:open"`
+	ExpenseID     *uint      // 締めたときに登録した支出
+	ClosedAt      *time.Time
+	Group         Group            `gorm:"foreignKey:GroupID"`
+	Payer         User             `gorm:"foreignKey:PayerID"`
+	Items         []GroupOrderItem `gorm:"foreignKey:OrderID"`
+}
+
+// GroupOrderItem はまとめ買いの注文の1品目を表します
+type GroupOrderItem struct {
+	gorm.Model
+	OrderID uint              `gorm:"index;not null"`
+	Name    string            `gorm:"size:100;not null"`
+	Price   float64           `gorm:"not null"`
+	Claims  []GroupOrderClaim `gorm:"foreignKey:ItemID"`
+}
+
+// GroupOrderClaim はメンバーがまとめ買いの品目を自分の分として申告したことを表します（同じ品目を複数のメンバーが申告した場合は均等に分けます）
+type GroupOrderClaim struct {
+	gorm.Model
+	ItemID uint `gorm:"uniqueIndex:idx_group_order_claim_user,where:deleted_at IS NULL;not null"`
+	UserID uint `gorm:"uniqueIndex:idx_group_order_claim_user,where:deleted_at IS NULL;not null"`
+	User   User `gorm:"foreignKey:UserID"`
+}
+
+// 通貨移行の形式
+const (
+	CurrencyMigrationConvert = "convert" // 過去の金額を換算し、換算前の金額は残さない
+	CurrencyMigrationDual    = "dual"    // 過去の金額を換算し、換算前の金額と通貨を併記用に残す
+)
+
 // Settlement はグループ内の精算を表します
 type Settlement struct {
 	gorm.Model
-	GroupID    uint    `gorm:"not null"`
-	PayerID    uint    `gorm:"not null"`
-	ReceiverID uint    `gorm:"not null"`
-	Amount     float64 `gorm:"not null"`
-	Group      Group   `gorm:"foreignKey:GroupID"`
-	Payer      User    `gorm:"foreignKey:PayerID"`
-	Receiver   User    `gorm:"foreignKey:ReceiverID"`
+	GroupID          uint       `gorm:"not null"`
+	PayerID          uint       `gorm:"not null"`
+	ReceiverID       uint       `gorm:"not null"`
+	Amount           float64    `gorm:"not null"`
+	Date             time.Time  `gorm:"index"`    // 支払った日付（グループのタイムゾーンでの日付を UTC の0時で保存、支出の Date と同じ形式）
+	Note             string     `gorm:"size:200"` // 「駅で返した」など支払いの状況のメモ
+	Method           string     `gorm:"size:20"`  // 支払い方法（cash / bank_transfer / paypay / card / other、未指定の場合は空）
+	Status           string     `gorm:"not null;default:confirmed"`
+	PaymentLinkNonce string     `gorm:"uniqueIndex:idx_settlement_payment_link_nonce,where:payment_link_nonce <> ''"` // 支払いリンクから記録した場合のリンクの値（同じリンクで二重に記録しない）
+	OriginalAmount   *float64   // 通貨移行前の金額（併記する形式で移行した場合のみ）
+	OriginalCurrency string     `gorm:"size:3"` // 通貨移行前の通貨コード（併記する形式で移行した場合のみ）
+	Currency         string     `gorm:"size:3"` // 支払った通貨（グループの通貨で支払った場合は空）
+	ForeignAmount    *float64   // Currency で支払った換算前の金額
+	ExchangeRate     *float64   // 記録時の換算レート（Currency の1単位あたりのグループの通貨の金額）
+	VoidedAt         *time.Time // 誤って記録した清算を取り消した日時（記録は残し、負債計算に含めない）
+	VoidedByID       *uint
+	BatchID          *uint                  `gorm:"index"`          // 複数の受取者にまとめて支払った場合の SettlementBatch
+	PaymentReference string                 `gorm:"size:255;index"` // アプリ内のカード決済で支払う場合の Stripe の PaymentIntent ID（入金との照合用）
+	Group            Group                  `gorm:"foreignKey:GroupID"`
+	Payer            User                   `gorm:"foreignKey:PayerID"`
+	Receiver         User                   `gorm:"foreignKey:ReceiverID"`
+	Allocations      []SettlementAllocation `gorm:"foreignKey:SettlementID"`
+}
+
+// SettlementRevision は清算を編集・取り消したときの理由と変更前の内容を表します
+// 支払いの有無で揉めた場合に、清算がいつ誰にどのような理由で変更されたかを確認できるようにします
+type SettlementRevision struct {
+	gorm.Model
+	SettlementID uint       `gorm:"index;not null"`
+	EditorID     uint       `gorm:"not null"`           // 編集・取り消したユーザー
+	Action       string     `gorm:"size:20;not null"`   // edited / voided
+	Reason       string     `gorm:"size:500;not null"`  // 編集・取り消しの理由
+	Snapshot     string     `gorm:"type:text;not null"` // 変更前の清算のJSON
+	Settlement   Settlement `gorm:"foreignKey:SettlementID"`
+	Editor       User       `gorm:"foreignKey:EditorID"`
+}
+
+// SettlementRevision.Action の値
+const (
+	SettlementRevisionEdited = "edited"
+	SettlementRevisionVoided = "voided"
+)
+
+// SettlementBatch は1人の送金者が複数の受取者にまとめて支払った1回の支払い（振込の一括送金など）を表します
+// 受取者ごとの清算は BatchID でこの記録に紐付けた Settlement として作成し、負債計算には Settlement だけを使います
+type SettlementBatch struct {
+	gorm.Model
+	GroupID     uint         `gorm:"index;not null"`
+	PayerID     uint         `gorm:"not null"`
+	CreatedByID uint         `gorm:"not null"`
+	Group       Group        `gorm:"foreignKey:GroupID"`
+	Payer       User         `gorm:"foreignKey:PayerID"`
+	Settlements []Settlement `gorm:"foreignKey:BatchID"`
+}
+
+// SettlementAllocation は清算をどの支出の負担額の支払いに充てたかを表します
+// 支出ごとに清算するグループで、支払い済みの支出と未払いの支出を区別するために使います（負債計算には影響しません）
+type SettlementAllocation struct {
+	gorm.Model
+	SettlementID uint       `gorm:"index;not null"`
+	ExpenseID    uint       `gorm:"index;not null"`
+	Amount       float64    `gorm:"not null"` // 支出での送金者の負担額のうちこの清算で支払った額
+	Settlement   Settlement `gorm:"foreignKey:SettlementID"`
+	Expense      Expense    `gorm:"foreignKey:ExpenseID"`
+}
+
+// DebtReminder はメンバーに未精算のリマインダーを送った記録を表します（次に送る日時の判定に使います）
+type DebtReminder struct {
+	gorm.Model
+	GroupID uint    `gorm:"index:idx_debt_reminder_group_user;not null"`
+	UserID  uint    `gorm:"index:idx_debt_reminder_group_user;not null"`
+	Amount  float64 `gorm:"not null"`               // 送った時点の借りの額
+	Manual  bool    `gorm:"not null;default:false"` // 管理者が手動で送った場合は true
+	Group   Group   `gorm:"foreignKey:GroupID"`
+	User    User    `gorm:"foreignKey:UserID"`
+}
+
+// PayoutProfile はユーザーが清算の受け取りに使う送金先を表します（送金の提案で送金者に支払いリンク・振込先として表示します）
+type PayoutProfile struct {
+	gorm.Model
+	UserID        uint   `gorm:"uniqueIndex;not null"`
+	PayPayLink    string `gorm:"size:500"` // PayPay アプリで発行した受け取りリンク
+	BankName      string `gorm:"size:100"`
+	BranchName    string `gorm:"size:100"`
+	AccountType   string `gorm:"size:20"` // ordinary（普通） / checking（当座）
+	AccountNumber string `gorm:"size:20"`
+	AccountHolder string `gorm:"size:100"` // 口座名義（カナ）
+	User          User   `gorm:"foreignKey:UserID"`
+}
+
+// StripeAccount はユーザーがカード決済で清算を受け取るための Stripe Connect の連結アカウントを表します
+type StripeAccount struct {
+	gorm.Model
+	UserID         uint   `gorm:"uniqueIndex;not null"`
+	AccountID      string `gorm:"size:255;uniqueIndex;not null"` // Stripe の連結アカウントID（acct_...）
+	ChargesEnabled bool   `gorm:"not null;default:false"`        // 本人確認などが済み、カード決済を受け取れる
+	User           User   `gorm:"foreignKey:UserID"`
+}
+
+// PayoutProfile.AccountType の値
+const (
+	AccountTypeOrdinary = "ordinary"
+	AccountTypeChecking = "checking"
+)
+
+// Settlement.Status の値
+const (
+	SettlementStatusConfirmed = "confirmed"
+	SettlementStatusPending   = "pending" // 一括清算で記録した送金の受取者による受け取りの確認待ち（負債計算に含めない）
+)
+
+// Settlement.Method の値
+const (
+	SettlementMethodCash         = "cash"
+	SettlementMethodBankTransfer = "bank_transfer"
+	SettlementMethodPayPay       = "paypay"
+	SettlementMethodOther        = "other"
+	SettlementMethodCard         = "card" // アプリ内のカード決済（Stripe）で支払った
+)
+
+// SettlementPeriod は全員の清算が済んだ期間を締めた記録を表します
+// 締めた時点で EndDate 以前の確定済みの支出をロックし、管理者がロックを解除するまで編集・削除できなくします
+type SettlementPeriod struct {
+	gorm.Model
+	GroupID      uint      `gorm:"index;not null"`
+	EndDate      time.Time `gorm:"not null"`               // 期間の最終日（この日以前の支出をロックする）
+	ClosedByID   uint      `gorm:"not null"`               // 期間を締めたユーザー
+	ExpenseCount int       `gorm:"not null"`               // 締めたときにロックした支出の数
+	Automatic    bool      `gorm:"not null;default:false"` // 締め日に自動で締めた期間
+	Balances     string    `gorm:"type:text"`              // 締めた時点のメンバーの貸借額（JSON）
+	Transfers    string    `gorm:"type:text"`              // 締めた時点の送金の提案（JSON）
+	Group        Group     `gorm:"foreignKey:GroupID"`
+	ClosedBy     User      `gorm:"foreignKey:ClosedByID"`
+}
+
+// 精算依頼のステータス
+const (
+	ReimbursementStatusRequested = "requested" // 依頼済み（負担者の支払い待ち）
+	ReimbursementStatusPaid      = "paid"      // 負担者が依頼から支払った（依頼者の受け取りの確認待ち）
+	ReimbursementStatusConfirmed = "confirmed" // 依頼者が受け取りを確認した
+)
+
+// ReimbursementRequest は支出を立て替えたメンバーが負担者に負担額の精算を依頼した記録を表します
+// 負担者が依頼から支払うと清算（Settlement）を記録して paid になり、依頼者が受け取りを確認すると confirmed になります
+type ReimbursementRequest struct {
+	gorm.Model
+	GroupID      uint    `gorm:"index;not null"`
+	ExpenseID    uint    `gorm:"index;not null"`
+	RequesterID  uint    `gorm:"not null"` // 精算を依頼したユーザー（支出の支払者）
+	DebtorID     uint    `gorm:"not null"` // 依頼された負担者
+	Amount       float64 `gorm:"not null"` // 依頼額（依頼した時点の負担者の負担額）
+	Note         string  `gorm:"size:200"`
+	Status       string  `gorm:"not null;default:requested"`
+	SettlementID *uint   // 負担者が支払ったときに記録した清算
+	PaidAt       *time.Time
+	ConfirmedAt  *time.Time
+	Group        Group   `gorm:"foreignKey:GroupID"`
+	Expense      Expense `gorm:"foreignKey:ExpenseID"`
+	Requester    User    `gorm:"foreignKey:RequesterID"`
+	Debtor       User    `gorm:"foreignKey:DebtorID"`
+}
+
+// 債務免除のステータス
+const (
+	ForgivenessStatusPending   = "pending"   // 免除する側（債権者）の確認待ち（負債計算に含めない）
+	ForgivenessStatusConfirmed = "confirmed" // 確定済み
+)
+
+// Forgiveness は債権者が債務者に対する貸しの一部を免除した記録を表します
+// 清算と異なり実際の支払いは伴いませんが、確定すると同額だけ貸借が相殺されます
+type Forgiveness struct {
+	gorm.Model
+	GroupID          uint    `gorm:"index;not null"`
+	DebtorID         uint    `gorm:"not null"` // 免除される側
+	ReceiverID       uint    `gorm:"not null"` // 免除する側（本来受け取るはずだったユーザー）
+	Amount           float64 `gorm:"not null"`
+	Note             string  // 免除の理由などのメモ
+	Status           string  `gorm:"not null;default:pending"`
+	CreatedByID      uint    `gorm:"not null"`
+	ConfirmedAt      *time.Time
+	OriginalAmount   *float64 // 通貨移行前の金額（併記する形式で移行した場合のみ）
+	OriginalCurrency string   `gorm:"size:3"` // 通貨移行前の通貨コード（併記する形式で移行した場合のみ）
+	Group            Group    `gorm:"foreignKey:GroupID"`
+	Debtor           User     `gorm:"foreignKey:DebtorID"`
+	Receiver         User     `gorm:"foreignKey:ReceiverID"`
+}
+
+// 個人間の貸し借りのステータス
+const (
+	IOUStatusOpen    = "open"    // 返済が残っている
+	IOUStatusSettled = "settled" // 全額返済された
+)
+
+// IOU はグループを作らずに2人のユーザーの間で記録した1回限りの貸し借りを表します
+type IOU struct {
+	gorm.Model
+	LenderID       uint         `gorm:"index;not null"` // 貸した側（返済を受け取る側）
+	BorrowerID     uint         `gorm:"index;not null"` // 借りた側（返済する側）
+	CreatedByID    uint         `gorm:"not null"`
+	Amount         float64      `gorm:"not null"`
+	Currency       string       `gorm:"size:3;not null"`
+	Description    string       `gorm:"size:200;not null"`
+	Date           time.Time    // 貸し借りした日付（UTC の0時で保存、支出の Date と同じ形式）
+	Status         string       `gorm:"size:20;not null;default:open"`
+	SettledAt      *time.Time   // 全額返済された日時
+	LastRemindedAt *time.Time   // 貸した側が最後に返済のリマインダーを送った日時
+	Lender         User         `gorm:"foreignKey:LenderID"`
+	Borrower       User         `gorm:"foreignKey:BorrowerID"`
+	Payments       []IOUPayment `gorm:"foreignKey:IOUID"`
+}
+
+// IOUPayment は個人間の貸し借りの返済を表します
+// 清算と同じく、借りた側が記録した返済は貸した側が受け取りを確認するまで確認待ち（SettlementStatusPending）になります
+type IOUPayment struct {
+	gorm.Model
+	IOUID        uint      `gorm:"column:iou_id;index;not null"`
+	Amount       float64   `gorm:"not null"`
+	Date         time.Time // 返済した日付
+	Method       string    `gorm:"size:20"`  // 支払い方法（清算の Method と同じ値）
+	Note         string    `gorm:"size:200"` // 返済の状況のメモ
+	Status       string    `gorm:"not null;default:confirmed"`
+	RecordedByID uint      `gorm:"not null"`
+	ConfirmedAt  *time.Time
+}
+
+// アクティビティの種類
+const (
+	ActivityExpenseAdded             = "expense_added"
+	ActivityExpenseEdited            = "expense_edited"
+	ActivityExpenseDeleted           = "expense_deleted"
+	ActivityExpenseApproved          = "expense_approved"
+	ActivityExpenseRestored          = "expense_restored"
+	ActivitySettlementRecorded       = "settlement_recorded"
+	ActivitySettlementVoided         = "settlement_voided"
+	ActivitySettlementConfirmed      = "settlement_confirmed"
+	ActivitySettlementEdited         = "settlement_edited"
+	ActivityDebtForgivenessRequested = "debt_forgiveness_requested"
+	ActivityDebtForgiven             = "debt_forgiven"
+	ActivityDebtForgivenessDeclined  = "debt_forgiveness_declined"
+	ActivityMemberJoined             = "member_joined"
+	ActivityMemberRoleChanged        = "member_role_changed"
+	ActivityMemberLeft               = "member_left"
+	ActivityMemberRemoved            = "member_removed"
+	ActivityGroupArchived            = "group_archived"
+	ActivityGroupUnarchived          = "group_unarchived"
+	ActivityLegalHoldPlaced          = "legal_hold_placed"
+	ActivityLegalHoldReleased        = "legal_hold_released"
+	ActivityCurrencyMigrated         = "currency_migrated"
+	ActivityReceiptAdded             = "receipt_added"
+	ActivitySettlementPeriodClosed   = "settlement_period_closed"
+	ActivityExpenseUnlocked          = "expense_unlocked"
+	ActivityReimbursementRequested   = "reimbursement_requested"
+	ActivityReimbursementPaid        = "reimbursement_paid"
+	ActivityReimbursementConfirmed   = "reimbursement_confirmed"
+)
+
+// ActivityLog はグループ内で行われた変更操作の記録を表します
+type ActivityLog struct {
+	gorm.Model
+	GroupID    uint   `gorm:"index;not null"`
+	ActorID    uint   `gorm:"not null"`
+	Action     string `gorm:"not null"`
+	TargetType string `gorm:"not null"` // "expense", "settlement", "forgiveness", "member", "group"
+	TargetID   uint   `gorm:"not null"`
+	Details    string `gorm:"type:text"` // 変更内容のJSON
+	Group      Group  `gorm:"foreignKey:GroupID"`
+	Actor      User   `gorm:"foreignKey:ActorID"`
+}
+
+// OAuthClient はOAuth2で登録されたサードパーティアプリを表します
+type OAuthClient struct {
+	gorm.Model
+	ClientID     string `gorm:"uniqueIndex;not null"`
+	SecretHash   string `gorm:"not null"`
+	Name         string `gorm:"not null"`
+	RedirectURIs string `gorm:"type:text;not null"` // スペース区切りのリダイレクトURI
+	OwnerID      uint   `gorm:"not null"`
+	Owner        User   `gorm:"foreignKey:OwnerID"`
+}
+
+// OAuthAuthorizationCode はユーザーの同意後に発行される一度限りの認可コードを表します
+type OAuthAuthorizationCode struct {
+	gorm.Model
+	CodeHash      string `gorm:"uniqueIndex;not null"`
+	ClientID      uint   `gorm:"not null"`
+	UserID        uint   `gorm:"not null"`
+	RedirectURI   string `gorm:"not null"`
+	Scope         string `gorm:"not null"`
+	GroupID       uint
+	CodeChallenge string    // PKCE (S256)
+	ExpiresAt     time.Time `gorm:"not null"`
+	UsedAt        *time.Time
+	Client        OAuthClient `gorm:"foreignKey:ClientID"`
+}
+
+// OAuthGrant はユーザーがアプリに与えた権限を表し、取り消されるとそのアクセストークンは無効になります
+type OAuthGrant struct {
+	gorm.Model
+	ClientID  uint   `gorm:"index;not null"`
+	UserID    uint   `gorm:"index;not null"`
+	Scope     string `gorm:"not null"`
+	GroupID   uint
+	RevokedAt *time.Time
+	Client    OAuthClient `gorm:"foreignKey:ClientID"`
+}
+
+// SupportAccessGrant はユーザーがサポート担当の管理者に与えた、期限付きの閲覧権限を表します
+// 有効な権限がある間だけ、管理者はなりすましトークンでユーザーのグループを閲覧できます（変更はできません）
+type SupportAccessGrant struct {
+	gorm.Model
+	UserID    uint      `gorm:"index;not null"`
+	Reason    string    `gorm:"size:500"` // 問い合わせの内容など、ユーザーが権限を与えた理由
+	ExpiresAt time.Time `gorm:"not null"`
+	RevokedAt *time.Time
+	User      User `gorm:"foreignKey:UserID"`
+}
+
+// SupportAccessLog はサポート担当の管理者がなりすましでユーザーのデータにアクセスした記録を表します（1リクエストごとに1件）
+type SupportAccessLog struct {
+	gorm.Model
+	GrantID uint   `gorm:"index;not null"`
+	AdminID uint   `gorm:"index;not null"`
+	UserID  uint   `gorm:"index;not null"`
+	Method  string `gorm:"size:10;not null"`
+	Path    string `gorm:"size:500;not null"` // クエリパラメータを含むリクエストのパス
+	Admin   User   `gorm:"foreignKey:AdminID"`
+}
+
+// 通知の種類
+const (
+	NotificationExpenseApprovalRequested = "expense_approval_requested"
+	NotificationExpenseApproved          = "expense_approved"
+	NotificationForgivenessRequested     = "forgiveness_requested"
+	NotificationForgivenessConfirmed     = "forgiveness_confirmed"
+	NotificationMemberRemoved            = "member_removed"
+	NotificationJoinRequested            = "join_requested"
+	NotificationJoinRequestApproved      = "join_request_approved"
+	NotificationJoinRequestRejected      = "join_request_rejected"
+	NotificationRecurringExpensePaused   = "recurring_expense_paused"
+	NotificationReimbursementRequested   = "reimbursement_requested"
+	NotificationReimbursementPaid        = "reimbursement_paid"
+	NotificationBudgetThreshold          = "budget_threshold"
+	NotificationSpendingLimitExceeded    = "spending_limit_exceeded"
+	NotificationSettlementPending        = "settlement_pending"
+	NotificationSettlementConfirmed      = "settlement_confirmed"
+	NotificationDebtReminder             = "debt_reminder"
+	NotificationSettlementPeriodClosed   = "settlement_period_closed"
+	NotificationIOURecorded              = "iou_recorded"
+	NotificationSupportAccessStarted     = "support_access_started"
+)
+
+// Notification はユーザー宛てのアプリ内通知を表します
+type Notification struct {
+	gorm.Model
+	UserID     uint   `gorm:"index;not null"`
+	GroupID    uint   `gorm:"index"`
+	Type       string `gorm:"not null"`
+	Message    string `gorm:"not null"`
+	TargetType string // "expense" など通知の対象
+	TargetID   uint
+	ReadAt     *time.Time
+}
+
+// 通知設定の区分（通知の種類ごとにいずれかの区分に属します）
+const (
+	NotificationCategoryNewExpense  = "new_expense"
+	NotificationCategoryEdits       = "edits"
+	NotificationCategorySettlements = "settlements"
+	NotificationCategoryReminders   = "reminders"
+)
+
+// NotificationSetting はユーザーがグループごとに選択した通知の受信設定を表します
+// 未保存の場合は全ての区分の通知を受け取ります
+type NotificationSetting struct {
+	gorm.Model
+	UserID      uint  `gorm:"uniqueIndex:idx_notification_user_group;not null"`
+	GroupID     uint  `gorm:"uniqueIndex:idx_notification_user_group;not null"`
+	NewExpense  bool  `gorm:"not null"` // 支出の追加（承認依頼を含む）
+	Edits       bool  `gorm:"not null"` // 支出の編集・承認
+	Settlements bool  `gorm:"not null"` // 清算・債務免除
+	Reminders   bool  `gorm:"not null"` // 未精算のリマインダー
+	Group       Group `gorm:"foreignKey:GroupID"`
+}
+
+// SpendingLimit はメンバーがグループごとに設定した月々の負担額の上限を表します
+// 月の負担額が上限を超えると、その月に一度だけ本人に通知されます
+type SpendingLimit struct {
+	gorm.Model
+	UserID        uint    `gorm:"uniqueIndex:idx_spending_limit_user_group,where:deleted_at IS NULL;not null"`
+	GroupID       uint    `gorm:"uniqueIndex:idx_spending_limit_user_group,where:deleted_at IS NULL;not null"`
+	MonthlyLimit  float64 `gorm:"not null"` // グループの基準通貨での上限額
+	NotifiedMonth string  `gorm:"size:7"`   // 上限を超えたことを通知した月（YYYY-MM）
+	User          User    `gorm:"foreignKey:UserID"`
+	Group         Group   `gorm:"foreignKey:GroupID"`
+}
+
+// OnboardingProgress はユーザーが初期設定の各ステップを完了した日時を表します
+// 行がない場合は既存の記録から作成され、以降はアクティビティの記録に合わせて更新されます
+type OnboardingProgress struct {
+	gorm.Model
+	UserID            uint       `gorm:"uniqueIndex;not null"`
+	EmailVerifiedAt   *time.Time // メールアドレスの確認
+	JoinedGroupAt     *time.Time // グループの作成または参加
+	FirstExpenseAt    *time.Time // 最初の支出の登録
+	FirstSettlementAt *time.Time // 最初の清算（送金者または受取者として）
+	User              User       `gorm:"foreignKey:UserID"`
+}
+
+// Webhook はグループのイベントを外部URLへ通知する設定を表します
+type Webhook struct {
+	gorm.Model
+	GroupID     uint   `gorm:"index;not null"`
+	URL         string `gorm:"not null"`
+	Secret      string `gorm:"not null"` // ペイロード署名用の共有シークレット
+	Events      string `gorm:"not null"` // 通知するイベントの種類（スペース区切り）
+	Active      bool   `gorm:"not null"`
+	CreatedByID uint   `gorm:"not null"`
+	Group       Group  `gorm:"foreignKey:GroupID"`
+}
+
+// WebhookDelivery はWebhookへの1回分の配信を表します（送信待ちのキューを兼ねます）
+type WebhookDelivery struct {
+	gorm.Model
+	WebhookID     uint      `gorm:"index;not null"`
+	Event         string    `gorm:"not null"`
+	Payload       string    `gorm:"type:text;not null"`
+	Attempts      int       `gorm:"not null"`
+	NextAttemptAt time.Time `gorm:"index;not null"`
+	DeliveredAt   *time.Time
+	FailedAt      *time.Time // 再試行の上限に達して配信を諦めた日時
+	LastStatus    int        // 直近の試行のHTTPステータス
+	LastError     string
+	Webhook       Webhook `gorm:"foreignKey:WebhookID"`
+}
+
+// AnalyticsEvent は匿名化された利用状況イベントを表します（送信先に Postgres を選んだ場合のみ保存されます）
+type AnalyticsEvent struct {
+	gorm.Model
+	Event       string    `gorm:"index;not null"`
+	AnonymousID string    `gorm:"index;not null"` // ユーザーIDを匿名化した値
+	Properties  string    `gorm:"type:text"`      // イベントの属性のJSON（個人情報は含めない）
+	OccurredAt  time.Time `gorm:"not null"`
 }
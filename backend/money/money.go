@@ -0,0 +1,121 @@
+// Package money は通貨の補助単位を単位とした金額の丸めと配分を提供します
+package money
+
+import (
+	"math"
+	"sort"
+
+	"github.com/ito-system/clear-up-share/backend/models"
+	"github.com/ito-system/clear-up-share/backend/utils"
+)
+
+// remainderEpsilon は端数の大小を比較するときに同じとみなす差（浮動小数点の誤差で順序が変わらないようにする）
+const remainderEpsilon = 1e-9
+
+// ToMinor は金額を通貨の補助単位の整数に変換します（補助単位未満は四捨五入）
+func ToMinor(amount float64, currency string) int64 {
+	return int64(math.Round(amount * scale(currency)))
+}
+
+// FromMinor は補助単位の整数を金額に変換します
+func FromMinor(units int64, currency string) float64 {
+	return float64(units) / scale(currency)
+}
+
+// Round は金額を通貨の補助単位に四捨五入します
+func Round(amount float64, currency string) float64 {
+	return FromMinor(ToMinor(amount, currency), currency)
+}
+
+// Allocate は金額を weights の比で分け、通貨の補助単位に丸めた配分を返します
+// 最大剰余法で配分し、切り捨てで余った補助単位は切り捨てた端数の大きい順（同じ場合は先頭から）に1単位ずつ加えるため、
+// 配分の合計は補助単位に丸めた金額と常に一致し、同じ入力には同じ結果を返します。weights の合計が0の場合は均等に分けます
+func Allocate(amount float64, weights []float64, currency string) []float64 {
+	shares := make([]float64, len(weights))
+	if len(weights) == 0 {
+		return shares
+	}
+
+	totalWeight := 0.0
+	for _, weight := range weights {
+		totalWeight += weight
+	}
+
+	total := ToMinor(amount, currency)
+	units := make([]int64, len(weights))
+	remainders := make([]float64, len(weights))
+	allocated := int64(0)
+	for i, weight := range weights {
+		exact := float64(total) / float64(len(weights))
+		if totalWeight != 0 {
+			exact = float64(total) * weight / totalWeight
+		}
+		units[i] = int64(math.Floor(exact))
+		remainders[i] = exact - float64(units[i])
+		allocated += units[i]
+	}
+
+	order := make([]int, len(weights))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return remainders[order[a]]-remainders[order[b]] > remainderEpsilon
+	})
+	for i := int64(0); i < total-allocated; i++ {
+		units[order[int(i)%len(order)]]++
+	}
+
+	for i, unit := range units {
+		shares[i] = FromMinor(unit, currency)
+	}
+	return shares
+}
+
+// AllocateRounded は金額を weights の比で分け、グループの端数処理モード（models.Rounding*）に従った配分を返します
+// floor / ceil では先頭以外の配分を補助単位に切り捨て・切り上げ、差額は先頭が負担します。
+// 少額を大人数で切り上げて分けたときなど、先頭の配分の符号が金額と逆になる場合と、none / round の場合は Allocate で配分します。
+// いずれの場合も配分の合計は補助単位に丸めた金額と一致します
+func AllocateRounded(amount float64, weights []float64, mode string, currency string) []float64 {
+	if (mode != models.RoundingFloor && mode != models.RoundingCeil) || len(weights) == 0 {
+		return Allocate(amount, weights, currency)
+	}
+
+	totalWeight := 0.0
+	for _, weight := range weights {
+		totalWeight += weight
+	}
+
+	total := ToMinor(amount, currency)
+	units := make([]int64, len(weights))
+	others := int64(0)
+	for i := 1; i < len(weights); i++ {
+		exact := float64(total) / float64(len(weights))
+		if totalWeight != 0 {
+			exact = float64(total) * weights[i] / totalWeight
+		}
+		// 割合の浮動小数点の誤差で、ちょうど割り切れる配分が1単位ずれないようにする
+		if mode == models.RoundingFloor {
+			units[i] = int64(math.Floor(exact + remainderEpsilon))
+		} else {
+			units[i] = int64(math.Ceil(exact - remainderEpsilon))
+		}
+		others += units[i]
+	}
+
+	units[0] = total - others
+	if units[0] != 0 && (units[0] < 0) != (total < 0) {
+		return Allocate(amount, weights, currency)
+	}
+
+	shares := make([]float64, len(weights))
+	for i, unit := range units {
+		shares[i] = FromMinor(unit, currency)
+	}
+	return shares
+}
+
+// scale は通貨の1単位あたりの補助単位の数を返します
+func scale(currency string) float64 {
+	return math.Pow10(utils.CurrencyMinorUnits(currency))
+}
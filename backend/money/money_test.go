@@ -0,0 +1,135 @@
+package money
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ito-system/clear-up-share/backend/models"
+)
+
+// minorUnits は配分を補助単位の整数にして返します（浮動小数点の比較を避けるため）
+func minorUnits(shares []float64, currency string) []int64 {
+	units := make([]int64, len(shares))
+	for i, share := range shares {
+		units[i] = ToMinor(share, currency)
+	}
+	return units
+}
+
+// checkAllocation は配分の合計が補助単位に丸めた金額と一致し、金額と逆の符号の配分がないことを確認します
+func checkAllocation(t *testing.T, amount float64, currency string, units []int64) {
+	t.Helper()
+	sum := int64(0)
+	for i, unit := range units {
+		sum += unit
+		if (amount >= 0 && unit < 0) || (amount < 0 && unit > 0) {
+			t.Errorf("share %d = %d has the opposite sign of amount %v", i, unit, amount)
+		}
+	}
+	if total := ToMinor(amount, currency); sum != total {
+		t.Errorf("shares add up to %d, want %d", sum, total)
+	}
+}
+
+func TestAllocate(t *testing.T) {
+	tests := []struct {
+		name     string
+		amount   float64
+		weights  []float64
+		currency string
+		want     []int64
+	}{
+		{"equal split gives the remainder to the first members", 100, []float64{1, 1, 1}, "JPY", []int64{34, 33, 33}},
+		{"equal split in cents", 10, []float64{1, 1, 1}, "USD", []int64{334, 333, 333}},
+		{"remainders go to the largest fractions first", 100, []float64{1, 2, 4}, "JPY", []int64{14, 29, 57}},
+		{"several leftover units", 100, []float64{1, 1, 1, 1, 1, 1}, "JPY", []int64{17, 17, 17, 17, 16, 16}},
+		{"exact weights", 10, []float64{1, 2, 2}, "JPY", []int64{2, 4, 4}},
+		{"percentages with float noise", 0.3, []float64{0.1, 0.2}, "USD", []int64{10, 20}},
+		{"more members than units", 0.03, []float64{1, 1, 1, 1, 1}, "USD", []int64{1, 1, 1, 0, 0}},
+		{"zero weights split equally", 10, []float64{0, 0}, "JPY", []int64{5, 5}},
+		{"amount is rounded to minor units", 10.005, []float64{1, 1}, "JPY", []int64{5, 5}},
+		{"negative amount floors every share and adds back from the front", -10, []float64{1, 1, 1}, "JPY", []int64{-3, -3, -4}},
+		{"single member", 12.34, []float64{3}, "USD", []int64{1234}},
+		{"no members", 100, nil, "JPY", []int64{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := minorUnits(Allocate(tt.amount, tt.weights, tt.currency), tt.currency)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Allocate(%v, %v, %q) = %v, want %v", tt.amount, tt.weights, tt.currency, got, tt.want)
+			}
+			if len(tt.weights) > 0 {
+				checkAllocation(t, tt.amount, tt.currency, got)
+			}
+			// 同じ入力には同じ結果を返す
+			if again := minorUnits(Allocate(tt.amount, tt.weights, tt.currency), tt.currency); !reflect.DeepEqual(again, got) {
+				t.Errorf("Allocate is not deterministic: %v then %v", got, again)
+			}
+		})
+	}
+}
+
+func TestAllocateRounded(t *testing.T) {
+	tests := []struct {
+		name     string
+		amount   float64
+		weights  []float64
+		mode     string
+		currency string
+		want     []int64
+	}{
+		{"floor gives the difference to the first member", 100, []float64{1, 1, 1}, models.RoundingFloor, "JPY", []int64{34, 33, 33}},
+		{"ceil takes the difference from the first member", 100, []float64{1, 1, 1}, models.RoundingCeil, "JPY", []int64{32, 34, 34}},
+		{"floor with weights", 1000, []float64{1, 2, 4}, models.RoundingFloor, "JPY", []int64{144, 285, 571}},
+		{"ceil with weights", 1000, []float64{1, 2, 4}, models.RoundingCeil, "JPY", []int64{142, 286, 572}},
+		{"floor does not lose a unit to float noise", 0.3, []float64{0.1, 0.2}, models.RoundingFloor, "USD", []int64{10, 20}},
+		{"ceil does not add a unit to float noise", 0.3, []float64{0.1, 0.2}, models.RoundingCeil, "USD", []int64{10, 20}},
+		{"ceil may leave the first member at zero", 0.03, []float64{1, 1, 1, 1}, models.RoundingCeil, "USD", []int64{0, 1, 1, 1}},
+		{"round uses Allocate", 100, []float64{1, 2, 4}, models.RoundingRound, "JPY", []int64{14, 29, 57}},
+		{"none uses Allocate", 10, []float64{1, 1, 1}, models.RoundingNone, "USD", []int64{334, 333, 333}},
+		{"empty mode uses Allocate", 100, []float64{1, 1, 1}, "", "JPY", []int64{34, 33, 33}},
+		{"zero weights split equally", 10, []float64{0, 0, 0}, models.RoundingCeil, "JPY", []int64{2, 4, 4}},
+		{"negative amount with floor", -100, []float64{1, 1, 1}, models.RoundingFloor, "JPY", []int64{-32, -34, -34}},
+		{"no members", 100, nil, models.RoundingFloor, "JPY", []int64{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := minorUnits(AllocateRounded(tt.amount, tt.weights, tt.mode, tt.currency), tt.currency)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("AllocateRounded(%v, %v, %q, %q) = %v, want %v", tt.amount, tt.weights, tt.mode, tt.currency, got, tt.want)
+			}
+			if len(tt.weights) > 0 {
+				checkAllocation(t, tt.amount, tt.currency, got)
+			}
+		})
+	}
+}
+
+// 先頭の配分の符号が金額と逆になる場合は、端数処理モードに関わらず Allocate と同じ配分になる
+func TestAllocateRoundedFallsBackToAllocate(t *testing.T) {
+	tests := []struct {
+		name     string
+		amount   float64
+		weights  []float64
+		mode     string
+		currency string
+	}{
+		{"ceil of a small amount among many members", 0.05, []float64{1, 1, 1, 1, 1, 1, 1, 1, 1, 1}, models.RoundingCeil, "USD"},
+		{"ceil of a small amount in yen", 3, []float64{1, 1, 1, 1, 1}, models.RoundingCeil, "JPY"},
+		{"ceil with a small first weight", 10, []float64{0.01, 1, 1, 1}, models.RoundingCeil, "JPY"},
+		{"floor of a negative amount among many members", -0.05, []float64{1, 1, 1, 1, 1, 1, 1, 1, 1, 1}, models.RoundingFloor, "USD"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := minorUnits(AllocateRounded(tt.amount, tt.weights, tt.mode, tt.currency), tt.currency)
+			want := minorUnits(Allocate(tt.amount, tt.weights, tt.currency), tt.currency)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("AllocateRounded(%v, %v, %q, %q) = %v, want Allocate's %v", tt.amount, tt.weights, tt.mode, tt.currency, got, want)
+			}
+			checkAllocation(t, tt.amount, tt.currency, got)
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode/utf16"
+)
+
+// A4 の用紙の大きさ（ポイント）
+const (
+	PageWidth  = 595.0
+	PageHeight = 842.0
+)
+
+// Document は日本語を含む文字と罫線だけの簡単な PDF を作成します
+// フォントは埋め込まず、PDF ビューアーが持つ標準の日本語フォント（HeiseiKakuGo-W5）で表示します
+type Document struct {
+	title string
+	pages []*bytes.Buffer
+}
+
+// New は1ページ目だけの空の文書を作成します
+func New(title string) *Document {
+	d := &Document{title: title}
+	d.AddPage()
+	return d
+}
+
+// AddPage は新しいページを追加し、以降の描画先にします
+func (d *Document) AddPage() {
+	d.pages = append(d.pages, &bytes.Buffer{})
+}
+
+// page は描画中のページの内容を返します
+func (d *Document) page() *bytes.Buffer {
+	return d.pages[len(d.pages)-1]
+}
+
+// Text は左下を原点とする座標 (x, y) に文字を描画します
+func (d *Document) Text(x, y, size float64, s string) {
+	fmt.Fprintf(d.page(), "BT /F1 %.1f Tf %.2f %.2f Td <%s> Tj ET\n", size, x, y, encode(s))
+}
+
+// TextRight は右端が x になるように文字を描画します
+func (d *Document) TextRight(x, y, size float64, s string) {
+	d.Text(x-Width(s, size), y, size, s)
+}
+
+// Line は (x1, y1) から (x2, y2) に罫線を描画します
+func (d *Document) Line(x1, y1, x2, y2 float64) {
+	fmt.Fprintf(d.page(), "0.5 w %.2f %.2f m %.2f %.2f l S\n", x1, y1, x2, y2)
+}
+
+// Width は文字を描画したときのおおよその幅を返します（半角文字は全角文字の半分の幅）
+func Width(s string, size float64) float64 {
+	width := 0.0
+	for _, r := range s {
+		if r < 0x80 || (r >= 0xff61 && r <= 0xff9f) {
+			width += size / 2
+		} else {
+			width += size
+		}
+	}
+	return width
+}
+
+// Truncate は幅が width に収まるように s の末尾を省略します
+func Truncate(s string, size, width float64) string {
+	if Width(s, size) <= width {
+		return s
+	}
+	runes := []rune(s)
+	for len(runes) > 0 && Width(string(runes)+"…", size) > width {
+		runes = runes[:len(runes)-1]
+	}
+	return string(runes) + "…"
+}
+
+// Wrap は幅が width に収まるように s を複数行に分けます
+func Wrap(s string, size, width float64) []string {
+	var lines []string
+	for _, paragraph := range strings.Split(s, "\n") {
+		line := []rune{}
+		for _, r := range paragraph {
+			if len(line) > 0 && Width(string(append(line, r)), size) > width {
+				lines = append(lines, string(line))
+				line = line[:0]
+			}
+			line = append(line, r)
+		}
+		lines = append(lines, string(line))
+	}
+	return lines
+}
+
+// encode は文字列をフォントのエンコーディング（UniJIS-UCS2-H）の16進数の文字列に変換します
+// UCS-2 で表せない文字は「?」にします
+func encode(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r > 0xffff || utf16.IsSurrogate(r) {
+			r = '?'
+		}
+		if r < 0x20 {
+			r = ' '
+		}
+		fmt.Fprintf(&b, "%04X", r)
+	}
+	return b.String()
+}
+
+// Bytes は PDF のファイルの内容を返します
+func (d *Document) Bytes() []byte {
+	var out bytes.Buffer
+	var offsets []int
+
+	// オブジェクト番号は 1 から順に振る（1: Catalog、2: Pages、3〜5: フォント、6: Info、7 以降: ページと内容）
+	object := func(body string) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	const firstPage = 7
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", firstPage+i*2)
+	}
+
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	object("<< /Type /Font /Subtype /Type0 /BaseFont /HeiseiKakuGo-W5 /Encoding /UniJIS-UCS2-H /DescendantFonts [4 0 R] >>")
+	// 半角の英数字（CID 1〜95）と半角カナ（CID 231〜632）は全角の半分の幅
+	object("<< /Type /Font /Subtype /CIDFontType0 /BaseFont /HeiseiKakuGo-W5 /CIDSystemInfo << /Registry (Adobe) /Ordering (Japan1) /Supplement 2 >> /FontDescriptor 5 0 R /DW 1000 /W [1 95 500 231 632 500] >>")
+	object("<< /Type /FontDescriptor /FontName /HeiseiKakuGo-W5 /Flags 4 /FontBBox [-92 -250 1010 922] /ItalicAngle 0 /Ascent 752 /Descent -221 /CapHeight 737 /StemV 114 >>")
+	object(fmt.Sprintf("<< /Title <FEFF%s> /Producer (ClearUp) >>", encode(d.title)))
+
+	for i, content := range d.pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.0f %.0f] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>",
+			PageWidth, PageHeight, firstPage+i*2+1))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", content.Len(), content.String()))
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R /Info 6 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	return out.Bytes()
+}
@@ -0,0 +1,263 @@
+package retention
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/ito-system/clear-up-share/backend/database"
+	"github.com/ito-system/clear-up-share/backend/models"
+	"github.com/ito-system/clear-up-share/backend/storage"
+	"gorm.io/gorm"
+)
+
+// 保持期間ポリシーの実行モード
+const (
+	ModeDisabled = ""        // 実行しない
+	ModeDryRun   = "dry-run" // 対象件数を集計してログに出力するのみ
+	ModeEnforce  = "enforce" // 削除・匿名化を実行してログに出力
+)
+
+const runInterval = 24 * time.Hour // 定期実行の間隔
+
+// Policy は保持期間のルールを表します（0 のルールは適用しません）
+type Policy struct {
+	PurgeDeletedAfterDays      int // 論理削除されたレコードを物理削除するまでの日数
+	AnonymizeInactiveAfterDays int // ログインのないアカウントの個人情報を削除するまでの日数
+}
+
+// RuleResult はルールごとの対象件数
+type RuleResult struct {
+	Rule  string `json:"rule"`
+	Count int64  `json:"count"`
+}
+
+// Report は保持期間ポリシーの実行結果
+type Report struct {
+	DryRun  bool         `json:"dryRun"`
+	RanAt   time.Time    `json:"ranAt"`
+	Results []RuleResult `json:"results"`
+}
+
+// LoadPolicy は環境変数から保持期間のルールを読み込みます
+func LoadPolicy() Policy {
+	return Policy{
+		PurgeDeletedAfterDays:      getEnvDays("RETENTION_PURGE_DELETED_DAYS"),
+		AnonymizeInactiveAfterDays: getEnvDays("RETENTION_ANONYMIZE_INACTIVE_DAYS"),
+	}
+}
+
+// getEnvDays は環境変数から日数を取得します（未設定・不正な値はルールを適用しない）
+func getEnvDays(key string) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return 0
+	}
+	days, err := strconv.Atoi(value)
+	if err != nil || days < 0 {
+		log.Printf("Warning: invalid value for %s, rule disabled", key)
+		return 0
+	}
+	return days
+}
+
+// StartScheduler は環境変数 RETENTION_MODE に従って保持期間ポリシーを定期実行します
+func StartScheduler() {
+	mode := os.Getenv("RETENTION_MODE")
+	switch mode {
+	case ModeDisabled:
+		return
+	case ModeDryRun, ModeEnforce:
+	default:
+		log.Printf("Warning: unknown RETENTION_MODE %q, retention disabled", mode)
+		return
+	}
+
+	policy := LoadPolicy()
+	go func() {
+		for {
+			report, err := Run(database.DB, policy, mode == ModeDryRun)
+			if err != nil {
+				log.Printf("Retention run failed: %v", err)
+			} else {
+				logReport(report)
+			}
+			time.Sleep(runInterval)
+		}
+	}()
+}
+
+// logReport は実行結果をログに出力します
+func logReport(report Report) {
+	action := "applied"
+	if report.DryRun {
+		action = "would apply (dry run)"
+	}
+	for _, r := range report.Results {
+		log.Printf("Retention %s: %s: %d records", action, r.Rule, r.Count)
+	}
+}
+
+// purgeTarget は物理削除の対象となるテーブル（外部キーで参照する側から順に削除します）
+type purgeTarget struct {
+	Rule  string
+	Model interface{}
+	Where string
+	Hold  string // リーガルホールド中のグループのレコードを除外する条件（空の場合は除外しない）
+}
+
+// heldGroupsSQL はリーガルホールド中のグループのIDを列挙するSQL
+const heldGroupsSQL = "SELECT id FROM groups WHERE legal_hold_at IS NOT NULL"
+
+// heldUsersSQL はリーガルホールド中のグループの記録に現れるユーザーのIDを列挙するSQL
+const heldUsersSQL = `
+	SELECT user_id FROM memberships WHERE group_id IN (` + heldGroupsSQL + `)
+	UNION SELECT payer_id FROM expenses WHERE group_id IN (` + heldGroupsSQL + `)
+	UNION SELECT debtor_id FROM splits WHERE expense_id IN (SELECT id FROM expenses WHERE group_id IN (` + heldGroupsSQL + `))
+	UNION SELECT payer_id FROM settlements WHERE group_id IN (` + heldGroupsSQL + `)
+	UNION SELECT receiver_id FROM settlements WHERE group_id IN (` + heldGroupsSQL + `)
+	UNION SELECT debtor_id FROM forgivenesses WHERE group_id IN (` + heldGroupsSQL + `)
+	UNION SELECT receiver_id FROM forgivenesses WHERE group_id IN (` + heldGroupsSQL + `)`
+
+// Run は保持期間ポリシーを適用し、ルールごとの対象件数を返します
+// dryRun が true の場合は件数を集計するのみで変更は行いません
+func Run(db *gorm.DB, policy Policy, dryRun bool) (Report, error) {
+	now := time.Now()
+	report := Report{DryRun: dryRun, RanAt: now}
+	var receiptKeys []string
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// 論理削除から一定期間が過ぎたレコードを物理削除
+		if policy.PurgeDeletedAfterDays > 0 {
+			cutoff := now.AddDate(0, 0, -policy.PurgeDeletedAfterDays)
+			targets := []purgeTarget{
+				{"purge_deleted_expense_items", &models.ExpenseItem{},
+					"deleted_at < @cutoff OR expense_id IN (SELECT id FROM expenses WHERE deleted_at < @cutoff)",
+					"expense_id NOT IN (SELECT id FROM expenses WHERE group_id IN (" + heldGroupsSQL + "))"},
+				{"purge_deleted_receipts", &models.Receipt{},
+					"deleted_at < @cutoff OR expense_id IN (SELECT id FROM expenses WHERE deleted_at < @cutoff)",
+					"expense_id NOT IN (SELECT id FROM expenses WHERE group_id IN (" + heldGroupsSQL + "))"},
+				{"purge_deleted_expense_tags", &models.ExpenseTag{},
+					"expense_id IN (SELECT id FROM expenses WHERE deleted_at < @cutoff)",
+					"expense_id NOT IN (SELECT id FROM expenses WHERE group_id IN (" + heldGroupsSQL + "))"},
+				{"purge_deleted_expense_acknowledgements", &models.ExpenseAcknowledgement{},
+					"deleted_at < @cutoff OR expense_id IN (SELECT id FROM expenses WHERE deleted_at < @cutoff)",
+					"expense_id NOT IN (SELECT id FROM expenses WHERE group_id IN (" + heldGroupsSQL + "))"},
+				{"purge_deleted_expense_revisions", &models.ExpenseRevision{},
+					"deleted_at < @cutoff OR expense_id IN (SELECT id FROM expenses WHERE deleted_at < @cutoff)",
+					"expense_id NOT IN (SELECT id FROM expenses WHERE group_id IN (" + heldGroupsSQL + "))"},
+				{"purge_deleted_splits", &models.Split{},
+					"deleted_at < @cutoff OR expense_id IN (SELECT id FROM expenses WHERE deleted_at < @cutoff)",
+					"expense_id NOT IN (SELECT id FROM expenses WHERE group_id IN (" + heldGroupsSQL + "))"},
+				{"purge_deleted_settlement_allocations", &models.SettlementAllocation{},
+					"deleted_at < @cutoff OR expense_id IN (SELECT id FROM expenses WHERE deleted_at < @cutoff) OR settlement_id IN (SELECT id FROM settlements WHERE deleted_at < @cutoff)",
+					"expense_id NOT IN (SELECT id FROM expenses WHERE group_id IN (" + heldGroupsSQL + "))"},
+				{"purge_deleted_settlement_revisions", &models.SettlementRevision{},
+					"deleted_at < @cutoff OR settlement_id IN (SELECT id FROM settlements WHERE deleted_at < @cutoff)",
+					"settlement_id NOT IN (SELECT id FROM settlements WHERE group_id IN (" + heldGroupsSQL + "))"},
+				{"purge_deleted_reimbursement_requests", &models.ReimbursementRequest{},
+					"deleted_at < @cutoff OR expense_id IN (SELECT id FROM expenses WHERE deleted_at < @cutoff)",
+					"group_id NOT IN (" + heldGroupsSQL + ")"},
+				{"purge_deleted_expenses", &models.Expense{}, "deleted_at < @cutoff", "group_id NOT IN (" + heldGroupsSQL + ")"},
+				{"purge_deleted_group_order_claims", &models.GroupOrderClaim{},
+					"deleted_at < @cutoff OR item_id IN (SELECT id FROM group_order_items WHERE deleted_at < @cutoff)",
+					"item_id NOT IN (SELECT id FROM group_order_items WHERE order_id IN (SELECT id FROM group_orders WHERE group_id IN (" + heldGroupsSQL + ")))"},
+				{"purge_deleted_group_order_items", &models.GroupOrderItem{},
+					"deleted_at < @cutoff OR order_id IN (SELECT id FROM group_orders WHERE deleted_at < @cutoff)",
+					"order_id NOT IN (SELECT id FROM group_orders WHERE group_id IN (" + heldGroupsSQL + "))"},
+				{"purge_deleted_group_orders", &models.GroupOrder{}, "deleted_at < @cutoff", "group_id NOT IN (" + heldGroupsSQL + ")"},
+				{"purge_deleted_budgets", &models.Budget{}, "deleted_at < @cutoff", "group_id NOT IN (" + heldGroupsSQL + ")"},
+				{"purge_deleted_categories", &models.Category{}, "deleted_at < @cutoff", "group_id NOT IN (" + heldGroupsSQL + ")"},
+				{"purge_deleted_recurring_expenses", &models.RecurringExpense{}, "deleted_at < @cutoff", "group_id NOT IN (" + heldGroupsSQL + ")"},
+				{"purge_deleted_split_presets", &models.SplitPreset{}, "deleted_at < @cutoff", "group_id NOT IN (" + heldGroupsSQL + ")"},
+				{"purge_deleted_settlement_periods", &models.SettlementPeriod{}, "deleted_at < @cutoff", "group_id NOT IN (" + heldGroupsSQL + ")"},
+				{"purge_deleted_settlements", &models.Settlement{}, "deleted_at < @cutoff", "group_id NOT IN (" + heldGroupsSQL + ")"},
+				{"purge_deleted_forgivenesses", &models.Forgiveness{}, "deleted_at < @cutoff", "group_id NOT IN (" + heldGroupsSQL + ")"},
+				{"purge_deleted_debt_reminders", &models.DebtReminder{}, "deleted_at < @cutoff", "group_id NOT IN (" + heldGroupsSQL + ")"},
+				{"purge_deleted_spending_limits", &models.SpendingLimit{}, "deleted_at < @cutoff", "group_id NOT IN (" + heldGroupsSQL + ")"},
+				{"purge_deleted_memberships", &models.Membership{}, "deleted_at < @cutoff", "group_id NOT IN (" + heldGroupsSQL + ")"},
+				{"purge_deleted_webhook_deliveries", &models.WebhookDelivery{},
+					"deleted_at < @cutoff OR webhook_id IN (SELECT id FROM webhooks WHERE deleted_at < @cutoff)", ""},
+				{"purge_deleted_webhooks", &models.Webhook{}, "deleted_at < @cutoff", ""},
+				{"purge_deleted_notifications", &models.Notification{}, "deleted_at < @cutoff", "group_id NOT IN (" + heldGroupsSQL + ")"},
+			}
+
+			for _, target := range targets {
+				query := tx.Unscoped().Model(target.Model).Where(target.Where, map[string]interface{}{"cutoff": cutoff})
+				if target.Hold != "" {
+					query = query.Where(target.Hold)
+				}
+
+				// 物理削除するレシートのファイルはコミット後に保存先から削除する
+				if _, ok := target.Model.(*models.Receipt); ok && !dryRun {
+					if err := query.Session(&gorm.Session{}).Pluck("storage_key", &receiptKeys).Error; err != nil {
+						return err
+					}
+				}
+
+				var count int64
+				if dryRun {
+					if err := query.Count(&count).Error; err != nil {
+						return err
+					}
+				} else {
+					result := query.Delete(target.Model)
+					if result.Error != nil {
+						return result.Error
+					}
+					count = result.RowsAffected
+				}
+				report.Results = append(report.Results, RuleResult{Rule: target.Rule, Count: count})
+			}
+		}
+
+		// 長期間ログインのないアカウントの個人情報を削除（支出・清算の記録は残す）
+		if policy.AnonymizeInactiveAfterDays > 0 {
+			cutoff := now.AddDate(0, 0, -policy.AnonymizeInactiveAfterDays)
+			query := tx.Model(&models.User{}).
+				Where("is_placeholder = ? AND anonymized_at IS NULL AND COALESCE(last_login_at, created_at) < ?", false, cutoff).
+				Where("id NOT IN (" + heldUsersSQL + ")")
+
+			var users []models.User
+			if err := query.Find(&users).Error; err != nil {
+				return err
+			}
+
+			if !dryRun {
+				for _, user := range users {
+					if err := tx.Model(&user).Updates(map[string]interface{}{
+						"username":        fmt.Sprintf("deleted-user-%d", user.ID),
+						"email":           fmt.Sprintf("deleted-user-%d@anonymized.invalid", user.ID),
+						"hashed_password": "",
+						"anonymized_at":   now,
+					}).Error; err != nil {
+						return err
+					}
+					if err := tx.Unscoped().Where("user_id = ?", user.ID).Delete(&models.PayoutProfile{}).Error; err != nil {
+						return err
+					}
+					if err := tx.Unscoped().Where("user_id = ?", user.ID).Delete(&models.StripeAccount{}).Error; err != nil {
+						return err
+					}
+				}
+			}
+			report.Results = append(report.Results, RuleResult{Rule: "anonymize_inactive_users", Count: int64(len(users))})
+		}
+
+		return nil
+	})
+	if err != nil {
+		return report, err
+	}
+
+	for _, key := range receiptKeys {
+		if err := storage.Delete(context.Background(), key); err != nil {
+			log.Printf("Failed to delete receipt file %s: %v", key, err)
+		}
+	}
+
+	return report, nil
+}
@@ -4,21 +4,51 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/ito-system/clear-up-share/backend/handler"
 	"github.com/ito-system/clear-up-share/backend/middleware"
+	"github.com/ito-system/clear-up-share/backend/utils"
 )
 
 // SetupRouter はGinルーターを初期化し、すべてのルートを設定します
 func SetupRouter() *gin.Engine {
 	r := gin.Default()
 
-	// APIルート
+	// 無効化できる機能のルートに付与するミドルウェア
+	oauthFeature := middleware.RequireFeature(utils.FeatureOAuth)
+	webhooksFeature := middleware.RequireFeature(utils.FeatureWebhooks)
+
+	// APIルート（データベース障害時は書き込みを拒否し、負債・履歴は直近のレスポンスを返す）
 	v1 := r.Group("/api/v1")
+	v1.Use(middleware.DegradedMode(
+		"/api/v1/groups/:groupID/debts",
+		"/api/v1/groups/:groupID/history",
+	))
 	{
+		// 呼び出し元が利用できる機能・権限の一覧
+		v1.GET("/capabilities", middleware.AuthMiddleware(), handler.GetCapabilities)
+
+		// ローカルに保存したレシートなどのファイル（認証不要、署名付きURLで保護）
+		v1.GET("/files/*key", handler.DownloadFile)
+
+		// Stripe の Webhook（認証不要、Stripe-Signature ヘッダーの署名で保護）
+		v1.POST("/stripe/webhook", handler.HandleStripeWebhook)
+
 		// 認証不要のルート
 		auth := v1.Group("/auth")
 		{
 			auth.POST("/register", handler.RegisterUser)
 			auth.POST("/login", handler.LoginUser)
 			auth.POST("/logout", handler.LogoutUser)
+			auth.POST("/token/exchange", middleware.AuthMiddleware(), handler.ExchangeToken)
+		}
+
+		// OAuth2 認可サーバー（トークン・取り消しエンドポイントはクライアント認証）
+		oauth := v1.Group("/oauth")
+		oauth.Use(oauthFeature)
+		{
+			oauth.POST("/token", handler.ExchangeOAuthToken)
+			oauth.POST("/revoke", handler.RevokeOAuthToken)
+			oauth.POST("/clients", middleware.AuthMiddleware(), handler.RegisterOAuthClient)
+			oauth.GET("/authorize", middleware.AuthMiddleware(), handler.GetOAuthConsent)
+			oauth.POST("/authorize", middleware.AuthMiddleware(), handler.AuthorizeOAuthClient)
 		}
 
 		// 認証が必要なルート
@@ -27,13 +57,161 @@ func SetupRouter() *gin.Engine {
 		{
 			groups.GET("", handler.GetGroups)
 			groups.POST("", handler.CreateGroup)
+			groups.GET("/lookup", handler.LookupGroupByCode)
+			groups.PUT("/:groupID", handler.UpdateGroup)
+			groups.POST("/:groupID/clone", handler.CloneGroup)
+			groups.PUT("/:groupID/appearance", handler.UpdateGroupAppearance)
+			groups.PUT("/:groupID/visibility", handler.UpdateGroupVisibility)
+			groups.PUT("/:groupID/pin", handler.PinGroup)
+			groups.GET("/:groupID/settings", handler.GetGroupSettings)
+			groups.PUT("/:groupID/settings", handler.UpdateGroupSettings)
+			groups.GET("/:groupID/notification-settings", handler.GetNotificationSettings)
+			groups.PUT("/:groupID/notification-settings", handler.UpdateNotificationSettings)
+			groups.GET("/:groupID/spending-limit", handler.GetSpendingLimit)
+			groups.PUT("/:groupID/spending-limit", handler.UpdateSpendingLimit)
+			groups.DELETE("/:groupID/spending-limit", handler.DeleteSpendingLimit)
+			groups.GET("/:groupID/webhooks", webhooksFeature, handler.GetWebhooks)
+			groups.POST("/:groupID/webhooks", webhooksFeature, handler.CreateWebhook)
+			groups.PUT("/:groupID/webhooks/:webhookID", webhooksFeature, handler.UpdateWebhook)
+			groups.DELETE("/:groupID/webhooks/:webhookID", webhooksFeature, handler.DeleteWebhook)
+			groups.POST("/:groupID/archive", handler.ArchiveGroup)
+			groups.POST("/:groupID/unarchive", handler.UnarchiveGroup)
+			groups.POST("/:groupID/currency-migration/preview", handler.PreviewCurrencyMigration)
+			groups.POST("/:groupID/currency-migration", handler.MigrateGroupCurrency)
+			groups.POST("/:groupID/legal-hold", handler.PlaceLegalHold)
+			groups.DELETE("/:groupID/legal-hold", handler.ReleaseLegalHold)
+			groups.GET("/:groupID/legal-hold/export", middleware.RequireFeature(utils.FeatureExports), handler.ExportGroupEvidence)
+			groups.GET("/:groupID/summary", handler.GetGroupSummary)
 			groups.GET("/:groupID/history", handler.GetGroupHistory)
+			groups.GET("/:groupID/activity", handler.GetGroupActivity)
 			groups.GET("/:groupID/members", handler.GetGroupMembers)
+			groups.PUT("/:groupID/members/:userID/role", handler.UpdateMemberRole)
+			groups.PUT("/:groupID/members/:userID/nickname", handler.UpdateMemberNickname)
+			groups.DELETE("/:groupID/members/:userID", handler.RemoveMember)
+			groups.POST("/:groupID/leave", handler.LeaveGroup)
+			groups.POST("/:groupID/invitations", handler.CreateInvitation)
+			groups.GET("/:groupID/join-requests", handler.GetJoinRequests)
+			groups.POST("/:groupID/join-requests", handler.CreateJoinRequest)
+			groups.POST("/:groupID/join-requests/:requestID/approve", handler.ApproveJoinRequest)
+			groups.POST("/:groupID/join-requests/:requestID/reject", handler.RejectJoinRequest)
+			groups.POST("/:groupID/placeholders", handler.CreatePlaceholderMember)
+			groups.PUT("/:groupID/placeholders/:userID", handler.RenamePlaceholderMember)
+			groups.GET("/:groupID/expenses", handler.GetExpenses)
 			groups.POST("/:groupID/expenses", handler.AddExpense)
+			groups.POST("/:groupID/expenses/bulk", handler.AddExpensesBulk)
+			groups.GET("/:groupID/expenses/drafts", handler.GetDrafts)
+			groups.GET("/:groupID/expenses/trash", handler.GetTrash)
+			groups.GET("/:groupID/expenses/map", handler.GetExpenseMap)
+			groups.GET("/:groupID/expenses/open", handler.GetOpenExpenses)
+			groups.GET("/:groupID/expenses/:expenseID", handler.GetExpense)
 			groups.PUT("/:groupID/expenses/:expenseID", handler.EditExpense)
 			groups.DELETE("/:groupID/expenses/:expenseID", handler.DeleteExpense)
+			groups.POST("/:groupID/expenses/:expenseID/approve", handler.ApproveExpense)
+			groups.POST("/:groupID/expenses/:expenseID/publish", handler.PublishExpense)
+			groups.POST("/:groupID/expenses/:expenseID/duplicate", handler.DuplicateExpense)
+			groups.POST("/:groupID/expenses/:expenseID/restore", handler.RestoreExpense)
+			groups.POST("/:groupID/expenses/:expenseID/unlock", handler.UnlockExpense)
+			groups.GET("/:groupID/expenses/:expenseID/revisions", handler.GetExpenseRevisions)
+			groups.POST("/:groupID/expenses/:expenseID/ack", handler.AcknowledgeExpense)
+			groups.DELETE("/:groupID/expenses/:expenseID/ack", handler.UnacknowledgeExpense)
+			groups.POST("/:groupID/expenses/:expenseID/reimbursement-requests", handler.CreateReimbursementRequest)
+			groups.POST("/:groupID/expenses/:expenseID/receipts", handler.UploadReceipt)
+			groups.GET("/:groupID/categories", handler.GetCategories)
+			groups.POST("/:groupID/categories", handler.CreateCategory)
+			groups.DELETE("/:groupID/categories/:categoryID", handler.DeleteCategory)
+			groups.GET("/:groupID/budgets", handler.GetBudgets)
+			groups.POST("/:groupID/budgets", handler.CreateBudget)
+			groups.PUT("/:groupID/budgets/:budgetID", handler.UpdateBudget)
+			groups.DELETE("/:groupID/budgets/:budgetID", handler.DeleteBudget)
+			groups.GET("/:groupID/tags", handler.GetTags)
+			groups.GET("/:groupID/search", handler.SearchExpenses)
+			groups.GET("/:groupID/recurring-expenses", handler.GetRecurringExpenses)
+			groups.POST("/:groupID/recurring-expenses", handler.CreateRecurringExpense)
+			groups.PUT("/:groupID/recurring-expenses/:recurringID", handler.UpdateRecurringExpense)
+			groups.DELETE("/:groupID/recurring-expenses/:recurringID", handler.DeleteRecurringExpense)
+			groups.GET("/:groupID/split-presets", handler.GetSplitPresets)
+			groups.POST("/:groupID/split-presets", handler.CreateSplitPreset)
+			groups.PUT("/:groupID/split-presets/:presetID", handler.UpdateSplitPreset)
+			groups.DELETE("/:groupID/split-presets/:presetID", handler.DeleteSplitPreset)
 			groups.GET("/:groupID/debts", handler.GetGroupDebts)
+			groups.GET("/:groupID/debts/pairwise", handler.GetPairwiseDebts)
+			groups.GET("/:groupID/debts/between", handler.GetDebtsBetween)
+			groups.GET("/:groupID/debts/aging", handler.GetDebtAging)
+			groups.GET("/:groupID/next-payer", handler.GetNextPayer)
+			groups.POST("/:groupID/debts/remind", handler.RemindDebts)
+			groups.POST("/:groupID/simulate", middleware.RequireFeature(utils.FeatureSimulate), handler.SimulateGroup)
 			groups.POST("/:groupID/settlements", handler.RecordSettlement)
+			groups.GET("/:groupID/settlements/suggestions", handler.GetSettlementSuggestions)
+			groups.GET("/:groupID/settlements/plan", middleware.RequireFeature(utils.FeatureExports), handler.GetSettlementPlan)
+			groups.POST("/:groupID/settlements/settle-all", handler.SettleAll)
+			groups.POST("/:groupID/settlements/payment-callback", handler.RecordPaymentCallback)
+			groups.GET("/:groupID/settlements/:settlementID", handler.GetSettlement)
+			groups.PUT("/:groupID/settlements/:settlementID", handler.UpdateSettlement)
+			groups.DELETE("/:groupID/settlements/:settlementID", handler.VoidSettlement)
+			groups.POST("/:groupID/settlements/:settlementID/confirm", handler.ConfirmSettlement)
+			groups.POST("/:groupID/settlements/:settlementID/payment-intent", handler.CreateSettlementPaymentIntent)
+			groups.GET("/:groupID/settlements/:settlementID/receipt", middleware.RequireFeature(utils.FeatureExports), handler.GetSettlementReceipt)
+			groups.POST("/:groupID/settlement-batches", handler.RecordSettlementBatch)
+			groups.GET("/:groupID/settlement-batches/:batchID", handler.GetSettlementBatch)
+			groups.GET("/:groupID/settlement-periods", handler.GetSettlementPeriods)
+			groups.POST("/:groupID/settlement-periods", handler.CloseSettlementPeriod)
+			groups.POST("/:groupID/forgivenesses", handler.CreateForgiveness)
+			groups.POST("/:groupID/forgivenesses/:forgivenessID/confirm", handler.ConfirmForgiveness)
+			groups.POST("/:groupID/forgivenesses/:forgivenessID/decline", handler.DeclineForgiveness)
+			groups.GET("/:groupID/orders", handler.GetGroupOrders)
+			groups.POST("/:groupID/orders", handler.CreateGroupOrder)
+			groups.GET("/:groupID/orders/:orderID", handler.GetGroupOrder)
+			groups.DELETE("/:groupID/orders/:orderID", handler.DeleteGroupOrder)
+			groups.POST("/:groupID/orders/:orderID/close", handler.CloseGroupOrder)
+			groups.POST("/:groupID/orders/:orderID/items/:itemID/claim", handler.ClaimGroupOrderItem)
+			groups.DELETE("/:groupID/orders/:orderID/items/:itemID/claim", handler.UnclaimGroupOrderItem)
+			groups.GET("/:groupID/reimbursement-requests", handler.GetReimbursementRequests)
+			groups.POST("/:groupID/reimbursement-requests/:requestID/pay", handler.PayReimbursementRequest)
+			groups.POST("/:groupID/reimbursement-requests/:requestID/confirm", handler.ConfirmReimbursementRequest)
+		}
+
+		// グループに属さない個人間の貸し借りのルート
+		iou := v1.Group("/iou")
+		iou.Use(middleware.AuthMiddleware())
+		{
+			iou.GET("", handler.GetIOUs)
+			iou.POST("", handler.CreateIOU)
+			iou.GET("/:iouID", handler.GetIOU)
+			iou.POST("/:iouID/settle", handler.SettleIOU)
+			iou.POST("/:iouID/payments/:paymentID/confirm", handler.ConfirmIOUPayment)
+			iou.POST("/:iouID/remind", handler.RemindIOU)
+		}
+
+		// サポート担当の管理者向けのルート
+		support := v1.Group("/support")
+		support.Use(middleware.AuthMiddleware())
+		{
+			support.POST("/impersonate", handler.StartSupportImpersonation)
+		}
+
+		// 認証ユーザー自身に関するルート
+		users := v1.Group("/users")
+		users.Use(middleware.AuthMiddleware())
+		{
+			users.GET("/me/glance", handler.GetMyGlance)
+			users.GET("/me/balances", handler.GetMyBalances)
+			users.GET("/me/onboarding", handler.GetMyOnboarding)
+			users.GET("/me/notifications", handler.GetMyNotifications)
+			users.POST("/me/notifications/:notificationID/read", handler.MarkNotificationRead)
+			users.GET("/me/invitations", handler.GetMyInvitations)
+			users.POST("/me/invitations/:invitationID/accept", handler.AcceptInvitation)
+			users.POST("/me/invitations/:invitationID/decline", handler.DeclineInvitation)
+			users.GET("/me/exports/tax-year", middleware.RequireFeature(utils.FeatureExports), handler.ExportTaxYear)
+			users.GET("/me/payout-profile", handler.GetMyPayoutProfile)
+			users.PUT("/me/payout-profile", handler.UpdateMyPayoutProfile)
+			users.DELETE("/me/payout-profile", handler.DeleteMyPayoutProfile)
+			users.GET("/me/stripe-account", handler.GetMyStripeAccount)
+			users.POST("/me/stripe-account", handler.ConnectMyStripeAccount)
+			users.GET("/me/oauth/grants", oauthFeature, handler.GetMyOAuthGrants)
+			users.DELETE("/me/oauth/grants/:grantID", oauthFeature, handler.RevokeMyOAuthGrant)
+			users.GET("/me/support-access", handler.GetMySupportAccess)
+			users.POST("/me/support-access", handler.GrantSupportAccess)
+			users.DELETE("/me/support-access/:grantID", handler.RevokeSupportAccess)
 		}
 	}
 
@@ -0,0 +1,90 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LocalDownloadPath はローカルに保存したファイルを配信するAPIのパス
+const LocalDownloadPath = "/api/v1/files/"
+
+// Local はサーバーのディレクトリにファイルを保存します
+// ダウンロードURLは SigningKey で署名し、LocalDownloadPath から配信します
+type Local struct {
+	Dir        string
+	BaseURL    string // URLの先頭に付けるオリジン（例: https://api.example.com、空の場合はパスのみ）
+	SigningKey []byte
+}
+
+// path は key に対応するファイルのパスを返します（Dir の外を指さないようにします）
+func (l *Local) path(key string) string {
+	return filepath.Join(l.Dir, filepath.FromSlash(path.Clean("/"+key)))
+}
+
+// Put はファイルを Dir 以下に保存します
+func (l *Local) Put(ctx context.Context, key, contentType string, body io.Reader, size int64) error {
+	p := l.path(key)
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+	f, err := os.Create(p)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, body); err != nil {
+		f.Close()
+		os.Remove(p)
+		return err
+	}
+	return f.Close()
+}
+
+// Delete はファイルを削除します（既にない場合は何もしません）
+func (l *Local) Delete(ctx context.Context, key string) error {
+	if err := os.Remove(l.path(key)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// SignedURL は有効期限と署名を付けたダウンロードURLを返します
+func (l *Local) SignedURL(key string, expires time.Duration) (string, error) {
+	expiresAt := strconv.FormatInt(time.Now().Add(expires).Unix(), 10)
+	query := url.Values{
+		"expires":   {expiresAt},
+		"signature": {l.sign(key, expiresAt)},
+	}
+	return l.BaseURL + LocalDownloadPath + key + "?" + query.Encode(), nil
+}
+
+// OpenSigned は署名と有効期限を検証してからファイルを開きます
+func (l *Local) OpenSigned(key, expires, signature string) (*os.File, error) {
+	key = strings.TrimPrefix(key, "/")
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > expiresAt {
+		return nil, ErrInvalidSignature
+	}
+	if !hmac.Equal([]byte(signature), []byte(l.sign(key, expires))) {
+		return nil, ErrInvalidSignature
+	}
+	return os.Open(l.path(key))
+}
+
+// sign は key と有効期限の署名を返します
+func (l *Local) sign(key, expires string) string {
+	mac := hmac.New(sha256.New, l.SigningKey)
+	fmt.Fprintf(mac, "%s\n%s", key, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,95 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
+
+// 保存先の種類
+const (
+	BackendLocal = "local" // サーバーのディレクトリに保存
+	BackendS3    = "s3"    // S3 互換のオブジェクトストレージに保存
+)
+
+const defaultLocalDir = "./uploads"
+
+var (
+	// ErrInvalidSignature は署名付きURLの署名が正しくないか期限切れの場合に返されます
+	ErrInvalidSignature = errors.New("invalid or expired signature")
+	// ErrNotLocal はサーバーから直接配信できない保存先の場合に返されます
+	ErrNotLocal = errors.New("storage backend does not serve files locally")
+)
+
+// Backend はファイルの保存先を表します
+type Backend interface {
+	// Put はファイルを key に保存します
+	Put(ctx context.Context, key, contentType string, body io.Reader, size int64) error
+	// Delete は key のファイルを削除します
+	Delete(ctx context.Context, key string) error
+	// SignedURL は key のファイルを expires の間ダウンロードできるURLを返します
+	SignedURL(key string, expires time.Duration) (string, error)
+}
+
+var backend Backend
+
+// Init は環境変数から保存先を設定します
+// STORAGE_BACKEND が未設定の場合は STORAGE_LOCAL_DIR（既定は ./uploads）に保存します
+func Init() {
+	switch name := os.Getenv("STORAGE_BACKEND"); name {
+	case "", BackendLocal:
+		dir := os.Getenv("STORAGE_LOCAL_DIR")
+		if dir == "" {
+			dir = defaultLocalDir
+		}
+		// 署名用の鍵（未設定の場合は起動ごとに変わるため、再起動すると発行済みのURLは使えなくなります）
+		key := []byte(os.Getenv("STORAGE_SIGNING_KEY"))
+		if len(key) == 0 {
+			log.Println("Warning: STORAGE_SIGNING_KEY not set, signed URLs will expire on restart")
+			key = []byte(strconv.FormatInt(time.Now().UnixNano(), 10))
+		}
+		backend = &Local{
+			Dir:        dir,
+			BaseURL:    os.Getenv("STORAGE_PUBLIC_URL"),
+			SigningKey: key,
+		}
+	case BackendS3:
+		backend = &S3{
+			Bucket:          os.Getenv("S3_BUCKET"),
+			Region:          os.Getenv("S3_REGION"),
+			Endpoint:        os.Getenv("S3_ENDPOINT"),
+			AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		}
+	default:
+		log.Fatalf("Unknown STORAGE_BACKEND %q", name)
+	}
+}
+
+// Put は設定された保存先にファイルを保存します
+func Put(ctx context.Context, key, contentType string, body io.Reader, size int64) error {
+	return backend.Put(ctx, key, contentType, body, size)
+}
+
+// Delete は設定された保存先からファイルを削除します
+func Delete(ctx context.Context, key string) error {
+	return backend.Delete(ctx, key)
+}
+
+// SignedURL は設定された保存先のファイルの署名付きダウンロードURLを返します
+func SignedURL(key string, expires time.Duration) (string, error) {
+	return backend.SignedURL(key, expires)
+}
+
+// OpenSigned はローカルに保存したファイルを署名付きURLのパラメーターを検証してから開きます
+func OpenSigned(key, expires, signature string) (*os.File, error) {
+	local, ok := backend.(*Local)
+	if !ok {
+		return nil, ErrNotLocal
+	}
+	return local.OpenSigned(key, expires, signature)
+}
@@ -0,0 +1,288 @@
+package stripe
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultAPIURL    = "https://api.stripe.com"
+	apiTimeout       = 30 * time.Second // 1回のリクエストのタイムアウト
+	webhookTolerance = 5 * time.Minute  // Webhook の署名のタイムスタンプとして受け付ける時刻のずれ
+)
+
+var (
+	// ErrNotConfigured は STRIPE_SECRET_KEY が設定されていない場合に返されます
+	ErrNotConfigured = errors.New("stripe is not configured")
+	// ErrInvalidSignature は Webhook の署名が正しくないか古い場合に返されます
+	ErrInvalidSignature = errors.New("invalid stripe signature")
+)
+
+var (
+	apiURL         string
+	secretKey      string
+	webhookSecret  string
+	publishableKey string
+	returnURL      string
+	refreshURL     string
+	client         = &http.Client{Timeout: apiTimeout}
+)
+
+// Error は Stripe API が返したエラー
+type Error struct {
+	Status  int
+	Type    string `json:"type"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("stripe: %d %s: %s", e.Status, e.Type, e.Message)
+}
+
+// Account は Stripe Connect の連結アカウント
+type Account struct {
+	ID             string `json:"id"`
+	ChargesEnabled bool   `json:"charges_enabled"`
+}
+
+// PaymentIntent は Stripe のカード決済の支払い
+type PaymentIntent struct {
+	ID           string            `json:"id"`
+	Amount       int64             `json:"amount"` // 補助単位
+	Currency     string            `json:"currency"`
+	Status       string            `json:"status"`
+	ClientSecret string            `json:"client_secret"` // クライアントが Stripe.js などで支払いを確定するための値
+	Metadata     map[string]string `json:"metadata"`
+}
+
+// PaymentIntent.Status の値
+const (
+	PaymentIntentSucceeded = "succeeded"
+	PaymentIntentCanceled  = "canceled"
+)
+
+// PaymentIntentParams は PaymentIntent の作成に指定する値
+type PaymentIntentParams struct {
+	Amount         int64  // 補助単位
+	Currency       string // ISO 4217 の通貨コード
+	Destination    string // 受取者の連結アカウントID（支払いは受取者に送金されます）
+	Description    string
+	Metadata       map[string]string
+	IdempotencyKey string // 同じ値で作成した場合は同じ PaymentIntent を返します
+}
+
+// Event は Stripe の Webhook で送られるイベント
+type Event struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+	Data struct {
+		Object json.RawMessage `json:"object"`
+	} `json:"data"`
+}
+
+// Event.Type の値
+const (
+	EventPaymentIntentSucceeded = "payment_intent.succeeded"
+	EventAccountUpdated         = "account.updated"
+)
+
+// Init は環境変数から Stripe の設定を読み込みます
+// STRIPE_SECRET_KEY が未設定の場合はアプリ内のカード決済を無効にします
+func Init() {
+	apiURL = os.Getenv("STRIPE_API_URL")
+	if apiURL == "" {
+		apiURL = defaultAPIURL
+	}
+	secretKey = os.Getenv("STRIPE_SECRET_KEY")
+	webhookSecret = os.Getenv("STRIPE_WEBHOOK_SECRET")
+	publishableKey = os.Getenv("STRIPE_PUBLISHABLE_KEY")
+	returnURL = os.Getenv("STRIPE_CONNECT_RETURN_URL")
+	refreshURL = os.Getenv("STRIPE_CONNECT_REFRESH_URL")
+}
+
+// Enabled はアプリ内のカード決済が有効かを返します
+func Enabled() bool {
+	return secretKey != ""
+}
+
+// PublishableKey はクライアントが Stripe.js などで使う公開可能キーを返します
+func PublishableKey() string {
+	return publishableKey
+}
+
+// CreateAccount はカード決済を受け取るための Express の連結アカウントを作成します
+func CreateAccount(ctx context.Context, email string, metadata map[string]string) (Account, error) {
+	form := url.Values{
+		"type":                                   {"express"},
+		"email":                                  {email},
+		"capabilities[card_payments][requested]": {"true"},
+		"capabilities[transfers][requested]":     {"true"},
+	}
+	setMetadata(form, metadata)
+
+	var account Account
+	err := do(ctx, http.MethodPost, "/v1/accounts", form, "", &account)
+	return account, err
+}
+
+// GetAccount は連結アカウントの現在の状態を取得します
+func GetAccount(ctx context.Context, accountID string) (Account, error) {
+	var account Account
+	err := do(ctx, http.MethodGet, "/v1/accounts/"+url.PathEscape(accountID), nil, "", &account)
+	return account, err
+}
+
+// CreateAccountLink は連結アカウントの本人確認・口座登録を行う Stripe の画面のURLを発行します
+func CreateAccountLink(ctx context.Context, accountID string) (string, error) {
+	form := url.Values{
+		"account":     {accountID},
+		"type":        {"account_onboarding"},
+		"return_url":  {returnURL},
+		"refresh_url": {refreshURL},
+	}
+
+	var link struct {
+		URL string `json:"url"`
+	}
+	err := do(ctx, http.MethodPost, "/v1/account_links", form, "", &link)
+	return link.URL, err
+}
+
+// CreatePaymentIntent は受取者の連結アカウントに送金されるカード決済の支払いを作成します
+func CreatePaymentIntent(ctx context.Context, params PaymentIntentParams) (PaymentIntent, error) {
+	form := url.Values{
+		"amount":                             {strconv.FormatInt(params.Amount, 10)},
+		"currency":                           {strings.ToLower(params.Currency)},
+		"automatic_payment_methods[enabled]": {"true"},
+		"transfer_data[destination]":         {params.Destination},
+	}
+	if params.Description != "" {
+		form.Set("description", params.Description)
+	}
+	setMetadata(form, params.Metadata)
+
+	var intent PaymentIntent
+	err := do(ctx, http.MethodPost, "/v1/payment_intents", form, params.IdempotencyKey, &intent)
+	return intent, err
+}
+
+// GetPaymentIntent はカード決済の支払いの現在の状態を取得します
+func GetPaymentIntent(ctx context.Context, id string) (PaymentIntent, error) {
+	var intent PaymentIntent
+	err := do(ctx, http.MethodGet, "/v1/payment_intents/"+url.PathEscape(id), nil, "", &intent)
+	return intent, err
+}
+
+// ParseWebhook は Stripe-Signature ヘッダーの署名を STRIPE_WEBHOOK_SECRET で検証し、イベントを返します
+func ParseWebhook(payload []byte, signatureHeader string) (Event, error) {
+	var event Event
+	if webhookSecret == "" {
+		return event, ErrNotConfigured
+	}
+
+	var timestamp string
+	var signatures []string
+	for _, part := range strings.Split(signatureHeader, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return event, ErrInvalidSignature
+	}
+	if age := time.Since(time.Unix(unix, 0)); age > webhookTolerance || age < -webhookTolerance {
+		return event, ErrInvalidSignature
+	}
+
+	mac := hmac.New(sha256.New, []byte(webhookSecret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(payload)
+	expected := hex.EncodeToString(mac.Sum(nil))
+
+	valid := false
+	for _, signature := range signatures {
+		if hmac.Equal([]byte(signature), []byte(expected)) {
+			valid = true
+		}
+	}
+	if !valid {
+		return event, ErrInvalidSignature
+	}
+
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return event, err
+	}
+	return event, nil
+}
+
+// setMetadata は metadata[key] の形式でフォームに追加します
+func setMetadata(form url.Values, metadata map[string]string) {
+	for key, value := range metadata {
+		form.Set("metadata["+key+"]", value)
+	}
+}
+
+// do は Stripe API にリクエストを送信し、レスポンスを out に読み込みます
+func do(ctx context.Context, method, path string, form url.Values, idempotencyKey string, out interface{}) error {
+	if !Enabled() {
+		return ErrNotConfigured
+	}
+
+	var body io.Reader
+	if form != nil {
+		body = strings.NewReader(form.Encode())
+	}
+	req, err := http.NewRequestWithContext(ctx, method, apiURL+path, body)
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(secretKey, "")
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	if idempotencyKey != "" {
+		req.Header.Set("Idempotency-Key", idempotencyKey)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		var failure struct {
+			Error Error `json:"error"`
+		}
+		json.Unmarshal(raw, &failure)
+		failure.Error.Status = resp.StatusCode
+		return &failure.Error
+	}
+	return json.Unmarshal(raw, out)
+}
@@ -0,0 +1,57 @@
+package utils
+
+import (
+	"sort"
+	"strings"
+)
+
+// DefaultCurrency はグループ作成時に通貨が指定されなかった場合の通貨コード
+const DefaultCurrency = "JPY"
+
+// currencyMinorUnits は対応している ISO 4217 通貨コードと補助単位の桁数
+var currencyMinorUnits = map[string]int{
+	"AUD": 2,
+	"CAD": 2,
+	"CHF": 2,
+	"CNY": 2,
+	"EUR": 2,
+	"GBP": 2,
+	"HKD": 2,
+	"IDR": 2,
+	"INR": 2,
+	"JPY": 0,
+	"KRW": 0,
+	"MYR": 2,
+	"NZD": 2,
+	"PHP": 2,
+	"SGD": 2,
+	"THB": 2,
+	"TWD": 2,
+	"USD": 2,
+	"VND": 0,
+}
+
+// NormalizeCurrency は通貨コードを大文字に揃え、対応している通貨かどうかを返します
+func NormalizeCurrency(code string) (string, bool) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	_, ok := currencyMinorUnits[code]
+	return code, ok
+}
+
+// CurrencyMinorUnits は通貨の補助単位の桁数を返します（未対応の通貨は2桁として扱います）
+func CurrencyMinorUnits(code string) int {
+	if digits, ok := currencyMinorUnits[code]; ok {
+		return digits
+	}
+	return 2
+}
+
+// SupportedCurrencies は対応している通貨コードの一覧をアルファベット順で返します
+func SupportedCurrencies() []string {
+	codes := make([]string, 0, len(currencyMinorUnits))
+	for code := range currencyMinorUnits {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
@@ -0,0 +1,9 @@
+package utils
+
+import "strings"
+
+// NormalizeEmail はメールアドレスの前後の空白を除き、小文字に揃えます
+// 登録・ログイン・検索で同じ形に揃え、大文字小文字だけが異なるアカウントを作らせないために使います
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
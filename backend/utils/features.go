@@ -0,0 +1,35 @@
+package utils
+
+import (
+	"os"
+	"strings"
+)
+
+// 無効化できる機能
+const (
+	FeatureOAuth    = "oauth"    // サードパーティアプリ連携（OAuth2）
+	FeatureWebhooks = "webhooks" // グループのWebhook
+	FeatureSimulate = "simulate" // 貸借額のシミュレーション
+	FeatureExports  = "exports"  // 支出データのエクスポート
+)
+
+// Features は無効化できる機能の一覧
+var Features = []string{FeatureOAuth, FeatureWebhooks, FeatureSimulate, FeatureExports}
+
+// disabledFeatures は無効化されている機能
+var disabledFeatures = map[string]bool{}
+
+// InitFeatures は環境変数 DISABLED_FEATURES（カンマ区切り）から無効化する機能を初期化します
+func InitFeatures() {
+	disabledFeatures = map[string]bool{}
+	for _, name := range strings.Split(os.Getenv("DISABLED_FEATURES"), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			disabledFeatures[name] = true
+		}
+	}
+}
+
+// FeatureEnabled は機能が有効かどうかを返します
+func FeatureEnabled(name string) bool {
+	return !disabledFeatures[name]
+}
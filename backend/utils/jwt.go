@@ -11,6 +11,12 @@ import (
 // JWTSecret はJWT署名用のシークレットキーを保持します
 var JWTSecret []byte
 
+// トークンのスコープ
+const (
+	ScopeFull = "full" // 全操作が可能
+	ScopeRead = "read" // 読み取り（GET）のみ可能
+)
+
 // InitJWT はJWTシークレットを初期化します
 func InitJWT() {
 	secret := os.Getenv("JWT_SECRET")
@@ -23,12 +29,132 @@ func InitJWT() {
 
 // GenerateJWT はユーザーIDを含むJWTトークンを生成します
 func GenerateJWT(userID uint) (string, error) {
+	return GenerateScopedJWT(userID, ScopeFull, 0, time.Hour*1) // 1時間後に有効期限切れ
+}
+
+// GenerateScopedJWT はスコープと対象グループを制限したJWTトークンを生成します
+// groupID が 0 の場合はグループを制限しません
+func GenerateScopedJWT(userID uint, scope string, groupID uint, ttl time.Duration) (string, error) {
+	return signJWT(scopedClaims(userID, scope, groupID, ttl))
+}
+
+// GenerateExchangedJWT はトークンの交換で発行する、権限を絞ったJWTトークンを生成します
+// 交換したトークンには exchanged クレームを付け、さらに交換して有効期限を延ばせないようにします
+func GenerateExchangedJWT(userID uint, scope string, groupID uint, ttl time.Duration) (string, error) {
+	claims := scopedClaims(userID, scope, groupID, ttl)
+	claims["exchanged"] = true
+	return signJWT(claims)
+}
+
+// GenerateOAuthJWT はOAuth2の権限付与 (grant) に紐づくアクセストークンを生成します
+// grant が取り消されるとミドルウェアでトークンが拒否されます
+func GenerateOAuthJWT(userID uint, scope string, groupID uint, grantID uint, ttl time.Duration) (string, error) {
+	claims := scopedClaims(userID, scope, groupID, ttl)
+	claims["grantID"] = grantID
+	return signJWT(claims)
+}
+
+// GenerateSupportJWT はサポート担当の管理者が userID のユーザーになりすますための読み取り専用トークンを生成します
+// ユーザーの閲覧権限 (grant) が取り消されるか期限が切れると、ミドルウェアでトークンが拒否されます
+func GenerateSupportJWT(userID uint, adminID uint, grantID uint, ttl time.Duration) (string, error) {
+	claims := scopedClaims(userID, ScopeRead, 0, ttl)
+	claims["supportGrantID"] = grantID
+	claims["impersonatorID"] = adminID
+	return signJWT(claims)
+}
+
+// ParseJWT はトークンの署名と有効期限を検証し、クレームを返します
+func ParseJWT(tokenString string) (jwt.MapClaims, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, jwt.ErrSignatureInvalid
+		}
+		return JWTSecret, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, jwt.ErrTokenInvalidClaims
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, jwt.ErrTokenInvalidClaims
+	}
+	return claims, nil
+}
+
+// scopedClaims はスコープ付きトークンのクレームを構築します
+func scopedClaims(userID uint, scope string, groupID uint, ttl time.Duration) jwt.MapClaims {
 	claims := jwt.MapClaims{
 		"userID": userID,
-		"exp":    time.Now().Add(time.Hour * 1).Unix(), // 1時間後に有効期限切れ
+		"scope":  scope,
+		"exp":    time.Now().Add(ttl).Unix(),
 		"iat":    time.Now().Unix(),
 	}
+	if groupID != 0 {
+		claims["groupID"] = groupID
+	}
+	return claims
+}
 
+// signJWT はクレームに署名してトークン文字列を返します
+func signJWT(claims jwt.MapClaims) (string, error) {
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString(JWTSecret)
 }
+
+// PaymentLinkPurpose は清算の支払いリンクのトークンであることを表すクレームの値
+// 支払いリンクのトークンには userID を含めないため、認証には使えません
+const PaymentLinkPurpose = "settlement_payment"
+
+// PaymentLinkClaims は清算の支払いリンクのトークンに含める送金の内容
+type PaymentLinkClaims struct {
+	GroupID    uint
+	PayerID    uint
+	ReceiverID uint
+	Amount     float64
+	Nonce      string // 同じトークンで二重に清算を記録しないための値
+}
+
+// GeneratePaymentLinkJWT は送金の提案から支払いを記録するためのトークンを生成します
+func GeneratePaymentLinkJWT(link PaymentLinkClaims, ttl time.Duration) (string, error) {
+	return signJWT(jwt.MapClaims{
+		"purpose":    PaymentLinkPurpose,
+		"groupID":    link.GroupID,
+		"payerID":    link.PayerID,
+		"receiverID": link.ReceiverID,
+		"amount":     link.Amount,
+		"nonce":      link.Nonce,
+		"exp":        time.Now().Add(ttl).Unix(),
+		"iat":        time.Now().Unix(),
+	})
+}
+
+// ParsePaymentLinkJWT は支払いリンクのトークンの署名と有効期限を検証し、送金の内容を返します
+func ParsePaymentLinkJWT(tokenString string) (PaymentLinkClaims, error) {
+	claims, err := ParseJWT(tokenString)
+	if err != nil {
+		return PaymentLinkClaims{}, err
+	}
+	if purpose, _ := claims["purpose"].(string); purpose != PaymentLinkPurpose {
+		return PaymentLinkClaims{}, jwt.ErrTokenInvalidClaims
+	}
+
+	groupID, ok1 := claims["groupID"].(float64)
+	payerID, ok2 := claims["payerID"].(float64)
+	receiverID, ok3 := claims["receiverID"].(float64)
+	amount, ok4 := claims["amount"].(float64)
+	nonce, ok5 := claims["nonce"].(string)
+	if !ok1 || !ok2 || !ok3 || !ok4 || !ok5 || nonce == "" {
+		return PaymentLinkClaims{}, jwt.ErrTokenInvalidClaims
+	}
+	return PaymentLinkClaims{
+		GroupID:    uint(groupID),
+		PayerID:    uint(payerID),
+		ReceiverID: uint(receiverID),
+		Amount:     amount,
+		Nonce:      nonce,
+	}, nil
+}
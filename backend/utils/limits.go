@@ -0,0 +1,40 @@
+package utils
+
+import (
+	"log"
+	"os"
+	"strconv"
+)
+
+// Limits はプランごとの利用上限を表します（0 は無制限）
+type Limits struct {
+	MaxGroupsPerUser    int // ユーザーが所属できるグループ数
+	MaxMembersPerGroup  int // グループに所属できるメンバー数（仮メンバーを含む）
+	MaxExpensesPerMonth int // グループで1か月に登録できる支出数
+}
+
+// DefaultLimits は全ユーザー・グループに適用される既定の利用上限
+var DefaultLimits Limits
+
+// InitLimits は環境変数から既定の利用上限を初期化します
+func InitLimits() {
+	DefaultLimits = Limits{
+		MaxGroupsPerUser:    getEnvLimit("LIMIT_MAX_GROUPS_PER_USER"),
+		MaxMembersPerGroup:  getEnvLimit("LIMIT_MAX_MEMBERS_PER_GROUP"),
+		MaxExpensesPerMonth: getEnvLimit("LIMIT_MAX_EXPENSES_PER_MONTH"),
+	}
+}
+
+// getEnvLimit は環境変数から上限値を取得します（未設定・不正な値は無制限として扱います）
+func getEnvLimit(key string) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return 0
+	}
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit < 0 {
+		log.Printf("Warning: invalid value for %s, treating as unlimited", key)
+		return 0
+	}
+	return limit
+}
@@ -0,0 +1,25 @@
+package utils
+
+import "time"
+
+// MonthPeriod は月の開始日が startDay の場合に、t を含む月次期間 [start, end) を返します
+// 例: startDay が 25 のとき、3月10日は 2月25日〜3月25日 の期間に含まれます
+func MonthPeriod(t time.Time, startDay int) (time.Time, time.Time) {
+	if startDay < 1 || startDay > 28 {
+		startDay = 1
+	}
+
+	start := time.Date(t.Year(), t.Month(), startDay, 0, 0, 0, 0, t.Location())
+	if t.Before(start) {
+		start = start.AddDate(0, -1, 0)
+	}
+	return start, start.AddDate(0, 1, 0)
+}
+
+// WeekPeriod は週の開始曜日が startDay の場合に、t を含む週次期間 [start, end) を返します
+func WeekPeriod(t time.Time, startDay time.Weekday) (time.Time, time.Time) {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	offset := (int(day.Weekday()) - int(startDay) + 7) % 7
+	start := day.AddDate(0, 0, -offset)
+	return start, start.AddDate(0, 0, 7)
+}
@@ -0,0 +1,34 @@
+package utils
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// supportAdmins はサポート担当の管理者のユーザーID
+var supportAdmins = map[uint]bool{}
+
+// InitSupportAdmins は環境変数 SUPPORT_ADMIN_USER_IDS（カンマ区切りのユーザーID）からサポート担当の管理者を初期化します
+// 登録時に確認しないメールアドレスではなくユーザーIDで指定するため、アドレスを先に登録しても管理者になれません
+// 未設定の場合は誰もサポートでのなりすましを利用できません
+func InitSupportAdmins() {
+	supportAdmins = map[uint]bool{}
+	for _, value := range strings.Split(os.Getenv("SUPPORT_ADMIN_USER_IDS"), ",") {
+		if value = strings.TrimSpace(value); value == "" {
+			continue
+		}
+		id, err := strconv.ParseUint(value, 10, 32)
+		if err != nil || id == 0 {
+			log.Printf("Warning: invalid value %q in SUPPORT_ADMIN_USER_IDS, ignoring", value)
+			continue
+		}
+		supportAdmins[uint(id)] = true
+	}
+}
+
+// IsSupportAdmin はユーザーがサポート担当の管理者かどうかを返します
+func IsSupportAdmin(userID uint) bool {
+	return supportAdmins[userID]
+}
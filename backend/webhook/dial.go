@@ -0,0 +1,71 @@
+package webhook
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+)
+
+// ErrForbiddenAddress はWebhookの送信先が内部アドレスの場合のエラー
+var ErrForbiddenAddress = errors.New("webhook destination is not a public address")
+
+// blockedPrefixes は net/netip の判定に含まれない、送信先として許可しないアドレス範囲
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),     // このネットワーク
+	netip.MustParsePrefix("100.64.0.0/10"), // キャリアグレードNAT
+	netip.MustParsePrefix("192.0.0.0/24"),  // IETFプロトコル割り当て
+	netip.MustParsePrefix("198.18.0.0/15"), // ベンチマーク用
+	netip.MustParsePrefix("240.0.0.0/4"),   // 予約済み・ブロードキャスト
+	netip.MustParsePrefix("64:ff9b::/96"),  // NAT64（IPv4の内部アドレスに変換されうる）
+}
+
+// IsPublicAddress はアドレスがWebhookの送信先として許可するインターネット上のアドレスかどうかを返します
+// ループバック・プライベート・リンクローカル（クラウドのメタデータサービスなど）・マルチキャストは許可しません
+func IsPublicAddress(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsValid() || addr.IsUnspecified() || addr.IsLoopback() || addr.IsPrivate() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() || addr.IsInterfaceLocalMulticast() || addr.IsMulticast() {
+		return false
+	}
+	for _, prefix := range blockedPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// checkDialAddress は名前解決後の接続先アドレスを接続の直前に確認します
+// 登録時のURLの確認だけでは、内部アドレスに解決されるホスト名やDNSの応答の変更を防げないため
+func checkDialAddress(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil || !IsPublicAddress(addr) {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, host)
+	}
+	return nil
+}
+
+// newClient はWebhookの送信に使うHTTPクライアントを作成します
+// 内部アドレスへの接続を拒否し、リダイレクトには従いません（3xx は送信の失敗として扱います）
+func newClient() *http.Client {
+	dialer := &net.Dialer{Timeout: httpTimeout, Control: checkDialAddress}
+	return &http.Client{
+		Timeout: httpTimeout,
+		Transport: &http.Transport{
+			// 環境変数のプロキシを経由すると接続先を確認できないため使わない
+			Proxy:               nil,
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: httpTimeout,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
@@ -0,0 +1,127 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ito-system/clear-up-share/backend/database"
+	"github.com/ito-system/clear-up-share/backend/models"
+)
+
+const (
+	pollInterval = 5 * time.Second  // 送信待ちの配信を確認する間隔
+	batchSize    = 20               // 1回の確認で送信する配信の最大数
+	maxAttempts  = 5                // 配信を諦めるまでの試行回数
+	httpTimeout  = 10 * time.Second // 1回の送信のタイムアウト
+)
+
+var client = newClient()
+
+// Sign はペイロードのHMAC-SHA256署名を "sha256=<hex>" の形式で返します
+// 受信側は X-ClearUp-Signature ヘッダーと同じ値を計算して検証します
+func Sign(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// StartWorker は送信待ちのWebhook配信を定期的に送信するワーカーを起動します
+func StartWorker() {
+	go func() {
+		ticker := time.NewTicker(pollInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			deliverPending()
+		}
+	}()
+}
+
+// deliverPending は送信時刻を過ぎた配信をまとめて送信します
+func deliverPending() {
+	var deliveries []models.WebhookDelivery
+	if err := database.DB.Preload("Webhook").
+		Where("delivered_at IS NULL AND failed_at IS NULL AND next_attempt_at <= ?", time.Now()).
+		Order("next_attempt_at").
+		Limit(batchSize).
+		Find(&deliveries).Error; err != nil {
+		log.Printf("Failed to fetch webhook deliveries: %v", err)
+		return
+	}
+
+	for _, delivery := range deliveries {
+		deliver(delivery)
+	}
+}
+
+// deliver は配信を1回試行し、結果に応じて配信済み・再試行・失敗として記録します
+func deliver(delivery models.WebhookDelivery) {
+	now := time.Now()
+	updates := map[string]interface{}{
+		"attempts": delivery.Attempts + 1,
+	}
+
+	// Webhookが削除・無効化されている場合は送信しない
+	if delivery.Webhook.ID == 0 || !delivery.Webhook.Active {
+		updates["failed_at"] = now
+		updates["last_error"] = "webhook is deleted or inactive"
+		saveResult(delivery, updates)
+		return
+	}
+
+	status, err := send(delivery)
+	updates["last_status"] = status
+	if err == nil {
+		updates["delivered_at"] = now
+		updates["last_error"] = ""
+		saveResult(delivery, updates)
+		return
+	}
+
+	// 失敗した場合は指数バックオフで再試行（上限に達したら諦める）
+	updates["last_error"] = err.Error()
+	if delivery.Attempts+1 >= maxAttempts {
+		updates["failed_at"] = now
+	} else {
+		updates["next_attempt_at"] = now.Add(time.Minute << delivery.Attempts)
+	}
+	saveResult(delivery, updates)
+}
+
+// send は署名付きのJSONペイロードをWebhookのURLへPOSTします
+func send(delivery models.WebhookDelivery) (int, error) {
+	payload := []byte(delivery.Payload)
+	req, err := http.NewRequest(http.MethodPost, delivery.Webhook.URL, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "ClearUp-Webhook")
+	req.Header.Set("X-ClearUp-Event", delivery.Event)
+	req.Header.Set("X-ClearUp-Delivery", strconv.FormatUint(uint64(delivery.ID), 10))
+	req.Header.Set("X-ClearUp-Signature", Sign(delivery.Webhook.Secret, payload))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// saveResult は配信の試行結果を保存します
+func saveResult(delivery models.WebhookDelivery, updates map[string]interface{}) {
+	if err := database.DB.Model(&models.WebhookDelivery{}).Where("id = ?", delivery.ID).Updates(updates).Error; err != nil {
+		log.Printf("Failed to update webhook delivery %d: %v", delivery.ID, err)
+	}
+}